@@ -0,0 +1,446 @@
+package config
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"github.com/jackc/pgx"
+	"gopkg.in/yaml.v3"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const redacted = "REDACTED"
+
+const (
+	BackendPostgres = "postgres"
+	BackendMemory   = "memory"
+	BackendSQLite   = "sqlite"
+)
+
+type Config struct {
+	Server   ServerConfig   `yaml:"server"`
+	Database DatabaseConfig `yaml:"database"`
+	Log      LogConfig      `yaml:"log"`
+	Rates    RatesConfig    `yaml:"rates"`
+	Funds    FundsConfig    `yaml:"funds"`
+	Tracing  TracingConfig  `yaml:"tracing"`
+}
+
+type ServerConfig struct {
+	Host            string        `yaml:"host"`
+	Port            int           `yaml:"port"`
+	ReadTimeout     time.Duration `yaml:"read_timeout"`
+	WriteTimeout    time.Duration `yaml:"write_timeout"`
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+}
+
+// DSN takes precedence over the separate connection settings, memory:// selects the in-memory backend
+// and sqlite:///path/to/file.db the SQLite one
+
+type DatabaseConfig struct {
+	DSN              string        `yaml:"dsn"`
+	Host             string        `yaml:"host"`
+	Port             int           `yaml:"port"`
+	Name             string        `yaml:"name"`
+	User             string        `yaml:"user"`
+	Password         string        `yaml:"password"`
+	MaxConnections   int           `yaml:"max_connections"`
+	OperationTimeout time.Duration `yaml:"operation_timeout"`
+	AutoMigrate      bool          `yaml:"auto_migrate"`
+}
+
+type LogConfig struct {
+	File    string `yaml:"file"`
+	Verbose bool   `yaml:"verbose"`
+}
+
+type RatesConfig struct {
+	Provider        string        `yaml:"provider"`
+	URL             string        `yaml:"url"`
+	APIKey          string        `yaml:"api_key"`
+	File            string        `yaml:"file"`
+	TTL             time.Duration `yaml:"ttl"`
+	MaxStale        time.Duration `yaml:"max_stale"`
+	RefreshInterval time.Duration `yaml:"refresh_interval"`
+	QuoteTTL        time.Duration `yaml:"quote_ttl"`
+	QuoteRateMaxAge time.Duration `yaml:"quote_max_rate_age"`
+}
+
+type FundsConfig struct {
+	IdempotencyRetention      time.Duration `yaml:"idempotency_retention"`
+	IdempotencyExpiryInterval time.Duration `yaml:"idempotency_expiry_interval"`
+	HoldTTL                   time.Duration `yaml:"hold_ttl"`
+	HoldExpiryInterval        time.Duration `yaml:"hold_expiry_interval"`
+}
+
+// spans are written by the stdout or the file exporter or sent to an OTLP/HTTP collector,
+// none only propagates the trace context
+
+type TracingConfig struct {
+	Exporter    string  `yaml:"exporter"`
+	File        string  `yaml:"file"`
+	Endpoint    string  `yaml:"endpoint"`
+	ServiceName string  `yaml:"service_name"`
+	SampleRatio float64 `yaml:"sample_ratio"`
+}
+
+func Default() Config {
+	return Config{
+		Server: ServerConfig{
+			Port:            5000,
+			ReadTimeout:     10 * time.Second,
+			WriteTimeout:    10 * time.Second,
+			ShutdownTimeout: 15 * time.Second,
+		},
+		Database: DatabaseConfig{
+			Host:             "localhost",
+			Port:             5432,
+			Name:             "user_balance_service",
+			User:             "docker",
+			Password:         "docker",
+			MaxConnections:   20,
+			OperationTimeout: 5 * time.Second,
+			AutoMigrate:      true,
+		},
+		Log: LogConfig{
+			File: "log.log",
+		},
+		Rates: RatesConfig{
+			Provider:        "http",
+			URL:             "http://api.exchangeratesapi.io/latest",
+			TTL:             time.Hour,
+			MaxStale:        24 * time.Hour,
+			RefreshInterval: time.Hour,
+			QuoteTTL:        30 * time.Second,
+			QuoteRateMaxAge: time.Hour,
+		},
+		Funds: FundsConfig{
+			IdempotencyRetention:      24 * time.Hour,
+			IdempotencyExpiryInterval: time.Hour,
+			HoldTTL:                   7 * 24 * time.Hour,
+			HoldExpiryInterval:        time.Minute,
+		},
+		Tracing: TracingConfig{
+			Exporter:    "none",
+			Endpoint:    "http://localhost:4318",
+			ServiceName: "userBalanceService",
+			SampleRatio: 1,
+		},
+	}
+}
+
+// setting binds a config field to its environment variable and flag, flags are named by the yaml path of the field
+
+type setting struct {
+	key    string
+	env    string
+	usage  string
+	field  func(config *Config) interface{}
+	secret bool
+}
+
+var settings = []setting{
+	{"server.host", "SERVER_HOST", "host to listen on", func(c *Config) interface{} { return &c.Server.Host }, false},
+	{"server.port", "SERVER_PORT", "port to listen on", func(c *Config) interface{} { return &c.Server.Port }, false},
+	{"server.read_timeout", "SERVER_READ_TIMEOUT", "timeout of reading a request",
+		func(c *Config) interface{} { return &c.Server.ReadTimeout }, false},
+	{"server.write_timeout", "SERVER_WRITE_TIMEOUT", "timeout of writing an answer",
+		func(c *Config) interface{} { return &c.Server.WriteTimeout }, false},
+	{"server.shutdown_timeout", "SERVER_SHUTDOWN_TIMEOUT", "time requests in flight are waited for on shutdown",
+		func(c *Config) interface{} { return &c.Server.ShutdownTimeout }, false},
+	{"database.dsn", "POSTGRES_DSN", "PostgreSQL connection string, memory:// or sqlite:///path, overrides the other database settings",
+		func(c *Config) interface{} { return &c.Database.DSN }, false},
+	{"database.host", "DB_HOST", "database host", func(c *Config) interface{} { return &c.Database.Host }, false},
+	{"database.port", "DB_PORT", "database port", func(c *Config) interface{} { return &c.Database.Port }, false},
+	{"database.name", "DB_NAME", "database name", func(c *Config) interface{} { return &c.Database.Name }, false},
+	{"database.user", "DB_USER", "database user", func(c *Config) interface{} { return &c.Database.User }, false},
+	{"database.password", "DB_PASSWORD", "database password", func(c *Config) interface{} { return &c.Database.Password }, true},
+	{"database.max_connections", "DB_MAX_CONNECTIONS", "size of the connection pool",
+		func(c *Config) interface{} { return &c.Database.MaxConnections }, false},
+	{"database.operation_timeout", "DB_OPERATION_TIMEOUT", "deadline of every database operation",
+		func(c *Config) interface{} { return &c.Database.OperationTimeout }, false},
+	{"database.auto_migrate", "DB_AUTO_MIGRATE", "apply pending migrations on start",
+		func(c *Config) interface{} { return &c.Database.AutoMigrate }, false},
+	{"log.file", "LOG_FILE", "log file", func(c *Config) interface{} { return &c.Log.File }, false},
+	{"log.verbose", "LOG_VERBOSE", "also log to stdout", func(c *Config) interface{} { return &c.Log.Verbose }, false},
+	{"rates.provider", "RATES_PROVIDER", "rates provider: http or file", func(c *Config) interface{} { return &c.Rates.Provider }, false},
+	{"rates.url", "RATES_URL", "address of the rates API", func(c *Config) interface{} { return &c.Rates.URL }, false},
+	{"rates.api_key", "RATES_API_KEY", "access key of the rates API", func(c *Config) interface{} { return &c.Rates.APIKey }, true},
+	{"rates.file", "RATES_FILE", "JSON file with fixed rates", func(c *Config) interface{} { return &c.Rates.File }, false},
+	{"rates.ttl", "RATES_TTL", "time rates are cached for", func(c *Config) interface{} { return &c.Rates.TTL }, false},
+	{"rates.max_stale", "RATES_MAX_STALE", "time stale rates are answered for while they are refreshed",
+		func(c *Config) interface{} { return &c.Rates.MaxStale }, false},
+	{"rates.refresh_interval", "RATES_REFRESH_INTERVAL", "interval of storing rate snapshots",
+		func(c *Config) interface{} { return &c.Rates.RefreshInterval }, false},
+	{"rates.quote_ttl", "QUOTE_TTL", "time a quote locks its rate for", func(c *Config) interface{} { return &c.Rates.QuoteTTL }, false},
+	{"rates.quote_max_rate_age", "QUOTE_MAX_RATE_AGE", "age of the oldest rate a quote is priced at",
+		func(c *Config) interface{} { return &c.Rates.QuoteRateMaxAge }, false},
+	{"funds.idempotency_retention", "IDEMPOTENCY_RETENTION", "time idempotency keys are kept for",
+		func(c *Config) interface{} { return &c.Funds.IdempotencyRetention }, false},
+	{"funds.idempotency_expiry_interval", "IDEMPOTENCY_EXPIRY_INTERVAL", "interval of forgetting old idempotency keys",
+		func(c *Config) interface{} { return &c.Funds.IdempotencyExpiryInterval }, false},
+	{"funds.hold_ttl", "HOLD_TTL", "default lifetime of holds", func(c *Config) interface{} { return &c.Funds.HoldTTL }, false},
+	{"funds.hold_expiry_interval", "HOLD_EXPIRY_INTERVAL", "interval of releasing expired holds",
+		func(c *Config) interface{} { return &c.Funds.HoldExpiryInterval }, false},
+	{"tracing.exporter", "TRACING_EXPORTER", "exporter of spans: none, stdout, file or otlp",
+		func(c *Config) interface{} { return &c.Tracing.Exporter }, false},
+	{"tracing.file", "TRACING_FILE", "file spans are appended to", func(c *Config) interface{} { return &c.Tracing.File }, false},
+	{"tracing.endpoint", "TRACING_ENDPOINT", "address of the OTLP/HTTP collector spans are sent to",
+		func(c *Config) interface{} { return &c.Tracing.Endpoint }, false},
+	{"tracing.service_name", "TRACING_SERVICE_NAME", "service name of the spans",
+		func(c *Config) interface{} { return &c.Tracing.ServiceName }, false},
+	{"tracing.sample_ratio", "TRACING_SAMPLE_RATIO", "share of traces started by the service that are sampled",
+		func(c *Config) interface{} { return &c.Tracing.SampleRatio }, false},
+}
+
+// Options are the command line options that aren't config settings, Args are the arguments
+// left after the flags, like a subcommand
+
+type Options struct {
+	File        string
+	PrintConfig bool
+	Args        []string
+}
+
+// loads the defaults, then the config file, then environment variables and then flags, every source overrides
+// the previous ones. The config file is set by the -config flag or the CONFIG_FILE environment variable
+
+func Load(args []string, lookupEnv func(key string) (string, bool)) (Config, Options, error) {
+	config := Default()
+	var options Options
+
+	flags := flag.NewFlagSet("userBalanceService", flag.ContinueOnError)
+	flags.StringVar(&options.File, "config", "", "YAML config file")
+	flags.BoolVar(&options.PrintConfig, "print-config", false, "print the effective config and exit")
+	flagValues := make(map[string]*flagValue)
+	for _, s := range settings {
+		flagValues[s.key] = &flagValue{}
+		flags.Var(flagValues[s.key], s.key, s.usage+" ("+s.env+")")
+	}
+	err := flags.Parse(args)
+	if err != nil {
+		return config, options, err
+	}
+	options.Args = flags.Args()
+	if options.File == "" {
+		options.File, _ = lookupEnv("CONFIG_FILE")
+	}
+
+	if options.File != "" {
+		data, err := ioutil.ReadFile(options.File)
+		if err != nil {
+			return config, options, fmt.Errorf("Failed to read config file: %v", err)
+		}
+		err = decodeFile(data, &config)
+		if err != nil {
+			return config, options, fmt.Errorf("Error parsing config file %s: %v", options.File, err)
+		}
+	}
+	for _, s := range settings {
+		value, ok := lookupEnv(s.env)
+		if !ok {
+			continue
+		}
+		err = setField(s.field(&config), value)
+		if err != nil {
+			return config, options, fmt.Errorf("bad %s: %v", s.env, err)
+		}
+	}
+	for _, s := range settings {
+		if !flagValues[s.key].set {
+			continue
+		}
+		err = setField(s.field(&config), flagValues[s.key].value)
+		if err != nil {
+			return config, options, fmt.Errorf("bad -%s: %v", s.key, err)
+		}
+	}
+	return config, options, config.Validate()
+}
+
+func decodeFile(data []byte, config *Config) error {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	err := decoder.Decode(config)
+	if err == io.EOF {
+		return nil
+	}
+	return err
+}
+
+func (config *Config) Validate() error {
+	if config.Server.Port <= 0 || config.Server.Port > 65535 {
+		return fmt.Errorf("server.port must be between 1 and 65535")
+	}
+	if config.Server.ReadTimeout <= 0 || config.Server.WriteTimeout <= 0 || config.Server.ShutdownTimeout <= 0 {
+		return fmt.Errorf("server timeouts must be positive")
+	}
+	switch {
+	case config.Backend() == BackendMemory:
+		// the in-memory backend has no connection settings
+	case config.Backend() == BackendSQLite:
+		_, err := config.SQLitePath()
+		if err != nil {
+			return err
+		}
+	case config.Database.DSN != "":
+		_, err := pgx.ParseConnectionString(config.Database.DSN)
+		if err != nil {
+			return fmt.Errorf("bad database.dsn: %v", err)
+		}
+	case config.Database.Host == "" || config.Database.Name == "" || config.Database.User == "":
+		return fmt.Errorf("database.dsn or database.host, database.name and database.user must be set")
+	}
+	if config.Database.Port <= 0 || config.Database.Port > 65535 {
+		return fmt.Errorf("database.port must be between 1 and 65535")
+	}
+	if config.Database.MaxConnections < 2 {
+		return fmt.Errorf("database.max_connections must be at least 2")
+	}
+	if config.Log.File == "" {
+		return fmt.Errorf("log.file must be set")
+	}
+	switch config.Rates.Provider {
+	case "http":
+		if config.Rates.URL == "" {
+			return fmt.Errorf("rates.url must be set for the http rates provider")
+		}
+		if config.Rates.QuoteRateMaxAge < config.Rates.TTL {
+			return fmt.Errorf("rates.quote_max_rate_age can't be shorter than rates.ttl, the rates are cached for that long")
+		}
+	case "file":
+		if config.Rates.File == "" {
+			return fmt.Errorf("rates.file must be set for the file rates provider")
+		}
+	default:
+		return fmt.Errorf("unknown rates.provider %q", config.Rates.Provider)
+	}
+	switch config.Tracing.Exporter {
+	case "none", "stdout":
+	case "file":
+		if config.Tracing.File == "" {
+			return fmt.Errorf("tracing.file must be set for the file exporter")
+		}
+	case "otlp":
+		endpoint, err := url.Parse(config.Tracing.Endpoint)
+		if err != nil || endpoint.Scheme != "http" && endpoint.Scheme != "https" || endpoint.Host == "" {
+			return fmt.Errorf("tracing.endpoint must be an http or https address for the otlp exporter")
+		}
+	default:
+		return fmt.Errorf("unknown tracing.exporter %q", config.Tracing.Exporter)
+	}
+	if config.Tracing.SampleRatio < 0 || config.Tracing.SampleRatio > 1 {
+		return fmt.Errorf("tracing.sample_ratio must be between 0 and 1")
+	}
+	if config.Rates.MaxStale < 0 {
+		return fmt.Errorf("rates.max_stale can't be negative")
+	}
+	for name, duration := range map[string]time.Duration{
+		"database.operation_timeout":        config.Database.OperationTimeout,
+		"rates.ttl":                         config.Rates.TTL,
+		"rates.refresh_interval":            config.Rates.RefreshInterval,
+		"rates.quote_ttl":                   config.Rates.QuoteTTL,
+		"rates.quote_max_rate_age":          config.Rates.QuoteRateMaxAge,
+		"funds.idempotency_retention":       config.Funds.IdempotencyRetention,
+		"funds.idempotency_expiry_interval": config.Funds.IdempotencyExpiryInterval,
+		"funds.hold_ttl":                    config.Funds.HoldTTL,
+		"funds.hold_expiry_interval":        config.Funds.HoldExpiryInterval,
+	} {
+		if duration <= 0 {
+			return fmt.Errorf("%s must be positive", name)
+		}
+	}
+	return nil
+}
+
+func (config *Config) Address() string {
+	return fmt.Sprintf("%s:%d", config.Server.Host, config.Server.Port)
+}
+
+// the storage backend is chosen by the scheme of the DSN, PostgreSQL is used unless it is memory or sqlite
+
+func (config *Config) Backend() string {
+	for _, backend := range []string{BackendMemory, BackendSQLite} {
+		if strings.HasPrefix(config.Database.DSN, backend+"://") {
+			return backend
+		}
+	}
+	return BackendPostgres
+}
+
+// the database file of a sqlite:// DSN, sqlite:///var/lib/balances.db is absolute and
+// sqlite://balances.db is relative to the working directory
+
+func (config *Config) SQLitePath() (string, error) {
+	dsn, err := url.Parse(config.Database.DSN)
+	if err != nil {
+		return "", fmt.Errorf("bad database.dsn: %v", err)
+	}
+	path := dsn.Host + dsn.Path
+	if path == "" || path == ":memory:" {
+		return "", fmt.Errorf("database.dsn must name a SQLite database file")
+	}
+	return path, nil
+}
+
+func (config *Config) ConnConfig() (pgx.ConnConfig, error) {
+	if config.Database.DSN != "" {
+		return pgx.ParseConnectionString(config.Database.DSN)
+	}
+	return pgx.ConnConfig{
+		Host:     config.Database.Host,
+		Port:     uint16(config.Database.Port),
+		Database: config.Database.Name,
+		User:     config.Database.User,
+		Password: config.Database.Password,
+	}, nil
+}
+
+var dsnPassword = regexp.MustCompile(`(password\s*=\s*)('(\\.|[^'])*'|\S+)`)
+
+// returns a copy of the config with secrets replaced, the password in the DSN too
+
+func (config Config) Redacted() Config {
+	for _, s := range settings {
+		if !s.secret {
+			continue
+		}
+		value, ok := s.field(&config).(*string)
+		if ok && *value != "" {
+			*value = redacted
+		}
+	}
+	if config.Database.DSN != "" {
+		config.Database.DSN = redactDSN(config.Database.DSN)
+	}
+	return config
+}
+
+func redactDSN(dsn string) string {
+	if uri, err := url.Parse(dsn); err == nil && uri.Scheme != "" {
+		if _, ok := uri.User.Password(); ok {
+			uri.User = url.UserPassword(uri.User.Username(), redacted)
+		}
+		if query := uri.Query(); query.Get("password") != "" {
+			query.Set("password", redacted)
+			uri.RawQuery = query.Encode()
+		}
+		return uri.String()
+	}
+	return dsnPassword.ReplaceAllString(dsn, "${1}"+redacted)
+}
+
+// the effective config as YAML, in the format of the config file
+
+func (config Config) String() string {
+	data, err := yaml.Marshal(config)
+	if err != nil {
+		return err.Error()
+	}
+	return string(data)
+}
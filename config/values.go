@@ -0,0 +1,43 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// flagValue keeps the raw flag value, it is applied to the config after the environment variables
+
+type flagValue struct {
+	value string
+	set   bool
+}
+
+func (value *flagValue) String() string {
+	return value.value
+}
+
+func (value *flagValue) Set(raw string) error {
+	value.value = raw
+	value.set = true
+	return nil
+}
+
+func setField(field interface{}, value string) error {
+	var err error
+	switch field := field.(type) {
+	case *string:
+		*field = value
+	case *int:
+		*field, err = strconv.Atoi(value)
+	case *bool:
+		*field, err = strconv.ParseBool(value)
+	case *float64:
+		*field, err = strconv.ParseFloat(value, 64)
+	case *time.Duration:
+		*field, err = time.ParseDuration(value)
+	default:
+		err = fmt.Errorf("unsupported setting type %T", field)
+	}
+	return err
+}
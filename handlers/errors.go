@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/logger"
+	"github.com/saskamegaprogrammist/userBalanceService/models"
+	"github.com/saskamegaprogrammist/userBalanceService/useCases"
+	"github.com/saskamegaprogrammist/userBalanceService/utils"
+	"net/http"
+)
+
+var errNotAcceptable = errors.New("export is available as text/csv or application/x-ndjson")
+
+type errorCode struct {
+	kind   error
+	code   string
+	status string
+}
+
+// codes answered in models.RequestError, the first matching kind wins
+// so specific errors go before the kinds they wrap
+
+var errorCodes = []errorCode{
+	{models.ErrInvalidUser, "invalid_user", "Bad Request"},
+	{models.ErrInvalidMoney, "invalid_amount", "Bad Request"},
+	{models.ErrInvalidCursor, "invalid_cursor", "Bad Request"},
+	{useCases.ErrTransactionNotFound, "transaction_not_found", "Not Found"},
+	{useCases.ErrHoldNotFound, "hold_not_found", "Not Found"},
+	{useCases.ErrQuoteNotFound, "quote_not_found", "Not Found"},
+	{useCases.ErrIdempotencyConflict, "idempotency_conflict", "Conflict"},
+	{useCases.ErrAlreadyReversed, "already_reversed", "Conflict"},
+	{useCases.ErrHoldNotActive, "hold_not_active", "Conflict"},
+	{useCases.ErrQuoteNotActive, "quote_not_active", "Conflict"},
+	{errNotAcceptable, "not_acceptable", "Not Acceptable"},
+	{models.ErrValidation, "validation_error", "Bad Request"},
+	{models.ErrInsufficientFunds, "insufficient_funds", "Payment Required"},
+	{models.ErrNotFound, "not_found", "Not Found"},
+	{models.ErrConflict, "conflict", "Conflict"},
+	{useCases.ErrRatesUnavailable, "rates_unavailable", "Service Unavailable"},
+	{context.DeadlineExceeded, "timeout", "Gateway Timeout"},
+}
+
+// answers err with the status and code of its kind, unknown errors are logged and answered with 500
+
+func answerError(writer http.ResponseWriter, err error) {
+	for _, known := range errorCodes {
+		if errors.Is(err, known.kind) {
+			utils.CreateErrorAnswerJson(writer, utils.StatusCode(known.status),
+				models.RequestError{Code: known.code, Message: err.Error()})
+			return
+		}
+	}
+	logger.Error(err)
+	utils.CreateErrorAnswerJson(writer, utils.StatusCode("Internal Server Error"),
+		models.RequestError{Code: "internal_error", Message: err.Error()})
+}
+
+// invalid amounts in the body are validation errors, other decoding failures are answered with 500
+
+func decodeError(err error) error {
+	if errors.Is(err, models.ErrValidation) {
+		return err
+	}
+	return fmt.Errorf("Error unmarshaling json: %v", err.Error())
+}
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/saskamegaprogrammist/userBalanceService/models"
+	"github.com/saskamegaprogrammist/userBalanceService/useCases"
+	"github.com/steinfletcher/apitest"
+	jsonpath "github.com/steinfletcher/apitest-jsonpath"
+	"net/http"
+	"testing"
+)
+
+func TestAnswerError(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		status  int
+		code    string
+		message string
+	}{
+		{"InvalidUser", models.ErrInvalidUser, http.StatusBadRequest, "invalid_user", "incorrect user id"},
+		{"InvalidAmount", fmt.Errorf("bad min_sum query param: %w", models.ErrInvalidMoney), http.StatusBadRequest,
+			"invalid_amount", "bad min_sum query param: invalid amount"},
+		{"Validation", models.Errorf(models.ErrValidation, "sum must be positive"), http.StatusBadRequest,
+			"validation_error", "sum must be positive"},
+		{"InsufficientFunds", models.Errorf(models.ErrInsufficientFunds, "you don't have enough funds"),
+			http.StatusPaymentRequired, "insufficient_funds", "you don't have enough funds"},
+		{"HoldNotFound", useCases.ErrHoldNotFound, http.StatusNotFound, "hold_not_found", "this hold doesn't exist"},
+		{"NotFound", models.Errorf(models.ErrNotFound, "this wallet doesn't exist"), http.StatusNotFound,
+			"not_found", "this wallet doesn't exist"},
+		{"QuoteNotActive", fmt.Errorf("%w: quote has expired", useCases.ErrQuoteNotActive), http.StatusConflict,
+			"quote_not_active", "quote can't be used: quote has expired"},
+		{"IdempotencyConflict", useCases.ErrIdempotencyConflict, http.StatusConflict, "idempotency_conflict",
+			"idempotency key was already used with a different request"},
+		{"Conflict", models.Errorf(models.ErrConflict, "wallet is locked"), http.StatusConflict, "conflict",
+			"wallet is locked"},
+		{"Timeout", fmt.Errorf("Failed to get balance: %w", context.DeadlineExceeded), http.StatusGatewayTimeout,
+			"timeout", "Failed to get balance: context deadline exceeded"},
+		{"Internal", errors.New("db error"), http.StatusInternalServerError, "internal_error", "db error"},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			apitest.New(test.name).
+				HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+					answerError(writer, test.err)
+				}).
+				Method(http.MethodGet).
+				URL("/").
+				Expect(t).
+				Status(test.status).
+				Assert(jsonpath.Equal("$.code", test.code)).
+				Assert(jsonpath.Equal("$.message", test.message)).
+				End()
+		})
+	}
+}
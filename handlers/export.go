@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"github.com/google/logger"
+	easy_json "github.com/mailru/easyjson"
+	"github.com/saskamegaprogrammist/userBalanceService/models"
+	"github.com/saskamegaprogrammist/userBalanceService/utils"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var exportContentTypes = map[string]string{
+	"csv":    "text/csv",
+	"ndjson": "application/x-ndjson",
+}
+
+var exportColumns = []string{"id", "user_id", "user_from_id", "currency", "operation_type", "sum", "created", "reversed_id",
+	"currency_from", "sum_from", "rate"}
+
+// the format query param takes precedence over the Accept header, csv is used when neither selects a format
+
+func readExportFormat(req *http.Request) (string, error) {
+	format := req.URL.Query().Get("format")
+	if format != "" {
+		if _, ok := exportContentTypes[format]; !ok {
+			return "", models.Errorf(models.ErrValidation, "unknown export format %q", format)
+		}
+		return format, nil
+	}
+	accept := req.Header.Get("Accept")
+	if accept == "" {
+		return "csv", nil
+	}
+	for _, mediaRange := range strings.Split(accept, ",") {
+		mediaType := strings.TrimSpace(strings.Split(mediaRange, ";")[0])
+		switch mediaType {
+		case "*/*", "text/*", exportContentTypes["csv"]:
+			return "csv", nil
+		case "application/*", exportContentTypes["ndjson"]:
+			return "ndjson", nil
+		}
+	}
+	return "", errNotAcceptable
+}
+
+func (fh *FundsHandlers) Export(writer http.ResponseWriter, req *http.Request) {
+	format, err := readExportFormat(req)
+	if err != nil {
+		answerError(writer, err)
+		return
+	}
+	query := req.URL.Query()
+	filter, err := readTransactionsFilter(query)
+	if err != nil {
+		answerError(writer, err)
+		return
+	}
+	var newUserId models.UserId
+	err = easy_json.UnmarshalFromReader(req.Body, &newUserId)
+	if err != nil {
+		answerError(writer, decodeError(err))
+		return
+	}
+
+	export := newTransactionsExport(writer, format, newUserId.UserId)
+	err = fh.FundsUC.ExportTransactions(req.Context(), &newUserId, &filter, query.Get("since"), export.write)
+	if err != nil && export.started {
+		logger.Errorf("Export was interrupted: %v", err)
+		return
+	}
+	if err != nil {
+		answerError(writer, err)
+		return
+	}
+	err = export.finish()
+	if err != nil {
+		logger.Errorf("Error writing export: %v", err)
+	}
+}
+
+// transactionsExport writes the answer headers with the first transaction, so that errors found
+// before any transaction is read can still be answered with an error status. The write deadline of the
+// connection is moved forward with every batch, so that exports can outlast the write timeout of the server
+
+type transactionsExport struct {
+	writer     http.ResponseWriter
+	controller *http.ResponseController
+	format     string
+	userId     int
+	csv        *csv.Writer
+	started    bool
+	written    int
+}
+
+func newTransactionsExport(writer http.ResponseWriter, format string, userId int) *transactionsExport {
+	return &transactionsExport{writer: writer, controller: http.NewResponseController(writer), format: format, userId: userId}
+}
+
+// writers that can't change the deadline, like the recorders of tests, have none
+
+func (export *transactionsExport) extendDeadline() error {
+	err := export.controller.SetWriteDeadline(time.Now().Add(utils.EXPORT_BATCH_TIMEOUT))
+	if errors.Is(err, http.ErrNotSupported) {
+		return nil
+	}
+	return err
+}
+
+func (export *transactionsExport) start() error {
+	export.started = true
+	err := export.extendDeadline()
+	if err != nil {
+		return err
+	}
+	export.writer.Header().Set("Access-Control-Allow-Origin", "*")
+	export.writer.Header().Set("Content-Type", exportContentTypes[export.format])
+	export.writer.Header().Set("Content-Disposition",
+		fmt.Sprintf(`attachment; filename="transactions-%d.%s"`, export.userId, export.format))
+	export.writer.WriteHeader(utils.StatusCode("OK"))
+	if export.format == "csv" {
+		export.csv = csv.NewWriter(export.writer)
+		return export.csv.Write(exportColumns)
+	}
+	return nil
+}
+
+func (export *transactionsExport) write(tx *models.Transaction) error {
+	if !export.started {
+		err := export.start()
+		if err != nil {
+			return err
+		}
+	}
+	var err error
+	if export.format == "csv" {
+		sumFrom, rate := "", ""
+		if tx.CurrencyFrom != "" {
+			sumFrom, rate = tx.SumFrom.String(), strconv.FormatFloat(tx.Rate, 'f', -1, 64)
+		}
+		err = export.csv.Write([]string{strconv.Itoa(tx.Id), strconv.Itoa(tx.UserId), strconv.Itoa(tx.UserFromId), tx.Currency,
+			strconv.Itoa(tx.OperationType), tx.Sum.String(), tx.Created.Format(time.RFC3339Nano), strconv.Itoa(tx.ReversedId),
+			tx.CurrencyFrom, sumFrom, rate})
+	} else {
+		var line []byte
+		line, err = easy_json.Marshal(tx)
+		if err == nil {
+			_, err = export.writer.Write(append(line, '\n'))
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	export.written++
+	if export.written%utils.EXPORT_BATCH_SIZE == 0 {
+		return export.flush()
+	}
+	return nil
+}
+
+func (export *transactionsExport) flush() error {
+	if export.csv != nil {
+		export.csv.Flush()
+		err := export.csv.Error()
+		if err != nil {
+			return err
+		}
+	}
+	if flusher, ok := export.writer.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	return export.extendDeadline()
+}
+
+func (export *transactionsExport) finish() error {
+	if !export.started {
+		err := export.start()
+		if err != nil {
+			return err
+		}
+	}
+	return export.flush()
+}
@@ -0,0 +1,274 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/golang/mock/gomock"
+	"github.com/saskamegaprogrammist/userBalanceService/models"
+	"github.com/saskamegaprogrammist/userBalanceService/useCases"
+	"github.com/saskamegaprogrammist/userBalanceService/utils"
+	"github.com/steinfletcher/apitest"
+	jsonpath "github.com/steinfletcher/apitest-jsonpath"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func exportTransactions(ctx context.Context, user *models.UserId, filter *models.TransactionsFilter, since string,
+	each func(tx *models.Transaction) error) error {
+	for i := range testTransactions {
+		err := each(&testTransactions[i])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func assertLines(expected ...string) func(res *http.Response, req *http.Request) error {
+	return func(res *http.Response, req *http.Request) error {
+		body, err := ioutil.ReadAll(res.Body)
+		if err != nil {
+			return err
+		}
+		lines := strings.Split(strings.TrimSuffix(string(body), "\n"), "\n")
+		if len(lines) != len(expected) {
+			return fmt.Errorf("expected %d lines, got %d: %q", len(expected), len(lines), body)
+		}
+		for i, prefix := range expected {
+			if !strings.HasPrefix(lines[i], prefix) {
+				return fmt.Errorf("line %d %q doesn't start with %q", i, lines[i], prefix)
+			}
+		}
+		return nil
+	}
+}
+
+func TestExportTransactions(t *testing.T) {
+	t.Run("ExportCSV", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockUseCase := useCases.NewMockFundsUCInterface(ctrl)
+		mockUseCase.EXPECT().ExportTransactions(gomock.Any(), &testUserOne, &testQuery, since, gomock.Any()).DoAndReturn(exportTransactions)
+
+		fh.FundsUC = mockUseCase
+
+		jsonBody := fmt.Sprintf(`{"user": %v}`, testUserOne.UserId)
+
+		apitest.New("ExportCSV").
+			Handler(http.HandlerFunc(fh.Export)).
+			Method("Post").
+			URL(utils.GetAPIAddress("exportTransactions")).
+			Body(jsonBody).
+			Expect(t).
+			Status(http.StatusOK).
+			Header("Content-Type", "text/csv").
+			Header("Content-Disposition", `attachment; filename="transactions-1.csv"`).
+			Assert(assertLines("id,user_id,user_from_id,currency,operation_type,sum,created,reversed_id,currency_from,sum_from,rate",
+				"1,1,2,,0,10.00,", "2,2,1,,0,10.00,")).
+			End()
+	})
+
+	t.Run("ExportNDJSON", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockUseCase := useCases.NewMockFundsUCInterface(ctrl)
+		mockUseCase.EXPECT().ExportTransactions(gomock.Any(), &testUserOne, &testQuery, since, gomock.Any()).DoAndReturn(exportTransactions)
+
+		fh.FundsUC = mockUseCase
+
+		jsonBody := fmt.Sprintf(`{"user": %v}`, testUserOne.UserId)
+
+		apitest.New("ExportNDJSON").
+			Handler(http.HandlerFunc(fh.Export)).
+			Method("Post").
+			URL(utils.GetAPIAddress("exportTransactions")).
+			Header("Accept", "application/x-ndjson").
+			Body(jsonBody).
+			Expect(t).
+			Status(http.StatusOK).
+			Header("Content-Type", "application/x-ndjson").
+			Assert(assertLines(`{"id":1,"user_id":1,"user_from_id":2,`, `{"id":2,"user_id":2,"user_from_id":1,`)).
+			End()
+	})
+
+	t.Run("ExportFormatParam", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockUseCase := useCases.NewMockFundsUCInterface(ctrl)
+		mockUseCase.EXPECT().ExportTransactions(gomock.Any(), &testUserOne, &models.TransactionsFilter{Limit: limitInt, OperationTypes: []int{3}},
+			since, gomock.Any()).Return(nil)
+
+		fh.FundsUC = mockUseCase
+
+		jsonBody := fmt.Sprintf(`{"user": %v}`, testUserOne.UserId)
+
+		apitest.New("ExportFormatParam").
+			Handler(http.HandlerFunc(fh.Export)).
+			Method("Post").
+			URL(utils.GetAPIAddress("exportTransactions")).
+			Query("format", "ndjson").
+			Query("operation_type", "3").
+			Header("Accept", "text/csv").
+			Body(jsonBody).
+			Expect(t).
+			Status(http.StatusOK).
+			Header("Content-Type", "application/x-ndjson").
+			Body("").
+			End()
+	})
+
+	t.Run("ExportEmptyCSV", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockUseCase := useCases.NewMockFundsUCInterface(ctrl)
+		mockUseCase.EXPECT().ExportTransactions(gomock.Any(), &testUserOne, &testQuery, since, gomock.Any()).Return(nil)
+
+		fh.FundsUC = mockUseCase
+
+		jsonBody := fmt.Sprintf(`{"user": %v}`, testUserOne.UserId)
+
+		apitest.New("ExportEmptyCSV").
+			Handler(http.HandlerFunc(fh.Export)).
+			Method("Post").
+			URL(utils.GetAPIAddress("exportTransactions")).
+			Body(jsonBody).
+			Expect(t).
+			Status(http.StatusOK).
+			Assert(assertLines("id,user_id,user_from_id,currency,operation_type,sum,created,reversed_id,currency_from,sum_from,rate")).
+			End()
+	})
+
+	t.Run("ExportWrongFormat", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		fh.FundsUC = useCases.NewMockFundsUCInterface(ctrl)
+
+		apitest.New("ExportWrongFormat").
+			Handler(http.HandlerFunc(fh.Export)).
+			Method("Post").
+			URL(utils.GetAPIAddress("exportTransactions")).
+			Query("format", "xlsx").
+			Body(`{"user": 1}`).
+			Expect(t).
+			Status(http.StatusBadRequest).
+			Assert(jsonpath.Contains(`$.message`, "unknown export format")).
+			End()
+	})
+
+	t.Run("ExportNotAcceptable", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		fh.FundsUC = useCases.NewMockFundsUCInterface(ctrl)
+
+		apitest.New("ExportNotAcceptable").
+			Handler(http.HandlerFunc(fh.Export)).
+			Method("Post").
+			URL(utils.GetAPIAddress("exportTransactions")).
+			Header("Accept", "application/pdf").
+			Body(`{"user": 1}`).
+			Expect(t).
+			Status(http.StatusNotAcceptable).
+			End()
+	})
+
+	t.Run("ExportUserError", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockUseCase := useCases.NewMockFundsUCInterface(ctrl)
+		mockUseCase.EXPECT().ExportTransactions(gomock.Any(), &testUserWrong, &testQuery, since, gomock.Any()).
+			Return(models.Errorf(models.ErrValidation, "incorrect user id"))
+
+		fh.FundsUC = mockUseCase
+
+		jsonBody := fmt.Sprintf(`{"user": %v}`, testUserWrong.UserId)
+
+		apitest.New("ExportUserError").
+			Handler(http.HandlerFunc(fh.Export)).
+			Method("Post").
+			URL(utils.GetAPIAddress("exportTransactions")).
+			Body(jsonBody).
+			Expect(t).
+			Status(http.StatusBadRequest).
+			Assert(jsonpath.Contains(`$.message`, "incorrect user id")).
+			End()
+	})
+
+	t.Run("ExportDBError", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockUseCase := useCases.NewMockFundsUCInterface(ctrl)
+		mockUseCase.EXPECT().ExportTransactions(gomock.Any(), &testUserOne, &testQuery, since, gomock.Any()).
+			Return(errors.New("db error"))
+
+		fh.FundsUC = mockUseCase
+
+		jsonBody := fmt.Sprintf(`{"user": %v}`, testUserOne.UserId)
+
+		apitest.New("ExportDBError").
+			Handler(http.HandlerFunc(fh.Export)).
+			Method("Post").
+			URL(utils.GetAPIAddress("exportTransactions")).
+			Body(jsonBody).
+			Expect(t).
+			Status(http.StatusInternalServerError).
+			End()
+	})
+}
+
+// the export outlasts the write timeout of the server, every batch extends the deadline
+
+func TestExportWriteTimeout(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	batches := 4
+	mockUseCase := useCases.NewMockFundsUCInterface(ctrl)
+	mockUseCase.EXPECT().ExportTransactions(gomock.Any(), &testUserOne, &testQuery, since, gomock.Any()).DoAndReturn(
+		func(ctx context.Context, user *models.UserId, filter *models.TransactionsFilter, since string,
+			each func(tx *models.Transaction) error) error {
+			for i := 0; i < batches*utils.EXPORT_BATCH_SIZE; i++ {
+				if i%utils.EXPORT_BATCH_SIZE == 0 {
+					time.Sleep(40 * time.Millisecond)
+				}
+				err := each(&models.Transaction{Id: i + 1, UserId: testUserOne.UserId, Sum: models.MoneyFromUnits(1)})
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	fh.FundsUC = mockUseCase
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+		fh.Export(&utils.StatusRecorder{ResponseWriter: writer}, req)
+	}))
+	server.Config.WriteTimeout = 50 * time.Millisecond
+	server.Start()
+	defer server.Close()
+
+	res, err := http.Post(server.URL+utils.GetAPIAddress("exportTransactions"), "application/json",
+		strings.NewReader(fmt.Sprintf(`{"user": %v}`, testUserOne.UserId)))
+	if err != nil {
+		t.Fatalf("Export request failed: %v", err)
+	}
+	defer res.Body.Close()
+	body, err := ioutil.ReadAll(res.Body)
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, batches*utils.EXPORT_BATCH_SIZE+1, strings.Count(string(body), "\n"))
+}
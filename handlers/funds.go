@@ -1,192 +1,271 @@
 package handlers
 
 import (
+	"context"
 	"fmt"
-	"github.com/google/logger"
 	easy_json "github.com/mailru/easyjson"
 	"github.com/saskamegaprogrammist/userBalanceService/models"
 	"github.com/saskamegaprogrammist/userBalanceService/useCases"
 	"github.com/saskamegaprogrammist/userBalanceService/utils"
 	"net/http"
+	"net/url"
 	"strconv"
+	"time"
 )
 
 type FundsHandlers struct {
 	FundsUC useCases.FundsUCInterface
+	RatesUC useCases.RatesUCInterface
 }
 
 func (fh *FundsHandlers) Add(writer http.ResponseWriter, req *http.Request) {
 	var newTransaction models.Transaction
 	err := easy_json.UnmarshalFromReader(req.Body, &newTransaction)
 	if err != nil {
-		jsonError := fmt.Sprintf("Error unmarshaling json: %v", err.Error())
-		logger.Errorf(jsonError)
-		utils.CreateErrorAnswerJson(writer, utils.StatusCode("Internal Server Error"), models.CreateMessage(jsonError))
+		answerError(writer, decodeError(err))
 		return
 	}
-	badRequest, err := fh.FundsUC.Add(&newTransaction)
-	if badRequest {
-		utils.CreateErrorAnswerJson(writer, utils.StatusCode("Bad Request"), models.CreateMessage(err.Error()))
+	err = readIdempotencyKey(req, &newTransaction)
+	if err != nil {
+		answerError(writer, err)
 		return
 	}
+	err = fh.FundsUC.Add(req.Context(), &newTransaction)
 	if err != nil {
-		logger.Error(err)
-		utils.CreateErrorAnswerJson(writer, utils.StatusCode("Internal Server Error"), models.CreateMessage(err.Error()))
+		answerError(writer, err)
 		return
 	}
 
-	utils.CreateEmptyBodyAnswerJson(writer, utils.StatusCode("OK"))
+	utils.CreateAnswerTransactionJson(writer, utils.StatusCode("OK"), newTransaction)
 }
 
 func (fh *FundsHandlers) Withdraw(writer http.ResponseWriter, req *http.Request) {
 	var newTransaction models.Transaction
 	err := easy_json.UnmarshalFromReader(req.Body, &newTransaction)
 	if err != nil {
-		jsonError := fmt.Sprintf("Error unmarshaling json: %v", err.Error())
-		logger.Errorf(jsonError)
-		utils.CreateErrorAnswerJson(writer, utils.StatusCode("Internal Server Error"), models.CreateMessage(jsonError))
-		return
-	}
-	badRequest, lowFunds, err := fh.FundsUC.Withdraw(&newTransaction)
-	if badRequest {
-		utils.CreateErrorAnswerJson(writer, utils.StatusCode("Bad Request"), models.CreateMessage(err.Error()))
+		answerError(writer, decodeError(err))
 		return
 	}
-	if lowFunds {
-		utils.CreateErrorAnswerJson(writer, utils.StatusCode("Payment Required"), models.CreateMessage(err.Error()))
+	err = readIdempotencyKey(req, &newTransaction)
+	if err != nil {
+		answerError(writer, err)
 		return
 	}
+	err = fh.FundsUC.Withdraw(req.Context(), &newTransaction)
 	if err != nil {
-		logger.Error(err)
-		utils.CreateErrorAnswerJson(writer, utils.StatusCode("Internal Server Error"), models.CreateMessage(err.Error()))
+		answerError(writer, err)
 		return
 	}
-	utils.CreateEmptyBodyAnswerJson(writer, utils.StatusCode("OK"))
+	utils.CreateAnswerTransactionJson(writer, utils.StatusCode("OK"), newTransaction)
 }
 
 func (fh *FundsHandlers) GetBalance(writer http.ResponseWriter, req *http.Request) {
 	query := req.URL.Query()
 	currency := query.Get("currency")
-	var newBalance models.Balance
 	var newUserId models.UserId
 	err := easy_json.UnmarshalFromReader(req.Body, &newUserId)
 	if err != nil {
-		jsonError := fmt.Sprintf("Error unmarshaling json: %v", err.Error())
-		logger.Errorf(jsonError)
-		utils.CreateErrorAnswerJson(writer, utils.StatusCode("Internal Server Error"), models.CreateMessage(jsonError))
+		answerError(writer, decodeError(err))
 		return
 	}
-	newBalance.UserId = newUserId.UserId
-	newBalance.Currency = utils.CURRENCY
-	badRequest, err := fh.FundsUC.Get(&newBalance)
-	if badRequest {
-		utils.CreateErrorAnswerJson(writer, utils.StatusCode("Bad Request"), models.CreateMessage(err.Error()))
+	if asOf := query.Get("as_of"); asOf != "" {
+		fh.getBalancesAsOf(req.Context(), writer, &newUserId, asOf, currency)
 		return
 	}
+	if newUserId.Currency == "" {
+		fh.getAllBalances(req.Context(), writer, &newUserId, currency)
+		return
+	}
+
+	var newBalance models.Balance
+	newBalance.UserId = newUserId.UserId
+	newBalance.Currency = newUserId.Currency
+	err = fh.FundsUC.Get(req.Context(), &newBalance)
 	if err != nil {
-		logger.Error(err)
-		utils.CreateErrorAnswerJson(writer, utils.StatusCode("Internal Server Error"), models.CreateMessage(err.Error()))
+		answerError(writer, err)
 		return
 	}
 	if currency != "" {
-		httpClient := &http.Client{}
-		address := fmt.Sprintf("%s%s%s", utils.CURRENCY_API, utils.CURRENCY_API_BASE, currency)
-		request, err := http.NewRequest("GET", address, nil)
+		err = fh.RatesUC.Convert(req.Context(), &newBalance, currency)
 		if err != nil {
-			logger.Error(err)
-			utils.CreateErrorAnswerJson(writer, utils.StatusCode("Internal Server Error"), models.CreateMessage(err.Error()))
-			return
-		}
-		response, err := httpClient.Do(request)
-		if err != nil {
-			logger.Error(err)
-			utils.CreateErrorAnswerJson(writer, utils.StatusCode("Internal Server Error"), models.CreateMessage(err.Error()))
+			answerError(writer, err)
 			return
 		}
+	}
+	utils.CreateAnswerBalanceJson(writer, utils.StatusCode("OK"), newBalance)
+}
 
-		var newCurrency models.CurrencyAll
-		err = easy_json.UnmarshalFromReader(response.Body, &newCurrency)
-		if err != nil {
-			jsonError := fmt.Sprintf("Error unmarshaling json: %v", err.Error())
-			logger.Errorf(jsonError)
-			utils.CreateErrorAnswerJson(writer, utils.StatusCode("Internal Server Error"), models.CreateMessage(jsonError))
-			return
-		}
-		unmarshalledValue, err := newCurrency.GetRatesFieldValueByName(currency)
+func (fh *FundsHandlers) getAllBalances(ctx context.Context, writer http.ResponseWriter, user *models.UserId, currency string) {
+	balances, err := fh.FundsUC.GetAll(ctx, user)
+	if err != nil {
+		answerError(writer, err)
+		return
+	}
+	if !fh.convertBalances(ctx, writer, balances, currency) {
+		return
+	}
+	utils.CreateAnswerBalancesJson(writer, utils.StatusCode("OK"), balances)
+}
+
+// answers from the transactions log, a single wallet is returned when the body has a currency
+
+func (fh *FundsHandlers) getBalancesAsOf(ctx context.Context, writer http.ResponseWriter, user *models.UserId,
+	asOf string, currency string) {
+	asOfTime, err := time.Parse(time.RFC3339Nano, asOf)
+	if err != nil {
+		answerError(writer, models.Errorf(models.ErrValidation, "bad as_of query param"))
+		return
+	}
+	balances, err := fh.FundsUC.GetAsOf(ctx, user, asOfTime)
+	if err != nil {
+		answerError(writer, err)
+		return
+	}
+	if !fh.convertBalances(ctx, writer, balances, currency) {
+		return
+	}
+	if user.Currency != "" {
+		utils.CreateAnswerBalanceJson(writer, utils.StatusCode("OK"), balances[0])
+		return
+	}
+	utils.CreateAnswerBalancesJson(writer, utils.StatusCode("OK"), balances)
+}
+
+// converts balances to currency unless it is empty, answers with an error and returns false on failure
+
+func (fh *FundsHandlers) convertBalances(ctx context.Context, writer http.ResponseWriter, balances []models.Balance,
+	currency string) bool {
+	if currency == "" {
+		return true
+	}
+	for i := range balances {
+		err := fh.RatesUC.Convert(ctx, &balances[i], currency)
 		if err != nil {
-			logger.Errorf(err.Error())
-			utils.CreateErrorAnswerJson(writer, utils.StatusCode("Bad Request"), models.CreateMessage(err.Error()))
-			return
+			answerError(writer, err)
+			return false
 		}
-		newBalance.Balance *= unmarshalledValue
-		newBalance.Currency = currency
 	}
-	utils.CreateAnswerBalanceJson(writer, utils.StatusCode("OK"), newBalance)
+	return true
 }
 
 func (fh *FundsHandlers) Transfer(writer http.ResponseWriter, req *http.Request) {
 	var newTransaction models.Transaction
 	err := easy_json.UnmarshalFromReader(req.Body, &newTransaction)
 	if err != nil {
-		jsonError := fmt.Sprintf("Error unmarshaling json: %v", err.Error())
-		logger.Errorf(jsonError)
-		utils.CreateErrorAnswerJson(writer, utils.StatusCode("Internal Server Error"), models.CreateMessage(jsonError))
+		answerError(writer, decodeError(err))
 		return
 	}
-	badRequest, lowFunds, err := fh.FundsUC.Transfer(&newTransaction)
-	if badRequest {
-		utils.CreateErrorAnswerJson(writer, utils.StatusCode("Bad Request"), models.CreateMessage(err.Error()))
+	err = readIdempotencyKey(req, &newTransaction)
+	if err != nil {
+		answerError(writer, err)
 		return
 	}
-	if lowFunds {
-		utils.CreateErrorAnswerJson(writer, utils.StatusCode("Payment Required"), models.CreateMessage(err.Error()))
+	err = fh.FundsUC.Transfer(req.Context(), &newTransaction)
+	if err != nil {
+		answerError(writer, err)
 		return
 	}
+	utils.CreateAnswerTransactionJson(writer, utils.StatusCode("OK"), newTransaction)
+}
+
+func (fh *FundsHandlers) Reverse(writer http.ResponseWriter, req *http.Request) {
+	var reversal models.Reversal
+	err := easy_json.UnmarshalFromReader(req.Body, &reversal)
+	if err != nil {
+		answerError(writer, decodeError(err))
+		return
+	}
+	var newTransaction models.Transaction
+	err = fh.FundsUC.Reverse(req.Context(), &reversal, &newTransaction)
 	if err != nil {
-		logger.Error(err)
-		utils.CreateErrorAnswerJson(writer, utils.StatusCode("Internal Server Error"), models.CreateMessage(err.Error()))
+		answerError(writer, err)
 		return
 	}
-	utils.CreateEmptyBodyAnswerJson(writer, utils.StatusCode("OK"))
+	utils.CreateAnswerTransactionJson(writer, utils.StatusCode("OK"), newTransaction)
 }
 
 func (fh *FundsHandlers) GetTransactions(writer http.ResponseWriter, req *http.Request) {
-	var err error
 	query := req.URL.Query()
-	limit := query.Get("limit")
-	since := query.Get("since")
-	desc := query.Get("desc")
-	sort := query.Get("sort")
-	limitInt := utils.LIMIT_DEFAULT
-	descBool := false
-	if limit != "" {
-		limitInt, err = strconv.Atoi(limit)
-		if err != nil {
-			utils.CreateErrorAnswerJson(writer, utils.StatusCode("Bad Request"), models.CreateMessage("bad limit query param"))
-			return
-		}
-	}
-	if desc == "true" {
-		descBool = true
+	filter, err := readTransactionsFilter(query)
+	if err != nil {
+		answerError(writer, err)
+		return
 	}
 	var newUserId models.UserId
 	err = easy_json.UnmarshalFromReader(req.Body, &newUserId)
 	if err != nil {
-		jsonError := fmt.Sprintf("Error unmarshaling json: %v", err.Error())
-		logger.Errorf(jsonError)
-		utils.CreateErrorAnswerJson(writer, utils.StatusCode("Internal Server Error"), models.CreateMessage(jsonError))
-		return
-	}
-	badRequest, txs, err := fh.FundsUC.GetTransactions(&newUserId, limitInt, since, sort, descBool)
-	if badRequest {
-		logger.Error(err)
-		utils.CreateErrorAnswerJson(writer, utils.StatusCode("Bad Request"), models.CreateMessage(err.Error()))
+		answerError(writer, decodeError(err))
 		return
 	}
+	page, err := fh.FundsUC.GetTransactions(req.Context(), &newUserId, &filter, query.Get("since"), query.Get("cursor"))
 	if err != nil {
-		logger.Error(err)
-		utils.CreateErrorAnswerJson(writer, utils.StatusCode("Internal Server Error"), models.CreateMessage(err.Error()))
+		answerError(writer, err)
 		return
 	}
-	utils.CreateAnswerTransactionsJson(writer, utils.StatusCode("OK"), txs)
+	utils.CreateAnswerTransactionsPageJson(writer, utils.StatusCode("OK"), page)
+}
+
+// parses the paging and filtering query params of /funds/details, operation_type can be repeated
+
+func readTransactionsFilter(query url.Values) (models.TransactionsFilter, error) {
+	var err error
+	filter := models.TransactionsFilter{Limit: utils.LIMIT_DEFAULT, Sort: query.Get("sort"), Desc: query.Get("desc") == "true"}
+	if limit := query.Get("limit"); limit != "" {
+		filter.Limit, err = strconv.Atoi(limit)
+		if err != nil {
+			return filter, models.Errorf(models.ErrValidation, "bad limit query param")
+		}
+	}
+	for _, operation := range query["operation_type"] {
+		operationType, err := strconv.Atoi(operation)
+		if err != nil {
+			return filter, models.Errorf(models.ErrValidation, "bad operation_type query param")
+		}
+		filter.OperationTypes = append(filter.OperationTypes, operationType)
+	}
+	if counterparty := query.Get("counterparty"); counterparty != "" {
+		filter.Counterparty, err = strconv.Atoi(counterparty)
+		if err != nil {
+			return filter, models.Errorf(models.ErrValidation, "bad counterparty query param")
+		}
+	}
+	if minSum := query.Get("min_sum"); minSum != "" {
+		filter.MinSum, err = models.ParseMoney(minSum)
+		if err != nil {
+			return filter, fmt.Errorf("bad min_sum query param: %w", err)
+		}
+	}
+	if maxSum := query.Get("max_sum"); maxSum != "" {
+		filter.MaxSum, err = models.ParseMoney(maxSum)
+		if err != nil {
+			return filter, fmt.Errorf("bad max_sum query param: %w", err)
+		}
+	}
+	if after := query.Get("created_after"); after != "" {
+		filter.From, err = time.Parse(time.RFC3339Nano, after)
+		if err != nil {
+			return filter, models.Errorf(models.ErrValidation, "bad created_after query param")
+		}
+	}
+	if before := query.Get("created_before"); before != "" {
+		filter.To, err = time.Parse(time.RFC3339Nano, before)
+		if err != nil {
+			return filter, models.Errorf(models.ErrValidation, "bad created_before query param")
+		}
+	}
+	return filter, nil
+}
+
+// the Idempotency-Key header takes the place of the idempotency_key field
+
+func readIdempotencyKey(req *http.Request, tx *models.Transaction) error {
+	key := req.Header.Get("Idempotency-Key")
+	if key == "" {
+		return nil
+	}
+	if tx.IdempotencyKey != "" && tx.IdempotencyKey != key {
+		return models.Errorf(models.ErrValidation, "Idempotency-Key header doesn't match idempotency_key field")
+	}
+	tx.IdempotencyKey = key
+	return nil
 }
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"github.com/golang/mock/gomock"
@@ -9,7 +10,10 @@ import (
 	"github.com/saskamegaprogrammist/userBalanceService/utils"
 	"github.com/steinfletcher/apitest"
 	jsonpath "github.com/steinfletcher/apitest-jsonpath"
+	"github.com/stretchr/testify/assert"
 	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 )
@@ -18,17 +22,17 @@ var fh FundsHandlers
 
 var testTxOne = models.Transaction{
 	UserId: 1,
-	Sum:    100,
+	Sum:    models.MoneyFromUnits(100),
 }
 
 var testTxTwo = models.Transaction{
 	UserId: 1,
-	Sum:    50,
+	Sum:    models.MoneyFromUnits(50),
 }
 
 var testTxWrong = models.Transaction{
 	UserId: 0,
-	Sum:    1001,
+	Sum:    models.MoneyFromUnits(1001),
 }
 
 var testUserOne = models.UserId{
@@ -52,24 +56,28 @@ var testBalanceWrongGet = models.Balance{
 var testTxOneTransfer = models.Transaction{
 	UserId:     2,
 	UserFromId: 1,
-	Sum:        100,
+	Sum:        models.MoneyFromUnits(100),
 }
 
 var testTxWrongTransfer = models.Transaction{
 	UserId:     2,
 	UserFromId: 1,
-	Sum:        -100,
+	Sum:        models.MoneyFromUnits(-100),
 }
 
 var testTransactions = []models.Transaction{
-	{Id: 1, UserId: 1, UserFromId: 2, Sum: 10, Balance: 101, BalanceFrom: 100, Created: time.Now()},
-	{Id: 2, UserId: 2, UserFromId: 1, Sum: 10, Balance: 101, BalanceFrom: 100, Created: time.Now()},
+	{Id: 1, UserId: 1, UserFromId: 2, Sum: models.MoneyFromUnits(10), Balance: models.MoneyFromUnits(101), BalanceFrom: models.MoneyFromUnits(100), Created: time.Now()},
+	{Id: 2, UserId: 2, UserFromId: 1, Sum: models.MoneyFromUnits(10), Balance: models.MoneyFromUnits(101), BalanceFrom: models.MoneyFromUnits(100), Created: time.Now()},
 }
 
+var testPage = models.TransactionsPage{Items: testTransactions}
+
 var limitInt = utils.LIMIT_DEFAULT
 var descBool = false
 var since = ""
 var sort = ""
+var cursor = ""
+var testQuery = models.TransactionsFilter{Limit: limitInt}
 
 func TestAddFunds(t *testing.T) {
 	t.Run("FundsAddOK", func(t *testing.T) {
@@ -77,7 +85,7 @@ func TestAddFunds(t *testing.T) {
 		defer ctrl.Finish()
 
 		mockUseCase := useCases.NewMockFundsUCInterface(ctrl)
-		mockUseCase.EXPECT().Add(&testTxOne).Return(false, nil)
+		mockUseCase.EXPECT().Add(gomock.Any(), &testTxOne).Return(nil)
 
 		fh.FundsUC = mockUseCase
 
@@ -98,7 +106,7 @@ func TestAddFunds(t *testing.T) {
 		defer ctrl.Finish()
 
 		mockUseCase := useCases.NewMockFundsUCInterface(ctrl)
-		mockUseCase.EXPECT().Add(&testTxWrong).Return(true, errors.New("invalid user id"))
+		mockUseCase.EXPECT().Add(gomock.Any(), &testTxWrong).Return(models.Errorf(models.ErrValidation, "invalid user id"))
 
 		fh.FundsUC = mockUseCase
 
@@ -119,7 +127,7 @@ func TestAddFunds(t *testing.T) {
 		defer ctrl.Finish()
 
 		mockUseCase := useCases.NewMockFundsUCInterface(ctrl)
-		mockUseCase.EXPECT().Add(&testTxOne).Return(false, errors.New("db error"))
+		mockUseCase.EXPECT().Add(gomock.Any(), &testTxOne).Return(errors.New("db error"))
 
 		fh.FundsUC = mockUseCase
 
@@ -155,6 +163,73 @@ func TestAddFunds(t *testing.T) {
 			Assert(jsonpath.Contains(`$.message`, "Error unmarshaling json")).
 			End()
 	})
+
+	t.Run("RequestContext", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		type contextKey struct{}
+		mockUseCase := useCases.NewMockFundsUCInterface(ctrl)
+		mockUseCase.EXPECT().Add(gomock.Any(), &testTxOne).DoAndReturn(func(ctx context.Context, tx *models.Transaction) error {
+			assert.Equal(t, "request", ctx.Value(contextKey{}))
+			return nil
+		})
+
+		fh.FundsUC = mockUseCase
+
+		jsonBody := fmt.Sprintf(`{"user_id": %v, "sum": %v}`, testTxOne.UserId, testTxOne.Sum)
+
+		ctx := context.WithValue(context.Background(), contextKey{}, "request")
+		req := httptest.NewRequest("POST", utils.GetAPIAddress("addFunds"), strings.NewReader(jsonBody)).WithContext(ctx)
+		recorder := httptest.NewRecorder()
+
+		fh.Add(recorder, req)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+	})
+}
+
+func TestAddFundsPrecision(t *testing.T) {
+	t.Run("TooManyDecimals", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockUseCase := useCases.NewMockFundsUCInterface(ctrl)
+		fh.FundsUC = mockUseCase
+
+		jsonBody := fmt.Sprintf(`{"user_id": %v, "sum": 0.001}`, testTxOne.UserId)
+
+		apitest.New("TooManyDecimals").
+			Handler(http.HandlerFunc(fh.Add)).
+			Method("Post").
+			URL(utils.GetAPIAddress("addFunds")).
+			Body(jsonBody).
+			Expect(t).
+			Status(http.StatusBadRequest).
+			Assert(jsonpath.Contains(`$.message`, "decimal places")).
+			End()
+	})
+
+	t.Run("DecimalString", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		tx := models.Transaction{UserId: 1, Sum: 30}
+
+		mockUseCase := useCases.NewMockFundsUCInterface(ctrl)
+		mockUseCase.EXPECT().Add(gomock.Any(), &tx).Return(nil)
+		fh.FundsUC = mockUseCase
+
+		apitest.New("DecimalString").
+			Handler(http.HandlerFunc(fh.Add)).
+			Method("Post").
+			URL(utils.GetAPIAddress("addFunds")).
+			Body(`{"user_id": 1, "sum": "0.30"}`).
+			Expect(t).
+			Status(http.StatusOK).
+			Assert(jsonpath.Equal(`$.sum`, "0.30")).
+			End()
+	})
 }
 
 func TestWithdrawFunds(t *testing.T) {
@@ -163,7 +238,7 @@ func TestWithdrawFunds(t *testing.T) {
 		defer ctrl.Finish()
 
 		mockUseCase := useCases.NewMockFundsUCInterface(ctrl)
-		mockUseCase.EXPECT().Withdraw(&testTxTwo).Return(false, false, nil)
+		mockUseCase.EXPECT().Withdraw(gomock.Any(), &testTxTwo).Return(nil)
 
 		fh.FundsUC = mockUseCase
 
@@ -184,7 +259,7 @@ func TestWithdrawFunds(t *testing.T) {
 		defer ctrl.Finish()
 
 		mockUseCase := useCases.NewMockFundsUCInterface(ctrl)
-		mockUseCase.EXPECT().Withdraw(&testTxWrong).Return(true, false, errors.New("invalid user id"))
+		mockUseCase.EXPECT().Withdraw(gomock.Any(), &testTxWrong).Return(models.Errorf(models.ErrValidation, "invalid user id"))
 
 		fh.FundsUC = mockUseCase
 
@@ -205,7 +280,7 @@ func TestWithdrawFunds(t *testing.T) {
 		defer ctrl.Finish()
 
 		mockUseCase := useCases.NewMockFundsUCInterface(ctrl)
-		mockUseCase.EXPECT().Withdraw(&testTxTwo).Return(false, true, errors.New("low funds error"))
+		mockUseCase.EXPECT().Withdraw(gomock.Any(), &testTxTwo).Return(models.Errorf(models.ErrInsufficientFunds, "low funds error"))
 
 		fh.FundsUC = mockUseCase
 
@@ -226,7 +301,7 @@ func TestWithdrawFunds(t *testing.T) {
 		defer ctrl.Finish()
 
 		mockUseCase := useCases.NewMockFundsUCInterface(ctrl)
-		mockUseCase.EXPECT().Withdraw(&testTxTwo).Return(false, false, errors.New("db error"))
+		mockUseCase.EXPECT().Withdraw(gomock.Any(), &testTxTwo).Return(errors.New("db error"))
 
 		fh.FundsUC = mockUseCase
 
@@ -270,11 +345,11 @@ func TestGetFunds(t *testing.T) {
 		defer ctrl.Finish()
 
 		mockUseCase := useCases.NewMockFundsUCInterface(ctrl)
-		mockUseCase.EXPECT().Get(&testBalanceOneGet).Return(false, nil)
+		mockUseCase.EXPECT().Get(gomock.Any(), &testBalanceOneGet).Return(nil)
 
 		fh.FundsUC = mockUseCase
 
-		jsonBody := fmt.Sprintf(`{"user": %v}`, testUserOne.UserId)
+		jsonBody := fmt.Sprintf(`{"user": %v, "currency": "%s"}`, testUserOne.UserId, utils.CURRENCY)
 
 		apitest.New("FundsGetSimpleOK").
 			Handler(http.HandlerFunc(fh.GetBalance)).
@@ -292,11 +367,11 @@ func TestGetFunds(t *testing.T) {
 		defer ctrl.Finish()
 
 		mockUseCase := useCases.NewMockFundsUCInterface(ctrl)
-		mockUseCase.EXPECT().Get(&testBalanceWrongGet).Return(true, errors.New("user id error"))
+		mockUseCase.EXPECT().Get(gomock.Any(), &testBalanceWrongGet).Return(models.Errorf(models.ErrValidation, "user id error"))
 
 		fh.FundsUC = mockUseCase
 
-		jsonBody := fmt.Sprintf(`{"user": %v}`, testUserWrong.UserId)
+		jsonBody := fmt.Sprintf(`{"user": %v, "currency": "%s"}`, testUserWrong.UserId, utils.CURRENCY)
 
 		apitest.New("UserIdError").
 			Handler(http.HandlerFunc(fh.GetBalance)).
@@ -313,11 +388,11 @@ func TestGetFunds(t *testing.T) {
 		defer ctrl.Finish()
 
 		mockUseCase := useCases.NewMockFundsUCInterface(ctrl)
-		mockUseCase.EXPECT().Get(&testBalanceOneGet).Return(false, errors.New("db error"))
+		mockUseCase.EXPECT().Get(gomock.Any(), &testBalanceOneGet).Return(errors.New("db error"))
 
 		fh.FundsUC = mockUseCase
 
-		jsonBody := fmt.Sprintf(`{"user": %v}`, testUserOne.UserId)
+		jsonBody := fmt.Sprintf(`{"user": %v, "currency": "%s"}`, testUserOne.UserId, utils.CURRENCY)
 
 		apitest.New("UserIdError").
 			Handler(http.HandlerFunc(fh.GetBalance)).
@@ -349,17 +424,76 @@ func TestGetFunds(t *testing.T) {
 			End()
 	})
 
-	t.Run("FundsGetCurrencyOK", func(t *testing.T) {
+	t.Run("FundsGetAllOK", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
 
 		mockUseCase := useCases.NewMockFundsUCInterface(ctrl)
-		mockUseCase.EXPECT().Get(&testBalanceOneGet).Return(false, nil)
+		mockUseCase.EXPECT().GetAll(gomock.Any(), &testUserOne).Return([]models.Balance{
+			{UserId: 1, Balance: models.MoneyFromUnits(100), Currency: utils.CURRENCY},
+			{UserId: 1, Balance: models.MoneyFromUnits(5), Currency: "USD"},
+		}, nil)
 
 		fh.FundsUC = mockUseCase
 
 		jsonBody := fmt.Sprintf(`{"user": %v}`, testUserOne.UserId)
 
+		apitest.New("FundsGetAllOK").
+			Handler(http.HandlerFunc(fh.GetBalance)).
+			Method("Post").
+			URL(utils.GetAPIAddress("getFunds")).
+			Body(jsonBody).
+			Expect(t).
+			Status(http.StatusOK).
+			Assert(jsonpath.Len("$", 2)).
+			Assert(jsonpath.Equal("$[1].currency", "USD")).
+			Assert(jsonpath.Equal("$[1].balance", "5.00")).
+			End()
+	})
+
+	t.Run("FundsGetAllUserIdError", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockUseCase := useCases.NewMockFundsUCInterface(ctrl)
+		mockUseCase.EXPECT().GetAll(gomock.Any(), &testUserWrong).Return([]models.Balance{}, models.Errorf(models.ErrValidation, "user id error"))
+
+		fh.FundsUC = mockUseCase
+
+		jsonBody := fmt.Sprintf(`{"user": %v}`, testUserWrong.UserId)
+
+		apitest.New("FundsGetAllUserIdError").
+			Handler(http.HandlerFunc(fh.GetBalance)).
+			Method("Post").
+			URL(utils.GetAPIAddress("getFunds")).
+			Body(jsonBody).
+			Expect(t).
+			Status(http.StatusBadRequest).
+			End()
+	})
+
+	t.Run("FundsGetCurrencyOK", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockUseCase := useCases.NewMockFundsUCInterface(ctrl)
+		mockUseCase.EXPECT().Get(gomock.Any(), &testBalanceOneGet).DoAndReturn(func(ctx context.Context, balance *models.Balance) error {
+			balance.Balance = models.MoneyFromUnits(1000)
+			return nil
+		})
+		mockRates := useCases.NewMockRatesUCInterface(ctrl)
+		mockRates.EXPECT().Convert(gomock.Any(), gomock.Any(), "EUR").DoAndReturn(func(ctx context.Context, balance *models.Balance, currency string) error {
+			balance.Balance = balance.Balance.Convert(0.011)
+			balance.Currency = currency
+			balance.RateId = 7
+			return nil
+		})
+
+		fh.FundsUC = mockUseCase
+		fh.RatesUC = mockRates
+
+		jsonBody := fmt.Sprintf(`{"user": %v, "currency": "%s"}`, testUserOne.UserId, utils.CURRENCY)
+
 		apitest.New("FundsGetSimpleOK").
 			Handler(http.HandlerFunc(fh.GetBalance)).
 			Method("Post").
@@ -370,6 +504,8 @@ func TestGetFunds(t *testing.T) {
 			Status(http.StatusOK).
 			Assert(jsonpath.Matches("$.balance", `([0-9]*[.])?[0-9]+`)).
 			Assert(jsonpath.Matches("$.currency", `EUR`)).
+			Assert(jsonpath.Equal("$.balance", "11.00")).
+			Assert(jsonpath.Equal("$.rate_id", float64(7))).
 			End()
 	})
 
@@ -378,11 +514,14 @@ func TestGetFunds(t *testing.T) {
 		defer ctrl.Finish()
 
 		mockUseCase := useCases.NewMockFundsUCInterface(ctrl)
-		mockUseCase.EXPECT().Get(&testBalanceOneGet).Return(false, nil)
+		mockUseCase.EXPECT().Get(gomock.Any(), &testBalanceOneGet).Return(nil)
+		mockRates := useCases.NewMockRatesUCInterface(ctrl)
+		mockRates.EXPECT().Convert(gomock.Any(), &testBalanceOneGet, "dsgsdg").Return(models.Errorf(models.ErrValidation, "invalid currency"))
 
 		fh.FundsUC = mockUseCase
+		fh.RatesUC = mockRates
 
-		jsonBody := fmt.Sprintf(`{"user": %v}`, testUserOne.UserId)
+		jsonBody := fmt.Sprintf(`{"user": %v, "currency": "%s"}`, testUserOne.UserId, utils.CURRENCY)
 
 		apitest.New("FundsGetSimpleOK").
 			Handler(http.HandlerFunc(fh.GetBalance)).
@@ -395,6 +534,151 @@ func TestGetFunds(t *testing.T) {
 			Assert(jsonpath.Contains("$.message", "invalid currency")).
 			End()
 	})
+
+	t.Run("FundsGetCurrencyRatesError", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockUseCase := useCases.NewMockFundsUCInterface(ctrl)
+		mockUseCase.EXPECT().Get(gomock.Any(), &testBalanceOneGet).Return(nil)
+		mockRates := useCases.NewMockRatesUCInterface(ctrl)
+		mockRates.EXPECT().Convert(gomock.Any(), &testBalanceOneGet, "EUR").Return(errors.New("rates API is unavailable"))
+
+		fh.FundsUC = mockUseCase
+		fh.RatesUC = mockRates
+
+		jsonBody := fmt.Sprintf(`{"user": %v, "currency": "%s"}`, testUserOne.UserId, utils.CURRENCY)
+
+		apitest.New("FundsGetCurrencyRatesError").
+			Handler(http.HandlerFunc(fh.GetBalance)).
+			Method("Post").
+			URL(utils.GetAPIAddress("getFunds")).
+			Query("currency", "EUR").
+			Body(jsonBody).
+			Expect(t).
+			Status(http.StatusInternalServerError).
+			End()
+	})
+}
+
+func TestGetFundsAsOf(t *testing.T) {
+	asOf := time.Date(2026, 3, 31, 23, 59, 59, 0, time.UTC)
+
+	t.Run("AsOfAllOK", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockUseCase := useCases.NewMockFundsUCInterface(ctrl)
+		mockUseCase.EXPECT().GetAsOf(gomock.Any(), &testUserOne, asOf).Return([]models.Balance{
+			{UserId: 1, Balance: models.MoneyFromUnits(7), Available: models.MoneyFromUnits(7), Currency: utils.CURRENCY},
+		}, nil)
+
+		fh.FundsUC = mockUseCase
+
+		jsonBody := fmt.Sprintf(`{"user": %v}`, testUserOne.UserId)
+
+		apitest.New("AsOfAllOK").
+			Handler(http.HandlerFunc(fh.GetBalance)).
+			Method("Post").
+			URL(utils.GetAPIAddress("getFunds")).
+			Query("as_of", "2026-03-31T23:59:59Z").
+			Body(jsonBody).
+			Expect(t).
+			Status(http.StatusOK).
+			Assert(jsonpath.Len("$", 1)).
+			Assert(jsonpath.Equal("$[0].balance", "7.00")).
+			End()
+	})
+
+	t.Run("AsOfCurrencyOK", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockUseCase := useCases.NewMockFundsUCInterface(ctrl)
+		mockUseCase.EXPECT().GetAsOf(gomock.Any(), &models.UserId{UserId: 1, Currency: "USD"}, asOf).Return([]models.Balance{
+			{UserId: 1, Currency: "USD"},
+		}, nil)
+
+		fh.FundsUC = mockUseCase
+
+		jsonBody := fmt.Sprintf(`{"user": %v, "currency": "USD"}`, testUserOne.UserId)
+
+		apitest.New("AsOfCurrencyOK").
+			Handler(http.HandlerFunc(fh.GetBalance)).
+			Method("Post").
+			URL(utils.GetAPIAddress("getFunds")).
+			Query("as_of", "2026-03-31T23:59:59Z").
+			Body(jsonBody).
+			Expect(t).
+			Status(http.StatusOK).
+			Assert(jsonpath.Equal("$.currency", "USD")).
+			Assert(jsonpath.Equal("$.balance", "0.00")).
+			End()
+	})
+
+	t.Run("AsOfWrong", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		fh.FundsUC = useCases.NewMockFundsUCInterface(ctrl)
+
+		jsonBody := fmt.Sprintf(`{"user": %v}`, testUserOne.UserId)
+
+		apitest.New("AsOfWrong").
+			Handler(http.HandlerFunc(fh.GetBalance)).
+			Method("Post").
+			URL(utils.GetAPIAddress("getFunds")).
+			Query("as_of", "2026-03-31").
+			Body(jsonBody).
+			Expect(t).
+			Status(http.StatusBadRequest).
+			Assert(jsonpath.Contains(`$.message`, "as_of")).
+			End()
+	})
+
+	t.Run("AsOfUserIdError", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockUseCase := useCases.NewMockFundsUCInterface(ctrl)
+		mockUseCase.EXPECT().GetAsOf(gomock.Any(), &testUserWrong, asOf).Return([]models.Balance{}, models.Errorf(models.ErrValidation, "incorrect user id"))
+
+		fh.FundsUC = mockUseCase
+
+		jsonBody := fmt.Sprintf(`{"user": %v}`, testUserWrong.UserId)
+
+		apitest.New("AsOfUserIdError").
+			Handler(http.HandlerFunc(fh.GetBalance)).
+			Method("Post").
+			URL(utils.GetAPIAddress("getFunds")).
+			Query("as_of", "2026-03-31T23:59:59Z").
+			Body(jsonBody).
+			Expect(t).
+			Status(http.StatusBadRequest).
+			End()
+	})
+
+	t.Run("AsOfDBError", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockUseCase := useCases.NewMockFundsUCInterface(ctrl)
+		mockUseCase.EXPECT().GetAsOf(gomock.Any(), &testUserOne, asOf).Return([]models.Balance{}, errors.New("db error"))
+
+		fh.FundsUC = mockUseCase
+
+		jsonBody := fmt.Sprintf(`{"user": %v}`, testUserOne.UserId)
+
+		apitest.New("AsOfDBError").
+			Handler(http.HandlerFunc(fh.GetBalance)).
+			Method("Post").
+			URL(utils.GetAPIAddress("getFunds")).
+			Query("as_of", "2026-03-31T23:59:59Z").
+			Body(jsonBody).
+			Expect(t).
+			Status(http.StatusInternalServerError).
+			End()
+	})
 }
 
 func TestTransferFunds(t *testing.T) {
@@ -403,7 +687,7 @@ func TestTransferFunds(t *testing.T) {
 		defer ctrl.Finish()
 
 		mockUseCase := useCases.NewMockFundsUCInterface(ctrl)
-		mockUseCase.EXPECT().Transfer(&testTxOneTransfer).Return(false, false, nil)
+		mockUseCase.EXPECT().Transfer(gomock.Any(), &testTxOneTransfer).Return(nil)
 
 		fh.FundsUC = mockUseCase
 
@@ -419,12 +703,50 @@ func TestTransferFunds(t *testing.T) {
 			End()
 	})
 
+	t.Run("FundsTransferQuoteNotFound", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockUseCase := useCases.NewMockFundsUCInterface(ctrl)
+		mockUseCase.EXPECT().Transfer(gomock.Any(), &models.Transaction{UserId: 2, UserFromId: 1, QuoteId: 3}).Return(useCases.ErrQuoteNotFound)
+
+		fh.FundsUC = mockUseCase
+
+		apitest.New("FundsTransferQuoteNotFound").
+			Handler(http.HandlerFunc(fh.Transfer)).
+			Method("Post").
+			URL(utils.GetAPIAddress("transferFunds")).
+			Body(`{"user_id": 2, "user_from_id": 1, "quote_id": 3}`).
+			Expect(t).
+			Status(http.StatusNotFound).
+			End()
+	})
+
+	t.Run("FundsTransferQuoteExpired", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockUseCase := useCases.NewMockFundsUCInterface(ctrl)
+		mockUseCase.EXPECT().Transfer(gomock.Any(), gomock.Any()).Return(fmt.Errorf("%w: quote has expired", useCases.ErrQuoteNotActive))
+
+		fh.FundsUC = mockUseCase
+
+		apitest.New("FundsTransferQuoteExpired").
+			Handler(http.HandlerFunc(fh.Transfer)).
+			Method("Post").
+			URL(utils.GetAPIAddress("transferFunds")).
+			Body(`{"user_id": 2, "user_from_id": 1, "quote_id": 3}`).
+			Expect(t).
+			Status(http.StatusConflict).
+			End()
+	})
+
 	t.Run("FundsTransferWrong", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
 
 		mockUseCase := useCases.NewMockFundsUCInterface(ctrl)
-		mockUseCase.EXPECT().Transfer(&testTxWrongTransfer).Return(true, false, errors.New("wrong sum"))
+		mockUseCase.EXPECT().Transfer(gomock.Any(), &testTxWrongTransfer).Return(models.Errorf(models.ErrValidation, "wrong sum"))
 
 		fh.FundsUC = mockUseCase
 
@@ -445,7 +767,7 @@ func TestTransferFunds(t *testing.T) {
 		defer ctrl.Finish()
 
 		mockUseCase := useCases.NewMockFundsUCInterface(ctrl)
-		mockUseCase.EXPECT().Transfer(&testTxOneTransfer).Return(false, true, errors.New("low funds"))
+		mockUseCase.EXPECT().Transfer(gomock.Any(), &testTxOneTransfer).Return(models.Errorf(models.ErrInsufficientFunds, "low funds"))
 
 		fh.FundsUC = mockUseCase
 
@@ -466,7 +788,7 @@ func TestTransferFunds(t *testing.T) {
 		defer ctrl.Finish()
 
 		mockUseCase := useCases.NewMockFundsUCInterface(ctrl)
-		mockUseCase.EXPECT().Transfer(&testTxOneTransfer).Return(false, false, errors.New("db error"))
+		mockUseCase.EXPECT().Transfer(gomock.Any(), &testTxOneTransfer).Return(errors.New("db error"))
 
 		fh.FundsUC = mockUseCase
 
@@ -510,7 +832,7 @@ func TestGetTransactions(t *testing.T) {
 		defer ctrl.Finish()
 
 		mockUseCase := useCases.NewMockFundsUCInterface(ctrl)
-		mockUseCase.EXPECT().GetTransactions(&testUserOne, limitInt, since, sort, descBool).Return(false, testTransactions, nil)
+		mockUseCase.EXPECT().GetTransactions(gomock.Any(), &testUserOne, &testQuery, since, cursor).Return(testPage, nil)
 
 		fh.FundsUC = mockUseCase
 
@@ -523,6 +845,8 @@ func TestGetTransactions(t *testing.T) {
 			Body(jsonBody).
 			Expect(t).
 			Status(http.StatusOK).
+			Assert(jsonpath.Len("$.items", 2)).
+			Assert(jsonpath.NotPresent("$.next_cursor")).
 			End()
 	})
 
@@ -531,7 +855,7 @@ func TestGetTransactions(t *testing.T) {
 		defer ctrl.Finish()
 
 		mockUseCase := useCases.NewMockFundsUCInterface(ctrl)
-		mockUseCase.EXPECT().GetTransactions(&testUserWrong, limitInt, since, sort, descBool).Return(true, []models.Transaction{}, errors.New("user error"))
+		mockUseCase.EXPECT().GetTransactions(gomock.Any(), &testUserWrong, &testQuery, since, cursor).Return(models.TransactionsPage{}, models.Errorf(models.ErrValidation, "user error"))
 
 		fh.FundsUC = mockUseCase
 
@@ -552,7 +876,7 @@ func TestGetTransactions(t *testing.T) {
 		defer ctrl.Finish()
 
 		mockUseCase := useCases.NewMockFundsUCInterface(ctrl)
-		mockUseCase.EXPECT().GetTransactions(&testUserOne, limitInt, since, sort, descBool).Return(false, []models.Transaction{}, errors.New("db error"))
+		mockUseCase.EXPECT().GetTransactions(gomock.Any(), &testUserOne, &testQuery, since, cursor).Return(models.TransactionsPage{}, errors.New("db error"))
 
 		fh.FundsUC = mockUseCase
 
@@ -593,7 +917,7 @@ func TestGetTransactions(t *testing.T) {
 		defer ctrl.Finish()
 
 		mockUseCase := useCases.NewMockFundsUCInterface(ctrl)
-		mockUseCase.EXPECT().GetTransactions(&testUserOne, limitInt, "2020-08-22T15:04:05.999999-07:00", sort, descBool).Return(false, testTransactions, nil)
+		mockUseCase.EXPECT().GetTransactions(gomock.Any(), &testUserOne, &testQuery, "2020-08-22T15:04:05.999999-07:00", cursor).Return(testPage, nil)
 
 		fh.FundsUC = mockUseCase
 
@@ -615,7 +939,7 @@ func TestGetTransactions(t *testing.T) {
 		defer ctrl.Finish()
 
 		mockUseCase := useCases.NewMockFundsUCInterface(ctrl)
-		mockUseCase.EXPECT().GetTransactions(&testUserOne, 2, "2020-08-22T15:04:05.999999-07:00", sort, descBool).Return(false, testTransactions, nil)
+		mockUseCase.EXPECT().GetTransactions(gomock.Any(), &testUserOne, &models.TransactionsFilter{Limit: 2}, "2020-08-22T15:04:05.999999-07:00", cursor).Return(testPage, nil)
 
 		fh.FundsUC = mockUseCase
 
@@ -638,7 +962,7 @@ func TestGetTransactions(t *testing.T) {
 		defer ctrl.Finish()
 
 		mockUseCase := useCases.NewMockFundsUCInterface(ctrl)
-		mockUseCase.EXPECT().GetTransactions(&testUserOne, 2, "2020-08-22T15:04:05.999999-07:00", "sum", true).Return(false, testTransactions, nil)
+		mockUseCase.EXPECT().GetTransactions(gomock.Any(), &testUserOne, &models.TransactionsFilter{Limit: 2, Sort: "sum", Desc: true}, "2020-08-22T15:04:05.999999-07:00", cursor).Return(testPage, nil)
 
 		fh.FundsUC = mockUseCase
 
@@ -679,4 +1003,294 @@ func TestGetTransactions(t *testing.T) {
 			Assert(jsonpath.Contains(`$.message`, "bad limit query param")).
 			End()
 	})
+
+	t.Run("TxsGetCursorOK", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		nextCursor := models.NewCursor("sum", false, &testTransactions[1]).Encode()
+		mockUseCase := useCases.NewMockFundsUCInterface(ctrl)
+		mockUseCase.EXPECT().GetTransactions(gomock.Any(), &testUserOne, &models.TransactionsFilter{Limit: 2, Sort: "sum"}, since, "abc").
+			Return(models.TransactionsPage{Items: testTransactions, NextCursor: nextCursor}, nil)
+
+		fh.FundsUC = mockUseCase
+
+		jsonBody := fmt.Sprintf(`{"user": %v}`, testUserOne.UserId)
+
+		apitest.New("TxsGetCursorOK").
+			Handler(http.HandlerFunc(fh.GetTransactions)).
+			Method("Post").
+			URL(utils.GetAPIAddress("getTransactions")).
+			Query("limit", "2").
+			Query("sort", "sum").
+			Query("cursor", "abc").
+			Body(jsonBody).
+			Expect(t).
+			Status(http.StatusOK).
+			Assert(jsonpath.Len("$.items", 2)).
+			Assert(jsonpath.Equal("$.next_cursor", nextCursor)).
+			End()
+	})
+
+	t.Run("TxsGetCursorWrong", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockUseCase := useCases.NewMockFundsUCInterface(ctrl)
+		mockUseCase.EXPECT().GetTransactions(gomock.Any(), &testUserOne, &testQuery, since, "abc").
+			Return(models.TransactionsPage{}, models.ErrInvalidCursor)
+
+		fh.FundsUC = mockUseCase
+
+		jsonBody := fmt.Sprintf(`{"user": %v}`, testUserOne.UserId)
+
+		apitest.New("TxsGetCursorWrong").
+			Handler(http.HandlerFunc(fh.GetTransactions)).
+			Method("Post").
+			URL(utils.GetAPIAddress("getTransactions")).
+			Query("cursor", "abc").
+			Body(jsonBody).
+			Expect(t).
+			Status(http.StatusBadRequest).
+			Assert(jsonpath.Contains(`$.message`, "invalid cursor")).
+			End()
+	})
+
+	t.Run("TxsGetFiltersOK", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		after := time.Date(2020, 8, 1, 0, 0, 0, 0, time.UTC)
+		before := time.Date(2020, 9, 1, 0, 0, 0, 0, time.UTC)
+		filter := models.TransactionsFilter{Limit: limitInt, OperationTypes: []int{1, 3}, Counterparty: 42,
+			MinSum: models.MoneyFromUnits(1000), MaxSum: 500050, From: after, To: before}
+		mockUseCase := useCases.NewMockFundsUCInterface(ctrl)
+		mockUseCase.EXPECT().GetTransactions(gomock.Any(), &testUserOne, &filter, since, cursor).Return(testPage, nil)
+
+		fh.FundsUC = mockUseCase
+
+		jsonBody := fmt.Sprintf(`{"user": %v}`, testUserOne.UserId)
+
+		apitest.New("TxsGetFiltersOK").
+			Handler(http.HandlerFunc(fh.GetTransactions)).
+			Method("Post").
+			URL(utils.GetAPIAddress("getTransactions")).
+			Query("operation_type", "1").
+			Query("operation_type", "3").
+			Query("counterparty", "42").
+			Query("min_sum", "1000").
+			Query("max_sum", "5000.50").
+			Query("created_after", "2020-08-01T00:00:00Z").
+			Query("created_before", "2020-09-01T00:00:00Z").
+			Body(jsonBody).
+			Expect(t).
+			Status(http.StatusOK).
+			Assert(jsonpath.Len("$.items", 2)).
+			End()
+	})
+
+	t.Run("TxsGetFiltersWrong", func(t *testing.T) {
+		params := map[string]string{
+			"operation_type": "transfer",
+			"counterparty":   "user",
+			"min_sum":        "10.001",
+			"max_sum":        "many",
+			"created_after":  "2020-08-01",
+			"created_before": "yesterday",
+		}
+		for param, value := range params {
+			ctrl := gomock.NewController(t)
+
+			fh.FundsUC = useCases.NewMockFundsUCInterface(ctrl)
+
+			jsonBody := fmt.Sprintf(`{"user": %v}`, testUserOne.UserId)
+
+			apitest.New("TxsGetFiltersWrong").
+				Handler(http.HandlerFunc(fh.GetTransactions)).
+				Method("Post").
+				URL(utils.GetAPIAddress("getTransactions")).
+				Query(param, value).
+				Body(jsonBody).
+				Expect(t).
+				Status(http.StatusBadRequest).
+				Assert(jsonpath.Contains(`$.message`, param)).
+				End()
+			ctrl.Finish()
+		}
+	})
+}
+
+func TestIdempotencyKey(t *testing.T) {
+	t.Run("HeaderOK", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		tx := models.Transaction{UserId: 1, Sum: models.MoneyFromUnits(100), IdempotencyKey: "key"}
+
+		mockUseCase := useCases.NewMockFundsUCInterface(ctrl)
+		mockUseCase.EXPECT().Add(gomock.Any(), &tx).Return(nil)
+
+		fh.FundsUC = mockUseCase
+
+		jsonBody := fmt.Sprintf(`{"user_id": %v, "sum": %v}`, tx.UserId, tx.Sum)
+
+		apitest.New("HeaderOK").
+			Handler(http.HandlerFunc(fh.Add)).
+			Method("Post").
+			URL(utils.GetAPIAddress("addFunds")).
+			Header("Idempotency-Key", "key").
+			Body(jsonBody).
+			Expect(t).
+			Status(http.StatusOK).
+			Assert(jsonpath.Equal(`$.idempotency_key`, "key")).
+			End()
+	})
+
+	t.Run("HeaderMismatch", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockUseCase := useCases.NewMockFundsUCInterface(ctrl)
+
+		fh.FundsUC = mockUseCase
+
+		jsonBody := fmt.Sprintf(`{"user_id": %v, "sum": %v, "idempotency_key": "body"}`, testTxTwo.UserId, testTxTwo.Sum)
+
+		apitest.New("HeaderMismatch").
+			Handler(http.HandlerFunc(fh.Withdraw)).
+			Method("Post").
+			URL(utils.GetAPIAddress("withdrawFunds")).
+			Header("Idempotency-Key", "header").
+			Body(jsonBody).
+			Expect(t).
+			Status(http.StatusBadRequest).
+			End()
+	})
+
+	t.Run("Conflict", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		tx := models.Transaction{UserId: 2, UserFromId: 1, Sum: models.MoneyFromUnits(100), IdempotencyKey: "key"}
+
+		mockUseCase := useCases.NewMockFundsUCInterface(ctrl)
+		mockUseCase.EXPECT().Transfer(gomock.Any(), &tx).Return(useCases.ErrIdempotencyConflict)
+
+		fh.FundsUC = mockUseCase
+
+		jsonBody := fmt.Sprintf(`{"user_id": %v, "user_from_id": %v, "sum": %v, "idempotency_key": "key"}`, tx.UserId, tx.UserFromId, tx.Sum)
+
+		apitest.New("Conflict").
+			Handler(http.HandlerFunc(fh.Transfer)).
+			Method("Post").
+			URL(utils.GetAPIAddress("transferFunds")).
+			Body(jsonBody).
+			Expect(t).
+			Status(http.StatusConflict).
+			End()
+	})
+}
+
+func TestReverseFunds(t *testing.T) {
+	t.Run("ReverseOK", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockUseCase := useCases.NewMockFundsUCInterface(ctrl)
+		mockUseCase.EXPECT().Reverse(gomock.Any(), &models.Reversal{TransactionId: 5, Sum: models.MoneyFromUnits(30)}, &models.Transaction{}).
+			DoAndReturn(func(ctx context.Context, reversal *models.Reversal, tx *models.Transaction) error {
+				tx.Id = 6
+				tx.ReversedId = reversal.TransactionId
+				tx.Sum = reversal.Sum
+				tx.OperationType = utils.GetOperationType("Reverse")
+				return nil
+			})
+
+		fh.FundsUC = mockUseCase
+
+		apitest.New("ReverseOK").
+			Handler(http.HandlerFunc(fh.Reverse)).
+			Method("Post").
+			URL(utils.GetAPIAddress("reverseFunds")).
+			Body(`{"transaction_id": 5, "sum": "30.00"}`).
+			Expect(t).
+			Status(http.StatusOK).
+			Assert(jsonpath.Equal("$.id", float64(6))).
+			Assert(jsonpath.Equal("$.reversed_id", float64(5))).
+			Assert(jsonpath.Equal("$.sum", "30.00")).
+			End()
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockUseCase := useCases.NewMockFundsUCInterface(ctrl)
+		mockUseCase.EXPECT().Reverse(gomock.Any(), gomock.Any(), gomock.Any()).Return(useCases.ErrTransactionNotFound)
+
+		fh.FundsUC = mockUseCase
+
+		apitest.New("NotFound").
+			Handler(http.HandlerFunc(fh.Reverse)).
+			Method("Post").
+			URL(utils.GetAPIAddress("reverseFunds")).
+			Body(`{"transaction_id": 5}`).
+			Expect(t).
+			Status(http.StatusNotFound).
+			End()
+	})
+
+	t.Run("AlreadyReversed", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockUseCase := useCases.NewMockFundsUCInterface(ctrl)
+		mockUseCase.EXPECT().Reverse(gomock.Any(), gomock.Any(), gomock.Any()).Return(useCases.ErrAlreadyReversed)
+
+		fh.FundsUC = mockUseCase
+
+		apitest.New("AlreadyReversed").
+			Handler(http.HandlerFunc(fh.Reverse)).
+			Method("Post").
+			URL(utils.GetAPIAddress("reverseFunds")).
+			Body(`{"transaction_id": 5}`).
+			Expect(t).
+			Status(http.StatusConflict).
+			End()
+	})
+
+	t.Run("LowFunds", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockUseCase := useCases.NewMockFundsUCInterface(ctrl)
+		mockUseCase.EXPECT().Reverse(gomock.Any(), gomock.Any(), gomock.Any()).Return(models.Errorf(models.ErrInsufficientFunds, "user doesn't have enough funds"))
+
+		fh.FundsUC = mockUseCase
+
+		apitest.New("LowFunds").
+			Handler(http.HandlerFunc(fh.Reverse)).
+			Method("Post").
+			URL(utils.GetAPIAddress("reverseFunds")).
+			Body(`{"transaction_id": 5}`).
+			Expect(t).
+			Status(http.StatusPaymentRequired).
+			End()
+	})
+
+	t.Run("SumPrecision", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		fh.FundsUC = useCases.NewMockFundsUCInterface(ctrl)
+
+		apitest.New("SumPrecision").
+			Handler(http.HandlerFunc(fh.Reverse)).
+			Method("Post").
+			URL(utils.GetAPIAddress("reverseFunds")).
+			Body(`{"transaction_id": 5, "sum": "0.001"}`).
+			Expect(t).
+			Status(http.StatusBadRequest).
+			End()
+	})
 }
@@ -4,15 +4,27 @@ import "github.com/saskamegaprogrammist/userBalanceService/useCases"
 
 type Handlers struct {
 	FundsHandlers *FundsHandlers
+	HoldsHandlers *HoldsHandlers
+	RatesHandlers *RatesHandlers
 }
 
 var h Handlers
 
-func Init(fundsUC useCases.FundsUCInterface) error {
-	h.FundsHandlers = &FundsHandlers{fundsUC}
+func Init(fundsUC useCases.FundsUCInterface, holdsUC useCases.HoldsUCInterface, ratesUC useCases.RatesUCInterface) error {
+	h.FundsHandlers = &FundsHandlers{fundsUC, ratesUC}
+	h.HoldsHandlers = &HoldsHandlers{holdsUC}
+	h.RatesHandlers = &RatesHandlers{ratesUC}
 	return nil
 }
 
 func GetUFundsH() *FundsHandlers {
 	return h.FundsHandlers
 }
+
+func GetHoldsH() *HoldsHandlers {
+	return h.HoldsHandlers
+}
+
+func GetRatesH() *RatesHandlers {
+	return h.RatesHandlers
+}
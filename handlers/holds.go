@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"github.com/gorilla/mux"
+	easy_json "github.com/mailru/easyjson"
+	"github.com/saskamegaprogrammist/userBalanceService/models"
+	"github.com/saskamegaprogrammist/userBalanceService/useCases"
+	"github.com/saskamegaprogrammist/userBalanceService/utils"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+)
+
+type HoldsHandlers struct {
+	HoldsUC useCases.HoldsUCInterface
+}
+
+func (hh *HoldsHandlers) Hold(writer http.ResponseWriter, req *http.Request) {
+	var newHold models.Hold
+	err := easy_json.UnmarshalFromReader(req.Body, &newHold)
+	if err != nil {
+		answerError(writer, decodeError(err))
+		return
+	}
+	err = hh.HoldsUC.Hold(req.Context(), &newHold)
+	if err != nil {
+		answerError(writer, err)
+		return
+	}
+	utils.CreateAnswerHoldJson(writer, utils.StatusCode("OK"), newHold)
+}
+
+// the body is optional, without "sum" the whole hold is captured
+
+func (hh *HoldsHandlers) Capture(writer http.ResponseWriter, req *http.Request) {
+	var hold models.Hold
+	var newTransaction models.Transaction
+	err := readHoldId(req, &hold)
+	if err != nil {
+		answerError(writer, err)
+		return
+	}
+	body, err := ioutil.ReadAll(req.Body)
+	if err == nil && len(body) != 0 {
+		err = easy_json.Unmarshal(body, &newTransaction)
+	}
+	if err != nil {
+		answerError(writer, decodeError(err))
+		return
+	}
+	err = hh.HoldsUC.Capture(req.Context(), &hold, &newTransaction)
+	if err != nil {
+		answerError(writer, err)
+		return
+	}
+	utils.CreateAnswerTransactionJson(writer, utils.StatusCode("OK"), newTransaction)
+}
+
+func (hh *HoldsHandlers) Void(writer http.ResponseWriter, req *http.Request) {
+	var hold models.Hold
+	err := readHoldId(req, &hold)
+	if err != nil {
+		answerError(writer, err)
+		return
+	}
+	err = hh.HoldsUC.Void(req.Context(), &hold)
+	if err != nil {
+		answerError(writer, err)
+		return
+	}
+	utils.CreateAnswerHoldJson(writer, utils.StatusCode("OK"), hold)
+}
+
+func readHoldId(req *http.Request, hold *models.Hold) error {
+	id, err := strconv.Atoi(mux.Vars(req)["id"])
+	if err != nil || id <= 0 {
+		return models.Errorf(models.ErrValidation, "incorrect hold id")
+	}
+	hold.Id = id
+	return nil
+}
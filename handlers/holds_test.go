@@ -0,0 +1,220 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"github.com/golang/mock/gomock"
+	"github.com/gorilla/mux"
+	"github.com/saskamegaprogrammist/userBalanceService/models"
+	"github.com/saskamegaprogrammist/userBalanceService/useCases"
+	"github.com/saskamegaprogrammist/userBalanceService/utils"
+	"github.com/steinfletcher/apitest"
+	jsonpath "github.com/steinfletcher/apitest-jsonpath"
+	"net/http"
+	"testing"
+)
+
+var hh HoldsHandlers
+
+var testHoldOne = models.Hold{
+	UserId: 1,
+	Sum:    models.MoneyFromUnits(100),
+}
+
+func holdsRouter() *mux.Router {
+	r := mux.NewRouter()
+	r.HandleFunc(utils.GetAPIAddress("holdFunds"), hh.Hold).Methods("POST")
+	r.HandleFunc(utils.GetAPIAddress("captureHold"), hh.Capture).Methods("POST")
+	r.HandleFunc(utils.GetAPIAddress("voidHold"), hh.Void).Methods("POST")
+	return r
+}
+
+func TestHoldFunds(t *testing.T) {
+	t.Run("HoldOK", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockUseCase := useCases.NewMockHoldsUCInterface(ctrl)
+		mockUseCase.EXPECT().Hold(gomock.Any(), &testHoldOne).DoAndReturn(func(ctx context.Context, hold *models.Hold) error {
+			hold.Id = 7
+			hold.Status = utils.HOLD_ACTIVE
+			return nil
+		})
+
+		hh.HoldsUC = mockUseCase
+
+		jsonBody := fmt.Sprintf(`{"user_id": %v, "sum": %v}`, testHoldOne.UserId, testHoldOne.Sum)
+
+		apitest.New("HoldOK").
+			Handler(holdsRouter()).
+			Method(http.MethodPost).
+			URL(utils.GetAPIAddress("holdFunds")).
+			Body(jsonBody).
+			Expect(t).
+			Status(http.StatusOK).
+			Assert(jsonpath.Equal("$.id", float64(7))).
+			Assert(jsonpath.Equal("$.status", utils.HOLD_ACTIVE)).
+			End()
+	})
+
+	t.Run("LowFunds", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockUseCase := useCases.NewMockHoldsUCInterface(ctrl)
+		mockUseCase.EXPECT().Hold(gomock.Any(), &testHoldOne).Return(models.Errorf(models.ErrInsufficientFunds, "you don't have enough funds"))
+
+		hh.HoldsUC = mockUseCase
+
+		jsonBody := fmt.Sprintf(`{"user_id": %v, "sum": %v}`, testHoldOne.UserId, testHoldOne.Sum)
+
+		apitest.New("LowFunds").
+			Handler(holdsRouter()).
+			Method(http.MethodPost).
+			URL(utils.GetAPIAddress("holdFunds")).
+			Body(jsonBody).
+			Expect(t).
+			Status(http.StatusPaymentRequired).
+			End()
+	})
+
+	t.Run("UserIdWrong", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockUseCase := useCases.NewMockHoldsUCInterface(ctrl)
+		mockUseCase.EXPECT().Hold(gomock.Any(), gomock.Any()).Return(models.Errorf(models.ErrValidation, "incorrect user id"))
+
+		hh.HoldsUC = mockUseCase
+
+		apitest.New("UserIdWrong").
+			Handler(holdsRouter()).
+			Method(http.MethodPost).
+			URL(utils.GetAPIAddress("holdFunds")).
+			Body(`{"user_id": 0, "sum": 100}`).
+			Expect(t).
+			Status(http.StatusBadRequest).
+			End()
+	})
+}
+
+func TestCaptureHold(t *testing.T) {
+	t.Run("CaptureFull", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockUseCase := useCases.NewMockHoldsUCInterface(ctrl)
+		mockUseCase.EXPECT().Capture(gomock.Any(), &models.Hold{Id: 7}, &models.Transaction{}).DoAndReturn(func(ctx context.Context, hold *models.Hold, tx *models.Transaction) error {
+			tx.UserId = 1
+			tx.Sum = models.MoneyFromUnits(100)
+			tx.OperationType = utils.GetOperationType("Capture")
+			return nil
+		})
+
+		hh.HoldsUC = mockUseCase
+
+		apitest.New("CaptureFull").
+			Handler(holdsRouter()).
+			Method(http.MethodPost).
+			URL("/funds/hold/7/capture").
+			Expect(t).
+			Status(http.StatusOK).
+			Assert(jsonpath.Equal("$.sum", "100.00")).
+			Assert(jsonpath.Equal("$.operation_type", float64(utils.GetOperationType("Capture")))).
+			End()
+	})
+
+	t.Run("CapturePartial", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockUseCase := useCases.NewMockHoldsUCInterface(ctrl)
+		mockUseCase.EXPECT().Capture(gomock.Any(), &models.Hold{Id: 7}, &models.Transaction{Sum: models.MoneyFromUnits(40)}).Return(nil)
+
+		hh.HoldsUC = mockUseCase
+
+		apitest.New("CapturePartial").
+			Handler(holdsRouter()).
+			Method(http.MethodPost).
+			URL("/funds/hold/7/capture").
+			Body(`{"sum": "40.00"}`).
+			Expect(t).
+			Status(http.StatusOK).
+			End()
+	})
+
+	t.Run("HoldNotFound", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockUseCase := useCases.NewMockHoldsUCInterface(ctrl)
+		mockUseCase.EXPECT().Capture(gomock.Any(), gomock.Any(), gomock.Any()).Return(useCases.ErrHoldNotFound)
+
+		hh.HoldsUC = mockUseCase
+
+		apitest.New("HoldNotFound").
+			Handler(holdsRouter()).
+			Method(http.MethodPost).
+			URL("/funds/hold/8/capture").
+			Expect(t).
+			Status(http.StatusNotFound).
+			End()
+	})
+
+	t.Run("HoldIdWrong", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		hh.HoldsUC = useCases.NewMockHoldsUCInterface(ctrl)
+
+		apitest.New("HoldIdWrong").
+			Handler(holdsRouter()).
+			Method(http.MethodPost).
+			URL("/funds/hold/abc/capture").
+			Expect(t).
+			Status(http.StatusBadRequest).
+			End()
+	})
+}
+
+func TestVoidHold(t *testing.T) {
+	t.Run("VoidOK", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockUseCase := useCases.NewMockHoldsUCInterface(ctrl)
+		mockUseCase.EXPECT().Void(gomock.Any(), &models.Hold{Id: 7}).DoAndReturn(func(ctx context.Context, hold *models.Hold) error {
+			hold.Status = utils.HOLD_VOIDED
+			return nil
+		})
+
+		hh.HoldsUC = mockUseCase
+
+		apitest.New("VoidOK").
+			Handler(holdsRouter()).
+			Method(http.MethodPost).
+			URL("/funds/hold/7/void").
+			Expect(t).
+			Status(http.StatusOK).
+			Assert(jsonpath.Equal("$.status", utils.HOLD_VOIDED)).
+			End()
+	})
+
+	t.Run("HoldNotActive", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockUseCase := useCases.NewMockHoldsUCInterface(ctrl)
+		mockUseCase.EXPECT().Void(gomock.Any(), &models.Hold{Id: 7}).Return(fmt.Errorf("%w: hold is already captured", useCases.ErrHoldNotActive))
+
+		hh.HoldsUC = mockUseCase
+
+		apitest.New("HoldNotActive").
+			Handler(holdsRouter()).
+			Method(http.MethodPost).
+			URL("/funds/hold/7/void").
+			Expect(t).
+			Status(http.StatusConflict).
+			End()
+	})
+}
@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	easy_json "github.com/mailru/easyjson"
+	"github.com/saskamegaprogrammist/userBalanceService/models"
+	"github.com/saskamegaprogrammist/userBalanceService/useCases"
+	"github.com/saskamegaprogrammist/userBalanceService/utils"
+	"net/http"
+)
+
+type RatesHandlers struct {
+	RatesUC useCases.RatesUCInterface
+}
+
+func (rh *RatesHandlers) GetRates(writer http.ResponseWriter, req *http.Request) {
+	query := req.URL.Query()
+	rates, err := rh.RatesUC.GetRates(req.Context(), query.Get("base"), query.Get("date"))
+	if err != nil {
+		answerError(writer, err)
+		return
+	}
+	utils.CreateAnswerRatesJson(writer, utils.StatusCode("OK"), rates)
+}
+
+func (rh *RatesHandlers) Quote(writer http.ResponseWriter, req *http.Request) {
+	var newQuote models.Quote
+	err := easy_json.UnmarshalFromReader(req.Body, &newQuote)
+	if err != nil {
+		answerError(writer, decodeError(err))
+		return
+	}
+	err = rh.RatesUC.Quote(req.Context(), &newQuote)
+	if err != nil {
+		answerError(writer, err)
+		return
+	}
+	utils.CreateAnswerQuoteJson(writer, utils.StatusCode("OK"), newQuote)
+}
@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"github.com/golang/mock/gomock"
+	"github.com/saskamegaprogrammist/userBalanceService/models"
+	"github.com/saskamegaprogrammist/userBalanceService/useCases"
+	"github.com/saskamegaprogrammist/userBalanceService/utils"
+	"github.com/steinfletcher/apitest"
+	jsonpath "github.com/steinfletcher/apitest-jsonpath"
+	"net/http"
+	"testing"
+	"time"
+)
+
+var rh RatesHandlers
+
+func TestGetRates(t *testing.T) {
+	t.Run("RatesGetOK", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockUseCase := useCases.NewMockRatesUCInterface(ctrl)
+		mockUseCase.EXPECT().GetRates(gomock.Any(), "USD", "2020-08-02").Return([]models.Rate{
+			{Id: 1, Base: "USD", Quote: "RUB", Rate: 73.5, Source: "test", FetchedAt: time.Date(2020, 8, 2, 10, 0, 0, 0, time.UTC)},
+		}, nil)
+
+		rh.RatesUC = mockUseCase
+
+		apitest.New("RatesGetOK").
+			Handler(http.HandlerFunc(rh.GetRates)).
+			Method(http.MethodGet).
+			URL(utils.GetAPIAddress("getRates")).
+			Query("base", "USD").
+			Query("date", "2020-08-02").
+			Expect(t).
+			Status(http.StatusOK).
+			Assert(jsonpath.Len("$", 1)).
+			Assert(jsonpath.Equal("$[0].quote", "RUB")).
+			Assert(jsonpath.Equal("$[0].rate", 73.5)).
+			Assert(jsonpath.Equal("$[0].fetched_at", "2020-08-02T10:00:00Z")).
+			End()
+	})
+
+	t.Run("RatesGetWrongDate", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockUseCase := useCases.NewMockRatesUCInterface(ctrl)
+		mockUseCase.EXPECT().GetRates(gomock.Any(), "", "yesterday").Return([]models.Rate{}, models.Errorf(models.ErrValidation, "date must be formatted as 2006-01-02"))
+
+		rh.RatesUC = mockUseCase
+
+		apitest.New("RatesGetWrongDate").
+			Handler(http.HandlerFunc(rh.GetRates)).
+			Method(http.MethodGet).
+			URL(utils.GetAPIAddress("getRates")).
+			Query("date", "yesterday").
+			Expect(t).
+			Status(http.StatusBadRequest).
+			Assert(jsonpath.Contains("$.message", "date")).
+			End()
+	})
+
+	t.Run("RatesGetDBError", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockUseCase := useCases.NewMockRatesUCInterface(ctrl)
+		mockUseCase.EXPECT().GetRates(gomock.Any(), "", "").Return([]models.Rate{}, errors.New("db error"))
+
+		rh.RatesUC = mockUseCase
+
+		apitest.New("RatesGetDBError").
+			Handler(http.HandlerFunc(rh.GetRates)).
+			Method(http.MethodGet).
+			URL(utils.GetAPIAddress("getRates")).
+			Expect(t).
+			Status(http.StatusInternalServerError).
+			End()
+	})
+}
+
+func TestQuote(t *testing.T) {
+	t.Run("QuoteOK", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockUseCase := useCases.NewMockRatesUCInterface(ctrl)
+		mockUseCase.EXPECT().Quote(gomock.Any(), &models.Quote{UserFromId: 1, CurrencyFrom: "USD", Currency: "RUB", SumFrom: models.MoneyFromUnits(10)}).
+			DoAndReturn(func(ctx context.Context, quote *models.Quote) error {
+				quote.Id, quote.Sum, quote.Rate, quote.RateId = 3, models.MoneyFromUnits(735), 73.5, 7
+				return nil
+			})
+
+		rh.RatesUC = mockUseCase
+
+		apitest.New("QuoteOK").
+			Handler(http.HandlerFunc(rh.Quote)).
+			Method("Post").
+			URL(utils.GetAPIAddress("quoteTransfer")).
+			Body(`{"user_from_id": 1, "currency_from": "USD", "currency": "RUB", "sum_from": 10}`).
+			Expect(t).
+			Status(http.StatusOK).
+			Assert(jsonpath.Equal("$.id", float64(3))).
+			Assert(jsonpath.Equal("$.sum", "735.00")).
+			Assert(jsonpath.Equal("$.rate", 73.5)).
+			End()
+	})
+
+	t.Run("QuoteWrong", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockUseCase := useCases.NewMockRatesUCInterface(ctrl)
+		mockUseCase.EXPECT().Quote(gomock.Any(), gomock.Any()).Return(models.Errorf(models.ErrValidation, "currencies of a quote must differ"))
+
+		rh.RatesUC = mockUseCase
+
+		apitest.New("QuoteWrong").
+			Handler(http.HandlerFunc(rh.Quote)).
+			Method("Post").
+			URL(utils.GetAPIAddress("quoteTransfer")).
+			Body(`{"currency_from": "RUB", "sum_from": 10}`).
+			Expect(t).
+			Status(http.StatusBadRequest).
+			End()
+	})
+
+	t.Run("QuoteWrongSum", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		rh.RatesUC = useCases.NewMockRatesUCInterface(ctrl)
+
+		apitest.New("QuoteWrongSum").
+			Handler(http.HandlerFunc(rh.Quote)).
+			Method("Post").
+			URL(utils.GetAPIAddress("quoteTransfer")).
+			Body(`{"currency_from": "USD", "sum_from": 10.001}`).
+			Expect(t).
+			Status(http.StatusBadRequest).
+			End()
+	})
+}
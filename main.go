@@ -1,41 +1,158 @@
 package main
 
 import (
+	"context"
+	"flag"
+	"fmt"
 	"github.com/google/logger"
 	"github.com/gorilla/handlers"
 	"github.com/gorilla/mux"
-	"github.com/jackc/pgx"
+	balance_config "github.com/saskamegaprogrammist/userBalanceService/config"
 	balance_handlers "github.com/saskamegaprogrammist/userBalanceService/handlers"
+	"github.com/saskamegaprogrammist/userBalanceService/metrics"
+	"github.com/saskamegaprogrammist/userBalanceService/rates"
 	"github.com/saskamegaprogrammist/userBalanceService/repository"
+	"github.com/saskamegaprogrammist/userBalanceService/tracing"
 	"github.com/saskamegaprogrammist/userBalanceService/useCases"
 	"github.com/saskamegaprogrammist/userBalanceService/utils"
+	"net"
 	"net/http"
+	"os"
+	"sync"
 	"time"
 )
 
+// subcommands run instead of the server, with the database set up by the config
+
+var commands = map[string]func(args []string) error{
+	"migrate": runMigrate,
+	"verify":  runVerify,
+}
+
 func main() {
 
+	// config loading
+	config, options, err := balance_config.Load(os.Args[1:], os.LookupEnv)
+	if err == flag.ErrHelp {
+		return
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Couldn't load config: %v\n", err)
+		os.Exit(2)
+	}
+	if options.PrintConfig {
+		fmt.Print(config.Redacted())
+		return
+	}
+	var command func(args []string) error
+	if len(options.Args) > 0 {
+		command = commands[options.Args[0]]
+		if command == nil {
+			fmt.Fprintf(os.Stderr, "Unknown command %q\n%s\n%s\n", options.Args[0], migrateUsage, verifyUsage)
+			os.Exit(2)
+		}
+	}
+
 	// logger initialization
-	utils.LoggerSetup()
+	utils.LoggerSetup(config.Log.File, config.Log.Verbose)
 	defer utils.LoggerClose()
+	logger.Infof("Effective config:\n%s", config.Redacted())
+
+	// tracing initialization
+	shutdownTracing, err := tracing.Init(tracing.Config{
+		Exporter:    config.Tracing.Exporter,
+		File:        config.Tracing.File,
+		Endpoint:    config.Tracing.Endpoint,
+		ServiceName: config.Tracing.ServiceName,
+		SampleRatio: config.Tracing.SampleRatio,
+	})
+	if err != nil {
+		logger.Fatalf("Couldn't initialize tracing: %v", err)
+	}
 
 	// database initialization
-	err := repository.Init(pgx.ConnConfig{
-		Database: utils.DBName,
-		Host:     "localhost",
-		User:     "docker",
-		Password: "docker",
+	switch config.Backend() {
+	case balance_config.BackendMemory:
+		repository.InitMemory(config.Database.OperationTimeout)
+	case balance_config.BackendSQLite:
+		path, err := config.SQLitePath()
+		if err != nil {
+			logger.Fatalf("Couldn't parse database config: %v", err)
+		}
+		err = repository.InitSQLite(path, config.Database.MaxConnections, config.Database.OperationTimeout)
+		if err != nil {
+			logger.Fatalf("Couldn't initialize database: %v", err)
+		}
+	default:
+		connConfig, err := config.ConnConfig()
+		if err != nil {
+			logger.Fatalf("Couldn't parse database config: %v", err)
+		}
+		err = repository.Init(connConfig, config.Database.MaxConnections, config.Database.OperationTimeout)
+		if err != nil {
+			logger.Fatalf("Couldn't initialize database: %v", err)
+		}
+	}
+
+	if command != nil {
+		err = command(options.Args[1:])
+		repository.Close()
+		if err != nil {
+			logger.Errorf("Command %s failed: %v", options.Args[0], err)
+			fmt.Fprintln(os.Stderr, err)
+			utils.LoggerClose()
+			os.Exit(1)
+		}
+		return
+	}
+	if config.Database.AutoMigrate && config.Backend() != balance_config.BackendMemory {
+		_, err = repository.MigrateUp(context.Background())
+		if err != nil {
+			logger.Fatalf("Couldn't migrate database: %v", err)
+		}
+	}
+
+	err = rates.Init(rates.Config{
+		Provider: config.Rates.Provider,
+		URL:      config.Rates.URL,
+		APIKey:   config.Rates.APIKey,
+		File:     config.Rates.File,
+		TTL:      config.Rates.TTL,
+		MaxStale: config.Rates.MaxStale,
 	})
 	if err != nil {
-		logger.Fatalf("Couldn't initialize database: %v", err)
+		logger.Fatalf("Couldn't initialize rates provider: %v", err)
 	}
 
-	err = useCases.Init(repository.GetBalanceRepo(), repository.GetTransactionsRepo())
+	err = useCases.Init(repository.GetBalanceRepo(), repository.GetTransactionsRepo(), repository.GetHoldsRepo(),
+		repository.GetRatesRepo(), repository.GetUnitOfWork(), rates.GetProvider(), config.Funds.IdempotencyRetention,
+		config.Rates.QuoteTTL, config.Rates.TTL+config.Rates.MaxStale,
+		config.Rates.QuoteRateMaxAge, config.Funds.HoldTTL)
 	if err != nil {
 		logger.Fatalf("Couldn't initialize useCases: %v", err)
 	}
 
-	err = balance_handlers.Init(useCases.GetFundsUC())
+	err = metrics.RegisterPool(repository.GetPoolStats)
+	if err != nil {
+		logger.Fatalf("Couldn't register metrics: %v", err)
+	}
+
+	// background workers initialization
+
+	workersCtx, stopWorkers := context.WithCancel(context.Background())
+	var workers sync.WaitGroup
+	startWorker := func(run func(context.Context, time.Duration), interval time.Duration) {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			run(workersCtx, interval)
+		}()
+	}
+	startWorker(useCases.RunIdempotencyKeysExpiry, config.Funds.IdempotencyExpiryInterval)
+	startWorker(useCases.RunHoldsExpiry, config.Funds.HoldExpiryInterval)
+	startWorker(useCases.RunRatesRefresh, config.Rates.RefreshInterval)
+
+	err = balance_handlers.Init(useCases.GetFundsUC(), useCases.GetHoldsUC(), useCases.GetRatesUC())
 	if err != nil {
 		logger.Fatalf("Couldn't initialize handlers: %v", err)
 	}
@@ -48,21 +165,48 @@ func main() {
 	r.HandleFunc(utils.GetAPIAddress("getFunds"), balance_handlers.GetUFundsH().GetBalance).Methods("POST")
 	r.HandleFunc(utils.GetAPIAddress("transferFunds"), balance_handlers.GetUFundsH().Transfer).Methods("POST")
 	r.HandleFunc(utils.GetAPIAddress("getTransactions"), balance_handlers.GetUFundsH().GetTransactions).Methods("POST")
+	r.HandleFunc(utils.GetAPIAddress("exportTransactions"), balance_handlers.GetUFundsH().Export).Methods("POST")
+	r.HandleFunc(utils.GetAPIAddress("quoteTransfer"), balance_handlers.GetRatesH().Quote).Methods("POST")
+	r.HandleFunc(utils.GetAPIAddress("reverseFunds"), balance_handlers.GetUFundsH().Reverse).Methods("POST")
+	r.HandleFunc(utils.GetAPIAddress("holdFunds"), balance_handlers.GetHoldsH().Hold).Methods("POST")
+	r.HandleFunc(utils.GetAPIAddress("captureHold"), balance_handlers.GetHoldsH().Capture).Methods("POST")
+	r.HandleFunc(utils.GetAPIAddress("voidHold"), balance_handlers.GetHoldsH().Void).Methods("POST")
+	r.HandleFunc(utils.GetAPIAddress("getRates"), balance_handlers.GetRatesH().GetRates).Methods("GET")
+	r.Handle(utils.GetAPIAddress("metrics"), metrics.Handler()).Methods("GET")
+	r.Use(tracing.Middleware)
 
 	cors := handlers.CORS(handlers.AllowCredentials(), handlers.AllowedMethods([]string{"POST", "GET", "PUT", "DELETE"}))
 
 	// server initialization
 
 	server := &http.Server{
-		Addr:         utils.PortNum,
-		Handler:      cors(r),
-		ReadTimeout:  10 * time.Second,
-		WriteTimeout: 10 * time.Second,
+		Addr:         config.Address(),
+		Handler:      cors(metrics.Middleware(r)),
+		ReadTimeout:  config.Server.ReadTimeout,
+		WriteTimeout: config.Server.WriteTimeout,
 	}
 
-	err = server.ListenAndServe()
-
+	listener, err := net.Listen("tcp", server.Addr)
 	if err != nil {
 		logger.Fatalf("Failed to start server: %v", err)
 	}
+	logger.Infof("Listening on %s", listener.Addr())
+
+	err = utils.Serve(server, listener, config.Server.ShutdownTimeout)
+	if err != nil {
+		logger.Errorf("Server stopped: %v", err)
+	}
+
+	// shutdown, the logger is flushed by the deferred LoggerClose
+
+	stopWorkers()
+	workers.Wait()
+	repository.Close()
+	tracingCtx, cancel := context.WithTimeout(context.Background(), config.Server.ShutdownTimeout)
+	err = shutdownTracing(tracingCtx)
+	cancel()
+	if err != nil {
+		logger.Errorf("Couldn't flush spans: %v", err)
+	}
+	logger.Info("Server stopped")
 }
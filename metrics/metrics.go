@@ -0,0 +1,103 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/saskamegaprogrammist/userBalanceService/models"
+	"github.com/saskamegaprogrammist/userBalanceService/utils"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const namespace = "balance"
+
+// the service registers its metrics in its own registry, so only they and the process metrics are exposed
+
+var registry = prometheus.NewRegistry()
+
+var (
+	requests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by route, method and status.",
+	}, []string{"route", "method", "status"})
+	requestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Latency of HTTP requests by route, method and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method", "status"})
+
+	operations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "operations_total",
+		Help:      "Committed operations by type, replayed requests aren't counted.",
+	}, []string{"operation"})
+	operationSums = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "operation_sum",
+		Help:      "Sums of committed operations in currency units by type and currency.",
+		Buckets:   prometheus.ExponentialBuckets(1, 10, 8),
+	}, []string{"operation", "currency"})
+	insufficientFunds = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "insufficient_funds_total",
+		Help:      "Operations rejected because the wallet didn't have enough available funds.",
+	}, []string{"operation"})
+
+	ratesFetchDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "rates_fetch_duration_seconds",
+		Help:      "Latency of requests to the exchange rates API.",
+		Buckets:   prometheus.DefBuckets,
+	})
+	ratesFetchFailures = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rates_fetch_failures_total",
+		Help:      "Failed requests to the exchange rates API.",
+	})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		requests, requestDuration, operations, operationSums, insufficientFunds, ratesFetchDuration, ratesFetchFailures,
+	)
+}
+
+// Handler answers the metrics in the Prometheus text format
+
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+func observeRequest(route string, method string, status int, duration time.Duration) {
+	labels := prometheus.Labels{"route": route, "method": method, "status": strconv.Itoa(status)}
+	requests.With(labels).Inc()
+	requestDuration.With(labels).Observe(duration.Seconds())
+}
+
+// ObserveOperation counts a committed transaction and its sum
+
+func ObserveOperation(tx *models.Transaction) {
+	operation := utils.GetOperationName(tx.OperationType)
+	operations.WithLabelValues(operation).Inc()
+	operationSums.WithLabelValues(operation, tx.Currency).Observe(float64(tx.Sum) / math.Pow10(models.MoneyExponent))
+}
+
+// ObserveInsufficientFunds counts a rejected operation, holds are counted as "Hold"
+
+func ObserveInsufficientFunds(operation string) {
+	insufficientFunds.WithLabelValues(operation).Inc()
+}
+
+func ObserveRatesFetch(duration time.Duration, err error) {
+	ratesFetchDuration.Observe(duration.Seconds())
+	if err != nil {
+		ratesFetchFailures.Inc()
+	}
+}
@@ -0,0 +1,117 @@
+package metrics
+
+import (
+	"errors"
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/saskamegaprogrammist/userBalanceService/models"
+	"github.com/saskamegaprogrammist/userBalanceService/repository"
+	"github.com/saskamegaprogrammist/userBalanceService/utils"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func scrape(t *testing.T) string {
+	recorder := httptest.NewRecorder()
+	Handler().ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	body, err := ioutil.ReadAll(recorder.Body)
+	assert.NoError(t, err)
+	return string(body)
+}
+
+func TestMiddleware(t *testing.T) {
+	router := mux.NewRouter()
+	router.HandleFunc("/funds/hold/{id}/capture", func(writer http.ResponseWriter, req *http.Request) {
+		writer.WriteHeader(http.StatusPaymentRequired)
+	}).Methods("POST")
+	router.HandleFunc("/funds/export", func(writer http.ResponseWriter, req *http.Request) {
+		writer.Write([]byte("row\n"))
+		writer.(http.Flusher).Flush()
+	}).Methods("POST")
+	handler := Middleware(router)
+
+	t.Run("RouteTemplate", func(t *testing.T) {
+		labels := []string{"/funds/hold/{id}/capture", "POST", "402"}
+		before := testutil.ToFloat64(requests.WithLabelValues(labels...))
+
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/funds/hold/7/capture", nil))
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/funds/hold/8/capture", nil))
+
+		assert.Equal(t, before+2, testutil.ToFloat64(requests.WithLabelValues(labels...)))
+		assert.True(t, strings.Contains(scrape(t),
+			`balance_http_request_duration_seconds_count{method="POST",route="/funds/hold/{id}/capture",status="402"}`))
+	})
+
+	t.Run("Flush", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+
+		handler.ServeHTTP(recorder, httptest.NewRequest("POST", "/funds/export", nil))
+
+		assert.True(t, recorder.Flushed)
+		assert.Equal(t, "row\n", recorder.Body.String())
+		assert.Equal(t, 1.0, testutil.ToFloat64(requests.WithLabelValues("/funds/export", "POST", "200")))
+	})
+
+	t.Run("Unmatched", func(t *testing.T) {
+		before := testutil.ToFloat64(requests.WithLabelValues(unmatchedRoute, "GET", "404"))
+
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/funds/unknown/1", nil))
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/funds/export", nil))
+
+		assert.Equal(t, before+1, testutil.ToFloat64(requests.WithLabelValues(unmatchedRoute, "GET", "404")))
+		assert.Equal(t, 1.0, testutil.ToFloat64(requests.WithLabelValues(unmatchedRoute, "GET", "405")))
+	})
+}
+
+func TestOperations(t *testing.T) {
+	t.Run("Operation", func(t *testing.T) {
+		ObserveOperation(&models.Transaction{
+			OperationType: utils.GetOperationType("Transfer"),
+			Currency:      "USD",
+			Sum:           models.MoneyFromUnits(250),
+		})
+
+		assert.Equal(t, 1.0, testutil.ToFloat64(operations.WithLabelValues("Transfer")))
+		metrics := scrape(t)
+		assert.True(t, strings.Contains(metrics, `balance_operation_sum_sum{currency="USD",operation="Transfer"} 250`))
+		assert.True(t, strings.Contains(metrics, `balance_operation_sum_bucket{currency="USD",operation="Transfer",le="100"} 0`))
+		assert.True(t, strings.Contains(metrics, `balance_operation_sum_bucket{currency="USD",operation="Transfer",le="1000"} 1`))
+	})
+
+	t.Run("InsufficientFunds", func(t *testing.T) {
+		ObserveInsufficientFunds("Hold")
+
+		assert.Equal(t, 1.0, testutil.ToFloat64(insufficientFunds.WithLabelValues("Hold")))
+	})
+
+	t.Run("RatesFetch", func(t *testing.T) {
+		ObserveRatesFetch(20*time.Millisecond, nil)
+		ObserveRatesFetch(time.Second, errors.New("timeout"))
+
+		assert.Equal(t, 1.0, testutil.ToFloat64(ratesFetchFailures))
+		assert.True(t, strings.Contains(scrape(t), "balance_rates_fetch_duration_seconds_count 2"))
+	})
+}
+
+func TestPoolCollector(t *testing.T) {
+	stats := repository.PoolStats{MaxConnections: 10, Acquired: 3, Available: 2, Waits: 5}
+	ok := false
+	collector := newPoolCollector(func() (repository.PoolStats, bool) { return stats, ok })
+
+	assert.Equal(t, 0, testutil.CollectAndCount(collector))
+	ok = true
+	assert.Equal(t, 4, testutil.CollectAndCount(collector))
+	assert.NoError(t, testutil.CollectAndCompare(collector, strings.NewReader(`
+# HELP balance_db_pool_acquired_connections Connections in use.
+# TYPE balance_db_pool_acquired_connections gauge
+balance_db_pool_acquired_connections 3
+# HELP balance_db_pool_waits_total Operations that found every connection in use and waited for one.
+# TYPE balance_db_pool_waits_total counter
+balance_db_pool_waits_total 5
+`), "balance_db_pool_acquired_connections", "balance_db_pool_waits_total"))
+}
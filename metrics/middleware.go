@@ -0,0 +1,32 @@
+package metrics
+
+import (
+	"github.com/gorilla/mux"
+	"github.com/saskamegaprogrammist/userBalanceService/utils"
+	"net/http"
+	"time"
+)
+
+// requests that match no route are counted under one route, so unknown paths don't add label values
+
+const unmatchedRoute = "unmatched"
+
+// Middleware counts the requests served by router and their latency, labelled by the path template of the route
+
+func Middleware(router *mux.Router) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+		start := time.Now()
+		route := unmatchedRoute
+		var match mux.RouteMatch
+		if router.Match(req, &match) && match.Route != nil {
+			template, err := match.Route.GetPathTemplate()
+			if err == nil {
+				route = template
+			}
+		}
+
+		recorder := &utils.StatusRecorder{ResponseWriter: writer}
+		router.ServeHTTP(recorder, req)
+		observeRequest(route, req.Method, recorder.Status(), time.Since(start))
+	})
+}
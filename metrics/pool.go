@@ -0,0 +1,56 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/saskamegaprogrammist/userBalanceService/repository"
+)
+
+// poolCollector reads the connection pool stats when the metrics are scraped
+
+type poolCollector struct {
+	stats func() (repository.PoolStats, bool)
+
+	maxConnections *prometheus.Desc
+	acquired       *prometheus.Desc
+	available      *prometheus.Desc
+	waits          *prometheus.Desc
+}
+
+func newPoolDesc(name string, help string) *prometheus.Desc {
+	return prometheus.NewDesc(prometheus.BuildFQName(namespace, "db_pool", name), help, nil, nil)
+}
+
+func newPoolCollector(stats func() (repository.PoolStats, bool)) *poolCollector {
+	return &poolCollector{
+		stats:          stats,
+		maxConnections: newPoolDesc("max_connections", "Maximum number of open connections."),
+		acquired:       newPoolDesc("acquired_connections", "Connections in use."),
+		available:      newPoolDesc("available_connections", "Open connections that are idle."),
+		waits:          newPoolDesc("waits_total", "Operations that found every connection in use and waited for one."),
+	}
+}
+
+// RegisterPool exposes the stats of the database connection pool, stats answers false when
+// the backend has no pool
+
+func RegisterPool(stats func() (repository.PoolStats, bool)) error {
+	return registry.Register(newPoolCollector(stats))
+}
+
+func (collector *poolCollector) Describe(descs chan<- *prometheus.Desc) {
+	descs <- collector.maxConnections
+	descs <- collector.acquired
+	descs <- collector.available
+	descs <- collector.waits
+}
+
+func (collector *poolCollector) Collect(metrics chan<- prometheus.Metric) {
+	stats, ok := collector.stats()
+	if !ok {
+		return
+	}
+	metrics <- prometheus.MustNewConstMetric(collector.maxConnections, prometheus.GaugeValue, float64(stats.MaxConnections))
+	metrics <- prometheus.MustNewConstMetric(collector.acquired, prometheus.GaugeValue, float64(stats.Acquired))
+	metrics <- prometheus.MustNewConstMetric(collector.available, prometheus.GaugeValue, float64(stats.Available))
+	metrics <- prometheus.MustNewConstMetric(collector.waits, prometheus.CounterValue, float64(stats.Waits))
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/saskamegaprogrammist/userBalanceService/repository"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+const migrateUsage = "usage: userBalanceService [flags] migrate up|down [steps]|status"
+
+// runs the migrate subcommand, down reverts one migration unless steps are given.
+// SIGINT and SIGTERM cancel the running migration, its transaction is rolled back
+
+func runMigrate(args []string) error {
+	if len(args) == 0 || len(args) > 2 || len(args) == 2 && args[0] != "down" {
+		return errors.New(migrateUsage)
+	}
+	ctx, stop := interruptibleContext()
+	defer stop()
+
+	switch args[0] {
+	case "up":
+		applied, err := repository.MigrateUp(ctx)
+		for _, migration := range applied {
+			fmt.Printf("applied %04d_%s\n", migration.Version, migration.Name)
+		}
+		if err == nil && len(applied) == 0 {
+			fmt.Println("database is up to date")
+		}
+		return err
+	case "down":
+		steps := 1
+		if len(args) == 2 {
+			var err error
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps <= 0 {
+				return fmt.Errorf("steps must be a positive number")
+			}
+		}
+		reverted, err := repository.MigrateDown(ctx, steps)
+		for _, migration := range reverted {
+			fmt.Printf("reverted %04d_%s\n", migration.Version, migration.Name)
+		}
+		if err == nil && len(reverted) == 0 {
+			fmt.Println("no migrations are applied")
+		}
+		return err
+	case "status":
+		states, err := repository.MigrationStatus(ctx)
+		if err != nil {
+			return err
+		}
+		for _, state := range states {
+			status := "pending"
+			if state.Missing {
+				status = "applied " + state.AppliedAt.Format(time.RFC3339) + ", missing from this binary"
+			} else if state.Applied {
+				status = "applied " + state.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%04d_%-40s %s\n", state.Version, state.Name, status)
+		}
+		return nil
+	}
+	return errors.New(migrateUsage)
+}
+
+// the returned context is cancelled by SIGINT and SIGTERM until stop is called
+
+func interruptibleContext() (context.Context, func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		select {
+		case <-signals:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, func() {
+		signal.Stop(signals)
+		cancel()
+	}
+}
@@ -2,8 +2,13 @@ package models
 
 //easyjson:json
 type Balance struct {
-	Id       int     `json:"-"`
-	UserId   int     `json:"user_id"`
-	Balance  float64 `json:"balance"`
-	Currency string  `json:"currency"`
+	Id        int    `json:"-"`
+	UserId    int    `json:"user_id"`
+	Balance   Money  `json:"balance"`
+	Available Money  `json:"available"`
+	Currency  string `json:"currency"`
+	RateId    int    `json:"rate_id,omitempty"`
 }
+
+//easyjson:json
+type Balances []Balance
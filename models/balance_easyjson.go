@@ -17,7 +17,73 @@ var (
 	_ easyjson.Marshaler
 )
 
-func easyjsonBed2650eDecodeGithubComSaskamegaprogrammistUserBalanceServiceModels(in *jlexer.Lexer, out *Balance) {
+func easyjsonBed2650eDecodeGithubComSaskamegaprogrammistUserBalanceServiceModels(in *jlexer.Lexer, out *Balances) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		in.Skip()
+		*out = nil
+	} else {
+		in.Delim('[')
+		if *out == nil {
+			if !in.IsDelim(']') {
+				*out = make(Balances, 0, 1)
+			} else {
+				*out = Balances{}
+			}
+		} else {
+			*out = (*out)[:0]
+		}
+		for !in.IsDelim(']') {
+			var v1 Balance
+			(v1).UnmarshalEasyJSON(in)
+			*out = append(*out, v1)
+			in.WantComma()
+		}
+		in.Delim(']')
+	}
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjsonBed2650eEncodeGithubComSaskamegaprogrammistUserBalanceServiceModels(out *jwriter.Writer, in Balances) {
+	if in == nil && (out.Flags&jwriter.NilSliceAsEmpty) == 0 {
+		out.RawString("null")
+	} else {
+		out.RawByte('[')
+		for v2, v3 := range in {
+			if v2 > 0 {
+				out.RawByte(',')
+			}
+			(v3).MarshalEasyJSON(out)
+		}
+		out.RawByte(']')
+	}
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v Balances) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjsonBed2650eEncodeGithubComSaskamegaprogrammistUserBalanceServiceModels(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v Balances) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjsonBed2650eEncodeGithubComSaskamegaprogrammistUserBalanceServiceModels(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *Balances) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjsonBed2650eDecodeGithubComSaskamegaprogrammistUserBalanceServiceModels(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *Balances) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjsonBed2650eDecodeGithubComSaskamegaprogrammistUserBalanceServiceModels(l, v)
+}
+func easyjsonBed2650eDecodeGithubComSaskamegaprogrammistUserBalanceServiceModels1(in *jlexer.Lexer, out *Balance) {
 	isTopLevel := in.IsStart()
 	if in.IsNull() {
 		if isTopLevel {
@@ -39,9 +105,13 @@ func easyjsonBed2650eDecodeGithubComSaskamegaprogrammistUserBalanceServiceModels
 		case "user_id":
 			out.UserId = int(in.Int())
 		case "balance":
-			out.Balance = float64(in.Float64())
+			(out.Balance).UnmarshalEasyJSON(in)
+		case "available":
+			(out.Available).UnmarshalEasyJSON(in)
 		case "currency":
 			out.Currency = string(in.String())
+		case "rate_id":
+			out.RateId = int(in.Int())
 		default:
 			in.SkipRecursive()
 		}
@@ -52,7 +122,7 @@ func easyjsonBed2650eDecodeGithubComSaskamegaprogrammistUserBalanceServiceModels
 		in.Consumed()
 	}
 }
-func easyjsonBed2650eEncodeGithubComSaskamegaprogrammistUserBalanceServiceModels(out *jwriter.Writer, in Balance) {
+func easyjsonBed2650eEncodeGithubComSaskamegaprogrammistUserBalanceServiceModels1(out *jwriter.Writer, in Balance) {
 	out.RawByte('{')
 	first := true
 	_ = first
@@ -69,36 +139,46 @@ func easyjsonBed2650eEncodeGithubComSaskamegaprogrammistUserBalanceServiceModels
 	{
 		const prefix string = ",\"balance\":"
 		out.RawString(prefix)
-		out.Float64(float64(in.Balance))
+		(in.Balance).MarshalEasyJSON(out)
+	}
+	{
+		const prefix string = ",\"available\":"
+		out.RawString(prefix)
+		(in.Available).MarshalEasyJSON(out)
 	}
 	{
 		const prefix string = ",\"currency\":"
 		out.RawString(prefix)
 		out.String(string(in.Currency))
 	}
+	if in.RateId != 0 {
+		const prefix string = ",\"rate_id\":"
+		out.RawString(prefix)
+		out.Int(int(in.RateId))
+	}
 	out.RawByte('}')
 }
 
 // MarshalJSON supports json.Marshaler interface
 func (v Balance) MarshalJSON() ([]byte, error) {
 	w := jwriter.Writer{}
-	easyjsonBed2650eEncodeGithubComSaskamegaprogrammistUserBalanceServiceModels(&w, v)
+	easyjsonBed2650eEncodeGithubComSaskamegaprogrammistUserBalanceServiceModels1(&w, v)
 	return w.Buffer.BuildBytes(), w.Error
 }
 
 // MarshalEasyJSON supports easyjson.Marshaler interface
 func (v Balance) MarshalEasyJSON(w *jwriter.Writer) {
-	easyjsonBed2650eEncodeGithubComSaskamegaprogrammistUserBalanceServiceModels(w, v)
+	easyjsonBed2650eEncodeGithubComSaskamegaprogrammistUserBalanceServiceModels1(w, v)
 }
 
 // UnmarshalJSON supports json.Unmarshaler interface
 func (v *Balance) UnmarshalJSON(data []byte) error {
 	r := jlexer.Lexer{Data: data}
-	easyjsonBed2650eDecodeGithubComSaskamegaprogrammistUserBalanceServiceModels(&r, v)
+	easyjsonBed2650eDecodeGithubComSaskamegaprogrammistUserBalanceServiceModels1(&r, v)
 	return r.Error()
 }
 
 // UnmarshalEasyJSON supports easyjson.Unmarshaler interface
 func (v *Balance) UnmarshalEasyJSON(l *jlexer.Lexer) {
-	easyjsonBed2650eDecodeGithubComSaskamegaprogrammistUserBalanceServiceModels(l, v)
+	easyjsonBed2650eDecodeGithubComSaskamegaprogrammistUserBalanceServiceModels1(l, v)
 }
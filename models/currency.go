@@ -2,57 +2,131 @@ package models
 
 import (
 	"fmt"
-	"reflect"
+	"github.com/mailru/easyjson/jlexer"
+	"github.com/mailru/easyjson/jwriter"
+	"sort"
+	"strings"
+	"time"
 )
 
+// CurrencyRates are rates by ISO 4217 code: codes are normalized when decoded,
+// currencies missing from the registry and non-positive rates are skipped
+
+type CurrencyRates map[string]float64
+
 //easyjson:json
 type CurrencyAll struct {
-	Rates struct {
-		CAD float64 `json:"CAD"`
-		HKD float64 `json:"HKD"`
-		ISK float64 `json:"ISK"`
-		PHP float64 `json:"PHP"`
-		DKK float64 `json:"DKK"`
-		HUF float64 `json:"HUF"`
-		CZK float64 `json:"CZK"`
-		EUR float64 `json:"EUR"`
-		AUD float64 `json:"AUD"`
-		RON float64 `json:"RON"`
-		SEK float64 `json:"SEK"`
-		IDR float64 `json:"IDR"`
-		INR float64 `json:"INR"`
-		BRL float64 `json:"BRL"`
-		RUB float64 `json:"RUB"`
-		HRK float64 `json:"HRK"`
-		JPY float64 `json:"JPY"`
-		THB float64 `json:"THB"`
-		CHF float64 `json:"CHF"`
-		SGD float64 `json:"SGD"`
-		PLN float64 `json:"PLN"`
-		BGN float64 `json:"BGN"`
-		TRY float64 `json:"TRY"`
-		CNY float64 `json:"CNY"`
-		NOK float64 `json:"NOK"`
-		NZD float64 `json:"NZD"`
-		ZAR float64 `json:"ZAR"`
-		USD float64 `json:"USD"`
-		MXN float64 `json:"MXN"`
-		ILS float64 `json:"ILS"`
-		GBP float64 `json:"GBP"`
-		KRW float64 `json:"KRW"`
-		MYR float64 `json:"MYR"`
-	} `json:"rates"`
-	Base string `json:"base"`
-	Date string `json:"date"`
-}
-
-func (currencyAll *CurrencyAll) GetRatesFieldValueByName(currency string) (float64, error) {
-	r := reflect.ValueOf(currencyAll.Rates)
-	f := reflect.Indirect(r).FieldByName(currency)
-	if f.IsValid() {
-		return f.Float(), nil
-	} else {
-		return 0, fmt.Errorf("invalid currency")
+	Rates  CurrencyRates `json:"rates"`
+	Base   string        `json:"base"`
+	Date   string        `json:"date"`
+	Source string        `json:"-"`
+
+	// FetchedAt is set by providers that answer rates fetched earlier
+	FetchedAt time.Time `json:"-"`
+}
+
+func (rates *CurrencyRates) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	if l.IsNull() {
+		l.Skip()
+		*rates = nil
+		return
+	}
+	decoded := make(CurrencyRates)
+	l.Delim('{')
+	for !l.IsDelim('}') {
+		code := l.String()
+		l.WantColon()
+		rate := l.Float64()
+		l.WantComma()
+		normalized, err := NormalizeCurrency(code)
+		if err == nil && rate > 0 {
+			decoded[normalized] = rate
+		}
+	}
+	l.Delim('}')
+	*rates = decoded
+}
+
+func (rates CurrencyRates) MarshalEasyJSON(w *jwriter.Writer) {
+	if rates == nil {
+		w.RawString("null")
+		return
+	}
+	codes := make([]string, 0, len(rates))
+	for code := range rates {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	w.RawByte('{')
+	for i, code := range codes {
+		if i > 0 {
+			w.RawByte(',')
+		}
+		w.String(code)
+		w.RawByte(':')
+		w.Float64(rates[code])
+	}
+	w.RawByte('}')
+}
+
+func (rates *CurrencyRates) UnmarshalJSON(data []byte) error {
+	l := jlexer.Lexer{Data: data}
+	rates.UnmarshalEasyJSON(&l)
+	return l.Error()
+}
+
+func (rates CurrencyRates) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	rates.MarshalEasyJSON(&w)
+	return w.BuildBytes()
+}
+
+// returns the rate of currency, the base currency has rate 1
+
+func (currencyAll *CurrencyAll) Rate(currency string) (float64, error) {
+	if currency == currencyAll.Base {
+		return 1, nil
+	}
+	rate, ok := currencyAll.Rates[currency]
+	if !ok {
+		return 0, fmt.Errorf("no rate for %s", currency)
+	}
+	return rate, nil
+}
+
+// converts rates to another base currency that is one of the rates
+
+func (currencyAll *CurrencyAll) Rebase(base string) (CurrencyAll, error) {
+	if base == currencyAll.Base {
+		return *currencyAll, nil
 	}
+	baseRate, err := currencyAll.Rate(base)
+	if err != nil {
+		return *currencyAll, err
+	}
+	rebased := CurrencyAll{Rates: make(CurrencyRates, len(currencyAll.Rates)), Base: base, Date: currencyAll.Date,
+		Source: currencyAll.Source}
+	for code, rate := range currencyAll.Rates {
+		rebased.Rates[code] = rate / baseRate
+	}
+	if currencyAll.Base != "" {
+		rebased.Rates[currencyAll.Base] = 1 / baseRate
+	}
+	return rebased, nil
+}
 
+// upper-cases an ISO 4217 currency code and checks that it is in the registry
+
+func NormalizeCurrency(code string) (string, error) {
+	currency, err := GetCurrency(code)
+	return currency.Code, err
+}
+
+func GetCurrency(code string) (Currency, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	exponent, ok := currencyExponents[code]
+	if !ok {
+		return Currency{}, Errorf(ErrValidation, "invalid currency %q", code)
+	}
+	return Currency{Code: code, Exponent: exponent}, nil
 }
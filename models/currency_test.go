@@ -0,0 +1,79 @@
+package models
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestNormalizeCurrency(t *testing.T) {
+	tests := []struct {
+		code       string
+		normalized string
+		err        bool
+	}{
+		{"USD", "USD", false},
+		{"usd", "USD", false},
+		{" eur ", "EUR", false},
+		{"NGN", "NGN", false},
+		{"XXX", "", true},
+		{"US", "", true},
+		{"dsgsdg", "", true},
+	}
+	for _, test := range tests {
+		normalized, err := NormalizeCurrency(test.code)
+		if test.err {
+			assert.Error(t, err, test.code)
+			continue
+		}
+		assert.NoError(t, err, test.code)
+		assert.Equal(t, test.normalized, normalized, test.code)
+	}
+}
+
+func TestCurrencyConvert(t *testing.T) {
+	jpy, _ := GetCurrency("JPY")
+	assert.Equal(t, MoneyFromUnits(1059), jpy.Convert(MoneyFromUnits(10), 105.87))
+	kwd, _ := GetCurrency("KWD")
+	assert.Equal(t, Money(306), kwd.Convert(MoneyFromUnits(10), 0.30587))
+	usd, _ := GetCurrency("USD")
+	assert.Equal(t, Money(1234), usd.Convert(MoneyFromUnits(100), 0.1234))
+}
+
+func TestCurrencyCheckAmount(t *testing.T) {
+	jpy, _ := GetCurrency("JPY")
+	halfYen, _ := ParseMoney("1.50")
+	assert.True(t, errors.Is(jpy.CheckAmount(halfYen), ErrInvalidMoney))
+	assert.NoError(t, jpy.CheckAmount(MoneyFromUnits(2)))
+	usd, _ := GetCurrency("USD")
+	assert.NoError(t, usd.CheckAmount(halfYen))
+}
+
+func TestGetWalletCurrency(t *testing.T) {
+	currency, err := GetWalletCurrency("jpy")
+	assert.NoError(t, err)
+	assert.Equal(t, Currency{Code: "JPY", Exponent: 0}, currency)
+
+	_, err = GetWalletCurrency("KWD")
+	assert.True(t, errors.Is(err, ErrValidation))
+}
+
+func TestCurrencyRates(t *testing.T) {
+	var rates CurrencyAll
+	err := rates.UnmarshalJSON([]byte(`{"base": "EUR", "rates": {"usd": 1.18, "NGN": 450.5, "XYZ": 2, "RUB": 0}}`))
+	assert.NoError(t, err)
+	assert.Equal(t, CurrencyRates{"USD": 1.18, "NGN": 450.5}, rates.Rates)
+
+	rebased, err := rates.Rebase("USD")
+	assert.NoError(t, err)
+	assert.Equal(t, "USD", rebased.Base)
+	assert.InDelta(t, 1/1.18, rebased.Rates["EUR"], 1e-12)
+	assert.InDelta(t, 450.5/1.18, rebased.Rates["NGN"], 1e-12)
+
+	_, err = rates.Rebase("JPY")
+	assert.Error(t, err)
+
+	data, err := rates.MarshalJSON()
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `"rates":{"NGN":450.5,"USD":1.18}`)
+}
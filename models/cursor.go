@@ -0,0 +1,43 @@
+package models
+
+import (
+	"encoding/base64"
+	"github.com/mailru/easyjson"
+	"time"
+)
+
+var ErrInvalidCursor = NewError(ErrValidation, "invalid cursor")
+
+// Cursor points right after the last transaction of a page, it keeps the sort mode
+// so that it can't be used with a different ordering
+
+//easyjson:json
+type Cursor struct {
+	Sort    string    `json:"s"`
+	Desc    bool      `json:"d,omitempty"`
+	Created time.Time `json:"c"`
+	Sum     Money     `json:"v"`
+	Id      int       `json:"i"`
+}
+
+func NewCursor(sort string, desc bool, tx *Transaction) Cursor {
+	return Cursor{Sort: sort, Desc: desc, Created: tx.Created, Sum: tx.Sum, Id: tx.Id}
+}
+
+func (cursor Cursor) Encode() string {
+	data, _ := easyjson.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func DecodeCursor(value string) (Cursor, error) {
+	var cursor Cursor
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return cursor, ErrInvalidCursor
+	}
+	err = easyjson.Unmarshal(data, &cursor)
+	if err != nil || cursor.Id <= 0 {
+		return cursor, ErrInvalidCursor
+	}
+	return cursor, nil
+}
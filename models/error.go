@@ -1,9 +1,47 @@
 package models
 
+import (
+	"errors"
+	"fmt"
+)
+
+//easyjson:json
 type RequestError struct {
+	Code    string `json:"code,omitempty"`
 	Message string `json:"message"`
 }
 
-func CreateMessage(message string) RequestError {
-	return RequestError{Message: message}
+// kinds of domain errors, errors.Is matches every Error against its kind
+
+var (
+	ErrValidation        = errors.New("validation error")
+	ErrInsufficientFunds = errors.New("insufficient funds")
+	ErrNotFound          = errors.New("not found")
+	ErrConflict          = errors.New("conflict")
+	ErrUnavailable       = errors.New("unavailable")
+)
+
+var ErrInvalidUser = NewError(ErrValidation, "incorrect user id")
+
+// Error is a domain error whose message can be answered to the client as is
+
+type Error struct {
+	Kind    error
+	Message string
+}
+
+func NewError(kind error, message string) *Error {
+	return &Error{Kind: kind, Message: message}
+}
+
+func Errorf(kind error, format string, args ...interface{}) error {
+	return NewError(kind, fmt.Sprintf(format, args...))
+}
+
+func (err *Error) Error() string {
+	return err.Message
+}
+
+func (err *Error) Unwrap() error {
+	return err.Kind
 }
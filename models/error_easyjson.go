@@ -36,6 +36,8 @@ func easyjsonE34310f8DecodeGithubComSaskamegaprogrammistUserBalanceServiceModels
 			continue
 		}
 		switch key {
+		case "code":
+			out.Code = string(in.String())
 		case "message":
 			out.Message = string(in.String())
 		default:
@@ -52,9 +54,20 @@ func easyjsonE34310f8EncodeGithubComSaskamegaprogrammistUserBalanceServiceModels
 	out.RawByte('{')
 	first := true
 	_ = first
+	if in.Code != "" {
+		const prefix string = ",\"code\":"
+		first = false
+		out.RawString(prefix[1:])
+		out.String(string(in.Code))
+	}
 	{
 		const prefix string = ",\"message\":"
-		out.RawString(prefix[1:])
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
 		out.String(string(in.Message))
 	}
 	out.RawByte('}')
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+)
+
+// TransactionsFilter selects transactions of one user, zero values of the optional fields
+// don't restrict the selection
+
+type TransactionsFilter struct {
+	UserId         int
+	From           time.Time
+	To             time.Time
+	OperationTypes []int
+	Counterparty   int
+	MinSum         Money
+	MaxSum         Money
+	Sort           string
+	Desc           bool
+	Limit          int
+	After          *Cursor
+}
@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+)
+
+//easyjson:json
+type Hold struct {
+	Id       int       `json:"id"`
+	UserId   int       `json:"user_id"`
+	Currency string    `json:"currency"`
+	Sum      Money     `json:"sum"`
+	Captured Money     `json:"captured"`
+	Status   string    `json:"status"`
+	Created  time.Time `json:"created"`
+	Expires  time.Time `json:"expires"`
+}
@@ -0,0 +1,138 @@
+// Code generated by easyjson for marshaling/unmarshaling. DO NOT EDIT.
+
+package models
+
+import (
+	json "encoding/json"
+	easyjson "github.com/mailru/easyjson"
+	jlexer "github.com/mailru/easyjson/jlexer"
+	jwriter "github.com/mailru/easyjson/jwriter"
+)
+
+// suppress unused package warning
+var (
+	_ *json.RawMessage
+	_ *jlexer.Lexer
+	_ *jwriter.Writer
+	_ easyjson.Marshaler
+)
+
+func easyjson725dd887DecodeGithubComSaskamegaprogrammistUserBalanceServiceModels(in *jlexer.Lexer, out *Hold) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "id":
+			out.Id = int(in.Int())
+		case "user_id":
+			out.UserId = int(in.Int())
+		case "currency":
+			out.Currency = string(in.String())
+		case "sum":
+			(out.Sum).UnmarshalEasyJSON(in)
+		case "captured":
+			(out.Captured).UnmarshalEasyJSON(in)
+		case "status":
+			out.Status = string(in.String())
+		case "created":
+			if data := in.Raw(); in.Ok() {
+				in.AddError((out.Created).UnmarshalJSON(data))
+			}
+		case "expires":
+			if data := in.Raw(); in.Ok() {
+				in.AddError((out.Expires).UnmarshalJSON(data))
+			}
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjson725dd887EncodeGithubComSaskamegaprogrammistUserBalanceServiceModels(out *jwriter.Writer, in Hold) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"id\":"
+		out.RawString(prefix[1:])
+		out.Int(int(in.Id))
+	}
+	{
+		const prefix string = ",\"user_id\":"
+		out.RawString(prefix)
+		out.Int(int(in.UserId))
+	}
+	{
+		const prefix string = ",\"currency\":"
+		out.RawString(prefix)
+		out.String(string(in.Currency))
+	}
+	{
+		const prefix string = ",\"sum\":"
+		out.RawString(prefix)
+		(in.Sum).MarshalEasyJSON(out)
+	}
+	{
+		const prefix string = ",\"captured\":"
+		out.RawString(prefix)
+		(in.Captured).MarshalEasyJSON(out)
+	}
+	{
+		const prefix string = ",\"status\":"
+		out.RawString(prefix)
+		out.String(string(in.Status))
+	}
+	{
+		const prefix string = ",\"created\":"
+		out.RawString(prefix)
+		out.Raw((in.Created).MarshalJSON())
+	}
+	{
+		const prefix string = ",\"expires\":"
+		out.RawString(prefix)
+		out.Raw((in.Expires).MarshalJSON())
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v Hold) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjson725dd887EncodeGithubComSaskamegaprogrammistUserBalanceServiceModels(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v Hold) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjson725dd887EncodeGithubComSaskamegaprogrammistUserBalanceServiceModels(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *Hold) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjson725dd887DecodeGithubComSaskamegaprogrammistUserBalanceServiceModels(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *Hold) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjson725dd887DecodeGithubComSaskamegaprogrammistUserBalanceServiceModels(l, v)
+}
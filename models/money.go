@@ -0,0 +1,167 @@
+package models
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"github.com/jackc/pgx/pgtype"
+	"github.com/mailru/easyjson/jlexer"
+	"github.com/mailru/easyjson/jwriter"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Money is an exact amount in minor currency units (hundredths),
+// matching the numeric(20, 2) columns of the database
+
+type Money int64
+
+const MoneyExponent = 2
+const moneyScale = 100
+const maxMoneyDigits = 15
+
+var ErrInvalidMoney = NewError(ErrValidation, "invalid amount")
+
+func MoneyFromUnits(units int64) Money {
+	return Money(units * moneyScale)
+}
+
+// parses an exact decimal string, amounts with more significant decimal places
+// than MoneyExponent are rejected instead of being rounded
+
+func ParseMoney(value string) (Money, error) {
+	digits := strings.TrimPrefix(value, "-")
+	negative := len(digits) != len(value)
+
+	integer, fraction := digits, ""
+	if point := strings.IndexByte(digits, '.'); point != -1 {
+		integer, fraction = digits[:point], digits[point+1:]
+	}
+	if integer == "" || !isDigits(integer) || !isDigits(fraction) {
+		return 0, fmt.Errorf("%w: %q is not a decimal number", ErrInvalidMoney, value)
+	}
+	if len(strings.TrimLeft(integer, "0")) > maxMoneyDigits {
+		return 0, fmt.Errorf("%w: %q is too large", ErrInvalidMoney, value)
+	}
+	if len(strings.TrimRight(fraction, "0")) > MoneyExponent {
+		return 0, fmt.Errorf("%w: %q has more than %d decimal places", ErrInvalidMoney, value, MoneyExponent)
+	}
+	if len(fraction) > MoneyExponent {
+		fraction = fraction[:MoneyExponent]
+	}
+	fraction += strings.Repeat("0", MoneyExponent-len(fraction))
+
+	minor, err := strconv.ParseInt(integer+fraction, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q is too large", ErrInvalidMoney, value)
+	}
+	if negative {
+		minor = -minor
+	}
+	return Money(minor), nil
+}
+
+func isDigits(value string) bool {
+	for _, c := range value {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func (m Money) String() string {
+	minor := int64(m)
+	sign := ""
+	if minor < 0 {
+		sign = "-"
+		minor = -minor
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, minor/moneyScale, minor%moneyScale)
+}
+
+// converts the amount by rate, rounding half away from zero to minor units
+
+func (m Money) Convert(rate float64) Money {
+	product := new(big.Rat).Mul(new(big.Rat).SetInt64(int64(m)), new(big.Rat).SetFloat64(rate))
+	quotient, remainder := new(big.Int).QuoRem(product.Num(), product.Denom(), new(big.Int))
+	if new(big.Int).Mul(remainder, big.NewInt(2)).CmpAbs(product.Denom()) >= 0 {
+		quotient.Add(quotient, big.NewInt(int64(remainder.Sign())))
+	}
+	return Money(quotient.Int64())
+}
+
+// rounds half away from zero to exponent decimal places, exponent is at most MoneyExponent
+
+func (m Money) Round(exponent int) Money {
+	step := Money(1)
+	for i := exponent; i < MoneyExponent; i++ {
+		step *= 10
+	}
+	remainder := m % step
+	rounded := m - remainder
+	if 2*remainder >= step {
+		rounded += step
+	} else if 2*remainder <= -step {
+		rounded -= step
+	}
+	return rounded
+}
+
+func (m Money) MarshalEasyJSON(w *jwriter.Writer) {
+	w.String(m.String())
+}
+
+// accepts both JSON numbers and decimal strings
+
+func (m *Money) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	number := l.JsonNumber()
+	if !l.Ok() {
+		return
+	}
+	money, err := ParseMoney(number.String())
+	if err != nil {
+		l.AddError(err)
+		return
+	}
+	*m = money
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	m.MarshalEasyJSON(&w)
+	return w.BuildBytes()
+}
+
+func (m *Money) UnmarshalJSON(data []byte) error {
+	l := jlexer.Lexer{Data: data}
+	m.UnmarshalEasyJSON(&l)
+	return l.Error()
+}
+
+func (m Money) EncodeText(ci *pgtype.ConnInfo, buf []byte) ([]byte, error) {
+	return append(buf, m.String()...), nil
+}
+
+func (m Money) Value() (driver.Value, error) {
+	return m.String(), nil
+}
+
+func (m *Money) Scan(src interface{}) error {
+	var err error
+	switch src := src.(type) {
+	case string:
+		*m, err = ParseMoney(src)
+	case []byte:
+		*m, err = ParseMoney(string(src))
+	case int64:
+		*m = MoneyFromUnits(src)
+	case float64:
+		*m, err = ParseMoney(strconv.FormatFloat(src, 'f', MoneyExponent, 64))
+	case nil:
+		*m = 0
+	default:
+		err = fmt.Errorf("cannot scan %T into Money", src)
+	}
+	return err
+}
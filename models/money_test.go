@@ -0,0 +1,79 @@
+package models
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		value string
+		money Money
+		err   bool
+	}{
+		{"100", 10000, false},
+		{"114.3", 11430, false},
+		{"0.01", 1, false},
+		{"-5.25", -525, false},
+		{"1.500", 150, false},
+		{"0.001", 0, true},
+		{"1.005", 0, true},
+		{"1e3", 0, true},
+		{".5", 0, true},
+		{"", 0, true},
+		{"1234567890123456", 0, true},
+	}
+	for _, test := range tests {
+		money, err := ParseMoney(test.value)
+		if test.err {
+			assert.True(t, errors.Is(err, ErrInvalidMoney), test.value)
+			continue
+		}
+		assert.NoError(t, err, test.value)
+		assert.Equal(t, test.money, money, test.value)
+	}
+}
+
+func TestMoneyArithmetic(t *testing.T) {
+	a, _ := ParseMoney("0.1")
+	b, _ := ParseMoney("0.2")
+	assert.Equal(t, "0.30", (a + b).String())
+	assert.Equal(t, "-0.05", Money(-5).String())
+	assert.Equal(t, Money(2), Money(3).Convert(0.5))
+	assert.Equal(t, Money(-2), Money(-3).Convert(0.5))
+	assert.Equal(t, Money(1234), MoneyFromUnits(100).Convert(0.1234))
+}
+
+func TestMoneyJSON(t *testing.T) {
+	var tx Transaction
+	err := tx.UnmarshalJSON([]byte(`{"user_id": 1, "sum": "10.10"}`))
+	assert.NoError(t, err)
+	assert.Equal(t, Money(1010), tx.Sum)
+
+	err = tx.UnmarshalJSON([]byte(`{"user_id": 1, "sum": 10.101}`))
+	assert.True(t, errors.Is(err, ErrInvalidMoney))
+
+	data, err := Balance{UserId: 1, Balance: 1010}.MarshalJSON()
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `"balance":"10.10"`)
+}
+
+func TestMoneyRound(t *testing.T) {
+	tests := []struct {
+		value    string
+		exponent int
+		rounded  string
+	}{
+		{"10.49", 0, "10.00"},
+		{"10.50", 0, "11.00"},
+		{"-10.50", 0, "-11.00"},
+		{"10.45", 1, "10.50"},
+		{"10.44", 1, "10.40"},
+		{"10.45", 2, "10.45"},
+	}
+	for _, test := range tests {
+		money, _ := ParseMoney(test.value)
+		assert.Equal(t, test.rounded, money.Round(test.exponent).String(), test.value)
+	}
+}
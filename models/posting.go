@@ -0,0 +1,12 @@
+package models
+
+// Posting is one leg of a transaction in the double-entry journal,
+// positive amounts credit the account and negative ones debit it
+
+type Posting struct {
+	Id            int
+	TransactionId int
+	AccountId     int
+	Currency      string
+	Amount        Money
+}
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+)
+
+// Quote locks the rate of a cross-currency transfer of UserFromId until it expires:
+// SumFrom is debited in CurrencyFrom and Sum is credited in Currency
+
+//easyjson:json
+type Quote struct {
+	Id            int       `json:"id"`
+	UserFromId    int       `json:"user_from_id"`
+	CurrencyFrom  string    `json:"currency_from"`
+	Currency      string    `json:"currency"`
+	SumFrom       Money     `json:"sum_from"`
+	Sum           Money     `json:"sum"`
+	Rate          float64   `json:"rate"`
+	RateId        int       `json:"rate_id"`
+	TransactionId int       `json:"transaction_id,omitempty"`
+	Created       time.Time `json:"created"`
+	Expires       time.Time `json:"expires"`
+}
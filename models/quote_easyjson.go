@@ -0,0 +1,159 @@
+// Code generated by easyjson for marshaling/unmarshaling. DO NOT EDIT.
+
+package models
+
+import (
+	json "encoding/json"
+	easyjson "github.com/mailru/easyjson"
+	jlexer "github.com/mailru/easyjson/jlexer"
+	jwriter "github.com/mailru/easyjson/jwriter"
+)
+
+// suppress unused package warning
+var (
+	_ *json.RawMessage
+	_ *jlexer.Lexer
+	_ *jwriter.Writer
+	_ easyjson.Marshaler
+)
+
+func easyjson80cad33aDecodeGithubComSaskamegaprogrammistUserBalanceServiceModels(in *jlexer.Lexer, out *Quote) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "id":
+			out.Id = int(in.Int())
+		case "user_from_id":
+			out.UserFromId = int(in.Int())
+		case "currency_from":
+			out.CurrencyFrom = string(in.String())
+		case "currency":
+			out.Currency = string(in.String())
+		case "sum_from":
+			(out.SumFrom).UnmarshalEasyJSON(in)
+		case "sum":
+			(out.Sum).UnmarshalEasyJSON(in)
+		case "rate":
+			out.Rate = float64(in.Float64())
+		case "rate_id":
+			out.RateId = int(in.Int())
+		case "transaction_id":
+			out.TransactionId = int(in.Int())
+		case "created":
+			if data := in.Raw(); in.Ok() {
+				in.AddError((out.Created).UnmarshalJSON(data))
+			}
+		case "expires":
+			if data := in.Raw(); in.Ok() {
+				in.AddError((out.Expires).UnmarshalJSON(data))
+			}
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjson80cad33aEncodeGithubComSaskamegaprogrammistUserBalanceServiceModels(out *jwriter.Writer, in Quote) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"id\":"
+		out.RawString(prefix[1:])
+		out.Int(int(in.Id))
+	}
+	{
+		const prefix string = ",\"user_from_id\":"
+		out.RawString(prefix)
+		out.Int(int(in.UserFromId))
+	}
+	{
+		const prefix string = ",\"currency_from\":"
+		out.RawString(prefix)
+		out.String(string(in.CurrencyFrom))
+	}
+	{
+		const prefix string = ",\"currency\":"
+		out.RawString(prefix)
+		out.String(string(in.Currency))
+	}
+	{
+		const prefix string = ",\"sum_from\":"
+		out.RawString(prefix)
+		(in.SumFrom).MarshalEasyJSON(out)
+	}
+	{
+		const prefix string = ",\"sum\":"
+		out.RawString(prefix)
+		(in.Sum).MarshalEasyJSON(out)
+	}
+	{
+		const prefix string = ",\"rate\":"
+		out.RawString(prefix)
+		out.Float64(float64(in.Rate))
+	}
+	{
+		const prefix string = ",\"rate_id\":"
+		out.RawString(prefix)
+		out.Int(int(in.RateId))
+	}
+	if in.TransactionId != 0 {
+		const prefix string = ",\"transaction_id\":"
+		out.RawString(prefix)
+		out.Int(int(in.TransactionId))
+	}
+	{
+		const prefix string = ",\"created\":"
+		out.RawString(prefix)
+		out.Raw((in.Created).MarshalJSON())
+	}
+	{
+		const prefix string = ",\"expires\":"
+		out.RawString(prefix)
+		out.Raw((in.Expires).MarshalJSON())
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v Quote) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjson80cad33aEncodeGithubComSaskamegaprogrammistUserBalanceServiceModels(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v Quote) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjson80cad33aEncodeGithubComSaskamegaprogrammistUserBalanceServiceModels(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *Quote) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjson80cad33aDecodeGithubComSaskamegaprogrammistUserBalanceServiceModels(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *Quote) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjson80cad33aDecodeGithubComSaskamegaprogrammistUserBalanceServiceModels(l, v)
+}
@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+)
+
+// Rate is a stored exchange rate: one unit of Base is worth Rate units of Quote
+
+//easyjson:json
+type Rate struct {
+	Id        int       `json:"id"`
+	Base      string    `json:"base"`
+	Quote     string    `json:"quote"`
+	Rate      float64   `json:"rate"`
+	Source    string    `json:"source"`
+	FetchedAt time.Time `json:"fetched_at"`
+}
+
+//easyjson:json
+type Rates []Rate
@@ -0,0 +1,188 @@
+// Code generated by easyjson for marshaling/unmarshaling. DO NOT EDIT.
+
+package models
+
+import (
+	json "encoding/json"
+	easyjson "github.com/mailru/easyjson"
+	jlexer "github.com/mailru/easyjson/jlexer"
+	jwriter "github.com/mailru/easyjson/jwriter"
+)
+
+// suppress unused package warning
+var (
+	_ *json.RawMessage
+	_ *jlexer.Lexer
+	_ *jwriter.Writer
+	_ easyjson.Marshaler
+)
+
+func easyjson12f5eb66DecodeGithubComSaskamegaprogrammistUserBalanceServiceModels(in *jlexer.Lexer, out *Rates) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		in.Skip()
+		*out = nil
+	} else {
+		in.Delim('[')
+		if *out == nil {
+			if !in.IsDelim(']') {
+				*out = make(Rates, 0, 0)
+			} else {
+				*out = Rates{}
+			}
+		} else {
+			*out = (*out)[:0]
+		}
+		for !in.IsDelim(']') {
+			var v1 Rate
+			(v1).UnmarshalEasyJSON(in)
+			*out = append(*out, v1)
+			in.WantComma()
+		}
+		in.Delim(']')
+	}
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjson12f5eb66EncodeGithubComSaskamegaprogrammistUserBalanceServiceModels(out *jwriter.Writer, in Rates) {
+	if in == nil && (out.Flags&jwriter.NilSliceAsEmpty) == 0 {
+		out.RawString("null")
+	} else {
+		out.RawByte('[')
+		for v2, v3 := range in {
+			if v2 > 0 {
+				out.RawByte(',')
+			}
+			(v3).MarshalEasyJSON(out)
+		}
+		out.RawByte(']')
+	}
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v Rates) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjson12f5eb66EncodeGithubComSaskamegaprogrammistUserBalanceServiceModels(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v Rates) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjson12f5eb66EncodeGithubComSaskamegaprogrammistUserBalanceServiceModels(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *Rates) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjson12f5eb66DecodeGithubComSaskamegaprogrammistUserBalanceServiceModels(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *Rates) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjson12f5eb66DecodeGithubComSaskamegaprogrammistUserBalanceServiceModels(l, v)
+}
+func easyjson12f5eb66DecodeGithubComSaskamegaprogrammistUserBalanceServiceModels1(in *jlexer.Lexer, out *Rate) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "id":
+			out.Id = int(in.Int())
+		case "base":
+			out.Base = string(in.String())
+		case "quote":
+			out.Quote = string(in.String())
+		case "rate":
+			out.Rate = float64(in.Float64())
+		case "source":
+			out.Source = string(in.String())
+		case "fetched_at":
+			if data := in.Raw(); in.Ok() {
+				in.AddError((out.FetchedAt).UnmarshalJSON(data))
+			}
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjson12f5eb66EncodeGithubComSaskamegaprogrammistUserBalanceServiceModels1(out *jwriter.Writer, in Rate) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"id\":"
+		out.RawString(prefix[1:])
+		out.Int(int(in.Id))
+	}
+	{
+		const prefix string = ",\"base\":"
+		out.RawString(prefix)
+		out.String(string(in.Base))
+	}
+	{
+		const prefix string = ",\"quote\":"
+		out.RawString(prefix)
+		out.String(string(in.Quote))
+	}
+	{
+		const prefix string = ",\"rate\":"
+		out.RawString(prefix)
+		out.Float64(float64(in.Rate))
+	}
+	{
+		const prefix string = ",\"source\":"
+		out.RawString(prefix)
+		out.String(string(in.Source))
+	}
+	{
+		const prefix string = ",\"fetched_at\":"
+		out.RawString(prefix)
+		out.Raw((in.FetchedAt).MarshalJSON())
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v Rate) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjson12f5eb66EncodeGithubComSaskamegaprogrammistUserBalanceServiceModels1(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v Rate) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjson12f5eb66EncodeGithubComSaskamegaprogrammistUserBalanceServiceModels1(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *Rate) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjson12f5eb66DecodeGithubComSaskamegaprogrammistUserBalanceServiceModels1(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *Rate) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjson12f5eb66DecodeGithubComSaskamegaprogrammistUserBalanceServiceModels1(l, v)
+}
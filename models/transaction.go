@@ -6,15 +6,37 @@ import (
 
 //easyjson:json
 type Transaction struct {
-	Id            int       `json:"-"`
-	UserId        int       `json:"user_id"`
-	UserFromId    int       `json:"user_from_id"`
-	OperationType int       `json:"operation_type"`
-	Sum           float64   `json:"sum"`
-	Balance       float64   `json:"-"`
-	BalanceFrom   float64   `json:"-"`
-	Created       time.Time `json:"created"`
+	Id             int       `json:"id"`
+	UserId         int       `json:"user_id"`
+	UserFromId     int       `json:"user_from_id"`
+	Currency       string    `json:"currency"`
+	OperationType  int       `json:"operation_type"`
+	Sum            Money     `json:"sum"`
+	Balance        Money     `json:"-"`
+	BalanceFrom    Money     `json:"-"`
+	Created        time.Time `json:"created"`
+	IdempotencyKey string    `json:"idempotency_key,omitempty"`
+	RequestHash    string    `json:"-"`
+	ReversedId     int       `json:"reversed_id,omitempty"`
+	Refunded       Money     `json:"-"`
+	CurrencyFrom   string    `json:"currency_from,omitempty"`
+	SumFrom        Money     `json:"sum_from,omitempty"`
+	Rate           float64   `json:"rate,omitempty"`
+	QuoteId        int       `json:"quote_id,omitempty"`
+	Postings       []Posting `json:"-"`
 }
 
 //easyjson:json
 type Transactions []Transaction
+
+//easyjson:json
+type TransactionsPage struct {
+	Items      Transactions `json:"items"`
+	NextCursor string       `json:"next_cursor,omitempty"`
+}
+
+//easyjson:json
+type Reversal struct {
+	TransactionId int   `json:"transaction_id"`
+	Sum           Money `json:"sum"`
+}
@@ -17,7 +17,80 @@ var (
 	_ easyjson.Marshaler
 )
 
-func easyjson461f4b12DecodeGithubComSaskamegaprogrammistUserBalanceServiceModels(in *jlexer.Lexer, out *Transactions) {
+func easyjson461f4b12DecodeGithubComSaskamegaprogrammistUserBalanceServiceModels(in *jlexer.Lexer, out *TransactionsPage) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "items":
+			(out.Items).UnmarshalEasyJSON(in)
+		case "next_cursor":
+			out.NextCursor = string(in.String())
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjson461f4b12EncodeGithubComSaskamegaprogrammistUserBalanceServiceModels(out *jwriter.Writer, in TransactionsPage) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"items\":"
+		out.RawString(prefix[1:])
+		(in.Items).MarshalEasyJSON(out)
+	}
+	if in.NextCursor != "" {
+		const prefix string = ",\"next_cursor\":"
+		out.RawString(prefix)
+		out.String(string(in.NextCursor))
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v TransactionsPage) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjson461f4b12EncodeGithubComSaskamegaprogrammistUserBalanceServiceModels(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v TransactionsPage) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjson461f4b12EncodeGithubComSaskamegaprogrammistUserBalanceServiceModels(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *TransactionsPage) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjson461f4b12DecodeGithubComSaskamegaprogrammistUserBalanceServiceModels(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *TransactionsPage) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjson461f4b12DecodeGithubComSaskamegaprogrammistUserBalanceServiceModels(l, v)
+}
+func easyjson461f4b12DecodeGithubComSaskamegaprogrammistUserBalanceServiceModels1(in *jlexer.Lexer, out *Transactions) {
 	isTopLevel := in.IsStart()
 	if in.IsNull() {
 		in.Skip()
@@ -45,7 +118,7 @@ func easyjson461f4b12DecodeGithubComSaskamegaprogrammistUserBalanceServiceModels
 		in.Consumed()
 	}
 }
-func easyjson461f4b12EncodeGithubComSaskamegaprogrammistUserBalanceServiceModels(out *jwriter.Writer, in Transactions) {
+func easyjson461f4b12EncodeGithubComSaskamegaprogrammistUserBalanceServiceModels1(out *jwriter.Writer, in Transactions) {
 	if in == nil && (out.Flags&jwriter.NilSliceAsEmpty) == 0 {
 		out.RawString("null")
 	} else {
@@ -63,27 +136,27 @@ func easyjson461f4b12EncodeGithubComSaskamegaprogrammistUserBalanceServiceModels
 // MarshalJSON supports json.Marshaler interface
 func (v Transactions) MarshalJSON() ([]byte, error) {
 	w := jwriter.Writer{}
-	easyjson461f4b12EncodeGithubComSaskamegaprogrammistUserBalanceServiceModels(&w, v)
+	easyjson461f4b12EncodeGithubComSaskamegaprogrammistUserBalanceServiceModels1(&w, v)
 	return w.Buffer.BuildBytes(), w.Error
 }
 
 // MarshalEasyJSON supports easyjson.Marshaler interface
 func (v Transactions) MarshalEasyJSON(w *jwriter.Writer) {
-	easyjson461f4b12EncodeGithubComSaskamegaprogrammistUserBalanceServiceModels(w, v)
+	easyjson461f4b12EncodeGithubComSaskamegaprogrammistUserBalanceServiceModels1(w, v)
 }
 
 // UnmarshalJSON supports json.Unmarshaler interface
 func (v *Transactions) UnmarshalJSON(data []byte) error {
 	r := jlexer.Lexer{Data: data}
-	easyjson461f4b12DecodeGithubComSaskamegaprogrammistUserBalanceServiceModels(&r, v)
+	easyjson461f4b12DecodeGithubComSaskamegaprogrammistUserBalanceServiceModels1(&r, v)
 	return r.Error()
 }
 
 // UnmarshalEasyJSON supports easyjson.Unmarshaler interface
 func (v *Transactions) UnmarshalEasyJSON(l *jlexer.Lexer) {
-	easyjson461f4b12DecodeGithubComSaskamegaprogrammistUserBalanceServiceModels(l, v)
+	easyjson461f4b12DecodeGithubComSaskamegaprogrammistUserBalanceServiceModels1(l, v)
 }
-func easyjson461f4b12DecodeGithubComSaskamegaprogrammistUserBalanceServiceModels1(in *jlexer.Lexer, out *Transaction) {
+func easyjson461f4b12DecodeGithubComSaskamegaprogrammistUserBalanceServiceModels2(in *jlexer.Lexer, out *Transaction) {
 	isTopLevel := in.IsStart()
 	if in.IsNull() {
 		if isTopLevel {
@@ -102,18 +175,34 @@ func easyjson461f4b12DecodeGithubComSaskamegaprogrammistUserBalanceServiceModels
 			continue
 		}
 		switch key {
+		case "id":
+			out.Id = int(in.Int())
 		case "user_id":
 			out.UserId = int(in.Int())
 		case "user_from_id":
 			out.UserFromId = int(in.Int())
+		case "currency":
+			out.Currency = string(in.String())
 		case "operation_type":
 			out.OperationType = int(in.Int())
 		case "sum":
-			out.Sum = float64(in.Float64())
+			(out.Sum).UnmarshalEasyJSON(in)
 		case "created":
 			if data := in.Raw(); in.Ok() {
 				in.AddError((out.Created).UnmarshalJSON(data))
 			}
+		case "idempotency_key":
+			out.IdempotencyKey = string(in.String())
+		case "reversed_id":
+			out.ReversedId = int(in.Int())
+		case "currency_from":
+			out.CurrencyFrom = string(in.String())
+		case "sum_from":
+			(out.SumFrom).UnmarshalEasyJSON(in)
+		case "rate":
+			out.Rate = float64(in.Float64())
+		case "quote_id":
+			out.QuoteId = int(in.Int())
 		default:
 			in.SkipRecursive()
 		}
@@ -124,18 +213,18 @@ func easyjson461f4b12DecodeGithubComSaskamegaprogrammistUserBalanceServiceModels
 		in.Consumed()
 	}
 }
-func easyjson461f4b12EncodeGithubComSaskamegaprogrammistUserBalanceServiceModels1(out *jwriter.Writer, in Transaction) {
+func easyjson461f4b12EncodeGithubComSaskamegaprogrammistUserBalanceServiceModels2(out *jwriter.Writer, in Transaction) {
 	out.RawByte('{')
 	first := true
 	_ = first
+	{
+		const prefix string = ",\"id\":"
+		out.RawString(prefix[1:])
+		out.Int(int(in.Id))
+	}
 	{
 		const prefix string = ",\"user_id\":"
-		if first {
-			first = false
-			out.RawString(prefix[1:])
-		} else {
-			out.RawString(prefix)
-		}
+		out.RawString(prefix)
 		out.Int(int(in.UserId))
 	}
 	{
@@ -143,6 +232,11 @@ func easyjson461f4b12EncodeGithubComSaskamegaprogrammistUserBalanceServiceModels
 		out.RawString(prefix)
 		out.Int(int(in.UserFromId))
 	}
+	{
+		const prefix string = ",\"currency\":"
+		out.RawString(prefix)
+		out.String(string(in.Currency))
+	}
 	{
 		const prefix string = ",\"operation_type\":"
 		out.RawString(prefix)
@@ -151,36 +245,139 @@ func easyjson461f4b12EncodeGithubComSaskamegaprogrammistUserBalanceServiceModels
 	{
 		const prefix string = ",\"sum\":"
 		out.RawString(prefix)
-		out.Float64(float64(in.Sum))
+		(in.Sum).MarshalEasyJSON(out)
 	}
 	{
 		const prefix string = ",\"created\":"
 		out.RawString(prefix)
 		out.Raw((in.Created).MarshalJSON())
 	}
+	if in.IdempotencyKey != "" {
+		const prefix string = ",\"idempotency_key\":"
+		out.RawString(prefix)
+		out.String(string(in.IdempotencyKey))
+	}
+	if in.ReversedId != 0 {
+		const prefix string = ",\"reversed_id\":"
+		out.RawString(prefix)
+		out.Int(int(in.ReversedId))
+	}
+	if in.CurrencyFrom != "" {
+		const prefix string = ",\"currency_from\":"
+		out.RawString(prefix)
+		out.String(string(in.CurrencyFrom))
+	}
+	if in.SumFrom != 0 {
+		const prefix string = ",\"sum_from\":"
+		out.RawString(prefix)
+		(in.SumFrom).MarshalEasyJSON(out)
+	}
+	if in.Rate != 0 {
+		const prefix string = ",\"rate\":"
+		out.RawString(prefix)
+		out.Float64(float64(in.Rate))
+	}
+	if in.QuoteId != 0 {
+		const prefix string = ",\"quote_id\":"
+		out.RawString(prefix)
+		out.Int(int(in.QuoteId))
+	}
 	out.RawByte('}')
 }
 
 // MarshalJSON supports json.Marshaler interface
 func (v Transaction) MarshalJSON() ([]byte, error) {
 	w := jwriter.Writer{}
-	easyjson461f4b12EncodeGithubComSaskamegaprogrammistUserBalanceServiceModels1(&w, v)
+	easyjson461f4b12EncodeGithubComSaskamegaprogrammistUserBalanceServiceModels2(&w, v)
 	return w.Buffer.BuildBytes(), w.Error
 }
 
 // MarshalEasyJSON supports easyjson.Marshaler interface
 func (v Transaction) MarshalEasyJSON(w *jwriter.Writer) {
-	easyjson461f4b12EncodeGithubComSaskamegaprogrammistUserBalanceServiceModels1(w, v)
+	easyjson461f4b12EncodeGithubComSaskamegaprogrammistUserBalanceServiceModels2(w, v)
 }
 
 // UnmarshalJSON supports json.Unmarshaler interface
 func (v *Transaction) UnmarshalJSON(data []byte) error {
 	r := jlexer.Lexer{Data: data}
-	easyjson461f4b12DecodeGithubComSaskamegaprogrammistUserBalanceServiceModels1(&r, v)
+	easyjson461f4b12DecodeGithubComSaskamegaprogrammistUserBalanceServiceModels2(&r, v)
 	return r.Error()
 }
 
 // UnmarshalEasyJSON supports easyjson.Unmarshaler interface
 func (v *Transaction) UnmarshalEasyJSON(l *jlexer.Lexer) {
-	easyjson461f4b12DecodeGithubComSaskamegaprogrammistUserBalanceServiceModels1(l, v)
+	easyjson461f4b12DecodeGithubComSaskamegaprogrammistUserBalanceServiceModels2(l, v)
+}
+func easyjson461f4b12DecodeGithubComSaskamegaprogrammistUserBalanceServiceModels3(in *jlexer.Lexer, out *Reversal) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "transaction_id":
+			out.TransactionId = int(in.Int())
+		case "sum":
+			(out.Sum).UnmarshalEasyJSON(in)
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjson461f4b12EncodeGithubComSaskamegaprogrammistUserBalanceServiceModels3(out *jwriter.Writer, in Reversal) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"transaction_id\":"
+		out.RawString(prefix[1:])
+		out.Int(int(in.TransactionId))
+	}
+	{
+		const prefix string = ",\"sum\":"
+		out.RawString(prefix)
+		(in.Sum).MarshalEasyJSON(out)
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v Reversal) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjson461f4b12EncodeGithubComSaskamegaprogrammistUserBalanceServiceModels3(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v Reversal) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjson461f4b12EncodeGithubComSaskamegaprogrammistUserBalanceServiceModels3(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *Reversal) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjson461f4b12DecodeGithubComSaskamegaprogrammistUserBalanceServiceModels3(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *Reversal) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjson461f4b12DecodeGithubComSaskamegaprogrammistUserBalanceServiceModels3(l, v)
 }
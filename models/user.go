@@ -2,5 +2,6 @@ package models
 
 //easyjson:json
 type UserId struct {
-	UserId int `json:"user"`
+	UserId   int    `json:"user"`
+	Currency string `json:"currency,omitempty"`
 }
@@ -38,6 +38,8 @@ func easyjson9e1087fdDecodeGithubComSaskamegaprogrammistUserBalanceServiceModels
 		switch key {
 		case "user":
 			out.UserId = int(in.Int())
+		case "currency":
+			out.Currency = string(in.String())
 		default:
 			in.SkipRecursive()
 		}
@@ -57,6 +59,11 @@ func easyjson9e1087fdEncodeGithubComSaskamegaprogrammistUserBalanceServiceModels
 		out.RawString(prefix[1:])
 		out.Int(int(in.UserId))
 	}
+	if in.Currency != "" {
+		const prefix string = ",\"currency\":"
+		out.RawString(prefix)
+		out.String(string(in.Currency))
+	}
 	out.RawByte('}')
 }
 
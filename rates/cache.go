@@ -0,0 +1,84 @@
+package rates
+
+import (
+	"context"
+	"github.com/google/logger"
+	"github.com/saskamegaprogrammist/userBalanceService/models"
+	"sync"
+	"time"
+)
+
+// CachedProvider keeps rates of every base for TTL. Rates older than TTL are still answered
+// for MaxStale more while they are refreshed in the background, older ones are fetched synchronously
+
+type CachedProvider struct {
+	Provider RatesProvider
+	TTL      time.Duration
+	MaxStale time.Duration
+
+	mutex   sync.Mutex
+	entries map[string]*cacheEntry
+	now     func() time.Time
+}
+
+type cacheEntry struct {
+	rates      models.CurrencyAll
+	fetched    time.Time
+	refreshing bool
+}
+
+func NewCachedProvider(provider RatesProvider, ttl time.Duration, maxStale time.Duration) *CachedProvider {
+	return &CachedProvider{
+		Provider: provider,
+		TTL:      ttl,
+		MaxStale: maxStale,
+		entries:  make(map[string]*cacheEntry),
+		now:      time.Now,
+	}
+}
+
+func (cache *CachedProvider) GetRates(ctx context.Context, base string) (models.CurrencyAll, error) {
+	cache.mutex.Lock()
+	entry, ok := cache.entries[base]
+	if ok {
+		age := cache.now().Sub(entry.fetched)
+		if age < cache.TTL {
+			cache.mutex.Unlock()
+			return entry.rates, nil
+		}
+		if age < cache.TTL+cache.MaxStale {
+			if !entry.refreshing {
+				entry.refreshing = true
+				go cache.refresh(base, entry)
+			}
+			cache.mutex.Unlock()
+			return entry.rates, nil
+		}
+	}
+	cache.mutex.Unlock()
+	return cache.fetch(ctx, base)
+}
+
+// the refresh outlives the request that started it, so it isn't cancelled with it
+
+func (cache *CachedProvider) refresh(base string, entry *cacheEntry) {
+	_, err := cache.fetch(context.Background(), base)
+	if err != nil {
+		logger.Errorf("Failed to refresh %s rates: %v", base, err)
+		cache.mutex.Lock()
+		entry.refreshing = false
+		cache.mutex.Unlock()
+	}
+}
+
+func (cache *CachedProvider) fetch(ctx context.Context, base string) (models.CurrencyAll, error) {
+	rates, err := cache.Provider.GetRates(ctx, base)
+	if err != nil {
+		return rates, err
+	}
+	rates.FetchedAt = cache.now()
+	cache.mutex.Lock()
+	cache.entries[base] = &cacheEntry{rates: rates, fetched: rates.FetchedAt}
+	cache.mutex.Unlock()
+	return rates, nil
+}
@@ -0,0 +1,116 @@
+package rates
+
+import (
+	"context"
+	"errors"
+	"github.com/golang/mock/gomock"
+	"github.com/saskamegaprogrammist/userBalanceService/models"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func testRates(base string, usd float64) models.CurrencyAll {
+	rates := models.CurrencyAll{Base: base, Rates: models.CurrencyRates{"USD": usd}}
+	return rates
+}
+
+func newTestCache(provider RatesProvider, now *time.Time) *CachedProvider {
+	cache := NewCachedProvider(provider, time.Hour, 24*time.Hour)
+	cache.now = func() time.Time {
+		return *now
+	}
+	return cache
+}
+
+func TestCachedProvider(t *testing.T) {
+	start := time.Date(2020, 8, 2, 0, 0, 0, 0, time.UTC)
+
+	t.Run("FreshHit", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		now := start
+		mockProvider := NewMockRatesProvider(ctrl)
+		mockProvider.EXPECT().GetRates(gomock.Any(), "RUB").Return(testRates("RUB", 0.013), nil).Times(1)
+
+		cache := newTestCache(mockProvider, &now)
+		_, err := cache.GetRates(context.Background(), "RUB")
+		assert.NoError(t, err)
+
+		now = start.Add(59 * time.Minute)
+		rates, err := cache.GetRates(context.Background(), "RUB")
+		assert.NoError(t, err)
+		assert.Equal(t, 0.013, rates.Rates["USD"])
+		assert.Equal(t, start, rates.FetchedAt)
+	})
+
+	t.Run("StaleWhileRevalidate", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		now := start
+		refreshed := make(chan struct{})
+		mockProvider := NewMockRatesProvider(ctrl)
+		gomock.InOrder(
+			mockProvider.EXPECT().GetRates(gomock.Any(), "RUB").Return(testRates("RUB", 0.013), nil),
+			mockProvider.EXPECT().GetRates(gomock.Any(), "RUB").DoAndReturn(func(ctx context.Context, base string) (models.CurrencyAll, error) {
+				defer close(refreshed)
+				return testRates("RUB", 0.014), nil
+			}),
+		)
+
+		cache := newTestCache(mockProvider, &now)
+		_, err := cache.GetRates(context.Background(), "RUB")
+		assert.NoError(t, err)
+
+		now = start.Add(2 * time.Hour)
+		rates, err := cache.GetRates(context.Background(), "RUB")
+		assert.NoError(t, err)
+		assert.Equal(t, 0.013, rates.Rates["USD"])
+
+		<-refreshed
+		assert.Eventually(t, func() bool {
+			rates, err := cache.GetRates(context.Background(), "RUB")
+			return err == nil && rates.Rates["USD"] == 0.014
+		}, time.Second, time.Millisecond)
+	})
+
+	t.Run("TooStale", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		now := start
+		mockProvider := NewMockRatesProvider(ctrl)
+		gomock.InOrder(
+			mockProvider.EXPECT().GetRates(gomock.Any(), "RUB").Return(testRates("RUB", 0.013), nil),
+			mockProvider.EXPECT().GetRates(gomock.Any(), "RUB").Return(models.CurrencyAll{}, errors.New("rates API is unavailable")),
+		)
+
+		cache := newTestCache(mockProvider, &now)
+		_, err := cache.GetRates(context.Background(), "RUB")
+		assert.NoError(t, err)
+
+		now = start.Add(26 * time.Hour)
+		_, err = cache.GetRates(context.Background(), "RUB")
+		assert.Error(t, err)
+	})
+
+	t.Run("BasesAreCachedSeparately", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		now := start
+		mockProvider := NewMockRatesProvider(ctrl)
+		mockProvider.EXPECT().GetRates(gomock.Any(), "RUB").Return(testRates("RUB", 0.013), nil)
+		mockProvider.EXPECT().GetRates(gomock.Any(), "EUR").Return(testRates("EUR", 1.18), nil)
+
+		cache := newTestCache(mockProvider, &now)
+		rub, err := cache.GetRates(context.Background(), "RUB")
+		assert.NoError(t, err)
+		eur, err := cache.GetRates(context.Background(), "EUR")
+		assert.NoError(t, err)
+		assert.Equal(t, "RUB", rub.Base)
+		assert.Equal(t, "EUR", eur.Base)
+	})
+}
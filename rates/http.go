@@ -0,0 +1,81 @@
+package rates
+
+import (
+	"context"
+	"fmt"
+	easy_json "github.com/mailru/easyjson"
+	"github.com/saskamegaprogrammist/userBalanceService/metrics"
+	"github.com/saskamegaprogrammist/userBalanceService/models"
+	"github.com/saskamegaprogrammist/userBalanceService/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const httpTimeout = 10 * time.Second
+
+// HTTPProvider reads rates from an exchangeratesapi.io compatible API
+
+type HTTPProvider struct {
+	URL    string
+	APIKey string
+	Client *http.Client
+}
+
+func NewHTTPProvider(address string, apiKey string) *HTTPProvider {
+	return &HTTPProvider{URL: address, APIKey: apiKey, Client: &http.Client{Timeout: httpTimeout}}
+}
+
+// the span of the request leaves the query out of the URL, it holds the API key
+
+func (provider *HTTPProvider) GetRates(ctx context.Context, base string) (models.CurrencyAll, error) {
+	ctx, span := tracing.StartClient(ctx, "rates.GetRates", semconv.HTTPMethod(http.MethodGet),
+		semconv.HTTPURL(provider.URL), attribute.String("rates.base", base))
+	start := time.Now()
+	rates, err := provider.fetch(ctx, base)
+	metrics.ObserveRatesFetch(time.Since(start), err)
+	tracing.End(span, err)
+	return rates, err
+}
+
+func (provider *HTTPProvider) fetch(ctx context.Context, base string) (models.CurrencyAll, error) {
+	var rates models.CurrencyAll
+	query := url.Values{}
+	query.Set("base", base)
+	if provider.APIKey != "" {
+		query.Set("access_key", provider.APIKey)
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, provider.URL+"?"+query.Encode(), nil)
+	if err != nil {
+		return rates, fmt.Errorf("Failed to create rates request: %v", err)
+	}
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(request.Header))
+	response, err := provider.Client.Do(request)
+	if err != nil {
+		return rates, fmt.Errorf("Failed to request rates: %v", err)
+	}
+	defer response.Body.Close()
+	trace.SpanFromContext(ctx).SetAttributes(semconv.HTTPStatusCode(response.StatusCode))
+
+	if response.StatusCode != http.StatusOK {
+		return rates, fmt.Errorf("Rates API answered with status %d", response.StatusCode)
+	}
+	err = easy_json.UnmarshalFromReader(response.Body, &rates)
+	if err != nil {
+		return rates, fmt.Errorf("Error unmarshaling json: %v", err.Error())
+	}
+	if rates.Base == "" {
+		return rates, fmt.Errorf("Rates API answered without rates")
+	}
+	rates.Base, err = models.NormalizeCurrency(rates.Base)
+	if err != nil {
+		return rates, fmt.Errorf("Rates API answered with base currency: %v", err)
+	}
+	rates.Source = provider.URL
+	return rates, nil
+}
@@ -0,0 +1,85 @@
+package rates
+
+import (
+	"context"
+	"github.com/saskamegaprogrammist/userBalanceService/tracing"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHTTPProvider(t *testing.T) {
+	t.Run("RatesOK", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+			assert.Equal(t, "RUB", req.URL.Query().Get("base"))
+			assert.Equal(t, "secret", req.URL.Query().Get("access_key"))
+			writer.Write([]byte(`{"base": "RUB", "date": "2020-08-02", "rates": {"USD": 0.013}}`))
+		}))
+		defer server.Close()
+
+		rates, err := NewHTTPProvider(server.URL, "secret").GetRates(context.Background(), "RUB")
+
+		assert.NoError(t, err)
+		assert.Equal(t, 0.013, rates.Rates["USD"])
+	})
+
+	t.Run("Traceparent", func(t *testing.T) {
+		otel.SetTextMapPropagator(propagation.TraceContext{})
+		otel.SetTracerProvider(sdktrace.NewTracerProvider())
+		defer otel.SetTracerProvider(trace.NewNoopTracerProvider())
+		ctx, span := tracing.Start(context.Background(), "RatesUC.Refresh")
+		defer span.End()
+		server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+			traceparent := req.Header.Get("traceparent")
+			assert.True(t, strings.HasPrefix(traceparent, "00-"+span.SpanContext().TraceID().String()+"-"))
+			assert.False(t, strings.Contains(traceparent, span.SpanContext().SpanID().String()))
+			writer.Write([]byte(`{"base": "RUB", "date": "2020-08-02", "rates": {"USD": 0.013}}`))
+		}))
+		defer server.Close()
+
+		_, err := NewHTTPProvider(server.URL, "").GetRates(ctx, "RUB")
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("StatusError", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+			writer.WriteHeader(http.StatusUnauthorized)
+		}))
+		defer server.Close()
+
+		_, err := NewHTTPProvider(server.URL, "").GetRates(context.Background(), "RUB")
+
+		assert.Error(t, err)
+	})
+
+	t.Run("ErrorAnswer", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+			writer.Write([]byte(`{"success": false, "error": {"code": 101, "type": "missing_access_key"}}`))
+		}))
+		defer server.Close()
+
+		_, err := NewHTTPProvider(server.URL, "").GetRates(context.Background(), "RUB")
+
+		assert.Error(t, err)
+	})
+
+	t.Run("Cancelled", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+			t.Error("cancelled request reached the rates API")
+		}))
+		defer server.Close()
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := NewHTTPProvider(server.URL, "").GetRates(ctx, "RUB")
+
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,10 @@
+package rates
+
+import (
+	"context"
+	"github.com/saskamegaprogrammist/userBalanceService/models"
+)
+
+type RatesProvider interface {
+	GetRates(ctx context.Context, base string) (models.CurrencyAll, error)
+}
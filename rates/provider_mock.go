@@ -0,0 +1,50 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: rates/provider_interface.go
+
+// Package mock_rates is a generated GoMock package.
+package rates
+
+import (
+	context "context"
+	gomock "github.com/golang/mock/gomock"
+	models "github.com/saskamegaprogrammist/userBalanceService/models"
+	reflect "reflect"
+)
+
+// MockRatesProvider is a mock of RatesProvider interface
+type MockRatesProvider struct {
+	ctrl     *gomock.Controller
+	recorder *MockRatesProviderMockRecorder
+}
+
+// MockRatesProviderMockRecorder is the mock recorder for MockRatesProvider
+type MockRatesProviderMockRecorder struct {
+	mock *MockRatesProvider
+}
+
+// NewMockRatesProvider creates a new mock instance
+func NewMockRatesProvider(ctrl *gomock.Controller) *MockRatesProvider {
+	mock := &MockRatesProvider{ctrl: ctrl}
+	mock.recorder = &MockRatesProviderMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockRatesProvider) EXPECT() *MockRatesProviderMockRecorder {
+	return m.recorder
+}
+
+// GetRates mocks base method
+func (m *MockRatesProvider) GetRates(ctx context.Context, base string) (models.CurrencyAll, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRates", ctx, base)
+	ret0, _ := ret[0].(models.CurrencyAll)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRates indicates an expected call of GetRates
+func (mr *MockRatesProviderMockRecorder) GetRates(ctx, base interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRates", reflect.TypeOf((*MockRatesProvider)(nil).GetRates), ctx, base)
+}
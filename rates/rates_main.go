@@ -0,0 +1,39 @@
+package rates
+
+import (
+	"fmt"
+	"time"
+)
+
+type Config struct {
+	Provider string
+	URL      string
+	APIKey   string
+	File     string
+	TTL      time.Duration
+	MaxStale time.Duration
+}
+
+var provider RatesProvider
+
+// "http" reads rates from the rates API through a cache, "file" reads them from a rates file once
+
+func Init(config Config) error {
+	switch config.Provider {
+	case "", "http":
+		provider = NewCachedProvider(NewHTTPProvider(config.URL, config.APIKey), config.TTL, config.MaxStale)
+	case "file":
+		fileProvider, err := NewFileProvider(config.File)
+		if err != nil {
+			return err
+		}
+		provider = fileProvider
+	default:
+		return fmt.Errorf("unknown rates provider %q", config.Provider)
+	}
+	return nil
+}
+
+func GetProvider() RatesProvider {
+	return provider
+}
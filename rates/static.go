@@ -0,0 +1,46 @@
+package rates
+
+import (
+	"context"
+	"fmt"
+	easy_json "github.com/mailru/easyjson"
+	"github.com/saskamegaprogrammist/userBalanceService/models"
+	"io/ioutil"
+)
+
+// StaticProvider answers from fixed rates of one base currency, rates of other bases are derived from them
+
+type StaticProvider struct {
+	Rates models.CurrencyAll
+}
+
+// reads rates in the format of the rates API answer: {"base": "EUR", "rates": {"USD": 1.18, ...}}
+
+func NewFileProvider(path string) (*StaticProvider, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read rates file: %v", err)
+	}
+	var rates models.CurrencyAll
+	err = easy_json.Unmarshal(data, &rates)
+	if err != nil {
+		return nil, fmt.Errorf("Error unmarshaling rates file: %v", err)
+	}
+	if rates.Base == "" {
+		return nil, fmt.Errorf("Rates file has no base currency")
+	}
+	rates.Base, err = models.NormalizeCurrency(rates.Base)
+	if err != nil {
+		return nil, fmt.Errorf("Rates file has base currency: %v", err)
+	}
+	rates.Source = "file:" + path
+	return &StaticProvider{Rates: rates}, nil
+}
+
+func (provider *StaticProvider) GetRates(ctx context.Context, base string) (models.CurrencyAll, error) {
+	rates, err := provider.Rates.Rebase(base)
+	if rates.Source == "" {
+		rates.Source = "static"
+	}
+	return rates, err
+}
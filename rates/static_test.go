@@ -0,0 +1,58 @@
+package rates
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileProvider(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rates")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	t.Run("Rebase", func(t *testing.T) {
+		path := filepath.Join(dir, "rates.json")
+		err := ioutil.WriteFile(path, []byte(`{"base": "EUR", "rates": {"USD": 1.25, "RUB": 100}}`), 0644)
+		assert.NoError(t, err)
+
+		provider, err := NewFileProvider(path)
+		assert.NoError(t, err)
+
+		rates, err := provider.GetRates(context.Background(), "EUR")
+		assert.NoError(t, err)
+		assert.Equal(t, 1.25, rates.Rates["USD"])
+
+		rates, err = provider.GetRates(context.Background(), "USD")
+		assert.NoError(t, err)
+		assert.Equal(t, "USD", rates.Base)
+		assert.Equal(t, 1.0, rates.Rates["USD"])
+		assert.Equal(t, 80.0, rates.Rates["RUB"])
+		assert.Equal(t, 0.8, rates.Rates["EUR"])
+	})
+
+	t.Run("UnknownBase", func(t *testing.T) {
+		provider := StaticProvider{}
+		provider.Rates.Base = "EUR"
+
+		_, err := provider.GetRates(context.Background(), "XXX")
+		assert.Error(t, err)
+	})
+
+	t.Run("MissingFile", func(t *testing.T) {
+		_, err := NewFileProvider(filepath.Join(dir, "missing.json"))
+		assert.Error(t, err)
+	})
+
+	t.Run("NoBase", func(t *testing.T) {
+		path := filepath.Join(dir, "nobase.json")
+		err := ioutil.WriteFile(path, []byte(`{"rates": {"USD": 1.25}}`), 0644)
+		assert.NoError(t, err)
+
+		_, err = NewFileProvider(path)
+		assert.Error(t, err)
+	})
+}
@@ -1,56 +1,66 @@
 package repository
 
 import (
+	"context"
 	"fmt"
 	"github.com/google/logger"
+	"github.com/jackc/pgx"
 	"github.com/saskamegaprogrammist/userBalanceService/models"
-	"github.com/saskamegaprogrammist/userBalanceService/utils"
 )
 
 type BalanceRepo struct {
 }
 
-func (balanceRepo *BalanceRepo) GetBalanceByUserId(balance *models.Balance) (int, error) {
+func (balanceRepo *BalanceRepo) GetBalanceByUserId(ctx context.Context, balance *models.Balance) error {
+	ctx, cancel := withOperationTimeout(ctx)
+	defer cancel()
 	db := getPool()
-	transaction, err := db.Begin()
+	transaction, err := db.BeginEx(ctx, nil)
 	if err != nil {
 		dbError := fmt.Errorf("Failed to start transaction: %v", err.Error())
 		logger.Errorf(dbError.Error())
-		return utils.SERVER_ERROR, dbError
+		return dbError
 	}
 
-	row := transaction.QueryRow("SELECT id, user_id, balance::numeric FROM balance WHERE user_id = $1", balance.UserId)
-	err = row.Scan(&balance.Id, &balance.UserId, &balance.Balance)
+	row := transaction.QueryRowEx(ctx, "SELECT id, user_id, balance::numeric, (balance - held)::numeric, currency FROM balance WHERE user_id = $1 AND currency = $2", nil,
+		balance.UserId, balance.Currency)
+	err = row.Scan(&balance.Id, &balance.UserId, &balance.Balance, &balance.Available, &balance.Currency)
 	if err != nil {
-		logger.Errorf("Failed to retrieve balance: %v", err)
 		errRollback := transaction.Rollback()
 		if errRollback != nil {
 			logger.Errorf("Failed to rollback: %v", err)
-			return utils.SERVER_ERROR, errRollback
+			return errRollback
 		}
-		return utils.USER_ERROR, fmt.Errorf("this user doesn't exist")
+		if err == pgx.ErrNoRows {
+			return models.Errorf(models.ErrNotFound, "this wallet doesn't exist")
+		}
+		dbError := fmt.Errorf("Failed to retrieve balance: %v", err.Error())
+		logger.Errorf(dbError.Error())
+		return dbError
 	}
 
 	err = transaction.Commit()
 	if err != nil {
 		dbError := fmt.Errorf("Error commit: %v", err.Error())
 		logger.Errorf(dbError.Error())
-		return utils.SERVER_ERROR, dbError
+		return dbError
 	}
-	return utils.NO_ERROR, nil
+	return nil
 }
 
-func (balanceRepo *BalanceRepo) InsertUser(balance *models.Balance) error {
+func (balanceRepo *BalanceRepo) InsertUser(ctx context.Context, balance *models.Balance) error {
+	ctx, cancel := withOperationTimeout(ctx)
+	defer cancel()
 	db := getPool()
-	transaction, err := db.Begin()
+	transaction, err := db.BeginEx(ctx, nil)
 	if err != nil {
 		dbError := fmt.Errorf("Failed to start transaction: %v", err.Error())
 		logger.Errorf(dbError.Error())
 		return dbError
 	}
 
-	row := transaction.QueryRow("INSERT INTO balance (user_id) VALUES ($1) returning id",
-		balance.UserId)
+	row := transaction.QueryRowEx(ctx, "INSERT INTO balance (user_id, currency) VALUES ($1, $2) returning id", nil,
+		balance.UserId, balance.Currency)
 	err = row.Scan(&balance.Id)
 	if err != nil {
 		logger.Errorf("Failed to scan row: %v", err)
@@ -70,3 +80,66 @@ func (balanceRepo *BalanceRepo) InsertUser(balance *models.Balance) error {
 	}
 	return nil
 }
+
+func (balanceRepo *BalanceRepo) GetBalancesByUserId(ctx context.Context, user *models.UserId) ([]models.Balance, error) {
+	ctx, cancel := withOperationTimeout(ctx)
+	defer cancel()
+	balances := make([]models.Balance, 0)
+	db := getPool()
+	rows, err := db.QueryEx(ctx, "SELECT id, user_id, balance::numeric, (balance - held)::numeric, currency FROM balance WHERE user_id = $1 ORDER BY currency", nil,
+		user.UserId)
+	if err != nil {
+		dbError := fmt.Errorf("Failed to retrieve balances: %v", err.Error())
+		logger.Errorf(dbError.Error())
+		return balances, dbError
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var balance models.Balance
+		err = rows.Scan(&balance.Id, &balance.UserId, &balance.Balance, &balance.Available, &balance.Currency)
+		if err != nil {
+			dbError := fmt.Errorf("Failed to retrieve balance: %v", err.Error())
+			logger.Errorf(dbError.Error())
+			return balances, dbError
+		}
+		balances = append(balances, balance)
+	}
+	return balances, rows.Err()
+}
+
+// checks that the journal is balanced and that every wallet balance equals the sum of its postings.
+// It reads the whole journal, so it isn't limited by the operation timeout, only by ctx
+
+func (balanceRepo *BalanceRepo) VerifyBalances(ctx context.Context) error {
+	db := getPool()
+	var currency string
+	var total models.Money
+	row := db.QueryRowEx(ctx, `SELECT currency, SUM(amount)::numeric FROM postings GROUP BY currency HAVING SUM(amount) != 0 LIMIT 1`, nil)
+	err := row.Scan(&currency, &total)
+	if err == nil {
+		return fmt.Errorf("postings in %s sum up to %s", currency, total)
+	}
+	if err != pgx.ErrNoRows {
+		dbError := fmt.Errorf("Failed to verify postings: %v", err.Error())
+		logger.Errorf(dbError.Error())
+		return dbError
+	}
+
+	var balance models.Balance
+	row = db.QueryRowEx(ctx, `SELECT balance.user_id, balance.currency, balance.balance::numeric, COALESCE(SUM(postings.amount), 0)::numeric
+		FROM balance LEFT JOIN postings ON postings.account_id = balance.id
+		WHERE balance.user_id > 0
+		GROUP BY balance.id HAVING balance.balance != COALESCE(SUM(postings.amount), 0) LIMIT 1`, nil)
+	err = row.Scan(&balance.UserId, &balance.Currency, &balance.Balance, &total)
+	if err == nil {
+		return fmt.Errorf("balance of user %d in %s is %s, postings sum up to %s",
+			balance.UserId, balance.Currency, balance.Balance, total)
+	}
+	if err != pgx.ErrNoRows {
+		dbError := fmt.Errorf("Failed to verify balances: %v", err.Error())
+		logger.Errorf(dbError.Error())
+		return dbError
+	}
+	return nil
+}
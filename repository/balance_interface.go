@@ -1,8 +1,13 @@
 package repository
 
-import "github.com/saskamegaprogrammist/userBalanceService/models"
+import (
+	"context"
+	"github.com/saskamegaprogrammist/userBalanceService/models"
+)
 
 type BalanceRepoI interface {
-	GetBalanceByUserId(user *models.Balance) (int, error)
-	InsertUser(balance *models.Balance) error
+	GetBalanceByUserId(ctx context.Context, user *models.Balance) error
+	InsertUser(ctx context.Context, balance *models.Balance) error
+	GetBalancesByUserId(ctx context.Context, user *models.UserId) ([]models.Balance, error)
+	VerifyBalances(ctx context.Context) error
 }
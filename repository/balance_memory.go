@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"github.com/saskamegaprogrammist/userBalanceService/models"
+	"sort"
+)
+
+type MemoryBalanceRepo struct {
+	store *MemoryStore
+}
+
+func (balanceRepo *MemoryBalanceRepo) GetBalanceByUserId(ctx context.Context, balance *models.Balance) error {
+	ctx, cancel := withOperationTimeout(ctx)
+	defer cancel()
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	store := balanceRepo.store
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	wallet := store.wallet(balance.UserId, balance.Currency)
+	if wallet == nil {
+		return models.Errorf(models.ErrNotFound, "this wallet doesn't exist")
+	}
+	wallet.read(balance)
+	return nil
+}
+
+func (balanceRepo *MemoryBalanceRepo) InsertUser(ctx context.Context, balance *models.Balance) error {
+	ctx, cancel := withOperationTimeout(ctx)
+	defer cancel()
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	store := balanceRepo.store
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	if store.wallet(balance.UserId, balance.Currency) != nil {
+		return fmt.Errorf("wallet of user %d in %s violates unique constraint balance_user_id_currency",
+			balance.UserId, balance.Currency)
+	}
+	balance.Id = store.addWallet(balance.UserId, balance.Currency).id
+	return nil
+}
+
+func (balanceRepo *MemoryBalanceRepo) GetBalancesByUserId(ctx context.Context, user *models.UserId) ([]models.Balance, error) {
+	ctx, cancel := withOperationTimeout(ctx)
+	defer cancel()
+	balances := make([]models.Balance, 0)
+	if ctx.Err() != nil {
+		return balances, ctx.Err()
+	}
+	store := balanceRepo.store
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	for _, wallet := range store.wallets {
+		if wallet.userId != user.UserId {
+			continue
+		}
+		var balance models.Balance
+		wallet.read(&balance)
+		balances = append(balances, balance)
+	}
+	sort.Slice(balances, func(i, j int) bool {
+		return balances[i].Currency < balances[j].Currency
+	})
+	return balances, nil
+}
+
+// checks that the journal is balanced and that every wallet balance equals the sum of its postings.
+// It reads the whole journal, so it isn't limited by the operation timeout, only by ctx
+
+func (balanceRepo *MemoryBalanceRepo) VerifyBalances(ctx context.Context) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	store := balanceRepo.store
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	totals := make(map[string]models.Money)
+	accounts := make(map[int]models.Money)
+	for _, posting := range store.postings {
+		totals[posting.Currency] += posting.Amount
+		accounts[posting.AccountId] += posting.Amount
+	}
+	currencies := make([]string, 0, len(totals))
+	for currency := range totals {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+	for _, currency := range currencies {
+		if totals[currency] != 0 {
+			return fmt.Errorf("postings in %s sum up to %s", currency, totals[currency])
+		}
+	}
+
+	for _, wallet := range store.wallets {
+		if wallet.userId > 0 && wallet.balance != accounts[wallet.id] {
+			return fmt.Errorf("balance of user %d in %s is %s, postings sum up to %s",
+				wallet.userId, wallet.currency, wallet.balance, accounts[wallet.id])
+		}
+	}
+	return nil
+}
@@ -5,6 +5,7 @@
 package repository
 
 import (
+	context "context"
 	gomock "github.com/golang/mock/gomock"
 	models "github.com/saskamegaprogrammist/userBalanceService/models"
 	reflect "reflect"
@@ -34,30 +35,58 @@ func (m *MockBalanceRepoI) EXPECT() *MockBalanceRepoIMockRecorder {
 }
 
 // GetBalanceByUserId mocks base method
-func (m *MockBalanceRepoI) GetBalanceByUserId(user *models.Balance) (int, error) {
+func (m *MockBalanceRepoI) GetBalanceByUserId(ctx context.Context, user *models.Balance) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetBalanceByUserId", user)
-	ret0, _ := ret[0].(int)
-	ret1, _ := ret[1].(error)
-	return ret0, ret1
+	ret := m.ctrl.Call(m, "GetBalanceByUserId", ctx, user)
+	ret0, _ := ret[0].(error)
+	return ret0
 }
 
 // GetBalanceByUserId indicates an expected call of GetBalanceByUserId
-func (mr *MockBalanceRepoIMockRecorder) GetBalanceByUserId(user interface{}) *gomock.Call {
+func (mr *MockBalanceRepoIMockRecorder) GetBalanceByUserId(ctx, user interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBalanceByUserId", reflect.TypeOf((*MockBalanceRepoI)(nil).GetBalanceByUserId), user)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBalanceByUserId", reflect.TypeOf((*MockBalanceRepoI)(nil).GetBalanceByUserId), ctx, user)
 }
 
 // InsertUser mocks base method
-func (m *MockBalanceRepoI) InsertUser(balance *models.Balance) error {
+func (m *MockBalanceRepoI) InsertUser(ctx context.Context, balance *models.Balance) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "InsertUser", balance)
+	ret := m.ctrl.Call(m, "InsertUser", ctx, balance)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // InsertUser indicates an expected call of InsertUser
-func (mr *MockBalanceRepoIMockRecorder) InsertUser(balance interface{}) *gomock.Call {
+func (mr *MockBalanceRepoIMockRecorder) InsertUser(ctx, balance interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertUser", reflect.TypeOf((*MockBalanceRepoI)(nil).InsertUser), ctx, balance)
+}
+
+// GetBalancesByUserId mocks base method
+func (m *MockBalanceRepoI) GetBalancesByUserId(ctx context.Context, user *models.UserId) ([]models.Balance, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetBalancesByUserId", ctx, user)
+	ret0, _ := ret[0].([]models.Balance)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetBalancesByUserId indicates an expected call of GetBalancesByUserId
+func (mr *MockBalanceRepoIMockRecorder) GetBalancesByUserId(ctx, user interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBalancesByUserId", reflect.TypeOf((*MockBalanceRepoI)(nil).GetBalancesByUserId), ctx, user)
+}
+
+// VerifyBalances mocks base method
+func (m *MockBalanceRepoI) VerifyBalances(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VerifyBalances", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// VerifyBalances indicates an expected call of VerifyBalances
+func (mr *MockBalanceRepoIMockRecorder) VerifyBalances(ctx interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertUser", reflect.TypeOf((*MockBalanceRepoI)(nil).InsertUser), balance)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyBalances", reflect.TypeOf((*MockBalanceRepoI)(nil).VerifyBalances), ctx)
 }
@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/google/logger"
+	"github.com/saskamegaprogrammist/userBalanceService/models"
+)
+
+type SQLiteBalanceRepo struct {
+}
+
+func (balanceRepo *SQLiteBalanceRepo) GetBalanceByUserId(ctx context.Context, balance *models.Balance) error {
+	ctx, cancel := withOperationTimeout(ctx)
+	defer cancel()
+	db := getSQLite()
+	row := db.QueryRowContext(ctx, "SELECT id, user_id, balance, balance - held, currency FROM balance WHERE user_id = ? AND currency = ?",
+		balance.UserId, balance.Currency)
+	err := row.Scan(&balance.Id, &balance.UserId, sqliteMoney{&balance.Balance}, sqliteMoney{&balance.Available}, &balance.Currency)
+	if err == sql.ErrNoRows {
+		return models.Errorf(models.ErrNotFound, "this wallet doesn't exist")
+	}
+	if err != nil {
+		dbError := fmt.Errorf("Failed to retrieve balance: %v", err.Error())
+		logger.Errorf(dbError.Error())
+		return dbError
+	}
+	return nil
+}
+
+func (balanceRepo *SQLiteBalanceRepo) InsertUser(ctx context.Context, balance *models.Balance) error {
+	ctx, cancel := withOperationTimeout(ctx)
+	defer cancel()
+	db := getSQLite()
+	row := db.QueryRowContext(ctx, "INSERT INTO balance (user_id, currency) VALUES (?, ?) RETURNING id",
+		balance.UserId, balance.Currency)
+	err := row.Scan(&balance.Id)
+	if err != nil {
+		logger.Errorf("Failed to scan row: %v", err)
+		return err
+	}
+	return nil
+}
+
+func (balanceRepo *SQLiteBalanceRepo) GetBalancesByUserId(ctx context.Context, user *models.UserId) ([]models.Balance, error) {
+	ctx, cancel := withOperationTimeout(ctx)
+	defer cancel()
+	balances := make([]models.Balance, 0)
+	db := getSQLite()
+	rows, err := db.QueryContext(ctx, "SELECT id, user_id, balance, balance - held, currency FROM balance WHERE user_id = ? ORDER BY currency",
+		user.UserId)
+	if err != nil {
+		dbError := fmt.Errorf("Failed to retrieve balances: %v", err.Error())
+		logger.Errorf(dbError.Error())
+		return balances, dbError
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var balance models.Balance
+		err = rows.Scan(&balance.Id, &balance.UserId, sqliteMoney{&balance.Balance}, sqliteMoney{&balance.Available}, &balance.Currency)
+		if err != nil {
+			dbError := fmt.Errorf("Failed to retrieve balance: %v", err.Error())
+			logger.Errorf(dbError.Error())
+			return balances, dbError
+		}
+		balances = append(balances, balance)
+	}
+	return balances, rows.Err()
+}
+
+// checks that the journal is balanced and that every wallet balance equals the sum of its postings.
+// It reads the whole journal, so it isn't limited by the operation timeout, only by ctx
+
+func (balanceRepo *SQLiteBalanceRepo) VerifyBalances(ctx context.Context) error {
+	db := getSQLite()
+	var currency string
+	var total models.Money
+	row := db.QueryRowContext(ctx, `SELECT currency, SUM(amount) FROM postings GROUP BY currency HAVING SUM(amount) != 0 LIMIT 1`)
+	err := row.Scan(&currency, sqliteMoney{&total})
+	if err == nil {
+		return fmt.Errorf("postings in %s sum up to %s", currency, total)
+	}
+	if err != sql.ErrNoRows {
+		dbError := fmt.Errorf("Failed to verify postings: %v", err.Error())
+		logger.Errorf(dbError.Error())
+		return dbError
+	}
+
+	var balance models.Balance
+	row = db.QueryRowContext(ctx, `SELECT balance.user_id, balance.currency, balance.balance, COALESCE(SUM(postings.amount), 0)
+		FROM balance LEFT JOIN postings ON postings.account_id = balance.id
+		WHERE balance.user_id > 0
+		GROUP BY balance.id HAVING balance.balance != COALESCE(SUM(postings.amount), 0) LIMIT 1`)
+	err = row.Scan(&balance.UserId, &balance.Currency, sqliteMoney{&balance.Balance}, sqliteMoney{&total})
+	if err == nil {
+		return fmt.Errorf("balance of user %d in %s is %s, postings sum up to %s",
+			balance.UserId, balance.Currency, balance.Balance, total)
+	}
+	if err != sql.ErrNoRows {
+		dbError := fmt.Errorf("Failed to verify balances: %v", err.Error())
+		logger.Errorf(dbError.Error())
+		return dbError
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package repository
+
+import (
+	"context"
+	"github.com/saskamegaprogrammist/userBalanceService/models"
+	"github.com/saskamegaprogrammist/userBalanceService/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+type TracedBalanceRepo struct {
+	balanceRepo BalanceRepoI
+	system      attribute.KeyValue
+}
+
+func (balanceRepo *TracedBalanceRepo) GetBalanceByUserId(ctx context.Context, balance *models.Balance) error {
+	ctx, span := startSpan(ctx, balanceRepo.system, "BalanceRepo.GetBalanceByUserId")
+	err := balanceRepo.balanceRepo.GetBalanceByUserId(ctx, balance)
+	tracing.End(span, err)
+	return err
+}
+
+func (balanceRepo *TracedBalanceRepo) InsertUser(ctx context.Context, balance *models.Balance) error {
+	ctx, span := startSpan(ctx, balanceRepo.system, "BalanceRepo.InsertUser")
+	err := balanceRepo.balanceRepo.InsertUser(ctx, balance)
+	tracing.End(span, err)
+	return err
+}
+
+func (balanceRepo *TracedBalanceRepo) GetBalancesByUserId(ctx context.Context, user *models.UserId) ([]models.Balance, error) {
+	ctx, span := startSpan(ctx, balanceRepo.system, "BalanceRepo.GetBalancesByUserId")
+	balances, err := balanceRepo.balanceRepo.GetBalancesByUserId(ctx, user)
+	tracing.End(span, err)
+	return balances, err
+}
+
+func (balanceRepo *TracedBalanceRepo) VerifyBalances(ctx context.Context) error {
+	ctx, span := startSpan(ctx, balanceRepo.system, "BalanceRepo.VerifyBalances")
+	err := balanceRepo.balanceRepo.VerifyBalances(ctx)
+	tracing.End(span, err)
+	return err
+}
@@ -0,0 +1,710 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgx"
+	"github.com/saskamegaprogrammist/userBalanceService/models"
+	"github.com/saskamegaprogrammist/userBalanceService/utils"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// the conformance suite runs against every backend, the PostgreSQL one needs POSTGRES_DSN. Its database
+// isn't emptied, so every test works with users of its own
+
+var conformanceBackends = []struct {
+	name string
+	open func(t *testing.T)
+}{
+	{"Memory", func(t *testing.T) { InitMemory(time.Minute) }},
+	{"SQLite", openTempSQLite},
+	{"Postgres", openPostgres},
+}
+
+func openTempSQLite(t *testing.T) {
+	err := InitSQLite(filepath.Join(t.TempDir(), "balances.db"), 5, time.Minute)
+	if err != nil {
+		t.Fatalf("Couldn't initialize database: %v", err)
+	}
+	t.Cleanup(Close)
+	_, err = MigrateUp(context.Background())
+	if err != nil {
+		t.Fatalf("Couldn't migrate database: %v", err)
+	}
+}
+
+func openPostgres(t *testing.T) {
+	dsn := os.Getenv("POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("POSTGRES_DSN is not set")
+	}
+	config, err := pgx.ParseConnectionString(dsn)
+	if err != nil {
+		t.Fatalf("Couldn't parse POSTGRES_DSN: %v", err)
+	}
+	err = Init(config, 5, time.Minute)
+	if err != nil {
+		t.Fatalf("Couldn't initialize database: %v", err)
+	}
+	t.Cleanup(Close)
+	_, err = MigrateUp(context.Background())
+	if err != nil {
+		t.Fatalf("Couldn't migrate database: %v", err)
+	}
+}
+
+var lastUserId = time.Now().Unix() % 1000000 * 1000
+
+func newUserId() int {
+	return int(atomic.AddInt64(&lastUserId, 1))
+}
+
+var (
+	addOperation      = utils.GetOperationType("Add")
+	withdrawOperation = utils.GetOperationType("Withdraw")
+	transferOperation = utils.GetOperationType("Transfer")
+	reverseOperation  = utils.GetOperationType("Reverse")
+)
+
+// fills the balance snapshots and postings of tx and adds it. Money comes from the cash-in account for
+// additions and from the sender for transfers, the other operations move it from the user to the cash-out
+// account. Transfers with CurrencyFrom are exchanged through the exchange account
+
+func post(work WorkI, tx *models.Transaction) error {
+	currencyFrom, sumFrom := tx.Currency, tx.Sum
+	if tx.CurrencyFrom != "" {
+		currencyFrom, sumFrom = tx.CurrencyFrom, tx.SumFrom
+	}
+	from := models.Balance{UserId: utils.CASH_IN_ACCOUNT, Currency: currencyFrom}
+	to := models.Balance{UserId: tx.UserId, Currency: tx.Currency}
+	switch tx.OperationType {
+	case addOperation:
+	case transferOperation:
+		from.UserId = tx.UserFromId
+	default:
+		from.UserId = tx.UserId
+		to.UserId = utils.CASH_OUT_ACCOUNT
+	}
+	accounts := []*models.Balance{&from, &to}
+	if currencyFrom != tx.Currency {
+		accounts = append(accounts, &models.Balance{UserId: utils.EXCHANGE_ACCOUNT, Currency: currencyFrom},
+			&models.Balance{UserId: utils.EXCHANGE_ACCOUNT, Currency: tx.Currency})
+	}
+	for _, account := range accounts {
+		var err error
+		if account.UserId > 0 {
+			err = work.LockBalances(account)
+		} else {
+			err = work.GetSystemAccount(account)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	switch {
+	case to.UserId > 0 && from.UserId > 0:
+		tx.Balance = to.Balance + tx.Sum
+		tx.BalanceFrom = from.Balance - sumFrom
+	case to.UserId > 0:
+		tx.Balance = to.Balance + tx.Sum
+	default:
+		tx.Balance = from.Balance - sumFrom
+	}
+	if tx.Created.IsZero() {
+		tx.Created = time.Now()
+	}
+	tx.Postings = []models.Posting{{AccountId: from.Id, Currency: currencyFrom, Amount: -sumFrom}}
+	if currencyFrom != tx.Currency {
+		tx.Postings = append(tx.Postings,
+			models.Posting{AccountId: accounts[2].Id, Currency: currencyFrom, Amount: sumFrom},
+			models.Posting{AccountId: accounts[3].Id, Currency: tx.Currency, Amount: -tx.Sum})
+	}
+	tx.Postings = append(tx.Postings, models.Posting{AccountId: to.Id, Currency: tx.Currency, Amount: tx.Sum})
+	return work.AddTransaction(tx)
+}
+
+func record(t *testing.T, tx models.Transaction) models.Transaction {
+	t.Helper()
+	if tx.Currency == "" {
+		tx.Currency = utils.CURRENCY
+	}
+	err := GetUnitOfWork().Do(context.Background(), func(work WorkI) error {
+		return post(work, &tx)
+	})
+	assert.NoError(t, err)
+	return tx
+}
+
+func balanceOf(t *testing.T, userId int, currency string) models.Balance {
+	t.Helper()
+	balance := models.Balance{UserId: userId, Currency: currency}
+	assert.NoError(t, GetBalanceRepo().GetBalanceByUserId(context.Background(), &balance))
+	return balance
+}
+
+func transactionIds(txs []models.Transaction) []int {
+	ids := make([]int, 0, len(txs))
+	for _, tx := range txs {
+		ids = append(ids, tx.Id)
+	}
+	return ids
+}
+
+func TestConformance(t *testing.T) {
+	for _, backend := range conformanceBackends {
+		backend := backend
+		t.Run(backend.name, func(t *testing.T) {
+			for _, test := range conformanceTests {
+				test := test
+				t.Run(test.name, func(t *testing.T) {
+					backend.open(t)
+					test.run(t)
+				})
+			}
+		})
+	}
+}
+
+var conformanceTests = []struct {
+	name string
+	run  func(t *testing.T)
+}{
+	{"Wallets", testWallets},
+	{"PostingsUpdateUserBalances", testPostingsUpdateUserBalances},
+	{"NonNegativeBalance", testNonNegativeBalance},
+	{"UnbalancedPostings", testUnbalancedPostings},
+	{"SelfTransfer", testSelfTransfer},
+	{"RollbackOnError", testRollbackOnError},
+	{"CancelledWorkRollsBack", testCancelledWorkRollsBack},
+	{"Holds", testHolds},
+	{"IdempotencyKeys", testIdempotencyKeys},
+	{"Reversals", testReversals},
+	{"UserTransactions", testUserTransactions},
+	{"BalancesAsOf", testBalancesAsOf},
+	{"RatesAndQuotes", testRatesAndQuotes},
+	{"ConcurrentTransfers", testConcurrentTransfers},
+}
+
+func testWallets(t *testing.T) {
+	ctx := context.Background()
+	balanceRepo := GetBalanceRepo()
+	userId := newUserId()
+
+	missing := models.Balance{UserId: userId, Currency: utils.CURRENCY}
+	err := balanceRepo.GetBalanceByUserId(ctx, &missing)
+	assert.True(t, errors.Is(err, models.ErrNotFound))
+
+	for _, currency := range []string{"USD", utils.CURRENCY} {
+		balance := models.Balance{UserId: userId, Currency: currency}
+		assert.NoError(t, balanceRepo.InsertUser(ctx, &balance))
+		assert.NotZero(t, balance.Id)
+	}
+	assert.Error(t, balanceRepo.InsertUser(ctx, &models.Balance{UserId: userId, Currency: "USD"}))
+
+	balance := balanceOf(t, userId, "USD")
+	assert.Equal(t, models.Money(0), balance.Balance)
+	assert.Equal(t, models.Money(0), balance.Available)
+
+	balances, err := balanceRepo.GetBalancesByUserId(ctx, &models.UserId{UserId: userId})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(balances))
+	assert.Equal(t, utils.CURRENCY, balances[0].Currency)
+	assert.Equal(t, "USD", balances[1].Currency)
+}
+
+func testPostingsUpdateUserBalances(t *testing.T) {
+	userId := newUserId()
+	record(t, models.Transaction{UserId: userId, OperationType: addOperation, Sum: models.MoneyFromUnits(100)})
+	withdrawal := record(t, models.Transaction{UserId: userId, OperationType: withdrawOperation, Sum: models.MoneyFromUnits(30)})
+
+	balance := balanceOf(t, userId, utils.CURRENCY)
+	assert.Equal(t, models.MoneyFromUnits(70), balance.Balance)
+	assert.Equal(t, models.MoneyFromUnits(70), balance.Available)
+	assert.Equal(t, models.MoneyFromUnits(70), withdrawal.Balance)
+	assert.Equal(t, models.Money(0), balanceOf(t, utils.CASH_OUT_ACCOUNT, utils.CURRENCY).Balance)
+
+	stored := models.Transaction{Id: withdrawal.Id}
+	err := GetUnitOfWork().Do(context.Background(), func(work WorkI) error {
+		return work.GetTransaction(&stored)
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, withdrawal.Sum, stored.Sum)
+	assert.Equal(t, 2, len(stored.Postings))
+	assert.Equal(t, stored.Postings[0].Amount, -stored.Postings[1].Amount)
+	assert.NoError(t, GetBalanceRepo().VerifyBalances(context.Background()))
+}
+
+func testNonNegativeBalance(t *testing.T) {
+	userId := newUserId()
+	record(t, models.Transaction{UserId: userId, OperationType: addOperation, Sum: models.MoneyFromUnits(50)})
+
+	err := GetUnitOfWork().Do(context.Background(), func(work WorkI) error {
+		wallet := models.Balance{UserId: userId, Currency: utils.CURRENCY}
+		cashOut := models.Balance{UserId: utils.CASH_OUT_ACCOUNT, Currency: utils.CURRENCY}
+		err := work.LockBalances(&wallet)
+		if err == nil {
+			err = work.GetSystemAccount(&cashOut)
+		}
+		if err != nil {
+			return err
+		}
+		// the snapshot is valid, only the balance update breaks the check
+		return work.AddTransaction(&models.Transaction{UserId: userId, Currency: utils.CURRENCY,
+			OperationType: withdrawOperation, Sum: models.MoneyFromUnits(80), Created: time.Now(),
+			Postings: []models.Posting{
+				{AccountId: wallet.Id, Currency: utils.CURRENCY, Amount: -models.MoneyFromUnits(80)},
+				{AccountId: cashOut.Id, Currency: utils.CURRENCY, Amount: models.MoneyFromUnits(80)},
+			}})
+	})
+	assert.Error(t, err)
+	assert.Equal(t, models.MoneyFromUnits(50), balanceOf(t, userId, utils.CURRENCY).Balance)
+	assert.NoError(t, GetBalanceRepo().VerifyBalances(context.Background()))
+}
+
+func testUnbalancedPostings(t *testing.T) {
+	userId := newUserId()
+	err := GetUnitOfWork().Do(context.Background(), func(work WorkI) error {
+		wallet := models.Balance{UserId: userId, Currency: utils.CURRENCY}
+		err := work.LockBalances(&wallet)
+		if err != nil {
+			return err
+		}
+		return work.AddTransaction(&models.Transaction{UserId: userId, Currency: utils.CURRENCY,
+			OperationType: addOperation, Sum: models.MoneyFromUnits(10), Balance: models.MoneyFromUnits(10),
+			Created: time.Now(), Postings: []models.Posting{
+				{AccountId: wallet.Id, Currency: utils.CURRENCY, Amount: models.MoneyFromUnits(10)},
+			}})
+	})
+	assert.Error(t, err)
+
+	err = GetUnitOfWork().Do(context.Background(), func(work WorkI) error {
+		return work.AddTransaction(&models.Transaction{UserId: userId, Currency: utils.CURRENCY,
+			OperationType: addOperation, Sum: models.MoneyFromUnits(10), Created: time.Now()})
+	})
+	assert.Error(t, err)
+}
+
+// the snapshots of a transfer to the sending wallet would both describe it, the later balance is ambiguous,
+// a conversion between two wallets of the user is allowed
+
+func testSelfTransfer(t *testing.T) {
+	userId := newUserId()
+	record(t, models.Transaction{UserId: userId, OperationType: addOperation, Sum: models.MoneyFromUnits(100)})
+
+	err := GetUnitOfWork().Do(context.Background(), func(work WorkI) error {
+		return post(work, &models.Transaction{UserId: userId, UserFromId: userId, Currency: utils.CURRENCY,
+			OperationType: transferOperation, Sum: models.MoneyFromUnits(30)})
+	})
+	assert.Error(t, err)
+
+	assert.Equal(t, models.MoneyFromUnits(100), balanceOf(t, userId, utils.CURRENCY).Balance)
+	balances, err := GetTransactionsRepo().GetBalancesAsOf(context.Background(), &models.UserId{UserId: userId}, time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(balances))
+	assert.Equal(t, models.MoneyFromUnits(100), balances[0].Balance)
+
+	err = GetUnitOfWork().Do(context.Background(), func(work WorkI) error {
+		return post(work, &models.Transaction{UserId: userId, UserFromId: userId, Currency: "USD",
+			CurrencyFrom: utils.CURRENCY, SumFrom: models.MoneyFromUnits(20), Sum: models.MoneyFromUnits(2),
+			Rate: 0.1, OperationType: transferOperation})
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, models.MoneyFromUnits(80), balanceOf(t, userId, utils.CURRENCY).Balance)
+	assert.Equal(t, models.MoneyFromUnits(2), balanceOf(t, userId, "USD").Balance)
+}
+
+func testRollbackOnError(t *testing.T) {
+	userId := newUserId()
+	stop := errors.New("stop")
+	err := GetUnitOfWork().Do(context.Background(), func(work WorkI) error {
+		err := post(work, &models.Transaction{UserId: userId, Currency: utils.CURRENCY, OperationType: addOperation,
+			Sum: models.MoneyFromUnits(100)})
+		if err != nil {
+			return err
+		}
+		return stop
+	})
+	assert.True(t, errors.Is(err, stop))
+
+	missing := models.Balance{UserId: userId, Currency: utils.CURRENCY}
+	err = GetBalanceRepo().GetBalanceByUserId(context.Background(), &missing)
+	assert.True(t, errors.Is(err, models.ErrNotFound))
+	txs, err := GetTransactionsRepo().GetUserTransactions(context.Background(),
+		&models.TransactionsFilter{UserId: userId, Limit: utils.LIMIT_DEFAULT})
+	assert.NoError(t, err)
+	assert.Empty(t, txs)
+}
+
+func testCancelledWorkRollsBack(t *testing.T) {
+	userId := newUserId()
+	record(t, models.Transaction{UserId: userId, OperationType: addOperation, Sum: models.MoneyFromUnits(100)})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	err := GetUnitOfWork().Do(ctx, func(work WorkI) error {
+		err := post(work, &models.Transaction{UserId: userId, Currency: utils.CURRENCY,
+			OperationType: withdrawOperation, Sum: models.MoneyFromUnits(40)})
+		cancel()
+		return err
+	})
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.Equal(t, models.MoneyFromUnits(100), balanceOf(t, userId, utils.CURRENCY).Balance)
+}
+
+func testHolds(t *testing.T) {
+	userId := newUserId()
+	record(t, models.Transaction{UserId: userId, OperationType: addOperation, Sum: models.MoneyFromUnits(100)})
+	addHold := func(hold *models.Hold) error {
+		return GetUnitOfWork().Do(context.Background(), func(work WorkI) error {
+			wallet := models.Balance{UserId: userId, Currency: utils.CURRENCY}
+			err := work.LockBalances(&wallet)
+			if err != nil {
+				return err
+			}
+			return work.AddHold(hold)
+		})
+	}
+
+	now := time.Now()
+	hold := models.Hold{UserId: userId, Currency: utils.CURRENCY, Sum: models.MoneyFromUnits(60),
+		Status: utils.HOLD_ACTIVE, Created: now.Add(-time.Hour), Expires: now.Add(-time.Minute)}
+	assert.NoError(t, addHold(&hold))
+	assert.NotZero(t, hold.Id)
+	assert.Equal(t, models.MoneyFromUnits(40), balanceOf(t, userId, utils.CURRENCY).Available)
+
+	tooLarge := models.Hold{UserId: userId, Currency: utils.CURRENCY, Sum: models.MoneyFromUnits(50),
+		Status: utils.HOLD_ACTIVE, Created: now, Expires: now.Add(time.Hour)}
+	assert.Error(t, addHold(&tooLarge))
+	assert.Equal(t, models.MoneyFromUnits(40), balanceOf(t, userId, utils.CURRENCY).Available)
+
+	expiredIds := func() []int {
+		holds, err := GetHoldsRepo().GetExpiredHolds(context.Background(), now)
+		assert.NoError(t, err)
+		ids := make([]int, 0)
+		for _, expired := range holds {
+			ids = append(ids, expired.Id)
+		}
+		return ids
+	}
+	assert.Contains(t, expiredIds(), hold.Id)
+
+	err := GetUnitOfWork().Do(context.Background(), func(work WorkI) error {
+		stored := models.Hold{Id: hold.Id}
+		err := work.GetHold(&stored)
+		if err != nil {
+			return err
+		}
+		assert.Equal(t, hold.Sum, stored.Sum)
+		stored.Status = utils.HOLD_VOIDED
+		return work.CloseHold(&stored)
+	})
+	assert.NoError(t, err)
+	balance := balanceOf(t, userId, utils.CURRENCY)
+	assert.Equal(t, models.MoneyFromUnits(100), balance.Available)
+	assert.Equal(t, models.MoneyFromUnits(100), balance.Balance)
+	assert.NotContains(t, expiredIds(), hold.Id)
+
+	err = GetUnitOfWork().Do(context.Background(), func(work WorkI) error {
+		return work.GetHold(&models.Hold{Id: hold.Id + 1000000})
+	})
+	assert.True(t, errors.Is(err, models.ErrNotFound))
+}
+
+func testIdempotencyKeys(t *testing.T) {
+	userId := newUserId()
+	created := time.Now().Add(-48 * time.Hour)
+	tx := record(t, models.Transaction{UserId: userId, OperationType: addOperation, Sum: models.MoneyFromUnits(10),
+		IdempotencyKey: "key", RequestHash: "hash", Created: created})
+
+	find := func(callerId int) (models.Transaction, error) {
+		found := models.Transaction{IdempotencyKey: "key"}
+		err := GetUnitOfWork().Do(context.Background(), func(work WorkI) error {
+			return work.GetTransactionByIdempotencyKey(&found, callerId)
+		})
+		return found, err
+	}
+	found, err := find(userId)
+	assert.NoError(t, err)
+	assert.Equal(t, tx.Id, found.Id)
+	assert.Equal(t, "hash", found.RequestHash)
+	_, err = find(newUserId())
+	assert.True(t, errors.Is(err, models.ErrNotFound))
+
+	err = GetUnitOfWork().Do(context.Background(), func(work WorkI) error {
+		return post(work, &models.Transaction{UserId: userId, Currency: utils.CURRENCY, OperationType: addOperation,
+			Sum: models.MoneyFromUnits(10), IdempotencyKey: "key", Created: created})
+	})
+	assert.Error(t, err)
+
+	assert.NoError(t, GetTransactionsRepo().ExpireIdempotencyKeys(context.Background(), created.Add(time.Hour)))
+	_, err = find(userId)
+	assert.True(t, errors.Is(err, models.ErrNotFound))
+}
+
+func testReversals(t *testing.T) {
+	userId := newUserId()
+	original := record(t, models.Transaction{UserId: userId, OperationType: addOperation, Sum: models.MoneyFromUnits(100)})
+	record(t, models.Transaction{UserId: userId, OperationType: reverseOperation, Sum: models.MoneyFromUnits(40),
+		ReversedId: original.Id})
+	record(t, models.Transaction{UserId: userId, OperationType: reverseOperation, Sum: models.MoneyFromUnits(60),
+		ReversedId: original.Id})
+
+	refund := func(sum models.Money) error {
+		return GetUnitOfWork().Do(context.Background(), func(work WorkI) error {
+			return work.AddRefund(&models.Transaction{Id: original.Id}, sum)
+		})
+	}
+	assert.NoError(t, refund(models.MoneyFromUnits(40)))
+	assert.Error(t, refund(models.MoneyFromUnits(61)))
+	assert.NoError(t, refund(models.MoneyFromUnits(60)))
+	assert.Error(t, refund(models.MoneyFromUnits(1)))
+
+	found := models.Transaction{Id: original.Id}
+	err := GetUnitOfWork().Do(context.Background(), func(work WorkI) error {
+		return work.GetTransaction(&found)
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, models.MoneyFromUnits(100), found.Refunded)
+
+	err = GetUnitOfWork().Do(context.Background(), func(work WorkI) error {
+		err := work.AddRefund(&models.Transaction{Id: original.Id + 1000000}, models.MoneyFromUnits(1))
+		assert.True(t, errors.Is(err, models.ErrNotFound))
+		return work.GetTransaction(&models.Transaction{Id: original.Id + 1000000})
+	})
+	assert.True(t, errors.Is(err, models.ErrNotFound))
+}
+
+func testUserTransactions(t *testing.T) {
+	ctx := context.Background()
+	userId, counterparty := newUserId(), newUserId()
+	start := time.Date(2020, 8, 1, 12, 0, 0, 0, time.UTC)
+	first := record(t, models.Transaction{UserId: userId, OperationType: addOperation, Sum: models.MoneyFromUnits(100),
+		Created: start.Add(time.Hour)})
+	record(t, models.Transaction{UserId: counterparty, OperationType: addOperation, Sum: models.MoneyFromUnits(500),
+		Created: start})
+	transfer := record(t, models.Transaction{UserId: userId, UserFromId: counterparty, OperationType: transferOperation,
+		Sum: models.MoneyFromUnits(50), Created: start.Add(2 * time.Hour)})
+	withdrawal := record(t, models.Transaction{UserId: userId, OperationType: withdrawOperation,
+		Sum: models.MoneyFromUnits(30), Created: start.Add(3 * time.Hour)})
+	last := record(t, models.Transaction{UserId: userId, OperationType: addOperation, Sum: models.MoneyFromUnits(30),
+		Created: start.Add(4 * time.Hour)})
+
+	tests := []struct {
+		name   string
+		filter models.TransactionsFilter
+		ids    []int
+	}{
+		{"All", models.TransactionsFilter{}, []int{first.Id, transfer.Id, withdrawal.Id, last.Id}},
+		{"OperationTypes", models.TransactionsFilter{OperationTypes: []int{addOperation}}, []int{first.Id, last.Id}},
+		{"Counterparty", models.TransactionsFilter{Counterparty: counterparty}, []int{transfer.Id}},
+		{"MinSum", models.TransactionsFilter{MinSum: models.MoneyFromUnits(40)}, []int{first.Id, transfer.Id}},
+		{"MaxSum", models.TransactionsFilter{MaxSum: models.MoneyFromUnits(40)}, []int{withdrawal.Id, last.Id}},
+		{"DateRange", models.TransactionsFilter{From: start.Add(2 * time.Hour), To: start.Add(3 * time.Hour)},
+			[]int{transfer.Id, withdrawal.Id}},
+		{"DateDesc", models.TransactionsFilter{Sort: "date", Desc: true}, []int{last.Id, withdrawal.Id, transfer.Id, first.Id}},
+		{"Sum", models.TransactionsFilter{Sort: "sum"}, []int{withdrawal.Id, last.Id, transfer.Id, first.Id}},
+		{"SumDesc", models.TransactionsFilter{Sort: "sum", Desc: true}, []int{first.Id, transfer.Id, last.Id, withdrawal.Id}},
+		{"Limit", models.TransactionsFilter{Sort: "sum", Limit: 2}, []int{withdrawal.Id, last.Id}},
+		{"AfterSum", models.TransactionsFilter{Sort: "sum", Limit: 2, After: &models.Cursor{Sum: last.Sum, Id: last.Id}},
+			[]int{transfer.Id, first.Id}},
+		{"AfterSumDesc", models.TransactionsFilter{Sort: "sum", Desc: true, Limit: 2,
+			After: &models.Cursor{Sum: transfer.Sum, Id: transfer.Id}}, []int{last.Id, withdrawal.Id}},
+		{"AfterDate", models.TransactionsFilter{Sort: "date", After: &models.Cursor{Created: transfer.Created, Id: transfer.Id}},
+			[]int{withdrawal.Id, last.Id}},
+		{"AfterId", models.TransactionsFilter{Desc: true, After: &models.Cursor{Id: withdrawal.Id}},
+			[]int{transfer.Id, first.Id}},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			filter := test.filter
+			filter.UserId = userId
+			if filter.Limit == 0 {
+				filter.Limit = utils.LIMIT_DEFAULT
+			}
+
+			txs, err := GetTransactionsRepo().GetUserTransactions(ctx, &filter)
+			assert.NoError(t, err)
+			assert.Equal(t, test.ids, transactionIds(txs))
+
+			exported := make([]models.Transaction, 0)
+			err = GetTransactionsRepo().ExportUserTransactions(ctx, &filter, func(tx *models.Transaction) error {
+				exported = append(exported, *tx)
+				return nil
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, test.ids, transactionIds(exported))
+		})
+	}
+
+	t.Run("Fields", func(t *testing.T) {
+		txs, err := GetTransactionsRepo().GetUserTransactions(ctx, &models.TransactionsFilter{UserId: counterparty,
+			Counterparty: userId, Limit: utils.LIMIT_DEFAULT})
+		assert.NoError(t, err)
+		assert.Equal(t, 1, len(txs))
+		assert.Equal(t, userId, txs[0].UserId)
+		assert.Equal(t, counterparty, txs[0].UserFromId)
+		assert.Equal(t, models.MoneyFromUnits(150), txs[0].Balance)
+		assert.Equal(t, models.MoneyFromUnits(450), txs[0].BalanceFrom)
+		assert.True(t, transfer.Created.Equal(txs[0].Created))
+	})
+
+	t.Run("WrongSort", func(t *testing.T) {
+		_, err := GetTransactionsRepo().GetUserTransactions(ctx, &models.TransactionsFilter{UserId: userId, Sort: "name",
+			Limit: utils.LIMIT_DEFAULT})
+		assert.True(t, errors.Is(err, models.ErrValidation))
+	})
+}
+
+func testBalancesAsOf(t *testing.T) {
+	ctx := context.Background()
+	userId, counterparty := newUserId(), newUserId()
+	start := time.Date(2020, 8, 1, 12, 0, 0, 0, time.UTC)
+	record(t, models.Transaction{UserId: userId, OperationType: addOperation, Sum: models.MoneyFromUnits(100), Created: start})
+	record(t, models.Transaction{UserId: counterparty, UserFromId: userId, OperationType: transferOperation,
+		Sum: models.MoneyFromUnits(40), Created: start.Add(time.Hour)})
+	record(t, models.Transaction{UserId: counterparty, UserFromId: userId, OperationType: transferOperation,
+		Currency: "USD", CurrencyFrom: utils.CURRENCY, SumFrom: models.MoneyFromUnits(20), Sum: models.MoneyFromUnits(2),
+		Rate: 0.1, Created: start.Add(2 * time.Hour)})
+
+	balancesAsOf := func(user models.UserId, asOf time.Time) map[string]models.Money {
+		balances, err := GetTransactionsRepo().GetBalancesAsOf(ctx, &user, asOf)
+		assert.NoError(t, err)
+		byCurrency := make(map[string]models.Money)
+		for _, balance := range balances {
+			assert.Equal(t, user.UserId, balance.UserId)
+			byCurrency[balance.Currency] = balance.Balance
+		}
+		return byCurrency
+	}
+	assert.Empty(t, balancesAsOf(models.UserId{UserId: userId}, start.Add(-time.Second)))
+	assert.Equal(t, map[string]models.Money{utils.CURRENCY: models.MoneyFromUnits(100)},
+		balancesAsOf(models.UserId{UserId: userId}, start.Add(30*time.Minute)))
+	assert.Equal(t, map[string]models.Money{utils.CURRENCY: models.MoneyFromUnits(60)},
+		balancesAsOf(models.UserId{UserId: userId}, start.Add(time.Hour)))
+	assert.Equal(t, map[string]models.Money{utils.CURRENCY: models.MoneyFromUnits(40)},
+		balancesAsOf(models.UserId{UserId: userId}, start.Add(3*time.Hour)))
+	assert.Equal(t, map[string]models.Money{utils.CURRENCY: models.MoneyFromUnits(40), "USD": models.MoneyFromUnits(2)},
+		balancesAsOf(models.UserId{UserId: counterparty}, start.Add(3*time.Hour)))
+	assert.Equal(t, map[string]models.Money{"USD": models.MoneyFromUnits(2)},
+		balancesAsOf(models.UserId{UserId: counterparty, Currency: "USD"}, start.Add(3*time.Hour)))
+	assert.NoError(t, GetBalanceRepo().VerifyBalances(ctx))
+}
+
+func testRatesAndQuotes(t *testing.T) {
+	ctx := context.Background()
+	ratesRepo := GetRatesRepo()
+	now := time.Now().Round(time.Microsecond)
+	rates := []models.Rate{
+		{Base: "XTS", Quote: "XAU", Rate: 1, Source: "test", FetchedAt: now.Add(-2 * time.Hour)},
+		{Base: "XTS", Quote: "XAU", Rate: 2, Source: "test", FetchedAt: now.Add(-time.Hour)},
+		{Base: "XTS", Quote: "XAG", Rate: 3, Source: "test", FetchedAt: now.Add(-time.Hour)},
+	}
+	assert.NoError(t, ratesRepo.AddRates(ctx, rates))
+	assert.NotZero(t, rates[2].Id)
+	assert.Error(t, ratesRepo.AddRates(ctx, []models.Rate{
+		{Base: "XTS", Quote: "XPT", Rate: 4, Source: "test", FetchedAt: now.Add(-time.Hour)},
+		{Base: "XTS", Quote: "XPD", Rate: 0, Source: "test", FetchedAt: now.Add(-time.Hour)},
+	}))
+
+	latest := models.Rate{Base: "XTS", Quote: "XAU"}
+	assert.NoError(t, ratesRepo.GetLatestRate(ctx, &latest, now))
+	assert.Equal(t, rates[1].Id, latest.Id)
+	assert.Equal(t, 2.0, latest.Rate)
+	older := models.Rate{Base: "XTS", Quote: "XAU"}
+	assert.NoError(t, ratesRepo.GetLatestRate(ctx, &older, now.Add(-90*time.Minute)))
+	assert.Equal(t, rates[0].Id, older.Id)
+	err := ratesRepo.GetLatestRate(ctx, &models.Rate{Base: "XTS", Quote: "XAU"}, now.Add(-3*time.Hour))
+	assert.True(t, errors.Is(err, models.ErrNotFound))
+
+	latestRates, err := ratesRepo.GetRates(ctx, "XTS", now)
+	assert.NoError(t, err)
+	quotes := make([]string, 0)
+	for _, rate := range latestRates {
+		if rate.FetchedAt.After(now.Add(-3 * time.Hour)) {
+			quotes = append(quotes, rate.Quote)
+		}
+	}
+	assert.Equal(t, []string{"XAG", "XAU"}, quotes)
+
+	userId, receiver := newUserId(), newUserId()
+	assert.NoError(t, GetBalanceRepo().InsertUser(ctx, &models.Balance{UserId: userId, Currency: "XTS"}))
+	err = GetUnitOfWork().Do(ctx, func(work WorkI) error {
+		return work.GetSystemAccount(&models.Balance{UserId: utils.CASH_IN_ACCOUNT, Currency: "XBT"})
+	})
+	assert.NoError(t, err)
+	bases, err := ratesRepo.GetBases(ctx)
+	assert.NoError(t, err)
+	assert.Contains(t, bases, "XTS")
+	assert.NotContains(t, bases, "XBT")
+
+	quote := models.Quote{UserFromId: userId, CurrencyFrom: utils.CURRENCY, Currency: "USD", SumFrom: models.MoneyFromUnits(100),
+		Sum: models.MoneyFromUnits(10), Rate: 0.1, RateId: rates[1].Id, Created: now, Expires: now.Add(time.Minute)}
+	assert.NoError(t, ratesRepo.AddQuote(ctx, &quote))
+	assert.NotZero(t, quote.Id)
+	getQuote := func(id int) (models.Quote, error) {
+		stored := models.Quote{Id: id}
+		err := GetUnitOfWork().Do(ctx, func(work WorkI) error {
+			return work.GetQuote(&stored)
+		})
+		return stored, err
+	}
+	stored, err := getQuote(quote.Id)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, stored.TransactionId)
+	assert.Equal(t, quote.SumFrom, stored.SumFrom)
+	assert.Equal(t, userId, stored.UserFromId)
+
+	record(t, models.Transaction{UserId: userId, OperationType: addOperation, Sum: models.MoneyFromUnits(100)})
+	transfer := record(t, models.Transaction{UserId: receiver, UserFromId: userId, OperationType: transferOperation,
+		Currency: "USD", CurrencyFrom: utils.CURRENCY, SumFrom: quote.SumFrom, Sum: quote.Sum, Rate: quote.Rate,
+		QuoteId: quote.Id})
+	stored, err = getQuote(quote.Id)
+	assert.NoError(t, err)
+	assert.Equal(t, transfer.Id, stored.TransactionId)
+	assert.Equal(t, models.Money(0), balanceOf(t, userId, utils.CURRENCY).Balance)
+	assert.Equal(t, models.MoneyFromUnits(10), balanceOf(t, receiver, "USD").Balance)
+
+	_, err = getQuote(quote.Id + 1000000)
+	assert.True(t, errors.Is(err, models.ErrNotFound))
+	assert.NoError(t, GetBalanceRepo().VerifyBalances(ctx))
+}
+
+// transfers both ways between two users at once, no money is lost and no balance goes negative
+
+func testConcurrentTransfers(t *testing.T) {
+	first, second := newUserId(), newUserId()
+	record(t, models.Transaction{UserId: first, OperationType: addOperation, Sum: models.MoneyFromUnits(100)})
+	record(t, models.Transaction{UserId: second, OperationType: addOperation, Sum: models.MoneyFromUnits(100)})
+
+	var wait sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		userFromId, userId := first, second
+		if i%2 == 1 {
+			userFromId, userId = second, first
+		}
+		wait.Add(1)
+		go func() {
+			defer wait.Done()
+			for j := 0; j < 10; j++ {
+				// a transfer from a drained wallet fails on the balance checks
+				_ = GetUnitOfWork().Do(context.Background(), func(work WorkI) error {
+					return post(work, &models.Transaction{UserId: userId, UserFromId: userFromId, Currency: utils.CURRENCY,
+						OperationType: transferOperation, Sum: models.MoneyFromUnits(15)})
+				})
+			}
+		}()
+	}
+	wait.Wait()
+
+	firstBalance, secondBalance := balanceOf(t, first, utils.CURRENCY), balanceOf(t, second, utils.CURRENCY)
+	assert.True(t, firstBalance.Balance >= 0 && secondBalance.Balance >= 0)
+	assert.Equal(t, models.MoneyFromUnits(200), firstBalance.Balance+secondBalance.Balance)
+	assert.NoError(t, GetBalanceRepo().VerifyBalances(context.Background()))
+}
@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"github.com/google/logger"
+	"github.com/saskamegaprogrammist/userBalanceService/models"
+	"github.com/saskamegaprogrammist/userBalanceService/utils"
+	"time"
+)
+
+type HoldsRepo struct {
+}
+
+func (holdsRepo *HoldsRepo) GetExpiredHolds(ctx context.Context, before time.Time) ([]models.Hold, error) {
+	ctx, cancel := withOperationTimeout(ctx)
+	defer cancel()
+	holds := make([]models.Hold, 0)
+	db := getPool()
+	rows, err := db.QueryEx(ctx, `SELECT id, user_id, currency, sum, captured, status, created, expires FROM holds
+		WHERE status = $1 AND expires < $2 ORDER BY expires`, nil, utils.HOLD_ACTIVE, before)
+	if err != nil {
+		dbError := fmt.Errorf("Failed to retrieve holds: %v", err.Error())
+		logger.Errorf(dbError.Error())
+		return holds, dbError
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var hold models.Hold
+		err = rows.Scan(&hold.Id, &hold.UserId, &hold.Currency, &hold.Sum, &hold.Captured, &hold.Status, &hold.Created, &hold.Expires)
+		if err != nil {
+			dbError := fmt.Errorf("Failed to retrieve hold: %v", err.Error())
+			logger.Errorf(dbError.Error())
+			return holds, dbError
+		}
+		holds = append(holds, hold)
+	}
+	return holds, rows.Err()
+}
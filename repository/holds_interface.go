@@ -0,0 +1,11 @@
+package repository
+
+import (
+	"context"
+	"github.com/saskamegaprogrammist/userBalanceService/models"
+	"time"
+)
+
+type HoldsRepoI interface {
+	GetExpiredHolds(ctx context.Context, before time.Time) ([]models.Hold, error)
+}
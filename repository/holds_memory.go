@@ -0,0 +1,35 @@
+package repository
+
+import (
+	"context"
+	"github.com/saskamegaprogrammist/userBalanceService/models"
+	"github.com/saskamegaprogrammist/userBalanceService/utils"
+	"sort"
+	"time"
+)
+
+type MemoryHoldsRepo struct {
+	store *MemoryStore
+}
+
+func (holdsRepo *MemoryHoldsRepo) GetExpiredHolds(ctx context.Context, before time.Time) ([]models.Hold, error) {
+	ctx, cancel := withOperationTimeout(ctx)
+	defer cancel()
+	holds := make([]models.Hold, 0)
+	if ctx.Err() != nil {
+		return holds, ctx.Err()
+	}
+	store := holdsRepo.store
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	for _, hold := range store.holds {
+		if hold.Status == utils.HOLD_ACTIVE && hold.Expires.Before(before) {
+			holds = append(holds, *hold)
+		}
+	}
+	sort.SliceStable(holds, func(i, j int) bool {
+		return holds[i].Expires.Before(holds[j].Expires)
+	})
+	return holds, nil
+}
@@ -0,0 +1,51 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: repository/holds_interface.go
+
+// Package mock_repository is a generated GoMock package.
+package repository
+
+import (
+	context "context"
+	gomock "github.com/golang/mock/gomock"
+	models "github.com/saskamegaprogrammist/userBalanceService/models"
+	reflect "reflect"
+	time "time"
+)
+
+// MockHoldsRepoI is a mock of HoldsRepoI interface
+type MockHoldsRepoI struct {
+	ctrl     *gomock.Controller
+	recorder *MockHoldsRepoIMockRecorder
+}
+
+// MockHoldsRepoIMockRecorder is the mock recorder for MockHoldsRepoI
+type MockHoldsRepoIMockRecorder struct {
+	mock *MockHoldsRepoI
+}
+
+// NewMockHoldsRepoI creates a new mock instance
+func NewMockHoldsRepoI(ctrl *gomock.Controller) *MockHoldsRepoI {
+	mock := &MockHoldsRepoI{ctrl: ctrl}
+	mock.recorder = &MockHoldsRepoIMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockHoldsRepoI) EXPECT() *MockHoldsRepoIMockRecorder {
+	return m.recorder
+}
+
+// GetExpiredHolds mocks base method
+func (m *MockHoldsRepoI) GetExpiredHolds(ctx context.Context, before time.Time) ([]models.Hold, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetExpiredHolds", ctx, before)
+	ret0, _ := ret[0].([]models.Hold)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetExpiredHolds indicates an expected call of GetExpiredHolds
+func (mr *MockHoldsRepoIMockRecorder) GetExpiredHolds(ctx, before interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetExpiredHolds", reflect.TypeOf((*MockHoldsRepoI)(nil).GetExpiredHolds), ctx, before)
+}
@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"github.com/google/logger"
+	"github.com/saskamegaprogrammist/userBalanceService/models"
+	"github.com/saskamegaprogrammist/userBalanceService/utils"
+	"time"
+)
+
+type SQLiteHoldsRepo struct {
+}
+
+func (holdsRepo *SQLiteHoldsRepo) GetExpiredHolds(ctx context.Context, before time.Time) ([]models.Hold, error) {
+	ctx, cancel := withOperationTimeout(ctx)
+	defer cancel()
+	holds := make([]models.Hold, 0)
+	db := getSQLite()
+	rows, err := db.QueryContext(ctx, `SELECT id, user_id, currency, sum, captured, status, created, expires FROM holds
+		WHERE status = ? AND expires < ? ORDER BY expires`, sqliteArgs(utils.HOLD_ACTIVE, before)...)
+	if err != nil {
+		dbError := fmt.Errorf("Failed to retrieve holds: %v", err.Error())
+		logger.Errorf(dbError.Error())
+		return holds, dbError
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var hold models.Hold
+		err = scanSQLiteHold(rows, &hold)
+		if err != nil {
+			dbError := fmt.Errorf("Failed to retrieve hold: %v", err.Error())
+			logger.Errorf(dbError.Error())
+			return holds, dbError
+		}
+		holds = append(holds, hold)
+	}
+	return holds, rows.Err()
+}
@@ -0,0 +1,21 @@
+package repository
+
+import (
+	"context"
+	"github.com/saskamegaprogrammist/userBalanceService/models"
+	"github.com/saskamegaprogrammist/userBalanceService/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"time"
+)
+
+type TracedHoldsRepo struct {
+	holdsRepo HoldsRepoI
+	system    attribute.KeyValue
+}
+
+func (holdsRepo *TracedHoldsRepo) GetExpiredHolds(ctx context.Context, before time.Time) ([]models.Hold, error) {
+	ctx, span := startSpan(ctx, holdsRepo.system, "HoldsRepo.GetExpiredHolds")
+	holds, err := holdsRepo.holdsRepo.GetExpiredHolds(ctx, before)
+	tracing.End(span, err)
+	return holds, err
+}
@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"fmt"
+	"github.com/saskamegaprogrammist/userBalanceService/models"
+	"sort"
+	"sync"
+)
+
+// MemoryStore keeps the tables of the in-memory backend. Rows of every table are kept in id order,
+// ids are never reused, like the serial columns of PostgreSQL. Reads take the read lock, writes and
+// units of work take the write lock, so units of work are serialized
+
+type MemoryStore struct {
+	mutex        sync.RWMutex
+	sequences    map[string]int
+	wallets      []*memoryWallet
+	walletsByKey map[walletKey]*memoryWallet
+	transactions []*models.Transaction
+	postings     []models.Posting
+	holds        []*models.Hold
+	rates        []models.Rate
+	quotes       []models.Quote
+}
+
+type walletKey struct {
+	userId   int
+	currency string
+}
+
+type memoryWallet struct {
+	id       int
+	userId   int
+	currency string
+	balance  models.Money
+	held     models.Money
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		sequences:    make(map[string]int),
+		walletsByKey: make(map[walletKey]*memoryWallet),
+	}
+}
+
+func (store *MemoryStore) nextId(table string) int {
+	store.sequences[table]++
+	return store.sequences[table]
+}
+
+// returns the index of the row with the given id in a table of count rows kept in id order, or -1
+
+func findById(count int, rowId func(i int) int, id int) int {
+	i := sort.Search(count, func(i int) bool {
+		return rowId(i) >= id
+	})
+	if i < count && rowId(i) == id {
+		return i
+	}
+	return -1
+}
+
+func (store *MemoryStore) wallet(userId int, currency string) *memoryWallet {
+	return store.walletsByKey[walletKey{userId, currency}]
+}
+
+func (store *MemoryStore) walletById(id int) *memoryWallet {
+	i := findById(len(store.wallets), func(i int) int { return store.wallets[i].id }, id)
+	if i < 0 {
+		return nil
+	}
+	return store.wallets[i]
+}
+
+func (store *MemoryStore) addWallet(userId int, currency string) *memoryWallet {
+	wallet := &memoryWallet{id: store.nextId("balance"), userId: userId, currency: currency}
+	store.wallets = append(store.wallets, wallet)
+	store.walletsByKey[walletKey{userId, currency}] = wallet
+	return wallet
+}
+
+// removes the last added wallet, undoes addWallet
+
+func (store *MemoryStore) removeLastWallet() {
+	wallet := store.wallets[len(store.wallets)-1]
+	store.wallets = store.wallets[:len(store.wallets)-1]
+	delete(store.walletsByKey, walletKey{wallet.userId, wallet.currency})
+}
+
+func (store *MemoryStore) transaction(id int) *models.Transaction {
+	i := findById(len(store.transactions), func(i int) int { return store.transactions[i].Id }, id)
+	if i < 0 {
+		return nil
+	}
+	return store.transactions[i]
+}
+
+func (store *MemoryStore) hold(id int) *models.Hold {
+	i := findById(len(store.holds), func(i int) int { return store.holds[i].Id }, id)
+	if i < 0 {
+		return nil
+	}
+	return store.holds[i]
+}
+
+// the check constraints of the balance table
+
+func checkWallet(wallet *memoryWallet) error {
+	if wallet.balance < 0 {
+		return fmt.Errorf("balance of wallet %d violates check constraint non_negative_balance", wallet.id)
+	}
+	if wallet.held < 0 || wallet.held > wallet.balance {
+		return fmt.Errorf("balance of wallet %d violates check constraint held_within_balance", wallet.id)
+	}
+	return nil
+}
+
+func (wallet *memoryWallet) read(balance *models.Balance) {
+	balance.Id = wallet.id
+	balance.UserId = wallet.userId
+	balance.Balance = wallet.balance
+	balance.Available = wallet.balance - wallet.held
+	balance.Currency = wallet.currency
+}
+
+// caller is the user whose money is spent, the idempotency keys are unique per caller
+
+func idempotencyCaller(tx *models.Transaction) int {
+	if tx.UserFromId != 0 {
+		return tx.UserFromId
+	}
+	return tx.UserId
+}
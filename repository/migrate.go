@@ -0,0 +1,321 @@
+package repository
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"github.com/google/logger"
+	"github.com/jackc/pgx"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// key of the advisory lock held while migrating, so that instances started together migrate one by one
+
+const migrationsLockKey = 7245315
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+//go:embed sqlite_migrations/*.sql
+var sqliteMigrationFiles embed.FS
+
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+type MigrationState struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+	Missing   bool
+}
+
+var migrationFileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// reads NNNN_name.up.sql and NNNN_name.down.sql pairs, versions must be numbered from 1 without gaps
+
+func loadMigrations(files fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(files, dir)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read migrations: %v", err)
+	}
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		match := migrationFileName.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			return nil, fmt.Errorf("unexpected migration file %s", entry.Name())
+		}
+		version, _ := strconv.Atoi(match[1])
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		}
+		if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %d is named both %s and %s", version, migration.Name, match[2])
+		}
+		data, err := fs.ReadFile(files, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read migration %s: %v", entry.Name(), err)
+		}
+		if match[3] == "up" {
+			migration.Up = string(data)
+		} else {
+			migration.Down = string(data)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	for i, migration := range migrations {
+		if migration.Version != i+1 {
+			return nil, fmt.Errorf("migration %d is missing", i+1)
+		}
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %d needs both up and down files", migration.Version)
+		}
+	}
+	return migrations, nil
+}
+
+func Migrations() ([]Migration, error) {
+	return loadMigrations(migrationFiles, "migrations")
+}
+
+// the SQLite backend has a schema of its own, its migrations are versioned separately
+
+func SQLiteMigrations() ([]Migration, error) {
+	return loadMigrations(sqliteMigrationFiles, "sqlite_migrations")
+}
+
+// applies the pending migrations in order, every migration runs in its own transaction.
+// Migrations aren't limited by the operation timeout, only by ctx
+
+func MigrateUp(ctx context.Context) ([]Migration, error) {
+	applied := make([]Migration, 0)
+	err := withMigrationsLock(ctx, func(migrator migrator, states []MigrationState) error {
+		for _, state := range states {
+			if state.Applied {
+				continue
+			}
+			err := migrator.run(ctx, state.Migration, true)
+			if err != nil {
+				return err
+			}
+			applied = append(applied, state.Migration)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// reverts the last steps applied migrations in reverse order
+
+func MigrateDown(ctx context.Context, steps int) ([]Migration, error) {
+	reverted := make([]Migration, 0)
+	err := withMigrationsLock(ctx, func(migrator migrator, states []MigrationState) error {
+		for i := len(states) - 1; i >= 0 && len(reverted) < steps; i-- {
+			if !states[i].Applied {
+				continue
+			}
+			if states[i].Missing {
+				return fmt.Errorf("migration %d was applied by a newer version and can't be reverted", states[i].Version)
+			}
+			err := migrator.run(ctx, states[i].Migration, false)
+			if err != nil {
+				return err
+			}
+			reverted = append(reverted, states[i].Migration)
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// lists the known migrations and the applied ones missing from this binary
+
+func MigrationStatus(ctx context.Context) ([]MigrationState, error) {
+	var result []MigrationState
+	err := withMigrationsLock(ctx, func(migrator migrator, states []MigrationState) error {
+		result = states
+		return nil
+	})
+	return result, err
+}
+
+// migrator applies migrations to the database of a backend and records them in schema_migrations
+
+type migrator interface {
+	applied(ctx context.Context) ([]MigrationState, error)
+	run(ctx context.Context, migration Migration, up bool) error
+}
+
+func withMigrationsLock(ctx context.Context, run func(migrator migrator, states []MigrationState) error) error {
+	if getSQLite() != nil {
+		migrations, err := SQLiteMigrations()
+		if err != nil {
+			return err
+		}
+		return runMigrator(ctx, &sqliteMigrator{db: getSQLite()}, migrations, run)
+	}
+	db := getPool()
+	if db == nil {
+		return fmt.Errorf("the memory backend has no migrations")
+	}
+	migrations, err := Migrations()
+	if err != nil {
+		return err
+	}
+	conn, err := db.AcquireEx(ctx)
+	if err != nil {
+		dbError := fmt.Errorf("Failed to acquire connection: %v", err.Error())
+		logger.Errorf(dbError.Error())
+		return dbError
+	}
+	defer db.Release(conn)
+
+	_, err = conn.ExecEx(ctx, "SELECT pg_advisory_lock($1)", nil, migrationsLockKey)
+	if err != nil {
+		dbError := fmt.Errorf("Failed to lock migrations: %v", err.Error())
+		logger.Errorf(dbError.Error())
+		return dbError
+	}
+	defer func() {
+		_, err := conn.Exec("SELECT pg_advisory_unlock($1)", migrationsLockKey)
+		if err != nil {
+			logger.Errorf("Failed to unlock migrations: %v", err)
+		}
+	}()
+	return runMigrator(ctx, &pgMigrator{conn: conn}, migrations, run)
+}
+
+func runMigrator(ctx context.Context, migrator migrator, migrations []Migration,
+	run func(migrator migrator, states []MigrationState) error) error {
+	applied, err := migrator.applied(ctx)
+	if err != nil {
+		return err
+	}
+	return run(migrator, migrationStates(migrations, applied))
+}
+
+// marks the applied migrations, the applied ones this binary doesn't have are appended as missing
+
+func migrationStates(migrations []Migration, applied []MigrationState) []MigrationState {
+	states := make([]MigrationState, 0, len(migrations))
+	for _, migration := range migrations {
+		states = append(states, MigrationState{Migration: migration})
+	}
+	for _, state := range applied {
+		state.Applied = true
+		if state.Version >= 1 && state.Version <= len(migrations) {
+			states[state.Version-1].Applied = true
+			states[state.Version-1].AppliedAt = state.AppliedAt
+			continue
+		}
+		state.Missing = true
+		states = append(states, state)
+	}
+	return states
+}
+
+type pgMigrator struct {
+	conn *pgx.Conn
+}
+
+// creates schema_migrations if it doesn't exist and reads the applied migrations in version order
+
+func (pgMigrator *pgMigrator) applied(ctx context.Context) ([]MigrationState, error) {
+	applied := make([]MigrationState, 0)
+	_, err := pgMigrator.conn.ExecEx(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version int NOT NULL PRIMARY KEY,
+		name text NOT NULL,
+		applied_at TIMESTAMPTZ NOT NULL
+	)`, nil)
+	if err != nil {
+		dbError := fmt.Errorf("Failed to create schema_migrations: %v", err.Error())
+		logger.Errorf(dbError.Error())
+		return applied, dbError
+	}
+
+	rows, err := pgMigrator.conn.QueryEx(ctx, "SELECT version, name, applied_at FROM schema_migrations ORDER BY version", nil)
+	if err != nil {
+		dbError := fmt.Errorf("Failed to retrieve applied migrations: %v", err.Error())
+		logger.Errorf(dbError.Error())
+		return applied, dbError
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var state MigrationState
+		err = rows.Scan(&state.Version, &state.Name, &state.AppliedAt)
+		if err != nil {
+			dbError := fmt.Errorf("Failed to retrieve applied migration: %v", err.Error())
+			logger.Errorf(dbError.Error())
+			return applied, dbError
+		}
+		applied = append(applied, state)
+	}
+	return applied, rows.Err()
+}
+
+func (pgMigrator *pgMigrator) run(ctx context.Context, migration Migration, up bool) error {
+	transaction, err := pgMigrator.conn.BeginEx(ctx, nil)
+	if err != nil {
+		dbError := fmt.Errorf("Failed to start transaction: %v", err.Error())
+		logger.Errorf(dbError.Error())
+		return dbError
+	}
+	defer transaction.Rollback()
+
+	script := migration.Up
+	if !up {
+		script = migration.Down
+	}
+	_, err = transaction.ExecEx(ctx, script, nil)
+	if err != nil {
+		dbError := fmt.Errorf("Failed to run migration %d %s: %v", migration.Version, migration.Name, err.Error())
+		logger.Errorf(dbError.Error())
+		return dbError
+	}
+	if up {
+		_, err = transaction.ExecEx(ctx, "INSERT INTO schema_migrations (version, name, applied_at) VALUES ($1, $2, $3)", nil,
+			migration.Version, migration.Name, time.Now())
+	} else {
+		_, err = transaction.ExecEx(ctx, "DELETE FROM schema_migrations WHERE version = $1", nil, migration.Version)
+	}
+	if err != nil {
+		dbError := fmt.Errorf("Failed to record migration %d: %v", migration.Version, err.Error())
+		logger.Errorf(dbError.Error())
+		return dbError
+	}
+
+	err = transaction.Commit()
+	if err != nil {
+		dbError := fmt.Errorf("Error commit: %v", err.Error())
+		logger.Errorf(dbError.Error())
+		return dbError
+	}
+	logMigration(migration, up)
+	return nil
+}
+
+func logMigration(migration Migration, up bool) {
+	if up {
+		logger.Infof("Applied migration %d %s", migration.Version, migration.Name)
+	} else {
+		logger.Infof("Reverted migration %d %s", migration.Version, migration.Name)
+	}
+}
@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/google/logger"
+	"time"
+)
+
+// migrations of a SQLite database need no lock, every script runs in a transaction that holds
+// the write lock of the file
+
+type sqliteMigrator struct {
+	db *sql.DB
+}
+
+// creates schema_migrations if it doesn't exist and reads the applied migrations in version order
+
+func (sqliteMigrator *sqliteMigrator) applied(ctx context.Context) ([]MigrationState, error) {
+	applied := make([]MigrationState, 0)
+	_, err := sqliteMigrator.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER NOT NULL PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at INTEGER NOT NULL
+	)`)
+	if err != nil {
+		dbError := fmt.Errorf("Failed to create schema_migrations: %v", err.Error())
+		logger.Errorf(dbError.Error())
+		return applied, dbError
+	}
+
+	rows, err := sqliteMigrator.db.QueryContext(ctx, "SELECT version, name, applied_at FROM schema_migrations ORDER BY version")
+	if err != nil {
+		dbError := fmt.Errorf("Failed to retrieve applied migrations: %v", err.Error())
+		logger.Errorf(dbError.Error())
+		return applied, dbError
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var state MigrationState
+		err = rows.Scan(&state.Version, &state.Name, sqliteTime{&state.AppliedAt})
+		if err != nil {
+			dbError := fmt.Errorf("Failed to retrieve applied migration: %v", err.Error())
+			logger.Errorf(dbError.Error())
+			return applied, dbError
+		}
+		applied = append(applied, state)
+	}
+	return applied, rows.Err()
+}
+
+func (sqliteMigrator *sqliteMigrator) run(ctx context.Context, migration Migration, up bool) error {
+	transaction, err := sqliteMigrator.db.BeginTx(ctx, nil)
+	if err != nil {
+		dbError := fmt.Errorf("Failed to start transaction: %v", err.Error())
+		logger.Errorf(dbError.Error())
+		return dbError
+	}
+	defer transaction.Rollback()
+
+	script := migration.Up
+	if !up {
+		script = migration.Down
+	}
+	_, err = transaction.ExecContext(ctx, script)
+	if err != nil {
+		dbError := fmt.Errorf("Failed to run migration %d %s: %v", migration.Version, migration.Name, err.Error())
+		logger.Errorf(dbError.Error())
+		return dbError
+	}
+	if up {
+		_, err = transaction.ExecContext(ctx, "INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)",
+			sqliteArgs(migration.Version, migration.Name, time.Now())...)
+	} else {
+		_, err = transaction.ExecContext(ctx, "DELETE FROM schema_migrations WHERE version = ?", migration.Version)
+	}
+	if err != nil {
+		dbError := fmt.Errorf("Failed to record migration %d: %v", migration.Version, err.Error())
+		logger.Errorf(dbError.Error())
+		return dbError
+	}
+
+	err = transaction.Commit()
+	if err != nil {
+		dbError := fmt.Errorf("Error commit: %v", err.Error())
+		logger.Errorf(dbError.Error())
+		return dbError
+	}
+	logMigration(migration, up)
+	return nil
+}
@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"context"
+	"github.com/jackc/pgx"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	t.Run("Embedded", func(t *testing.T) {
+		migrations, err := Migrations()
+
+		assert.NoError(t, err)
+		assert.True(t, len(migrations) >= 2)
+		assert.Equal(t, "initial", migrations[0].Name)
+		assert.Equal(t, "fix_balance_from_check", migrations[1].Name)
+	})
+
+	t.Run("Ordered", func(t *testing.T) {
+		migrations, err := loadMigrations(fstest.MapFS{
+			"sql/0002_second.up.sql":   {Data: []byte("up 2")},
+			"sql/0002_second.down.sql": {Data: []byte("down 2")},
+			"sql/0001_first.up.sql":    {Data: []byte("up 1")},
+			"sql/0001_first.down.sql":  {Data: []byte("down 1")},
+		}, "sql")
+
+		assert.NoError(t, err)
+		assert.Equal(t, []Migration{
+			{Version: 1, Name: "first", Up: "up 1", Down: "down 1"},
+			{Version: 2, Name: "second", Up: "up 2", Down: "down 2"},
+		}, migrations)
+	})
+
+	tests := []struct {
+		name  string
+		files fstest.MapFS
+	}{
+		{"Gap", fstest.MapFS{
+			"sql/0001_first.up.sql":   {Data: []byte("up 1")},
+			"sql/0001_first.down.sql": {Data: []byte("down 1")},
+			"sql/0003_third.up.sql":   {Data: []byte("up 3")},
+			"sql/0003_third.down.sql": {Data: []byte("down 3")},
+		}},
+		{"MissingDown", fstest.MapFS{
+			"sql/0001_first.up.sql": {Data: []byte("up 1")},
+		}},
+		{"NameMismatch", fstest.MapFS{
+			"sql/0001_first.up.sql":   {Data: []byte("up 1")},
+			"sql/0001_other.down.sql": {Data: []byte("down 1")},
+		}},
+		{"UnexpectedFile", fstest.MapFS{
+			"sql/0001_first.up.sql":   {Data: []byte("up 1")},
+			"sql/0001_first.down.sql": {Data: []byte("down 1")},
+			"sql/README.md":           {Data: []byte("notes")},
+		}},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			_, err := loadMigrations(test.files, "sql")
+
+			assert.Error(t, err)
+		})
+	}
+}
+
+// runs against a live database, set POSTGRES_DSN to enable
+
+func TestMigrate(t *testing.T) {
+	dsn := os.Getenv("POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("POSTGRES_DSN is not set")
+	}
+	config, err := pgx.ParseConnectionString(dsn)
+	if err != nil {
+		t.Fatalf("Couldn't parse POSTGRES_DSN: %v", err)
+	}
+	err = Init(config, 5, time.Minute)
+	if err != nil {
+		t.Fatalf("Couldn't initialize database: %v", err)
+	}
+	migrations, err := Migrations()
+	assert.NoError(t, err)
+
+	checkMigrations(t, migrations)
+}
+
+func TestMigrateSQLite(t *testing.T) {
+	err := InitSQLite(filepath.Join(t.TempDir(), "balances.db"), 5, time.Minute)
+	if err != nil {
+		t.Fatalf("Couldn't initialize database: %v", err)
+	}
+	t.Cleanup(Close)
+	migrations, err := SQLiteMigrations()
+	assert.NoError(t, err)
+
+	checkMigrations(t, migrations)
+}
+
+// migrates the initialized database up, one step down and up again
+
+func checkMigrations(t *testing.T, migrations []Migration) {
+	ctx := context.Background()
+	_, err := MigrateUp(ctx)
+	assert.NoError(t, err)
+	states, err := MigrationStatus(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, len(migrations), len(states))
+	for _, state := range states {
+		assert.True(t, state.Applied)
+	}
+
+	reverted, err := MigrateDown(ctx, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []Migration{migrations[len(migrations)-1]}, reverted)
+	states, err = MigrationStatus(ctx)
+	assert.NoError(t, err)
+	assert.False(t, states[len(states)-1].Applied)
+
+	applied, err := MigrateUp(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []Migration{migrations[len(migrations)-1]}, applied)
+	applied, err = MigrateUp(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, applied)
+}
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS postings, holds, transactions, quotes, rates, balance;
+
+DROP FUNCTION IF EXISTS apply_posting();
+DROP FUNCTION IF EXISTS check_postings_balanced();
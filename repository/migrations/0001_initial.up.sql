@@ -0,0 +1,189 @@
+-- baseline schema: the statements are idempotent, so databases created before migrations
+-- were introduced converge to the same schema when this migration is applied to them
+
+CREATE TABLE IF NOT EXISTS balance (
+    id SERIAL NOT NULL PRIMARY KEY,
+    user_id int NOT NULL,
+    currency char(3) NOT NULL DEFAULT 'RUB',
+    balance numeric(20, 2)  DEFAULT 0 CONSTRAINT non_negative_balance CHECK (balance >=0)
+);
+
+ALTER TABLE balance ADD COLUMN IF NOT EXISTS currency char(3) NOT NULL DEFAULT 'RUB';
+
+CREATE INDEX IF NOT EXISTS balance_user_id ON balance (user_id );
+
+CREATE TABLE IF NOT EXISTS transactions  (
+    id SERIAL NOT NULL PRIMARY KEY,
+    user_id int NOT NULL,
+    user_from_id int DEFAULT 0,
+    currency char(3) NOT NULL DEFAULT 'RUB',
+    operation int CONSTRAINT op_types CHECK (operation >=1 AND operation <= 3),
+    sum numeric(20, 2) NOT NULL CONSTRAINT positive_sum CHECK (sum > 0),
+    balance numeric(20, 2) CONSTRAINT non_negative_balance CHECK (balance >= 0),
+    balance_from numeric(20, 2) CONSTRAINT non_negative_balance_from CHECK (balance >= 0),
+    created TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS transactions_user_id ON transactions (user_id );
+
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS currency char(3) NOT NULL DEFAULT 'RUB';
+
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS transactions_user_id_fkey;
+ALTER TABLE balance DROP CONSTRAINT IF EXISTS balance_user_id_key;
+
+CREATE UNIQUE INDEX IF NOT EXISTS balance_user_id_currency ON balance (user_id, currency);
+
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'transactions_balance_fkey') THEN
+        ALTER TABLE transactions ADD CONSTRAINT transactions_balance_fkey
+            FOREIGN KEY (user_id, currency) REFERENCES balance (user_id, currency);
+    END IF;
+END
+$$;
+
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS idempotency_key text;
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS request_hash text;
+
+CREATE UNIQUE INDEX IF NOT EXISTS transactions_idempotency_key
+    ON transactions ((CASE WHEN user_from_id != 0 THEN user_from_id ELSE user_id END), idempotency_key)
+    WHERE idempotency_key IS NOT NULL;
+
+ALTER TABLE balance ADD COLUMN IF NOT EXISTS held numeric(20, 2) NOT NULL DEFAULT 0;
+
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'held_within_balance') THEN
+        ALTER TABLE balance ADD CONSTRAINT held_within_balance CHECK (held >= 0 AND held <= balance);
+    END IF;
+END
+$$;
+
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS op_types;
+ALTER TABLE transactions ADD CONSTRAINT op_types CHECK (operation >=1 AND operation <= 5);
+
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS reversed_id int REFERENCES transactions (id);
+
+CREATE UNIQUE INDEX IF NOT EXISTS transactions_reversed_id ON transactions (reversed_id) WHERE reversed_id IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS holds (
+    id SERIAL NOT NULL PRIMARY KEY,
+    user_id int NOT NULL,
+    currency char(3) NOT NULL,
+    sum numeric(20, 2) NOT NULL CONSTRAINT positive_hold_sum CHECK (sum > 0),
+    captured numeric(20, 2) NOT NULL DEFAULT 0,
+    status text NOT NULL,
+    created TIMESTAMPTZ NOT NULL,
+    expires TIMESTAMPTZ NOT NULL,
+    FOREIGN KEY (user_id, currency) REFERENCES balance (user_id, currency)
+);
+
+CREATE INDEX IF NOT EXISTS holds_active_expires ON holds (expires) WHERE status = 'active';
+
+-- double-entry journal: every transaction is split into postings that sum up to zero,
+-- accounts are balance rows, system accounts for cash-in and cash-out have negative user ids
+
+CREATE TABLE IF NOT EXISTS postings (
+    id SERIAL NOT NULL PRIMARY KEY,
+    transaction_id int NOT NULL REFERENCES transactions (id),
+    account_id int NOT NULL REFERENCES balance (id),
+    currency char(3) NOT NULL,
+    amount numeric(20, 2) NOT NULL CONSTRAINT non_zero_amount CHECK (amount != 0)
+);
+
+CREATE INDEX IF NOT EXISTS postings_transaction_id ON postings (transaction_id);
+CREATE INDEX IF NOT EXISTS postings_account_id ON postings (account_id);
+
+DROP TRIGGER IF EXISTS UpdateBalance on transactions;
+DROP FUNCTION IF EXISTS update_balance();
+
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM postings) AND EXISTS (SELECT 1 FROM transactions) THEN
+        INSERT INTO balance (user_id, currency)
+            SELECT DISTINCT system.user_id, transactions.currency FROM transactions, (VALUES (-1), (-2)) AS system (user_id)
+            ON CONFLICT (user_id, currency) DO NOTHING;
+
+        INSERT INTO postings (transaction_id, account_id, currency, amount)
+            SELECT transactions.id, balance.id, transactions.currency,
+                CASE WHEN transactions.operation IN (1, 3) THEN transactions.sum ELSE -transactions.sum END
+            FROM transactions JOIN balance
+                ON balance.user_id = transactions.user_id AND balance.currency = transactions.currency;
+
+        INSERT INTO postings (transaction_id, account_id, currency, amount)
+            SELECT transactions.id, balance.id, transactions.currency,
+                CASE WHEN transactions.operation IN (1, 3) THEN -transactions.sum ELSE transactions.sum END
+            FROM transactions JOIN balance
+                ON balance.user_id = (CASE transactions.operation WHEN 1 THEN -1 WHEN 3 THEN transactions.user_from_id ELSE -2 END)
+                AND balance.currency = transactions.currency;
+    END IF;
+END
+$$;
+
+CREATE OR REPLACE FUNCTION apply_posting() RETURNS TRIGGER
+LANGUAGE  plpgsql
+AS $apply_posting$
+BEGIN
+   UPDATE balance SET balance = balance + NEW.amount WHERE id = NEW.account_id AND user_id > 0;
+   RETURN NEW;
+END
+$apply_posting$;
+
+DROP TRIGGER IF EXISTS ApplyPosting on postings;
+
+CREATE TRIGGER ApplyPosting
+    AFTER INSERT on postings
+    FOR EACH ROW
+    EXECUTE PROCEDURE apply_posting();
+
+CREATE OR REPLACE FUNCTION check_postings_balanced() RETURNS TRIGGER
+LANGUAGE  plpgsql
+AS $check_postings_balanced$
+BEGIN
+   IF (SELECT SUM(amount) FROM postings WHERE transaction_id = NEW.transaction_id AND currency = NEW.currency) != 0 THEN
+       RAISE EXCEPTION 'postings of transaction % are not balanced', NEW.transaction_id;
+   END IF;
+   RETURN NEW;
+END
+$check_postings_balanced$;
+
+DROP TRIGGER IF EXISTS CheckPostingsBalanced on postings;
+
+CREATE CONSTRAINT TRIGGER CheckPostingsBalanced
+    AFTER INSERT on postings
+    DEFERRABLE INITIALLY DEFERRED
+    FOR EACH ROW
+    EXECUTE PROCEDURE check_postings_balanced();
+
+CREATE TABLE IF NOT EXISTS rates (
+    id SERIAL NOT NULL PRIMARY KEY,
+    base char(3) NOT NULL,
+    quote char(3) NOT NULL,
+    rate double precision NOT NULL CONSTRAINT positive_rate CHECK (rate > 0),
+    source text NOT NULL,
+    fetched_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS rates_base_quote_fetched_at ON rates (base, quote, fetched_at);
+
+-- cross-currency transfers debit the sender in currency_from and credit the receiver in currency
+-- at the rate locked by a quote, the legs are balanced through the system exchange account
+
+CREATE TABLE IF NOT EXISTS quotes (
+    id SERIAL NOT NULL PRIMARY KEY,
+    currency_from char(3) NOT NULL,
+    currency char(3) NOT NULL,
+    sum_from numeric(20, 2) NOT NULL CONSTRAINT positive_quote_sum_from CHECK (sum_from > 0),
+    sum numeric(20, 2) NOT NULL CONSTRAINT positive_quote_sum CHECK (sum > 0),
+    rate double precision NOT NULL,
+    rate_id int NOT NULL REFERENCES rates (id),
+    created TIMESTAMPTZ NOT NULL,
+    expires TIMESTAMPTZ NOT NULL
+);
+
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS currency_from char(3);
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS sum_from numeric(20, 2);
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS rate double precision;
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS quote_id int REFERENCES quotes (id);
+
+CREATE UNIQUE INDEX IF NOT EXISTS transactions_quote_id ON transactions (quote_id) WHERE quote_id IS NOT NULL;
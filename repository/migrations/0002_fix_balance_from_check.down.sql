@@ -0,0 +1,2 @@
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS non_negative_balance_from;
+ALTER TABLE transactions ADD CONSTRAINT non_negative_balance_from CHECK (balance >= 0);
@@ -0,0 +1,4 @@
+-- non_negative_balance_from checked balance instead of balance_from
+
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS non_negative_balance_from;
+ALTER TABLE transactions ADD CONSTRAINT non_negative_balance_from CHECK (balance_from >= 0);
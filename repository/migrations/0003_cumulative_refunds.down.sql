@@ -0,0 +1,7 @@
+-- fails if a transaction was refunded more than once
+
+DROP INDEX IF EXISTS transactions_reversed_id;
+CREATE UNIQUE INDEX transactions_reversed_id ON transactions (reversed_id) WHERE reversed_id IS NOT NULL;
+
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS refunded_up_to_sum;
+ALTER TABLE transactions DROP COLUMN IF EXISTS refunded;
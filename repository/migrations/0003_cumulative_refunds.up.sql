@@ -0,0 +1,13 @@
+-- a transaction can be refunded partially several times, the refunded total is kept on the transaction
+
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS refunded numeric(20, 2) NOT NULL DEFAULT 0;
+
+UPDATE transactions SET refunded = reversals.sum
+FROM (SELECT reversed_id, SUM(sum) AS sum FROM transactions WHERE reversed_id IS NOT NULL GROUP BY reversed_id) reversals
+WHERE transactions.id = reversals.reversed_id;
+
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS refunded_up_to_sum;
+ALTER TABLE transactions ADD CONSTRAINT refunded_up_to_sum CHECK (refunded >= 0 AND refunded <= sum);
+
+DROP INDEX IF EXISTS transactions_reversed_id;
+CREATE INDEX transactions_reversed_id ON transactions (reversed_id) WHERE reversed_id IS NOT NULL;
@@ -0,0 +1 @@
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS no_self_transfer;
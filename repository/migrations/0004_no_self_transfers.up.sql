@@ -0,0 +1,5 @@
+-- a transfer to the sender would store two balance snapshots of one wallet at the same time,
+-- existing rows aren't checked
+
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS no_self_transfer;
+ALTER TABLE transactions ADD CONSTRAINT no_self_transfer CHECK (user_from_id <> user_id) NOT VALID;
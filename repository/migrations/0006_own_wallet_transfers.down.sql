@@ -0,0 +1,2 @@
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS no_self_transfer;
+ALTER TABLE transactions ADD CONSTRAINT no_self_transfer CHECK (user_from_id <> user_id) NOT VALID;
@@ -0,0 +1,6 @@
+-- a user can convert funds between their own wallets of different currencies,
+-- only a transfer to the sending wallet itself stays forbidden
+
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS no_self_transfer;
+ALTER TABLE transactions ADD CONSTRAINT no_self_transfer
+    CHECK (user_from_id <> user_id OR currency_from IS NOT NULL AND currency_from <> currency) NOT VALID;
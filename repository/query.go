@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"fmt"
+	"strings"
+)
+
+// queryBuilder composes a SELECT from independent conditions, every condition is wrapped
+// in parentheses so that an OR inside it can't bind to its neighbours
+
+type queryBuilder struct {
+	selectClause string
+	conditions   []string
+	orderBy      []string
+	limit        string
+	args         []interface{}
+}
+
+// the select clause may have ? placeholders too, they are numbered before the ones of conditions
+
+func newQueryBuilder(selectClause string, values ...interface{}) *queryBuilder {
+	builder := &queryBuilder{}
+	builder.selectClause = builder.bind(selectClause, values)
+	return builder
+}
+
+func (builder *queryBuilder) Where(condition string, values ...interface{}) *queryBuilder {
+	builder.conditions = append(builder.conditions, "("+builder.bind(condition, values)+")")
+	return builder
+}
+
+func (builder *queryBuilder) WhereIn(column string, values ...interface{}) *queryBuilder {
+	placeholders := make([]string, len(values))
+	for i := range values {
+		placeholders[i] = "?"
+	}
+	return builder.Where(column+" IN ("+strings.Join(placeholders, ", ")+")", values...)
+}
+
+func (builder *queryBuilder) OrderBy(column string, desc bool) *queryBuilder {
+	if desc {
+		column += " DESC"
+	}
+	builder.orderBy = append(builder.orderBy, column)
+	return builder
+}
+
+func (builder *queryBuilder) Limit(limit int) *queryBuilder {
+	builder.limit = builder.arg(limit)
+	return builder
+}
+
+func (builder *queryBuilder) Build() (string, []interface{}) {
+	query := builder.selectClause
+	if len(builder.conditions) > 0 {
+		query += " WHERE " + strings.Join(builder.conditions, " AND ")
+	}
+	if len(builder.orderBy) > 0 {
+		query += " ORDER BY " + strings.Join(builder.orderBy, ", ")
+	}
+	if builder.limit != "" {
+		query += " LIMIT " + builder.limit
+	}
+	return query, builder.args
+}
+
+// replaces every ? in clause with the placeholder of the next value
+
+func (builder *queryBuilder) bind(clause string, values []interface{}) string {
+	parts := strings.Split(clause, "?")
+	var bound strings.Builder
+	bound.WriteString(parts[0])
+	for i, part := range parts[1:] {
+		bound.WriteString(builder.arg(values[i]))
+		bound.WriteString(part)
+	}
+	return bound.String()
+}
+
+func (builder *queryBuilder) arg(value interface{}) string {
+	builder.args = append(builder.args, value)
+	return fmt.Sprintf("$%d", len(builder.args))
+}
@@ -0,0 +1,143 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"github.com/google/logger"
+	"github.com/jackc/pgx"
+	"github.com/saskamegaprogrammist/userBalanceService/models"
+	"time"
+)
+
+type RatesRepo struct {
+}
+
+// stores a snapshot of rates in one transaction
+
+func (ratesRepo *RatesRepo) AddRates(ctx context.Context, rates []models.Rate) error {
+	ctx, cancel := withOperationTimeout(ctx)
+	defer cancel()
+	db := getPool()
+	transaction, err := db.BeginEx(ctx, nil)
+	if err != nil {
+		dbError := fmt.Errorf("Failed to start transaction: %v", err.Error())
+		logger.Errorf(dbError.Error())
+		return dbError
+	}
+	defer transaction.Rollback()
+
+	for i := range rates {
+		rate := &rates[i]
+		row := transaction.QueryRowEx(ctx, `INSERT INTO rates (base, quote, rate, source, fetched_at)
+			VALUES ($1, $2, $3, $4, $5) returning id`, nil,
+			rate.Base, rate.Quote, rate.Rate, rate.Source, rate.FetchedAt)
+		err = row.Scan(&rate.Id)
+		if err != nil {
+			dbError := fmt.Errorf("Failed to insert rate: %v", err.Error())
+			logger.Errorf(dbError.Error())
+			return dbError
+		}
+	}
+
+	err = transaction.Commit()
+	if err != nil {
+		dbError := fmt.Errorf("Error commit: %v", err.Error())
+		logger.Errorf(dbError.Error())
+		return dbError
+	}
+	return nil
+}
+
+// finds the latest rate from rate.Base to rate.Quote fetched before the given time
+
+func (ratesRepo *RatesRepo) GetLatestRate(ctx context.Context, rate *models.Rate, before time.Time) error {
+	ctx, cancel := withOperationTimeout(ctx)
+	defer cancel()
+	db := getPool()
+	row := db.QueryRowEx(ctx, `SELECT id, base, quote, rate, source, fetched_at FROM rates
+		WHERE base = $1 AND quote = $2 AND fetched_at < $3 ORDER BY fetched_at DESC, id DESC LIMIT 1`, nil,
+		rate.Base, rate.Quote, before)
+	err := row.Scan(&rate.Id, &rate.Base, &rate.Quote, &rate.Rate, &rate.Source, &rate.FetchedAt)
+	if err == pgx.ErrNoRows {
+		return models.Errorf(models.ErrNotFound, "this rate doesn't exist")
+	}
+	if err != nil {
+		dbError := fmt.Errorf("Failed to retrieve rate: %v", err.Error())
+		logger.Errorf(dbError.Error())
+		return dbError
+	}
+	return nil
+}
+
+// returns the latest rate of every quote currency fetched before the given time
+
+func (ratesRepo *RatesRepo) GetRates(ctx context.Context, base string, before time.Time) ([]models.Rate, error) {
+	ctx, cancel := withOperationTimeout(ctx)
+	defer cancel()
+	rates := make([]models.Rate, 0)
+	db := getPool()
+	rows, err := db.QueryEx(ctx, `SELECT DISTINCT ON (quote) id, base, quote, rate, source, fetched_at FROM rates
+		WHERE base = $1 AND fetched_at < $2 ORDER BY quote, fetched_at DESC, id DESC`, nil, base, before)
+	if err != nil {
+		dbError := fmt.Errorf("Failed to retrieve rates: %v", err.Error())
+		logger.Errorf(dbError.Error())
+		return rates, dbError
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var rate models.Rate
+		err = rows.Scan(&rate.Id, &rate.Base, &rate.Quote, &rate.Rate, &rate.Source, &rate.FetchedAt)
+		if err != nil {
+			dbError := fmt.Errorf("Failed to retrieve rate: %v", err.Error())
+			logger.Errorf(dbError.Error())
+			return rates, dbError
+		}
+		rates = append(rates, rate)
+	}
+	return rates, rows.Err()
+}
+
+// bases are the currencies of user wallets
+
+func (ratesRepo *RatesRepo) GetBases(ctx context.Context) ([]string, error) {
+	ctx, cancel := withOperationTimeout(ctx)
+	defer cancel()
+	bases := make([]string, 0)
+	db := getPool()
+	rows, err := db.QueryEx(ctx, `SELECT DISTINCT currency FROM balance WHERE user_id > 0 ORDER BY currency`, nil)
+	if err != nil {
+		dbError := fmt.Errorf("Failed to retrieve currencies: %v", err.Error())
+		logger.Errorf(dbError.Error())
+		return bases, dbError
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var base string
+		err = rows.Scan(&base)
+		if err != nil {
+			dbError := fmt.Errorf("Failed to retrieve currency: %v", err.Error())
+			logger.Errorf(dbError.Error())
+			return bases, dbError
+		}
+		bases = append(bases, base)
+	}
+	return bases, rows.Err()
+}
+
+func (ratesRepo *RatesRepo) AddQuote(ctx context.Context, quote *models.Quote) error {
+	ctx, cancel := withOperationTimeout(ctx)
+	defer cancel()
+	db := getPool()
+	row := db.QueryRowEx(ctx, `INSERT INTO quotes (user_from_id, currency_from, currency, sum_from, sum, rate, rate_id,
+		created, expires) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) returning id`, nil, quote.UserFromId,
+		quote.CurrencyFrom, quote.Currency, quote.SumFrom, quote.Sum, quote.Rate, quote.RateId, quote.Created, quote.Expires)
+	err := row.Scan(&quote.Id)
+	if err != nil {
+		dbError := fmt.Errorf("Failed to insert quote: %v", err.Error())
+		logger.Errorf(dbError.Error())
+		return dbError
+	}
+	return nil
+}
@@ -0,0 +1,15 @@
+package repository
+
+import (
+	"context"
+	"github.com/saskamegaprogrammist/userBalanceService/models"
+	"time"
+)
+
+type RatesRepoI interface {
+	AddRates(ctx context.Context, rates []models.Rate) error
+	GetLatestRate(ctx context.Context, rate *models.Rate, before time.Time) error
+	GetRates(ctx context.Context, base string, before time.Time) ([]models.Rate, error)
+	GetBases(ctx context.Context) ([]string, error)
+	AddQuote(ctx context.Context, quote *models.Quote) error
+}
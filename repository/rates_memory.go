@@ -0,0 +1,160 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"github.com/saskamegaprogrammist/userBalanceService/models"
+	"sort"
+	"time"
+)
+
+type MemoryRatesRepo struct {
+	store *MemoryStore
+}
+
+// stores a snapshot of rates, none of them is stored if one is invalid
+
+func (ratesRepo *MemoryRatesRepo) AddRates(ctx context.Context, rates []models.Rate) error {
+	ctx, cancel := withOperationTimeout(ctx)
+	defer cancel()
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	store := ratesRepo.store
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	for _, rate := range rates {
+		if rate.Rate <= 0 {
+			return fmt.Errorf("rate from %s to %s violates check constraint positive_rate", rate.Base, rate.Quote)
+		}
+	}
+	for i := range rates {
+		rate := &rates[i]
+		rate.Id = store.nextId("rates")
+		stored := *rate
+		stored.FetchedAt = stored.FetchedAt.Round(time.Microsecond)
+		store.rates = append(store.rates, stored)
+	}
+	return nil
+}
+
+// latest orders rates by fetched_at and then by id
+
+func laterRate(rate models.Rate, than models.Rate) bool {
+	if !rate.FetchedAt.Equal(than.FetchedAt) {
+		return rate.FetchedAt.After(than.FetchedAt)
+	}
+	return rate.Id > than.Id
+}
+
+// finds the latest rate from rate.Base to rate.Quote fetched before the given time
+
+func (ratesRepo *MemoryRatesRepo) GetLatestRate(ctx context.Context, rate *models.Rate, before time.Time) error {
+	ctx, cancel := withOperationTimeout(ctx)
+	defer cancel()
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	store := ratesRepo.store
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	found := false
+	var latest models.Rate
+	for _, stored := range store.rates {
+		if stored.Base != rate.Base || stored.Quote != rate.Quote || !stored.FetchedAt.Before(before) {
+			continue
+		}
+		if !found || laterRate(stored, latest) {
+			latest = stored
+			found = true
+		}
+	}
+	if !found {
+		return models.Errorf(models.ErrNotFound, "this rate doesn't exist")
+	}
+	*rate = latest
+	return nil
+}
+
+// returns the latest rate of every quote currency fetched before the given time
+
+func (ratesRepo *MemoryRatesRepo) GetRates(ctx context.Context, base string, before time.Time) ([]models.Rate, error) {
+	ctx, cancel := withOperationTimeout(ctx)
+	defer cancel()
+	rates := make([]models.Rate, 0)
+	if ctx.Err() != nil {
+		return rates, ctx.Err()
+	}
+	store := ratesRepo.store
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	latest := make(map[string]models.Rate)
+	for _, stored := range store.rates {
+		if stored.Base != base || !stored.FetchedAt.Before(before) {
+			continue
+		}
+		previous, ok := latest[stored.Quote]
+		if !ok || laterRate(stored, previous) {
+			latest[stored.Quote] = stored
+		}
+	}
+	for _, rate := range latest {
+		rates = append(rates, rate)
+	}
+	sort.Slice(rates, func(i, j int) bool {
+		return rates[i].Quote < rates[j].Quote
+	})
+	return rates, nil
+}
+
+// bases are the currencies of user wallets
+
+func (ratesRepo *MemoryRatesRepo) GetBases(ctx context.Context) ([]string, error) {
+	ctx, cancel := withOperationTimeout(ctx)
+	defer cancel()
+	bases := make([]string, 0)
+	if ctx.Err() != nil {
+		return bases, ctx.Err()
+	}
+	store := ratesRepo.store
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	seen := make(map[string]bool)
+	for _, wallet := range store.wallets {
+		if wallet.userId > 0 && !seen[wallet.currency] {
+			seen[wallet.currency] = true
+			bases = append(bases, wallet.currency)
+		}
+	}
+	sort.Strings(bases)
+	return bases, nil
+}
+
+func (ratesRepo *MemoryRatesRepo) AddQuote(ctx context.Context, quote *models.Quote) error {
+	ctx, cancel := withOperationTimeout(ctx)
+	defer cancel()
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	store := ratesRepo.store
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	if quote.SumFrom <= 0 || quote.Sum <= 0 {
+		return fmt.Errorf("quote violates check constraint positive_quote_sum")
+	}
+	if findById(len(store.rates), func(i int) int { return store.rates[i].Id }, quote.RateId) < 0 {
+		return fmt.Errorf("quote violates foreign key constraint: rate %d doesn't exist", quote.RateId)
+	}
+	quote.Id = store.nextId("quotes")
+	stored := *quote
+	stored.TransactionId = 0
+	stored.Created = stored.Created.Round(time.Microsecond)
+	stored.Expires = stored.Expires.Round(time.Microsecond)
+	store.quotes = append(store.quotes, stored)
+	return nil
+}
@@ -0,0 +1,108 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: repository/rates_interface.go
+
+// Package mock_repository is a generated GoMock package.
+package repository
+
+import (
+	context "context"
+	gomock "github.com/golang/mock/gomock"
+	models "github.com/saskamegaprogrammist/userBalanceService/models"
+	reflect "reflect"
+	time "time"
+)
+
+// MockRatesRepoI is a mock of RatesRepoI interface
+type MockRatesRepoI struct {
+	ctrl     *gomock.Controller
+	recorder *MockRatesRepoIMockRecorder
+}
+
+// MockRatesRepoIMockRecorder is the mock recorder for MockRatesRepoI
+type MockRatesRepoIMockRecorder struct {
+	mock *MockRatesRepoI
+}
+
+// NewMockRatesRepoI creates a new mock instance
+func NewMockRatesRepoI(ctrl *gomock.Controller) *MockRatesRepoI {
+	mock := &MockRatesRepoI{ctrl: ctrl}
+	mock.recorder = &MockRatesRepoIMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockRatesRepoI) EXPECT() *MockRatesRepoIMockRecorder {
+	return m.recorder
+}
+
+// AddRates mocks base method
+func (m *MockRatesRepoI) AddRates(ctx context.Context, rates []models.Rate) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddRates", ctx, rates)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddRates indicates an expected call of AddRates
+func (mr *MockRatesRepoIMockRecorder) AddRates(ctx, rates interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddRates", reflect.TypeOf((*MockRatesRepoI)(nil).AddRates), ctx, rates)
+}
+
+// GetLatestRate mocks base method
+func (m *MockRatesRepoI) GetLatestRate(ctx context.Context, rate *models.Rate, before time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLatestRate", ctx, rate, before)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// GetLatestRate indicates an expected call of GetLatestRate
+func (mr *MockRatesRepoIMockRecorder) GetLatestRate(ctx, rate, before interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLatestRate", reflect.TypeOf((*MockRatesRepoI)(nil).GetLatestRate), ctx, rate, before)
+}
+
+// GetRates mocks base method
+func (m *MockRatesRepoI) GetRates(ctx context.Context, base string, before time.Time) ([]models.Rate, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRates", ctx, base, before)
+	ret0, _ := ret[0].([]models.Rate)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRates indicates an expected call of GetRates
+func (mr *MockRatesRepoIMockRecorder) GetRates(ctx, base, before interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRates", reflect.TypeOf((*MockRatesRepoI)(nil).GetRates), ctx, base, before)
+}
+
+// GetBases mocks base method
+func (m *MockRatesRepoI) GetBases(ctx context.Context) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetBases", ctx)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetBases indicates an expected call of GetBases
+func (mr *MockRatesRepoIMockRecorder) GetBases(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBases", reflect.TypeOf((*MockRatesRepoI)(nil).GetBases), ctx)
+}
+
+// AddQuote mocks base method
+func (m *MockRatesRepoI) AddQuote(ctx context.Context, quote *models.Quote) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddQuote", ctx, quote)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddQuote indicates an expected call of AddQuote
+func (mr *MockRatesRepoIMockRecorder) AddQuote(ctx, quote interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddQuote", reflect.TypeOf((*MockRatesRepoI)(nil).AddQuote), ctx, quote)
+}
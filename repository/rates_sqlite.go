@@ -0,0 +1,148 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/google/logger"
+	"github.com/saskamegaprogrammist/userBalanceService/models"
+	"time"
+)
+
+type SQLiteRatesRepo struct {
+}
+
+// stores a snapshot of rates in one transaction
+
+func (ratesRepo *SQLiteRatesRepo) AddRates(ctx context.Context, rates []models.Rate) error {
+	ctx, cancel := withOperationTimeout(ctx)
+	defer cancel()
+	db := getSQLite()
+	transaction, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		dbError := fmt.Errorf("Failed to start transaction: %v", err.Error())
+		logger.Errorf(dbError.Error())
+		return dbError
+	}
+	defer transaction.Rollback()
+
+	for i := range rates {
+		rate := &rates[i]
+		row := transaction.QueryRowContext(ctx, `INSERT INTO rates (base, quote, rate, source, fetched_at)
+			VALUES (?, ?, ?, ?, ?) RETURNING id`,
+			sqliteArgs(rate.Base, rate.Quote, rate.Rate, rate.Source, rate.FetchedAt)...)
+		err = row.Scan(&rate.Id)
+		if err != nil {
+			dbError := fmt.Errorf("Failed to insert rate: %v", err.Error())
+			logger.Errorf(dbError.Error())
+			return dbError
+		}
+	}
+
+	err = transaction.Commit()
+	if err != nil {
+		dbError := fmt.Errorf("Error commit: %v", err.Error())
+		logger.Errorf(dbError.Error())
+		return dbError
+	}
+	return nil
+}
+
+// finds the latest rate from rate.Base to rate.Quote fetched before the given time
+
+func (ratesRepo *SQLiteRatesRepo) GetLatestRate(ctx context.Context, rate *models.Rate, before time.Time) error {
+	ctx, cancel := withOperationTimeout(ctx)
+	defer cancel()
+	db := getSQLite()
+	row := db.QueryRowContext(ctx, `SELECT id, base, quote, rate, source, fetched_at FROM rates
+		WHERE base = ? AND quote = ? AND fetched_at < ? ORDER BY fetched_at DESC, id DESC LIMIT 1`,
+		sqliteArgs(rate.Base, rate.Quote, before)...)
+	err := scanSQLiteRate(row, rate)
+	if err == sql.ErrNoRows {
+		return models.Errorf(models.ErrNotFound, "this rate doesn't exist")
+	}
+	if err != nil {
+		dbError := fmt.Errorf("Failed to retrieve rate: %v", err.Error())
+		logger.Errorf(dbError.Error())
+		return dbError
+	}
+	return nil
+}
+
+// returns the latest rate of every quote currency fetched before the given time, SQLite has no
+// DISTINCT ON so the rates are ranked by a window function
+
+func (ratesRepo *SQLiteRatesRepo) GetRates(ctx context.Context, base string, before time.Time) ([]models.Rate, error) {
+	ctx, cancel := withOperationTimeout(ctx)
+	defer cancel()
+	rates := make([]models.Rate, 0)
+	db := getSQLite()
+	rows, err := db.QueryContext(ctx, `SELECT id, base, quote, rate, source, fetched_at FROM (
+		SELECT id, base, quote, rate, source, fetched_at,
+			ROW_NUMBER() OVER (PARTITION BY quote ORDER BY fetched_at DESC, id DESC) AS position
+		FROM rates WHERE base = ? AND fetched_at < ?
+	) WHERE position = 1 ORDER BY quote`, sqliteArgs(base, before)...)
+	if err != nil {
+		dbError := fmt.Errorf("Failed to retrieve rates: %v", err.Error())
+		logger.Errorf(dbError.Error())
+		return rates, dbError
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var rate models.Rate
+		err = scanSQLiteRate(rows, &rate)
+		if err != nil {
+			dbError := fmt.Errorf("Failed to retrieve rate: %v", err.Error())
+			logger.Errorf(dbError.Error())
+			return rates, dbError
+		}
+		rates = append(rates, rate)
+	}
+	return rates, rows.Err()
+}
+
+// bases are the currencies of user wallets
+
+func (ratesRepo *SQLiteRatesRepo) GetBases(ctx context.Context) ([]string, error) {
+	ctx, cancel := withOperationTimeout(ctx)
+	defer cancel()
+	bases := make([]string, 0)
+	db := getSQLite()
+	rows, err := db.QueryContext(ctx, `SELECT DISTINCT currency FROM balance WHERE user_id > 0 ORDER BY currency`)
+	if err != nil {
+		dbError := fmt.Errorf("Failed to retrieve currencies: %v", err.Error())
+		logger.Errorf(dbError.Error())
+		return bases, dbError
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var base string
+		err = rows.Scan(&base)
+		if err != nil {
+			dbError := fmt.Errorf("Failed to retrieve currency: %v", err.Error())
+			logger.Errorf(dbError.Error())
+			return bases, dbError
+		}
+		bases = append(bases, base)
+	}
+	return bases, rows.Err()
+}
+
+func (ratesRepo *SQLiteRatesRepo) AddQuote(ctx context.Context, quote *models.Quote) error {
+	ctx, cancel := withOperationTimeout(ctx)
+	defer cancel()
+	db := getSQLite()
+	row := db.QueryRowContext(ctx, `INSERT INTO quotes (user_from_id, currency_from, currency, sum_from, sum, rate, rate_id,
+		created, expires) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?) RETURNING id`,
+		sqliteArgs(quote.UserFromId, quote.CurrencyFrom, quote.Currency, quote.SumFrom, quote.Sum, quote.Rate, quote.RateId,
+			quote.Created, quote.Expires)...)
+	err := row.Scan(&quote.Id)
+	if err != nil {
+		dbError := fmt.Errorf("Failed to insert quote: %v", err.Error())
+		logger.Errorf(dbError.Error())
+		return dbError
+	}
+	return nil
+}
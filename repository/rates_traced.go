@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"context"
+	"github.com/saskamegaprogrammist/userBalanceService/models"
+	"github.com/saskamegaprogrammist/userBalanceService/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"time"
+)
+
+type TracedRatesRepo struct {
+	ratesRepo RatesRepoI
+	system    attribute.KeyValue
+}
+
+func (ratesRepo *TracedRatesRepo) AddRates(ctx context.Context, rates []models.Rate) error {
+	ctx, span := startSpan(ctx, ratesRepo.system, "RatesRepo.AddRates")
+	err := ratesRepo.ratesRepo.AddRates(ctx, rates)
+	tracing.End(span, err)
+	return err
+}
+
+func (ratesRepo *TracedRatesRepo) GetLatestRate(ctx context.Context, rate *models.Rate, before time.Time) error {
+	ctx, span := startSpan(ctx, ratesRepo.system, "RatesRepo.GetLatestRate")
+	err := ratesRepo.ratesRepo.GetLatestRate(ctx, rate, before)
+	tracing.End(span, err)
+	return err
+}
+
+func (ratesRepo *TracedRatesRepo) GetRates(ctx context.Context, base string, before time.Time) ([]models.Rate, error) {
+	ctx, span := startSpan(ctx, ratesRepo.system, "RatesRepo.GetRates")
+	rates, err := ratesRepo.ratesRepo.GetRates(ctx, base, before)
+	tracing.End(span, err)
+	return rates, err
+}
+
+func (ratesRepo *TracedRatesRepo) GetBases(ctx context.Context) ([]string, error) {
+	ctx, span := startSpan(ctx, ratesRepo.system, "RatesRepo.GetBases")
+	bases, err := ratesRepo.ratesRepo.GetBases(ctx)
+	tracing.End(span, err)
+	return bases, err
+}
+
+func (ratesRepo *TracedRatesRepo) AddQuote(ctx context.Context, quote *models.Quote) error {
+	ctx, span := startSpan(ctx, ratesRepo.system, "RatesRepo.AddQuote")
+	err := ratesRepo.ratesRepo.AddQuote(ctx, quote)
+	tracing.End(span, err)
+	return err
+}
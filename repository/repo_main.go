@@ -1,91 +1,189 @@
 package repository
 
-import "github.com/jackc/pgx"
+import (
+	"context"
+	"database/sql"
+	"github.com/jackc/pgx"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"sync/atomic"
+	"time"
+)
 
 type Repository struct {
 	pool             *pgx.ConnPool
-	TransactionsRepo *TransactionsRepo
-	BalanceRepo      *BalanceRepo
+	sqlite           *sql.DB
+	operationTimeout time.Duration
+	poolWaits        uint64
+	TransactionsRepo TransactionsRepoI
+	BalanceRepo      BalanceRepoI
+	HoldsRepo        HoldsRepoI
+	RatesRepo        RatesRepoI
+	UnitOfWork       UnitOfWorkI
 }
 
 var repo Repository
 
-const dbConnections = 20
+// the pool logs a warning every time an operation has to wait for a connection to be released,
+// poolWaitCounter counts them and passes everything else on to the logger of the config it replaced,
+// at the level that logger was set up with. The message is the one of pgx v3.6.2 (conn_pool.go),
+// TestPoolWaitMessageVersion fails when pgx is upgraded and it has to be checked again
 
-func Init(config pgx.ConnConfig) error {
+const poolWaitMessage = "waiting for available connection"
+
+type poolWaitCounter struct {
+	waits *uint64
+	next  pgx.Logger
+	level pgx.LogLevel
+}
+
+func newPoolWaitCounter(waits *uint64, config *pgx.ConnConfig) poolWaitCounter {
+	// pgx logs everything up to debug when the level isn't set
+	level := config.LogLevel
+	if level == 0 {
+		level = pgx.LogLevelDebug
+	}
+	return poolWaitCounter{waits: waits, next: config.Logger, level: level}
+}
+
+func (counter poolWaitCounter) Log(level pgx.LogLevel, msg string, data map[string]interface{}) {
+	if level == pgx.LogLevelWarn && msg == poolWaitMessage {
+		atomic.AddUint64(counter.waits, 1)
+		return
+	}
+	if counter.next != nil && level <= counter.level {
+		counter.next.Log(level, msg, data)
+	}
+}
+
+func Init(config pgx.ConnConfig, maxConnections int, operationTimeout time.Duration) error {
 	var err error
+	repo.operationTimeout = operationTimeout
+	counter := newPoolWaitCounter(&repo.poolWaits, &config)
+	config.Logger = counter
+	config.LogLevel = counter.level
+	if config.LogLevel < pgx.LogLevelWarn {
+		config.LogLevel = pgx.LogLevelWarn
+	}
 	repo.pool, err = pgx.NewConnPool(pgx.ConnPoolConfig{
 		ConnConfig:     config,
-		MaxConnections: dbConnections,
+		MaxConnections: maxConnections,
 	})
 	if err != nil {
 		return err
 	}
-	err = repo.createTables()
-	if err != nil {
-		return err
-	}
+	repo.sqlite = nil
 	repo.TransactionsRepo = &TransactionsRepo{}
 	repo.BalanceRepo = &BalanceRepo{}
+	repo.HoldsRepo = &HoldsRepo{}
+	repo.RatesRepo = &RatesRepo{}
+	repo.UnitOfWork = &UnitOfWork{}
+	traceRepositories(semconv.DBSystemPostgreSQL)
 	return nil
 }
 
-// relation style tables creation
-
-func (repo *Repository) createTables() error {
-	_, err := repo.pool.Exec(`
-CREATE TABLE IF NOT EXISTS balance (
-    id SERIAL NOT NULL PRIMARY KEY,
-    user_id int NOT NULL UNIQUE,
-    balance numeric(20, 2)  DEFAULT 0 CONSTRAINT non_negative_balance CHECK (balance >=0)
-);
-
-CREATE INDEX IF NOT EXISTS balance_user_id ON balance (user_id );
-
-CREATE TABLE IF NOT EXISTS transactions  (
-    id SERIAL NOT NULL PRIMARY KEY,
-    user_id int NOT NULL REFERENCES balance(user_id) ON DELETE SET NULL,
-    user_from_id int DEFAULT 0,
-    operation int CONSTRAINT op_types CHECK (operation >=1 AND operation <= 3),
-    sum numeric(20, 2) NOT NULL CONSTRAINT positive_sum CHECK (sum > 0),
-    balance numeric(20, 2) CONSTRAINT non_negative_balance CHECK (balance >= 0),
-    balance_from numeric(20, 2) CONSTRAINT non_negative_balance_from CHECK (balance >= 0),
-    created TIMESTAMPTZ NOT NULL
-);
-
-CREATE INDEX IF NOT EXISTS transactions_user_id ON transactions (user_id );
-
-CREATE OR REPLACE FUNCTION update_balance() RETURNS TRIGGER
-LANGUAGE  plpgsql
-AS $add_transaction$
-BEGIN
-   UPDATE balance SET balance = NEW.balance WHERE user_id = NEW.user_id;
-   IF NEW.user_from_id != 0 THEN
-    BEGIN
-        UPDATE balance SET  balance = NEW.balance_from WHERE user_id = NEW.user_from_id;
-    END;
-    END IF;
-   RETURN NEW;
-END
-$add_transaction$;
-
-DROP TRIGGER IF EXISTS UpdateBalance on transactions;
-
-CREATE TRIGGER  UpdateBalance
-    AFTER INSERT on transactions
-    FOR EACH ROW
-    EXECUTE PROCEDURE update_balance();
-`)
+// sets up the repositories on an empty in-memory store, the data is lost when the process exits
+
+func InitMemory(operationTimeout time.Duration) {
+	repo.operationTimeout = operationTimeout
+	repo.pool = nil
+	repo.sqlite = nil
+	store := NewMemoryStore()
+	repo.TransactionsRepo = &MemoryTransactionsRepo{store: store}
+	repo.BalanceRepo = &MemoryBalanceRepo{store: store}
+	repo.HoldsRepo = &MemoryHoldsRepo{store: store}
+	repo.RatesRepo = &MemoryRatesRepo{store: store}
+	repo.UnitOfWork = &MemoryUnitOfWork{store: store}
+	traceRepositories(semconv.DBSystemKey.String("memory"))
+}
+
+// opens the SQLite database file at path, it is created if it doesn't exist
+
+func InitSQLite(path string, maxConnections int, operationTimeout time.Duration) error {
+	var err error
+	repo.operationTimeout = operationTimeout
+	repo.pool = nil
+	repo.sqlite, err = openSQLite(path, maxConnections, operationTimeout)
 	if err != nil {
 		return err
 	}
+	repo.TransactionsRepo = &SQLiteTransactionsRepo{}
+	repo.BalanceRepo = &SQLiteBalanceRepo{}
+	repo.HoldsRepo = &SQLiteHoldsRepo{}
+	repo.RatesRepo = &SQLiteRatesRepo{}
+	repo.UnitOfWork = &SQLiteUnitOfWork{}
+	traceRepositories(semconv.DBSystemSqlite)
 	return nil
 }
 
+// wraps the repositories of the backend so that every call is traced, system is the db.system attribute of the spans
+
+func traceRepositories(system attribute.KeyValue) {
+	repo.TransactionsRepo = &TracedTransactionsRepo{transactionsRepo: repo.TransactionsRepo, system: system}
+	repo.BalanceRepo = &TracedBalanceRepo{balanceRepo: repo.BalanceRepo, system: system}
+	repo.HoldsRepo = &TracedHoldsRepo{holdsRepo: repo.HoldsRepo, system: system}
+	repo.RatesRepo = &TracedRatesRepo{ratesRepo: repo.RatesRepo, system: system}
+	repo.UnitOfWork = &TracedUnitOfWork{unitOfWork: repo.UnitOfWork, system: system}
+}
+
 func getPool() *pgx.ConnPool {
 	return repo.pool
 }
 
+func getSQLite() *sql.DB {
+	return repo.sqlite
+}
+
+// every database operation gets its own deadline, on top of the deadline of the request it is run for
+
+func withOperationTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, repo.operationTimeout)
+}
+
+// waits for the connections in use to be released and closes the pool
+
+func Close() {
+	if repo.pool != nil {
+		repo.pool.Close()
+	}
+	if repo.sqlite != nil {
+		repo.sqlite.Close()
+	}
+}
+
+// Waits counts the operations that found every connection in use and waited for one to be released
+
+type PoolStats struct {
+	MaxConnections int
+	Acquired       int
+	Available      int
+	Waits          uint64
+}
+
+// stats of the PostgreSQL or SQLite connection pool, the memory backend has none
+
+func GetPoolStats() (PoolStats, bool) {
+	switch {
+	case repo.pool != nil:
+		stat := repo.pool.Stat()
+		return PoolStats{
+			MaxConnections: stat.MaxConnections,
+			Acquired:       stat.CheckedOutConnections(),
+			Available:      stat.AvailableConnections,
+			Waits:          atomic.LoadUint64(&repo.poolWaits),
+		}, true
+	case repo.sqlite != nil:
+		stat := repo.sqlite.Stats()
+		return PoolStats{
+			MaxConnections: stat.MaxOpenConnections,
+			Acquired:       stat.InUse,
+			Available:      stat.Idle,
+			Waits:          uint64(stat.WaitCount),
+		}, true
+	}
+	return PoolStats{}, false
+}
+
 func GetBalanceRepo() BalanceRepoI {
 	return repo.BalanceRepo
 }
@@ -93,3 +191,15 @@ func GetBalanceRepo() BalanceRepoI {
 func GetTransactionsRepo() TransactionsRepoI {
 	return repo.TransactionsRepo
 }
+
+func GetHoldsRepo() HoldsRepoI {
+	return repo.HoldsRepo
+}
+
+func GetRatesRepo() RatesRepoI {
+	return repo.RatesRepo
+}
+
+func GetUnitOfWork() UnitOfWorkI {
+	return repo.UnitOfWork
+}
@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"context"
+	"github.com/jackc/pgx"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"runtime/debug"
+	"sync"
+	"testing"
+	"time"
+)
+
+type recordingLogger struct {
+	messages []string
+}
+
+func (logger *recordingLogger) Log(level pgx.LogLevel, msg string, data map[string]interface{}) {
+	logger.messages = append(logger.messages, msg)
+}
+
+func TestPoolWaitCounter(t *testing.T) {
+	t.Run("Count", func(t *testing.T) {
+		var waits uint64
+		counter := newPoolWaitCounter(&waits, &pgx.ConnConfig{})
+
+		counter.Log(pgx.LogLevelWarn, poolWaitMessage, nil)
+		counter.Log(pgx.LogLevelError, "Exec", map[string]interface{}{"err": "syntax error"})
+		counter.Log(pgx.LogLevelWarn, poolWaitMessage, nil)
+
+		assert.Equal(t, uint64(2), waits)
+	})
+
+	t.Run("PassOn", func(t *testing.T) {
+		var waits uint64
+		previous := &recordingLogger{}
+		counter := newPoolWaitCounter(&waits, &pgx.ConnConfig{Logger: previous, LogLevel: pgx.LogLevelError})
+
+		counter.Log(pgx.LogLevelWarn, poolWaitMessage, nil)
+		counter.Log(pgx.LogLevelError, "Exec", map[string]interface{}{"err": "syntax error"})
+		counter.Log(pgx.LogLevelInfo, "Dialing PostgreSQL server", nil)
+
+		assert.Equal(t, uint64(1), waits)
+		assert.Equal(t, []string{"Exec"}, previous.messages)
+	})
+}
+
+// poolWaitMessage is matched against the text of the pgx version below
+
+func TestPoolWaitMessageVersion(t *testing.T) {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		t.Skip("build info isn't available")
+	}
+	for _, dep := range info.Deps {
+		if dep.Path == "github.com/jackc/pgx" {
+			assert.Equal(t, "v3.6.2+incompatible", dep.Version,
+				"check that pgx still logs %q when an operation waits for a connection", poolWaitMessage)
+			return
+		}
+	}
+	t.Fatal("pgx isn't a dependency of the build")
+}
+
+// runs against a live database, set POSTGRES_DSN to enable
+
+func TestPoolWaits(t *testing.T) {
+	dsn := os.Getenv("POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("POSTGRES_DSN is not set")
+	}
+	config, err := pgx.ParseConnectionString(dsn)
+	if err != nil {
+		t.Fatalf("Couldn't parse POSTGRES_DSN: %v", err)
+	}
+	err = Init(config, 2, time.Minute)
+	if err != nil {
+		t.Fatalf("Couldn't initialize database: %v", err)
+	}
+	t.Cleanup(Close)
+	ctx := context.Background()
+	before, _ := GetPoolStats()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			GetUnitOfWork().Do(ctx, func(work WorkI) error {
+				started <- struct{}{}
+				<-release
+				return nil
+			})
+		}()
+	}
+	<-started
+	<-started
+
+	stats, _ := GetPoolStats()
+	assert.Equal(t, before.Waits, stats.Waits)
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		GetUnitOfWork().Do(ctx, func(work WorkI) error { return nil })
+	}()
+	assert.Eventually(t, func() bool {
+		stats, _ := GetPoolStats()
+		return stats.Waits == before.Waits+1
+	}, 5*time.Second, 10*time.Millisecond)
+	close(release)
+	wg.Wait()
+}
@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/saskamegaprogrammist/userBalanceService/models"
+	"net/url"
+	"time"
+)
+
+// every connection enforces foreign keys and waits for the write lock instead of failing. Transactions
+// take the write lock when they start, so units of work are serialized like rows locked FOR UPDATE,
+// and WAL lets reads run alongside them
+
+func sqliteDSN(path string, operationTimeout time.Duration) string {
+	params := url.Values{}
+	params.Set("_foreign_keys", "on")
+	params.Set("_journal_mode", "WAL")
+	params.Set("_txlock", "immediate")
+	params.Set("_busy_timeout", fmt.Sprint(operationTimeout.Milliseconds()))
+	return "file:" + path + "?" + params.Encode()
+}
+
+// amounts are stored in minor units and times in microseconds since the epoch, which is
+// the precision of PostgreSQL timestamps
+
+func sqliteArgs(args ...interface{}) []interface{} {
+	converted := make([]interface{}, len(args))
+	for i, arg := range args {
+		switch value := arg.(type) {
+		case models.Money:
+			converted[i] = int64(value)
+		case time.Time:
+			converted[i] = value.Round(time.Microsecond).UnixNano() / int64(time.Microsecond)
+		default:
+			converted[i] = arg
+		}
+	}
+	return converted
+}
+
+type sqliteMoney struct {
+	money *models.Money
+}
+
+func (money sqliteMoney) Scan(src interface{}) error {
+	switch src := src.(type) {
+	case int64:
+		*money.money = models.Money(src)
+	case nil:
+		*money.money = 0
+	default:
+		return fmt.Errorf("cannot scan %T into Money", src)
+	}
+	return nil
+}
+
+type sqliteTime struct {
+	time *time.Time
+}
+
+func (t sqliteTime) Scan(src interface{}) error {
+	micros, ok := src.(int64)
+	if !ok {
+		return fmt.Errorf("cannot scan %T into time", src)
+	}
+	*t.time = time.Unix(0, micros*int64(time.Microsecond))
+	return nil
+}
+
+// sqliteRow is *sql.Row or *sql.Rows
+
+type sqliteRow interface {
+	Scan(dest ...interface{}) error
+}
+
+// scans a row of the columns of sqliteTransactionColumns
+
+func scanSQLiteTransaction(row sqliteRow, tx *models.Transaction) error {
+	return row.Scan(&tx.Id, &tx.UserId, &tx.UserFromId, &tx.Currency, &tx.OperationType, sqliteMoney{&tx.Sum},
+		sqliteMoney{&tx.Balance}, sqliteMoney{&tx.BalanceFrom}, sqliteTime{&tx.Created}, &tx.IdempotencyKey,
+		&tx.RequestHash, &tx.ReversedId, &tx.CurrencyFrom, sqliteMoney{&tx.SumFrom}, &tx.Rate, &tx.QuoteId,
+		sqliteMoney{&tx.Refunded})
+}
+
+const sqliteTransactionColumns = `id, user_id, user_from_id, currency, operation, sum, COALESCE(balance, 0),
+	COALESCE(balance_from, 0), created, COALESCE(idempotency_key, ''), COALESCE(request_hash, ''),
+	COALESCE(reversed_id, 0), COALESCE(currency_from, ''), COALESCE(sum_from, 0), COALESCE(rate, 0), COALESCE(quote_id, 0),
+	refunded`
+
+func scanSQLiteHold(row sqliteRow, hold *models.Hold) error {
+	return row.Scan(&hold.Id, &hold.UserId, &hold.Currency, sqliteMoney{&hold.Sum}, sqliteMoney{&hold.Captured},
+		&hold.Status, sqliteTime{&hold.Created}, sqliteTime{&hold.Expires})
+}
+
+func scanSQLiteRate(row sqliteRow, rate *models.Rate) error {
+	return row.Scan(&rate.Id, &rate.Base, &rate.Quote, &rate.Rate, &rate.Source, sqliteTime{&rate.FetchedAt})
+}
+
+func openSQLite(path string, maxConnections int, operationTimeout time.Duration) (*sql.DB, error) {
+	db, err := sql.Open("sqlite3", sqliteDSN(path, operationTimeout))
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(maxConnections)
+	err = db.Ping()
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
@@ -0,0 +1,7 @@
+DROP TRIGGER IF EXISTS apply_posting;
+DROP TABLE IF EXISTS postings;
+DROP TABLE IF EXISTS holds;
+DROP TABLE IF EXISTS transactions;
+DROP TABLE IF EXISTS quotes;
+DROP TABLE IF EXISTS rates;
+DROP TABLE IF EXISTS balance;
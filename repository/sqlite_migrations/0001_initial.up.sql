@@ -0,0 +1,98 @@
+-- the schema of the PostgreSQL migrations for SQLite: money is kept in minor units and times in microseconds
+-- since the epoch, both as integers, so they compare exactly. Postings are checked to be balanced in Go,
+-- SQLite has no deferred triggers
+
+CREATE TABLE balance (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    currency TEXT NOT NULL DEFAULT 'RUB',
+    balance INTEGER NOT NULL DEFAULT 0 CONSTRAINT non_negative_balance CHECK (balance >= 0),
+    held INTEGER NOT NULL DEFAULT 0,
+    CONSTRAINT held_within_balance CHECK (held >= 0 AND held <= balance)
+);
+
+CREATE INDEX balance_user_id ON balance (user_id);
+CREATE UNIQUE INDEX balance_user_id_currency ON balance (user_id, currency);
+
+CREATE TABLE rates (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    base TEXT NOT NULL,
+    quote TEXT NOT NULL,
+    rate REAL NOT NULL CONSTRAINT positive_rate CHECK (rate > 0),
+    source TEXT NOT NULL,
+    fetched_at INTEGER NOT NULL
+);
+
+CREATE INDEX rates_base_quote_fetched_at ON rates (base, quote, fetched_at);
+
+CREATE TABLE quotes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    currency_from TEXT NOT NULL,
+    currency TEXT NOT NULL,
+    sum_from INTEGER NOT NULL CONSTRAINT positive_quote_sum_from CHECK (sum_from > 0),
+    sum INTEGER NOT NULL CONSTRAINT positive_quote_sum CHECK (sum > 0),
+    rate REAL NOT NULL,
+    rate_id INTEGER NOT NULL REFERENCES rates (id),
+    created INTEGER NOT NULL,
+    expires INTEGER NOT NULL
+);
+
+CREATE TABLE transactions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    user_from_id INTEGER NOT NULL DEFAULT 0,
+    currency TEXT NOT NULL DEFAULT 'RUB',
+    operation INTEGER NOT NULL CONSTRAINT op_types CHECK (operation >= 1 AND operation <= 5),
+    sum INTEGER NOT NULL CONSTRAINT positive_sum CHECK (sum > 0),
+    balance INTEGER CONSTRAINT non_negative_balance CHECK (balance >= 0),
+    balance_from INTEGER CONSTRAINT non_negative_balance_from CHECK (balance_from >= 0),
+    created INTEGER NOT NULL,
+    idempotency_key TEXT,
+    request_hash TEXT,
+    reversed_id INTEGER REFERENCES transactions (id),
+    currency_from TEXT,
+    sum_from INTEGER,
+    rate REAL,
+    quote_id INTEGER REFERENCES quotes (id),
+    CONSTRAINT transactions_balance_fkey FOREIGN KEY (user_id, currency) REFERENCES balance (user_id, currency)
+);
+
+CREATE INDEX transactions_user_id ON transactions (user_id);
+CREATE INDEX transactions_user_from_id ON transactions (user_from_id);
+
+CREATE UNIQUE INDEX transactions_idempotency_key
+    ON transactions ((CASE WHEN user_from_id != 0 THEN user_from_id ELSE user_id END), idempotency_key)
+    WHERE idempotency_key IS NOT NULL;
+
+CREATE UNIQUE INDEX transactions_reversed_id ON transactions (reversed_id) WHERE reversed_id IS NOT NULL;
+CREATE UNIQUE INDEX transactions_quote_id ON transactions (quote_id) WHERE quote_id IS NOT NULL;
+
+CREATE TABLE holds (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    currency TEXT NOT NULL,
+    sum INTEGER NOT NULL CONSTRAINT positive_hold_sum CHECK (sum > 0),
+    captured INTEGER NOT NULL DEFAULT 0,
+    status TEXT NOT NULL,
+    created INTEGER NOT NULL,
+    expires INTEGER NOT NULL,
+    FOREIGN KEY (user_id, currency) REFERENCES balance (user_id, currency)
+);
+
+CREATE INDEX holds_active_expires ON holds (expires) WHERE status = 'active';
+
+CREATE TABLE postings (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    transaction_id INTEGER NOT NULL REFERENCES transactions (id),
+    account_id INTEGER NOT NULL REFERENCES balance (id),
+    currency TEXT NOT NULL,
+    amount INTEGER NOT NULL CONSTRAINT non_zero_amount CHECK (amount != 0)
+);
+
+CREATE INDEX postings_transaction_id ON postings (transaction_id);
+CREATE INDEX postings_account_id ON postings (account_id);
+
+CREATE TRIGGER apply_posting AFTER INSERT ON postings
+BEGIN
+    UPDATE balance SET balance = balance + NEW.amount WHERE id = NEW.account_id AND user_id > 0;
+END;
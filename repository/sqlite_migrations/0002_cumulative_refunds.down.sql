@@ -0,0 +1,6 @@
+-- fails if a transaction was refunded more than once
+
+DROP INDEX transactions_reversed_id;
+CREATE UNIQUE INDEX transactions_reversed_id ON transactions (reversed_id) WHERE reversed_id IS NOT NULL;
+
+ALTER TABLE transactions DROP COLUMN refunded;
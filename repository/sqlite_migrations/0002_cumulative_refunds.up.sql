@@ -0,0 +1,11 @@
+-- a transaction can be refunded partially several times, the refunded total is kept on the transaction
+
+ALTER TABLE transactions ADD COLUMN refunded INTEGER NOT NULL DEFAULT 0
+    CONSTRAINT refunded_up_to_sum CHECK (refunded >= 0 AND refunded <= sum);
+
+UPDATE transactions SET refunded = (SELECT SUM(reversals.sum) FROM transactions reversals
+    WHERE reversals.reversed_id = transactions.id)
+WHERE id IN (SELECT reversed_id FROM transactions WHERE reversed_id IS NOT NULL);
+
+DROP INDEX transactions_reversed_id;
+CREATE INDEX transactions_reversed_id ON transactions (reversed_id) WHERE reversed_id IS NOT NULL;
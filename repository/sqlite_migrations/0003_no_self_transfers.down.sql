@@ -0,0 +1 @@
+DROP TRIGGER no_self_transfer;
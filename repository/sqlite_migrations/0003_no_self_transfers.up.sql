@@ -0,0 +1,8 @@
+-- a transfer to the sender would store two balance snapshots of one wallet at the same time,
+-- SQLite can't add a check constraint to an existing table
+
+CREATE TRIGGER no_self_transfer BEFORE INSERT ON transactions
+WHEN NEW.user_from_id = NEW.user_id
+BEGIN
+    SELECT RAISE(ABORT, 'transaction violates check constraint no_self_transfer');
+END;
@@ -0,0 +1 @@
+ALTER TABLE quotes DROP COLUMN user_from_id;
@@ -0,0 +1,4 @@
+-- a quote can only be used by the sender it was priced for, quotes created before
+-- have no sender and can't be used anymore
+
+ALTER TABLE quotes ADD COLUMN user_from_id INTEGER;
@@ -0,0 +1,6 @@
+DROP TRIGGER no_self_transfer;
+CREATE TRIGGER no_self_transfer BEFORE INSERT ON transactions
+WHEN NEW.user_from_id = NEW.user_id
+BEGIN
+    SELECT RAISE(ABORT, 'transaction violates check constraint no_self_transfer');
+END;
@@ -0,0 +1,9 @@
+-- a user can convert funds between their own wallets of different currencies,
+-- only a transfer to the sending wallet itself stays forbidden
+
+DROP TRIGGER no_self_transfer;
+CREATE TRIGGER no_self_transfer BEFORE INSERT ON transactions
+WHEN NEW.user_from_id = NEW.user_id AND (NEW.currency_from IS NULL OR NEW.currency_from = NEW.currency)
+BEGIN
+    SELECT RAISE(ABORT, 'transaction violates check constraint no_self_transfer');
+END;
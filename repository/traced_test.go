@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"context"
+	"github.com/saskamegaprogrammist/userBalanceService/models"
+	"github.com/saskamegaprogrammist/userBalanceService/utils"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"testing"
+	"time"
+)
+
+func TestTraced(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(previous)
+	openTempSQLite(t)
+	userId := newUserId()
+
+	err := GetUnitOfWork().Do(context.Background(), func(work WorkI) error {
+		wallet := models.Balance{UserId: userId, Currency: utils.CURRENCY}
+		err := work.LockBalances(&wallet)
+		if err != nil {
+			return err
+		}
+		return work.AddTransaction(&models.Transaction{UserId: userId, Currency: utils.CURRENCY,
+			OperationType: addOperation, Sum: models.MoneyFromUnits(10), Created: time.Now()})
+	})
+	assert.Error(t, err)
+	_, err = GetBalanceRepo().GetBalancesByUserId(context.Background(), &models.UserId{UserId: userId})
+	assert.NoError(t, err)
+
+	spans := recorder.Ended()
+	names := make([]string, 0, len(spans))
+	for _, span := range spans {
+		names = append(names, span.Name())
+		assert.Contains(t, span.Attributes(), semconv.DBSystemSqlite)
+	}
+	assert.Equal(t, []string{"Work.LockBalances", "Work.AddTransaction", "UnitOfWork.Do",
+		"BalanceRepo.GetBalancesByUserId"}, names)
+	do := spans[2]
+	assert.Equal(t, do.SpanContext().SpanID(), spans[0].Parent().SpanID())
+	assert.Equal(t, do.SpanContext().SpanID(), spans[1].Parent().SpanID())
+	assert.Equal(t, codes.Error, spans[1].Status().Code)
+	assert.Equal(t, codes.Error, do.Status().Code)
+	assert.False(t, spans[3].Parent().IsValid())
+}
@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"context"
 	"fmt"
 	"github.com/google/logger"
 	"github.com/jackc/pgx"
@@ -12,198 +13,259 @@ import (
 type TransactionsRepo struct {
 }
 
-func (transactionsRepo *TransactionsRepo) Add(tx *models.Transaction) error {
+var sortColumns = map[string]string{
+	"":     "",
+	"date": "created",
+	"sum":  "sum",
+}
+
+// builds the query of GetUserTransactions, transactions are ordered by the sort column and then by id,
+// so every sort mode has a stable order and the page after cursor starts right after the (sort column, id) pair it holds
+
+func transactionsQuery(filter *models.TransactionsFilter) (string, []interface{}, error) {
+	column, ok := sortColumns[filter.Sort]
+	if !ok {
+		return "", nil, models.Errorf(models.ErrValidation, "Wrong sort param")
+	}
+
+	builder := newQueryBuilder(`SELECT id, user_id, user_from_id, currency, operation, sum, balance, balance_from, created,
+		COALESCE(reversed_id, 0), COALESCE(currency_from, ''), COALESCE(sum_from, 0), COALESCE(rate, 0),
+		COALESCE(quote_id, 0) FROM transactions`)
+	builder.Where("user_id = ? OR user_from_id = ?", filter.UserId, filter.UserId)
+	if !filter.From.IsZero() {
+		builder.Where("created >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		builder.Where("created <= ?", filter.To)
+	}
+	if len(filter.OperationTypes) > 0 {
+		operations := make([]interface{}, len(filter.OperationTypes))
+		for i, operation := range filter.OperationTypes {
+			operations[i] = operation
+		}
+		builder.WhereIn("operation", operations...)
+	}
+	if filter.Counterparty != utils.ERROR_ID {
+		builder.Where("(user_id = ? AND user_from_id = ?) OR (user_from_id = ? AND user_id = ?)",
+			filter.UserId, filter.Counterparty, filter.UserId, filter.Counterparty)
+	}
+	if filter.MinSum != 0 {
+		builder.Where("sum >= ?", filter.MinSum)
+	}
+	if filter.MaxSum != 0 {
+		builder.Where("sum <= ?", filter.MaxSum)
+	}
+
+	comparison := ">"
+	if filter.Desc {
+		comparison = "<"
+	}
+	if filter.After != nil {
+		switch column {
+		case "":
+			builder.Where("id "+comparison+" ?", filter.After.Id)
+		case "created":
+			builder.Where("(created, id) "+comparison+" (?, ?)", filter.After.Created, filter.After.Id)
+		default:
+			builder.Where("(sum, id) "+comparison+" (?, ?)", filter.After.Sum, filter.After.Id)
+		}
+	}
+	if column != "" {
+		builder.OrderBy(column, filter.Desc)
+	}
+	builder.OrderBy("id", filter.Desc)
+	if filter.Limit != utils.LIMIT_DEFAULT {
+		builder.Limit(filter.Limit)
+	}
+
+	query, args := builder.Build()
+	return query, args, nil
+}
+
+func (transactionsRepo *TransactionsRepo) GetUserTransactions(ctx context.Context,
+	filter *models.TransactionsFilter) ([]models.Transaction, error) {
+	ctx, cancel := withOperationTimeout(ctx)
+	defer cancel()
+	txs := make([]models.Transaction, 0)
+	query, args, err := transactionsQuery(filter)
+	if err != nil {
+		logger.Errorf(err.Error())
+		return txs, err
+	}
+
 	db := getPool()
-	transaction, err := db.Begin()
+	rows, err := db.QueryEx(ctx, query, nil, args...)
 	if err != nil {
-		dbError := fmt.Errorf("Failed to start transaction: %v", err.Error())
+		dbError := fmt.Errorf("Failed to retrieve transactions: %v", err.Error())
 		logger.Errorf(dbError.Error())
-		return dbError
+		return txs, dbError
 	}
+	defer rows.Close()
 
-	row := transaction.QueryRow(`INSERT INTO transactions (user_id, user_from_id, operation, sum, balance, balance_from, created) 
-		VALUES ($1, $2, $3, $4, $5, $6, $7) returning id`,
-		tx.UserId, tx.UserFromId, tx.OperationType, tx.Sum, tx.Balance, tx.BalanceFrom, tx.Created)
-	err = row.Scan(&tx.Id)
-	if err != nil {
-		logger.Errorf("Failed to scan row: %v", err)
-		errRollback := transaction.Rollback()
-		if errRollback != nil {
-			logger.Errorf("Failed to rollback: %v", err)
-			return errRollback
+	for rows.Next() {
+		var txFound models.Transaction
+		err = scanListedTransaction(rows, &txFound)
+		if err != nil {
+			logger.Errorf("Failed to retrieve transaction: %v", err)
+			return txs, err
 		}
+		txs = append(txs, txFound)
+	}
+	if rows.Err() != nil {
+		return txs, rows.Err()
+	}
+	return txs, nil
+}
+
+// reads the transactions through a server-side cursor in batches of utils.EXPORT_BATCH_SIZE,
+// so that memory use doesn't depend on the number of transactions. The export isn't limited
+// by the operation timeout as a whole, every batch is
+
+func (transactionsRepo *TransactionsRepo) ExportUserTransactions(ctx context.Context, filter *models.TransactionsFilter,
+	each func(tx *models.Transaction) error) error {
+	query, args, err := transactionsQuery(filter)
+	if err != nil {
+		logger.Errorf(err.Error())
 		return err
 	}
 
-	err = transaction.Commit()
+	db := getPool()
+	transaction, err := db.BeginEx(ctx, &pgx.TxOptions{AccessMode: pgx.ReadOnly})
 	if err != nil {
-		dbError := fmt.Errorf("Error commit: %v", err.Error())
+		dbError := fmt.Errorf("Failed to start transaction: %v", err.Error())
 		logger.Errorf(dbError.Error())
 		return dbError
 	}
-	return nil
-}
+	defer transaction.Rollback()
 
-func (transactionsRepo *TransactionsRepo) GetUserTransactions(user *models.UserId, limit int, since string, sort string, desc bool) ([]models.Transaction, int, error) {
-	txs := make([]models.Transaction, 0)
-	db := getPool()
-	transaction, err := db.Begin()
+	declareCtx, cancel := withOperationTimeout(ctx)
+	_, err = transaction.ExecEx(declareCtx, `DECLARE export_cursor NO SCROLL CURSOR FOR `+query, nil, args...)
+	cancel()
 	if err != nil {
-		dbError := fmt.Errorf("Failed to start transaction: %v", err.Error())
+		dbError := fmt.Errorf("Failed to declare cursor: %v", err.Error())
 		logger.Errorf(dbError.Error())
-		return txs, utils.SERVER_ERROR, dbError
+		return dbError
 	}
-	var sinceTime time.Time
-	if since != "" {
-		sinceTime, err = time.Parse(time.RFC3339Nano, since)
+
+	for fetched := utils.EXPORT_BATCH_SIZE; fetched == utils.EXPORT_BATCH_SIZE; {
+		fetched, err = exportBatch(ctx, transaction, each)
 		if err != nil {
-			parseError := fmt.Errorf("Failed to parse since param: %v", err.Error())
-			logger.Errorf(parseError.Error())
-			return txs, utils.USER_ERROR, parseError
+			return err
 		}
 	}
-	var rows *pgx.Rows
-	if desc {
-		if limit != utils.LIMIT_DEFAULT {
-			if since != "" {
-				if sort == "date" {
-					rows, err = transaction.Query(`SELECT * FROM transactions WHERE user_id = $1 OR user_from_id = $1 AND created <= $2
-							ORDER BY created DESC LIMIT $3`, user.UserId, sinceTime, limit)
-				} else if sort == "sum" {
-					rows, err = transaction.Query(`SELECT * FROM transactions WHERE user_id = $1 OR user_from_id = $1 AND created <= $2
-							ORDER BY sum DESC LIMIT $3`, user.UserId, sinceTime, limit)
-				} else {
-					userError := fmt.Errorf("Wrong sort param")
-					logger.Errorf(userError.Error())
-					return txs, utils.USER_ERROR, userError
-				}
-			} else {
-				if sort == "date" {
-					rows, err = transaction.Query(`SELECT * FROM transactions WHERE user_id = $1 OR user_from_id = $1
-							ORDER BY created DESC LIMIT $2`, user.UserId, limit)
-				} else if sort == "sum" {
-					rows, err = transaction.Query(`SELECT * FROM transactions WHERE user_id = $1 OR user_from_id = $1
-							ORDER BY sum DESC LIMIT $2`, user.UserId, limit)
-				} else {
-					userError := fmt.Errorf("Wrong sort param")
-					logger.Errorf(userError.Error())
-					return txs, utils.USER_ERROR, userError
-				}
-			}
-		} else {
-			if since != "" {
-				if sort == "date" {
-					rows, err = transaction.Query(`SELECT * FROM transactions WHERE user_id = $1 OR user_from_id = $1 AND created <= $2
-							ORDER BY created DESC`, user.UserId, sinceTime)
-				} else if sort == "sum" {
-					rows, err = transaction.Query(`SELECT * FROM transactions WHERE user_id = $1 OR user_from_id = $1 AND created <= $2
-							ORDER BY sum DESC`, user.UserId, sinceTime)
-				} else {
-					userError := fmt.Errorf("Wrong sort param")
-					logger.Errorf(userError.Error())
-					return txs, utils.USER_ERROR, userError
-				}
-			} else {
-				if sort == "date" {
-					rows, err = transaction.Query(`SELECT * FROM transactions WHERE user_id = $1 OR user_from_id = $1
-							ORDER BY created DESC`, user.UserId)
-				} else if sort == "sum" {
-					rows, err = transaction.Query(`SELECT * FROM transactions WHERE user_id = $1 OR user_from_id = $1
-							ORDER BY sum DESC `, user.UserId)
-				} else {
-					userError := fmt.Errorf("Wrong sort param")
-					logger.Errorf(userError.Error())
-					return txs, utils.USER_ERROR, userError
-				}
-			}
-		}
-	} else {
-		if limit != utils.LIMIT_DEFAULT {
-			if since != "" {
-				if sort == "date" {
-					rows, err = transaction.Query(`SELECT * FROM transactions WHERE user_id = $1 OR user_from_id = $1 AND created >= $2
-							ORDER BY created LIMIT $3`, user.UserId, sinceTime, limit)
-				} else if sort == "sum" {
-					rows, err = transaction.Query(`SELECT * FROM transactions WHERE user_id = $1 OR user_from_id = $1 AND created >= $2
-							ORDER BY sum LIMIT $3`, user.UserId, sinceTime, limit)
-				} else if sort == "" {
-					rows, err = transaction.Query(`SELECT * FROM transactions WHERE user_id = $1 OR user_from_id = $1 AND created >= $2
-							LIMIT $3`, user.UserId, sinceTime, limit)
-				} else {
-					userError := fmt.Errorf("Wrong sort param")
-					logger.Errorf(userError.Error())
-					return txs, utils.USER_ERROR, userError
-				}
-			} else {
-				if sort == "date" {
-					rows, err = transaction.Query(`SELECT * FROM transactions WHERE user_id = $1 OR user_from_id = $1
-							ORDER BY created LIMIT $2`, user.UserId, limit)
-				} else if sort == "sum" {
-					rows, err = transaction.Query(`SELECT * FROM transactions WHERE user_id = $1 OR user_from_id = $1
-							ORDER BY sum LIMIT $2`, user.UserId, limit)
-				} else if sort == "" {
-					rows, err = transaction.Query(`SELECT * FROM transactions WHERE user_id = $1 OR user_from_id = $1
-							LIMIT $2`, user.UserId, limit)
-				} else {
-					userError := fmt.Errorf("Wrong sort param")
-					logger.Errorf(userError.Error())
-					return txs, utils.USER_ERROR, userError
-				}
-			}
-		} else {
-			if since != "" {
-				if sort == "date" {
-					rows, err = transaction.Query(`SELECT * FROM transactions WHERE user_id = $1 OR user_from_id = $1 AND created >= $2
-							ORDER BY created DESC`, user.UserId, since)
-				} else if sort == "sum" {
-					rows, err = transaction.Query(`SELECT * FROM transactions WHERE user_id = $1 OR user_from_id = $1 AND created >= $2
-							ORDER BY sum DESC`, user.UserId, since)
-				} else if sort == "" {
-					rows, err = transaction.Query(`SELECT * FROM transactions WHERE user_id = $1 OR user_from_id = $1 AND created >= $2`, user.UserId, sinceTime)
-				} else {
-					userError := fmt.Errorf("Wrong sort param")
-					logger.Errorf(userError.Error())
-					return txs, utils.USER_ERROR, userError
-				}
-			} else {
-				if sort == "date" {
-					rows, err = transaction.Query(`SELECT * FROM transactions WHERE user_id = $1 OR user_from_id = $1
-							ORDER BY created `, user.UserId)
-				} else if sort == "sum" {
-					rows, err = transaction.Query(`SELECT * FROM transactions WHERE user_id = $1 OR user_from_id = $1
-							ORDER BY sum `, user.UserId)
-				} else if sort == "" {
-					rows, err = transaction.Query(`SELECT * FROM transactions WHERE user_id = $1 OR user_from_id = $1`, user.UserId)
-				} else {
-					userError := fmt.Errorf("Wrong sort param")
-					logger.Errorf(userError.Error())
-					return txs, utils.USER_ERROR, userError
-				}
-			}
+	return nil
+}
+
+// only the fetch of a batch runs under the operation timeout, its rows are passed to each
+// once the fetch is done, so that slow clients aren't cut off by the database timeout
+
+func exportBatch(ctx context.Context, transaction *pgx.Tx, each func(tx *models.Transaction) error) (int, error) {
+	batch, err := fetchBatch(ctx, transaction)
+	if err != nil {
+		return 0, err
+	}
+	for i := range batch {
+		err = each(&batch[i])
+		if err != nil {
+			logger.Errorf("Failed to export transaction: %v", err)
+			return i, err
 		}
 	}
+	return len(batch), nil
+}
+
+func fetchBatch(ctx context.Context, transaction *pgx.Tx) ([]models.Transaction, error) {
+	ctx, cancel := withOperationTimeout(ctx)
+	defer cancel()
+	rows, err := transaction.QueryEx(ctx, fmt.Sprintf(`FETCH %d FROM export_cursor`, utils.EXPORT_BATCH_SIZE), nil)
 	if err != nil {
-		logger.Errorf("Failed to retrieve transactions: %v", err)
+		dbError := fmt.Errorf("Failed to fetch transactions: %v", err.Error())
+		logger.Errorf(dbError.Error())
+		return nil, dbError
 	}
+	defer rows.Close()
+
+	batch := make([]models.Transaction, 0, utils.EXPORT_BATCH_SIZE)
 	for rows.Next() {
 		var txFound models.Transaction
-		err = rows.Scan(&txFound.Id, &txFound.UserId, &txFound.UserFromId, &txFound.OperationType, &txFound.Sum, &txFound.Balance, &txFound.BalanceFrom, &txFound.Created)
+		err = scanListedTransaction(rows, &txFound)
 		if err != nil {
-			logger.Errorf("Failed to retrieve transaction: %v", err)
-			errRollback := transaction.Rollback()
-			if errRollback != nil {
-				logger.Errorf("Failed to rollback: %v", err)
-				return txs, utils.SERVER_ERROR, errRollback
-			}
-			return txs, utils.SERVER_ERROR, err
+			logger.Errorf("Failed to export transaction: %v", err)
+			return nil, err
 		}
-		txs = append(txs, txFound)
+		batch = append(batch, txFound)
 	}
-	err = transaction.Commit()
+	return batch, rows.Err()
+}
+
+func scanListedTransaction(rows *pgx.Rows, tx *models.Transaction) error {
+	return rows.Scan(&tx.Id, &tx.UserId, &tx.UserFromId, &tx.Currency, &tx.OperationType, &tx.Sum,
+		&tx.Balance, &tx.BalanceFrom, &tx.Created, &tx.ReversedId, &tx.CurrencyFrom, &tx.SumFrom, &tx.Rate, &tx.QuoteId)
+}
+
+// the sender's wallet of a cross-currency transfer is in currency_from, so both sides of every transaction
+// are read as separate rows of the wallet history
+
+func balancesAsOfQuery(user *models.UserId, asOf time.Time) (string, []interface{}) {
+	builder := newQueryBuilder(`SELECT DISTINCT ON (currency) currency, balance::numeric FROM (
+		SELECT id, created, currency, balance FROM transactions WHERE user_id = ?
+		UNION ALL
+		SELECT id, created, COALESCE(currency_from, currency), balance_from FROM transactions WHERE user_from_id = ?
+	) AS wallet_history`, user.UserId, user.UserId)
+	if user.Currency != "" {
+		builder.Where("currency = ?", user.Currency)
+	}
+	builder.Where("created <= ?", asOf)
+	builder.OrderBy("currency", false).OrderBy("created", true).OrderBy("id", true)
+	return builder.Build()
+}
+
+// reads the wallet balances of the user from the balance snapshots of the latest transaction of every currency
+// created at or before asOf, wallets without such transactions are omitted. Only the wallet in user.Currency
+// is read when it is set
+
+func (transactionsRepo *TransactionsRepo) GetBalancesAsOf(ctx context.Context, user *models.UserId,
+	asOf time.Time) ([]models.Balance, error) {
+	ctx, cancel := withOperationTimeout(ctx)
+	defer cancel()
+	balances := make([]models.Balance, 0)
+	query, args := balancesAsOfQuery(user, asOf)
+
+	db := getPool()
+	rows, err := db.QueryEx(ctx, query, nil, args...)
 	if err != nil {
-		dbError := fmt.Errorf("Error commit: %v", err.Error())
+		dbError := fmt.Errorf("Failed to retrieve balances: %v", err.Error())
 		logger.Errorf(dbError.Error())
-		return txs, utils.SERVER_ERROR, dbError
+		return balances, dbError
 	}
-	return txs, utils.NO_ERROR, nil
+	defer rows.Close()
+
+	for rows.Next() {
+		balance := models.Balance{UserId: user.UserId}
+		err = rows.Scan(&balance.Currency, &balance.Balance)
+		if err != nil {
+			logger.Errorf("Failed to retrieve balance: %v", err)
+			return balances, err
+		}
+		balance.Available = balance.Balance
+		balances = append(balances, balance)
+	}
+	if rows.Err() != nil {
+		return balances, rows.Err()
+	}
+	return balances, nil
+}
+
+func (transactionsRepo *TransactionsRepo) ExpireIdempotencyKeys(ctx context.Context, before time.Time) error {
+	ctx, cancel := withOperationTimeout(ctx)
+	defer cancel()
+	db := getPool()
+	_, err := db.ExecEx(ctx, `UPDATE transactions SET idempotency_key = NULL, request_hash = NULL
+		WHERE idempotency_key IS NOT NULL AND created < $1`, nil, before)
+	if err != nil {
+		dbError := fmt.Errorf("Failed to expire idempotency keys: %v", err.Error())
+		logger.Errorf(dbError.Error())
+		return dbError
+	}
+	return nil
 }
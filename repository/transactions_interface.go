@@ -1,8 +1,15 @@
 package repository
 
-import "github.com/saskamegaprogrammist/userBalanceService/models"
+import (
+	"context"
+	"github.com/saskamegaprogrammist/userBalanceService/models"
+	"time"
+)
 
 type TransactionsRepoI interface {
-	Add(transaction *models.Transaction) error
-	GetUserTransactions(user *models.UserId, limit int, since string, sort string, desc bool) ([]models.Transaction, int, error)
+	GetUserTransactions(ctx context.Context, filter *models.TransactionsFilter) ([]models.Transaction, error)
+	ExportUserTransactions(ctx context.Context, filter *models.TransactionsFilter,
+		each func(tx *models.Transaction) error) error
+	GetBalancesAsOf(ctx context.Context, user *models.UserId, asOf time.Time) ([]models.Balance, error)
+	ExpireIdempotencyKeys(ctx context.Context, before time.Time) error
 }
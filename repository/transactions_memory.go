@@ -0,0 +1,218 @@
+package repository
+
+import (
+	"context"
+	"github.com/google/logger"
+	"github.com/saskamegaprogrammist/userBalanceService/models"
+	"github.com/saskamegaprogrammist/userBalanceService/utils"
+	"sort"
+	"time"
+)
+
+type MemoryTransactionsRepo struct {
+	store *MemoryStore
+}
+
+// compares tx with the (sort column, id) pair of the cursor, like the row comparison of transactionsQuery
+
+func compareTransaction(column string, tx *models.Transaction, after *models.Cursor) int {
+	switch {
+	case column == "created" && tx.Created.Before(after.Created):
+		return -1
+	case column == "created" && tx.Created.After(after.Created):
+		return 1
+	case column == "sum" && tx.Sum < after.Sum:
+		return -1
+	case column == "sum" && tx.Sum > after.Sum:
+		return 1
+	case tx.Id < after.Id:
+		return -1
+	case tx.Id > after.Id:
+		return 1
+	}
+	return 0
+}
+
+func matchesFilter(tx *models.Transaction, filter *models.TransactionsFilter) bool {
+	if tx.UserId != filter.UserId && tx.UserFromId != filter.UserId {
+		return false
+	}
+	if !filter.From.IsZero() && tx.Created.Before(filter.From) {
+		return false
+	}
+	if !filter.To.IsZero() && tx.Created.After(filter.To) {
+		return false
+	}
+	if len(filter.OperationTypes) > 0 {
+		found := false
+		for _, operation := range filter.OperationTypes {
+			found = found || tx.OperationType == operation
+		}
+		if !found {
+			return false
+		}
+	}
+	if filter.Counterparty != utils.ERROR_ID &&
+		!(tx.UserId == filter.UserId && tx.UserFromId == filter.Counterparty) &&
+		!(tx.UserFromId == filter.UserId && tx.UserId == filter.Counterparty) {
+		return false
+	}
+	if filter.MinSum != 0 && tx.Sum < filter.MinSum {
+		return false
+	}
+	if filter.MaxSum != 0 && tx.Sum > filter.MaxSum {
+		return false
+	}
+	return true
+}
+
+// selects the transactions of GetUserTransactions in the same order as transactionsQuery,
+// the listed transactions have no idempotency keys and postings
+
+func (store *MemoryStore) userTransactions(filter *models.TransactionsFilter) ([]models.Transaction, error) {
+	txs := make([]models.Transaction, 0)
+	column, ok := sortColumns[filter.Sort]
+	if !ok {
+		return txs, models.Errorf(models.ErrValidation, "Wrong sort param")
+	}
+
+	for _, tx := range store.transactions {
+		if !matchesFilter(tx, filter) {
+			continue
+		}
+		if filter.After != nil {
+			comparison := compareTransaction(column, tx, filter.After)
+			if filter.Desc && comparison >= 0 || !filter.Desc && comparison <= 0 {
+				continue
+			}
+		}
+		listed := *tx
+		listed.IdempotencyKey = ""
+		listed.RequestHash = ""
+		txs = append(txs, listed)
+	}
+	sort.Slice(txs, func(i, j int) bool {
+		comparison := compareTransaction(column, &txs[i], &models.Cursor{Created: txs[j].Created, Sum: txs[j].Sum, Id: txs[j].Id})
+		if filter.Desc {
+			return comparison > 0
+		}
+		return comparison < 0
+	})
+	if filter.Limit != utils.LIMIT_DEFAULT && filter.Limit < len(txs) {
+		txs = txs[:filter.Limit]
+	}
+	return txs, nil
+}
+
+func (transactionsRepo *MemoryTransactionsRepo) GetUserTransactions(ctx context.Context,
+	filter *models.TransactionsFilter) ([]models.Transaction, error) {
+	ctx, cancel := withOperationTimeout(ctx)
+	defer cancel()
+	if ctx.Err() != nil {
+		return make([]models.Transaction, 0), ctx.Err()
+	}
+	store := transactionsRepo.store
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	txs, err := store.userTransactions(filter)
+	if err != nil {
+		logger.Errorf(err.Error())
+	}
+	return txs, err
+}
+
+// the transactions are selected at once, like the snapshot the cursor of the pgx implementation reads,
+// and the store isn't locked while they are passed to each
+
+func (transactionsRepo *MemoryTransactionsRepo) ExportUserTransactions(ctx context.Context, filter *models.TransactionsFilter,
+	each func(tx *models.Transaction) error) error {
+	store := transactionsRepo.store
+	store.mutex.RLock()
+	txs, err := store.userTransactions(filter)
+	store.mutex.RUnlock()
+	if err != nil {
+		logger.Errorf(err.Error())
+		return err
+	}
+
+	for i := range txs {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		err = each(&txs[i])
+		if err != nil {
+			logger.Errorf("Failed to export transaction: %v", err)
+			return err
+		}
+	}
+	return nil
+}
+
+// reads the same wallet history as balancesAsOfQuery: the latest snapshot of every currency created at or before asOf
+
+func (transactionsRepo *MemoryTransactionsRepo) GetBalancesAsOf(ctx context.Context, user *models.UserId,
+	asOf time.Time) ([]models.Balance, error) {
+	ctx, cancel := withOperationTimeout(ctx)
+	defer cancel()
+	balances := make([]models.Balance, 0)
+	if ctx.Err() != nil {
+		return balances, ctx.Err()
+	}
+	store := transactionsRepo.store
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	latest := make(map[string]*models.Transaction)
+	snapshots := make(map[string]models.Money)
+	record := func(tx *models.Transaction, currency string, balance models.Money) {
+		if user.Currency != "" && currency != user.Currency || tx.Created.After(asOf) {
+			return
+		}
+		previous, ok := latest[currency]
+		if ok && compareTransaction("created", tx, &models.Cursor{Created: previous.Created, Id: previous.Id}) < 0 {
+			return
+		}
+		latest[currency] = tx
+		snapshots[currency] = balance
+	}
+	for _, tx := range store.transactions {
+		if tx.UserId == user.UserId {
+			record(tx, tx.Currency, tx.Balance)
+		}
+		if tx.UserFromId == user.UserId {
+			currency := tx.CurrencyFrom
+			if currency == "" {
+				currency = tx.Currency
+			}
+			record(tx, currency, tx.BalanceFrom)
+		}
+	}
+
+	for currency, balance := range snapshots {
+		balances = append(balances, models.Balance{UserId: user.UserId, Currency: currency, Balance: balance, Available: balance})
+	}
+	sort.Slice(balances, func(i, j int) bool {
+		return balances[i].Currency < balances[j].Currency
+	})
+	return balances, nil
+}
+
+func (transactionsRepo *MemoryTransactionsRepo) ExpireIdempotencyKeys(ctx context.Context, before time.Time) error {
+	ctx, cancel := withOperationTimeout(ctx)
+	defer cancel()
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	store := transactionsRepo.store
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	for _, tx := range store.transactions {
+		if tx.IdempotencyKey != "" && tx.Created.Before(before) {
+			tx.IdempotencyKey = ""
+			tx.RequestHash = ""
+		}
+	}
+	return nil
+}
@@ -5,9 +5,11 @@
 package repository
 
 import (
+	context "context"
 	gomock "github.com/golang/mock/gomock"
 	models "github.com/saskamegaprogrammist/userBalanceService/models"
 	reflect "reflect"
+	time "time"
 )
 
 // MockTransactionsRepoI is a mock of TransactionsRepoI interface
@@ -33,32 +35,60 @@ func (m *MockTransactionsRepoI) EXPECT() *MockTransactionsRepoIMockRecorder {
 	return m.recorder
 }
 
-// Add mocks base method
-func (m *MockTransactionsRepoI) Add(transaction *models.Transaction) error {
+// GetUserTransactions mocks base method
+func (m *MockTransactionsRepoI) GetUserTransactions(ctx context.Context, filter *models.TransactionsFilter) ([]models.Transaction, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserTransactions", ctx, filter)
+	ret0, _ := ret[0].([]models.Transaction)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserTransactions indicates an expected call of GetUserTransactions
+func (mr *MockTransactionsRepoIMockRecorder) GetUserTransactions(ctx, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserTransactions", reflect.TypeOf((*MockTransactionsRepoI)(nil).GetUserTransactions), ctx, filter)
+}
+
+// ExportUserTransactions mocks base method
+func (m *MockTransactionsRepoI) ExportUserTransactions(ctx context.Context, filter *models.TransactionsFilter, each func(*models.Transaction) error) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Add", transaction)
+	ret := m.ctrl.Call(m, "ExportUserTransactions", ctx, filter, each)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// Add indicates an expected call of Add
-func (mr *MockTransactionsRepoIMockRecorder) Add(transaction interface{}) *gomock.Call {
+// ExportUserTransactions indicates an expected call of ExportUserTransactions
+func (mr *MockTransactionsRepoIMockRecorder) ExportUserTransactions(ctx, filter, each interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Add", reflect.TypeOf((*MockTransactionsRepoI)(nil).Add), transaction)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExportUserTransactions", reflect.TypeOf((*MockTransactionsRepoI)(nil).ExportUserTransactions), ctx, filter, each)
 }
 
-// GetUserTransactions mocks base method
-func (m *MockTransactionsRepoI) GetUserTransactions(user *models.UserId, limit int, since, sort string, desc bool) ([]models.Transaction, int, error) {
+// GetBalancesAsOf mocks base method
+func (m *MockTransactionsRepoI) GetBalancesAsOf(ctx context.Context, user *models.UserId, asOf time.Time) ([]models.Balance, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetUserTransactions", user, limit, since, sort, desc)
-	ret0, _ := ret[0].([]models.Transaction)
-	ret1, _ := ret[1].(int)
-	ret2, _ := ret[2].(error)
-	return ret0, ret1, ret2
+	ret := m.ctrl.Call(m, "GetBalancesAsOf", ctx, user, asOf)
+	ret0, _ := ret[0].([]models.Balance)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
-// GetUserTransactions indicates an expected call of GetUserTransactions
-func (mr *MockTransactionsRepoIMockRecorder) GetUserTransactions(user, limit, since, sort, desc interface{}) *gomock.Call {
+// GetBalancesAsOf indicates an expected call of GetBalancesAsOf
+func (mr *MockTransactionsRepoIMockRecorder) GetBalancesAsOf(ctx, user, asOf interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBalancesAsOf", reflect.TypeOf((*MockTransactionsRepoI)(nil).GetBalancesAsOf), ctx, user, asOf)
+}
+
+// ExpireIdempotencyKeys mocks base method
+func (m *MockTransactionsRepoI) ExpireIdempotencyKeys(ctx context.Context, before time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExpireIdempotencyKeys", ctx, before)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ExpireIdempotencyKeys indicates an expected call of ExpireIdempotencyKeys
+func (mr *MockTransactionsRepoIMockRecorder) ExpireIdempotencyKeys(ctx, before interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserTransactions", reflect.TypeOf((*MockTransactionsRepoI)(nil).GetUserTransactions), user, limit, since, sort, desc)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExpireIdempotencyKeys", reflect.TypeOf((*MockTransactionsRepoI)(nil).ExpireIdempotencyKeys), ctx, before)
 }
@@ -0,0 +1,147 @@
+package repository
+
+import (
+	"github.com/saskamegaprogrammist/userBalanceService/models"
+	"github.com/saskamegaprogrammist/userBalanceService/utils"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+const selectTransactions = `SELECT id, user_id, user_from_id, currency, operation, sum, balance, balance_from, created,
+		COALESCE(reversed_id, 0), COALESCE(currency_from, ''), COALESCE(sum_from, 0), COALESCE(rate, 0),
+		COALESCE(quote_id, 0) FROM transactions`
+
+func TestTransactionsQuery(t *testing.T) {
+	from := time.Date(2020, 8, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2020, 8, 31, 0, 0, 0, 0, time.UTC)
+	cursor := models.Cursor{Created: from, Sum: models.MoneyFromUnits(50), Id: 7}
+
+	tests := []struct {
+		name   string
+		filter models.TransactionsFilter
+		where  string
+		args   []interface{}
+	}{
+		{
+			name:   "UserOnly",
+			filter: models.TransactionsFilter{UserId: 1, Limit: utils.LIMIT_DEFAULT},
+			where:  " WHERE (user_id = $1 OR user_from_id = $2) ORDER BY id",
+			args:   []interface{}{1, 1},
+		},
+		{
+			// the user condition used to be unparenthesized, so created only restricted user_from_id
+			name:   "FromBindsToBothUserColumns",
+			filter: models.TransactionsFilter{UserId: 1, From: from, Limit: utils.LIMIT_DEFAULT},
+			where:  " WHERE (user_id = $1 OR user_from_id = $2) AND (created >= $3) ORDER BY id",
+			args:   []interface{}{1, 1, from},
+		},
+		{
+			name:   "DateRangeDesc",
+			filter: models.TransactionsFilter{UserId: 1, From: from, To: to, Sort: "date", Desc: true, Limit: 10},
+			where: " WHERE (user_id = $1 OR user_from_id = $2) AND (created >= $3) AND (created <= $4)" +
+				" ORDER BY created DESC, id DESC LIMIT $5",
+			args: []interface{}{1, 1, from, to, 10},
+		},
+		{
+			// ascending sorts must never order by DESC
+			name:   "SumAscending",
+			filter: models.TransactionsFilter{UserId: 1, Sort: "sum", Limit: 10},
+			where:  " WHERE (user_id = $1 OR user_from_id = $2) ORDER BY sum, id LIMIT $3",
+			args:   []interface{}{1, 1, 10},
+		},
+		{
+			name:   "OperationTypes",
+			filter: models.TransactionsFilter{UserId: 1, OperationTypes: []int{1, 3}, Limit: utils.LIMIT_DEFAULT},
+			where:  " WHERE (user_id = $1 OR user_from_id = $2) AND (operation IN ($3, $4)) ORDER BY id",
+			args:   []interface{}{1, 1, 1, 3},
+		},
+		{
+			name:   "Counterparty",
+			filter: models.TransactionsFilter{UserId: 1, Counterparty: 2, Limit: utils.LIMIT_DEFAULT},
+			where: " WHERE (user_id = $1 OR user_from_id = $2)" +
+				" AND ((user_id = $3 AND user_from_id = $4) OR (user_from_id = $5 AND user_id = $6)) ORDER BY id",
+			args: []interface{}{1, 1, 1, 2, 1, 2},
+		},
+		{
+			name: "SumRange",
+			filter: models.TransactionsFilter{UserId: 1, MinSum: models.MoneyFromUnits(10), MaxSum: models.MoneyFromUnits(20),
+				Limit: utils.LIMIT_DEFAULT},
+			where: " WHERE (user_id = $1 OR user_from_id = $2) AND (sum >= $3) AND (sum <= $4) ORDER BY id",
+			args:  []interface{}{1, 1, models.MoneyFromUnits(10), models.MoneyFromUnits(20)},
+		},
+		{
+			name:   "CursorById",
+			filter: models.TransactionsFilter{UserId: 1, After: &cursor, Limit: 2},
+			where:  " WHERE (user_id = $1 OR user_from_id = $2) AND (id > $3) ORDER BY id LIMIT $4",
+			args:   []interface{}{1, 1, 7, 2},
+		},
+		{
+			name:   "CursorBySumDesc",
+			filter: models.TransactionsFilter{UserId: 1, Sort: "sum", Desc: true, After: &cursor, Limit: 2},
+			where: " WHERE (user_id = $1 OR user_from_id = $2) AND ((sum, id) < ($3, $4))" +
+				" ORDER BY sum DESC, id DESC LIMIT $5",
+			args: []interface{}{1, 1, models.MoneyFromUnits(50), 7, 2},
+		},
+		{
+			name:   "CursorByDate",
+			filter: models.TransactionsFilter{UserId: 1, Sort: "date", After: &cursor, Limit: 2},
+			where:  " WHERE (user_id = $1 OR user_from_id = $2) AND ((created, id) > ($3, $4)) ORDER BY created, id LIMIT $5",
+			args:   []interface{}{1, 1, from, 7, 2},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			query, args, err := transactionsQuery(&test.filter)
+
+			assert.NoError(t, err)
+			assert.Equal(t, selectTransactions+test.where, query)
+			assert.Equal(t, test.args, args)
+		})
+	}
+
+	t.Run("WrongSort", func(t *testing.T) {
+		_, _, err := transactionsQuery(&models.TransactionsFilter{UserId: 1, Sort: "name"})
+
+		assert.Error(t, err)
+	})
+}
+
+func TestBalancesAsOfQuery(t *testing.T) {
+	asOf := time.Date(2026, 3, 31, 23, 59, 59, 0, time.UTC)
+	selectBalances := `SELECT DISTINCT ON (currency) currency, balance::numeric FROM (
+		SELECT id, created, currency, balance FROM transactions WHERE user_id = $1
+		UNION ALL
+		SELECT id, created, COALESCE(currency_from, currency), balance_from FROM transactions WHERE user_from_id = $2
+	) AS wallet_history`
+
+	tests := []struct {
+		name  string
+		user  models.UserId
+		where string
+		args  []interface{}
+	}{
+		{
+			name:  "AllWallets",
+			user:  models.UserId{UserId: 1},
+			where: " WHERE (created <= $3) ORDER BY currency, created DESC, id DESC",
+			args:  []interface{}{1, 1, asOf},
+		},
+		{
+			name:  "OneWallet",
+			user:  models.UserId{UserId: 1, Currency: "USD"},
+			where: " WHERE (currency = $3) AND (created <= $4) ORDER BY currency, created DESC, id DESC",
+			args:  []interface{}{1, 1, "USD", asOf},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			query, args := balancesAsOfQuery(&test.user, asOf)
+
+			assert.Equal(t, selectBalances+test.where, query)
+			assert.Equal(t, test.args, args)
+		})
+	}
+}
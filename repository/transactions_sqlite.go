@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"github.com/google/logger"
+	"github.com/saskamegaprogrammist/userBalanceService/models"
+	"time"
+)
+
+type SQLiteTransactionsRepo struct {
+}
+
+func scanSQLiteListedTransaction(row sqliteRow, tx *models.Transaction) error {
+	return row.Scan(&tx.Id, &tx.UserId, &tx.UserFromId, &tx.Currency, &tx.OperationType, sqliteMoney{&tx.Sum},
+		sqliteMoney{&tx.Balance}, sqliteMoney{&tx.BalanceFrom}, sqliteTime{&tx.Created}, &tx.ReversedId, &tx.CurrencyFrom,
+		sqliteMoney{&tx.SumFrom}, &tx.Rate, &tx.QuoteId)
+}
+
+// transactionsQuery works for SQLite as well: its $N placeholders are numbered in the order
+// they appear in, which is the order of the arguments
+
+func (transactionsRepo *SQLiteTransactionsRepo) GetUserTransactions(ctx context.Context,
+	filter *models.TransactionsFilter) ([]models.Transaction, error) {
+	ctx, cancel := withOperationTimeout(ctx)
+	defer cancel()
+	txs := make([]models.Transaction, 0)
+	query, args, err := transactionsQuery(filter)
+	if err != nil {
+		logger.Errorf(err.Error())
+		return txs, err
+	}
+
+	db := getSQLite()
+	rows, err := db.QueryContext(ctx, query, sqliteArgs(args...)...)
+	if err != nil {
+		dbError := fmt.Errorf("Failed to retrieve transactions: %v", err.Error())
+		logger.Errorf(dbError.Error())
+		return txs, dbError
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var txFound models.Transaction
+		err = scanSQLiteListedTransaction(rows, &txFound)
+		if err != nil {
+			logger.Errorf("Failed to retrieve transaction: %v", err)
+			return txs, err
+		}
+		txs = append(txs, txFound)
+	}
+	return txs, rows.Err()
+}
+
+// SQLite reads the rows of a query one by one, so the transactions are read by one query that sees
+// a snapshot of the database. The export is only limited by ctx
+
+func (transactionsRepo *SQLiteTransactionsRepo) ExportUserTransactions(ctx context.Context, filter *models.TransactionsFilter,
+	each func(tx *models.Transaction) error) error {
+	query, args, err := transactionsQuery(filter)
+	if err != nil {
+		logger.Errorf(err.Error())
+		return err
+	}
+
+	db := getSQLite()
+	rows, err := db.QueryContext(ctx, query, sqliteArgs(args...)...)
+	if err != nil {
+		dbError := fmt.Errorf("Failed to retrieve transactions: %v", err.Error())
+		logger.Errorf(dbError.Error())
+		return dbError
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var txFound models.Transaction
+		err = scanSQLiteListedTransaction(rows, &txFound)
+		if err == nil {
+			err = each(&txFound)
+		}
+		if err != nil {
+			logger.Errorf("Failed to export transaction: %v", err)
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// reads the same wallet history as balancesAsOfQuery, the latest snapshot of every currency is picked
+// by a window function instead of DISTINCT ON
+
+func (transactionsRepo *SQLiteTransactionsRepo) GetBalancesAsOf(ctx context.Context, user *models.UserId,
+	asOf time.Time) ([]models.Balance, error) {
+	ctx, cancel := withOperationTimeout(ctx)
+	defer cancel()
+	balances := make([]models.Balance, 0)
+	query := `SELECT currency, balance FROM (
+		SELECT currency, balance, ROW_NUMBER() OVER (PARTITION BY currency ORDER BY created DESC, id DESC) AS position
+		FROM (
+			SELECT id, created, currency, balance FROM transactions WHERE user_id = ?
+			UNION ALL
+			SELECT id, created, COALESCE(currency_from, currency), balance_from FROM transactions WHERE user_from_id = ?
+		) AS wallet_history
+		WHERE created <= ? AND (? = '' OR currency = ?)
+	) WHERE position = 1 ORDER BY currency`
+
+	db := getSQLite()
+	rows, err := db.QueryContext(ctx, query, sqliteArgs(user.UserId, user.UserId, asOf, user.Currency, user.Currency)...)
+	if err != nil {
+		dbError := fmt.Errorf("Failed to retrieve balances: %v", err.Error())
+		logger.Errorf(dbError.Error())
+		return balances, dbError
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		balance := models.Balance{UserId: user.UserId}
+		err = rows.Scan(&balance.Currency, sqliteMoney{&balance.Balance})
+		if err != nil {
+			logger.Errorf("Failed to retrieve balance: %v", err)
+			return balances, err
+		}
+		balance.Available = balance.Balance
+		balances = append(balances, balance)
+	}
+	return balances, rows.Err()
+}
+
+func (transactionsRepo *SQLiteTransactionsRepo) ExpireIdempotencyKeys(ctx context.Context, before time.Time) error {
+	ctx, cancel := withOperationTimeout(ctx)
+	defer cancel()
+	db := getSQLite()
+	_, err := db.ExecContext(ctx, `UPDATE transactions SET idempotency_key = NULL, request_hash = NULL
+		WHERE idempotency_key IS NOT NULL AND created < ?`, sqliteArgs(before)...)
+	if err != nil {
+		dbError := fmt.Errorf("Failed to expire idempotency keys: %v", err.Error())
+		logger.Errorf(dbError.Error())
+		return dbError
+	}
+	return nil
+}
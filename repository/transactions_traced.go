@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"context"
+	"github.com/saskamegaprogrammist/userBalanceService/models"
+	"github.com/saskamegaprogrammist/userBalanceService/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"time"
+)
+
+type TracedTransactionsRepo struct {
+	transactionsRepo TransactionsRepoI
+	system           attribute.KeyValue
+}
+
+func (transactionsRepo *TracedTransactionsRepo) GetUserTransactions(ctx context.Context,
+	filter *models.TransactionsFilter) ([]models.Transaction, error) {
+	ctx, span := startSpan(ctx, transactionsRepo.system, "TransactionsRepo.GetUserTransactions")
+	txs, err := transactionsRepo.transactionsRepo.GetUserTransactions(ctx, filter)
+	tracing.End(span, err)
+	return txs, err
+}
+
+// the span of an export lasts until the last transaction is passed to each
+
+func (transactionsRepo *TracedTransactionsRepo) ExportUserTransactions(ctx context.Context, filter *models.TransactionsFilter,
+	each func(tx *models.Transaction) error) error {
+	ctx, span := startSpan(ctx, transactionsRepo.system, "TransactionsRepo.ExportUserTransactions")
+	err := transactionsRepo.transactionsRepo.ExportUserTransactions(ctx, filter, each)
+	tracing.End(span, err)
+	return err
+}
+
+func (transactionsRepo *TracedTransactionsRepo) GetBalancesAsOf(ctx context.Context, user *models.UserId,
+	asOf time.Time) ([]models.Balance, error) {
+	ctx, span := startSpan(ctx, transactionsRepo.system, "TransactionsRepo.GetBalancesAsOf")
+	balances, err := transactionsRepo.transactionsRepo.GetBalancesAsOf(ctx, user, asOf)
+	tracing.End(span, err)
+	return balances, err
+}
+
+func (transactionsRepo *TracedTransactionsRepo) ExpireIdempotencyKeys(ctx context.Context, before time.Time) error {
+	ctx, span := startSpan(ctx, transactionsRepo.system, "TransactionsRepo.ExpireIdempotencyKeys")
+	err := transactionsRepo.transactionsRepo.ExpireIdempotencyKeys(ctx, before)
+	tracing.End(span, err)
+	return err
+}
@@ -0,0 +1,325 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"github.com/google/logger"
+	"github.com/jackc/pgx"
+	"github.com/saskamegaprogrammist/userBalanceService/models"
+	"sort"
+)
+
+type UnitOfWork struct {
+}
+
+type Work struct {
+	ctx         context.Context
+	transaction *pgx.Tx
+}
+
+// runs work in one database transaction limited by the operation timeout, rolling it back if work fails
+// or ctx is done before the commit
+
+func (unitOfWork *UnitOfWork) Do(ctx context.Context, work func(work WorkI) error) error {
+	ctx, cancel := withOperationTimeout(ctx)
+	defer cancel()
+	db := getPool()
+	transaction, err := db.BeginEx(ctx, nil)
+	if err != nil {
+		dbError := fmt.Errorf("Failed to start transaction: %v", err.Error())
+		logger.Errorf(dbError.Error())
+		return dbError
+	}
+
+	err = work(&Work{ctx: ctx, transaction: transaction})
+	if err == nil {
+		err = ctx.Err()
+	}
+	if err != nil {
+		errRollback := transaction.Rollback()
+		if errRollback != nil {
+			logger.Errorf("Failed to rollback: %v", errRollback)
+			return errRollback
+		}
+		return err
+	}
+
+	err = transaction.Commit()
+	if err != nil {
+		dbError := fmt.Errorf("Error commit: %v", err.Error())
+		logger.Errorf(dbError.Error())
+		return dbError
+	}
+	return nil
+}
+
+// locks balance rows in (user_id, currency) order so that concurrent transfers can't deadlock,
+// missing wallets are created with zero balance
+
+func (work *Work) LockBalances(balances ...*models.Balance) error {
+	ordered := make([]*models.Balance, len(balances))
+	copy(ordered, balances)
+	sort.Slice(ordered, func(i, j int) bool {
+		if ordered[i].UserId != ordered[j].UserId {
+			return ordered[i].UserId < ordered[j].UserId
+		}
+		return ordered[i].Currency < ordered[j].Currency
+	})
+
+	for _, balance := range ordered {
+		_, err := work.transaction.ExecEx(work.ctx, `INSERT INTO balance (user_id, currency) VALUES ($1, $2)
+			ON CONFLICT (user_id, currency) DO NOTHING`, nil,
+			balance.UserId, balance.Currency)
+		if err != nil {
+			dbError := fmt.Errorf("Failed to insert user: %v", err.Error())
+			logger.Errorf(dbError.Error())
+			return dbError
+		}
+
+		row := work.transaction.QueryRowEx(work.ctx, `SELECT id, user_id, balance::numeric, (balance - held)::numeric FROM balance
+			WHERE user_id = $1 AND currency = $2 FOR UPDATE`, nil,
+			balance.UserId, balance.Currency)
+		err = row.Scan(&balance.Id, &balance.UserId, &balance.Balance, &balance.Available)
+		if err != nil {
+			dbError := fmt.Errorf("Failed to lock balance: %v", err.Error())
+			logger.Errorf(dbError.Error())
+			return dbError
+		}
+	}
+	return nil
+}
+
+// system accounts aren't locked, their balances are only derived from postings
+
+func (work *Work) GetSystemAccount(account *models.Balance) error {
+	_, err := work.transaction.ExecEx(work.ctx, `INSERT INTO balance (user_id, currency) VALUES ($1, $2)
+		ON CONFLICT (user_id, currency) DO NOTHING`, nil,
+		account.UserId, account.Currency)
+	if err != nil {
+		dbError := fmt.Errorf("Failed to insert account: %v", err.Error())
+		logger.Errorf(dbError.Error())
+		return dbError
+	}
+
+	row := work.transaction.QueryRowEx(work.ctx, `SELECT id FROM balance WHERE user_id = $1 AND currency = $2`, nil,
+		account.UserId, account.Currency)
+	err = row.Scan(&account.Id)
+	if err != nil {
+		dbError := fmt.Errorf("Failed to retrieve account: %v", err.Error())
+		logger.Errorf(dbError.Error())
+		return dbError
+	}
+	return nil
+}
+
+// inserts the transaction with its postings, balances are updated by the postings
+
+func (work *Work) AddTransaction(tx *models.Transaction) error {
+	var total models.Money
+	for _, posting := range tx.Postings {
+		total += posting.Amount
+	}
+	if len(tx.Postings) == 0 || total != 0 {
+		dbError := fmt.Errorf("Postings of transaction are not balanced")
+		logger.Errorf(dbError.Error())
+		return dbError
+	}
+
+	row := work.transaction.QueryRowEx(work.ctx, `INSERT INTO transactions (user_id, user_from_id, currency, operation, sum, balance,
+		balance_from, created, idempotency_key, request_hash, reversed_id, currency_from, sum_from, rate, quote_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NULLIF($9, ''), NULLIF($10, ''), NULLIF($11, 0), NULLIF($12, ''),
+		NULLIF($13::numeric, 0), NULLIF($14::double precision, 0), NULLIF($15, 0)) returning id`, nil,
+		tx.UserId, tx.UserFromId, tx.Currency, tx.OperationType, tx.Sum, tx.Balance, tx.BalanceFrom, tx.Created,
+		tx.IdempotencyKey, tx.RequestHash, tx.ReversedId, tx.CurrencyFrom, tx.SumFrom, tx.Rate, tx.QuoteId)
+	err := row.Scan(&tx.Id)
+	if err != nil {
+		logger.Errorf("Failed to scan row: %v", err)
+		return err
+	}
+
+	for i := range tx.Postings {
+		posting := &tx.Postings[i]
+		posting.TransactionId = tx.Id
+		row = work.transaction.QueryRowEx(work.ctx, `INSERT INTO postings (transaction_id, account_id, currency, amount)
+			VALUES ($1, $2, $3, $4) returning id`, nil,
+			posting.TransactionId, posting.AccountId, posting.Currency, posting.Amount)
+		err = row.Scan(&posting.Id)
+		if err != nil {
+			dbError := fmt.Errorf("Failed to insert posting: %v", err.Error())
+			logger.Errorf(dbError.Error())
+			return dbError
+		}
+	}
+	return nil
+}
+
+const transactionColumns = `id, user_id, user_from_id, currency, operation, sum, balance, balance_from,
+	created, COALESCE(idempotency_key, ''), COALESCE(request_hash, ''), COALESCE(reversed_id, 0),
+	COALESCE(currency_from, ''), COALESCE(sum_from, 0), COALESCE(rate, 0), COALESCE(quote_id, 0), refunded`
+
+func scanTransaction(row *pgx.Row, tx *models.Transaction) error {
+	return row.Scan(&tx.Id, &tx.UserId, &tx.UserFromId, &tx.Currency, &tx.OperationType, &tx.Sum, &tx.Balance, &tx.BalanceFrom,
+		&tx.Created, &tx.IdempotencyKey, &tx.RequestHash, &tx.ReversedId, &tx.CurrencyFrom, &tx.SumFrom, &tx.Rate, &tx.QuoteId,
+		&tx.Refunded)
+}
+
+// caller is the user whose money is spent: user_from_id for transfers, user_id otherwise
+
+func (work *Work) GetTransactionByIdempotencyKey(tx *models.Transaction, callerId int) error {
+	row := work.transaction.QueryRowEx(work.ctx, `SELECT `+transactionColumns+` FROM transactions
+		WHERE (CASE WHEN user_from_id != 0 THEN user_from_id ELSE user_id END) = $1 AND idempotency_key = $2`, nil,
+		callerId, tx.IdempotencyKey)
+	err := scanTransaction(row, tx)
+	if err == pgx.ErrNoRows {
+		return models.Errorf(models.ErrNotFound, "this idempotency key doesn't exist")
+	}
+	if err != nil {
+		dbError := fmt.Errorf("Failed to retrieve transaction: %v", err.Error())
+		logger.Errorf(dbError.Error())
+		return dbError
+	}
+	return nil
+}
+
+// locks the transaction row and loads its postings
+
+func (work *Work) GetTransaction(tx *models.Transaction) error {
+	row := work.transaction.QueryRowEx(work.ctx, `SELECT `+transactionColumns+` FROM transactions WHERE id = $1 FOR UPDATE`, nil, tx.Id)
+	err := scanTransaction(row, tx)
+	if err == pgx.ErrNoRows {
+		return models.Errorf(models.ErrNotFound, "this transaction doesn't exist")
+	}
+	if err != nil {
+		dbError := fmt.Errorf("Failed to retrieve transaction: %v", err.Error())
+		logger.Errorf(dbError.Error())
+		return dbError
+	}
+
+	rows, err := work.transaction.QueryEx(work.ctx, `SELECT id, transaction_id, account_id, currency, amount FROM postings
+		WHERE transaction_id = $1 ORDER BY id`, nil, tx.Id)
+	if err != nil {
+		dbError := fmt.Errorf("Failed to retrieve postings: %v", err.Error())
+		logger.Errorf(dbError.Error())
+		return dbError
+	}
+	defer rows.Close()
+
+	tx.Postings = make([]models.Posting, 0)
+	for rows.Next() {
+		var posting models.Posting
+		err = rows.Scan(&posting.Id, &posting.TransactionId, &posting.AccountId, &posting.Currency, &posting.Amount)
+		if err != nil {
+			dbError := fmt.Errorf("Failed to retrieve posting: %v", err.Error())
+			logger.Errorf(dbError.Error())
+			return dbError
+		}
+		tx.Postings = append(tx.Postings, posting)
+	}
+	if rows.Err() != nil {
+		return rows.Err()
+	}
+	return nil
+}
+
+// adds sum to the refunded total of tx, refunds can't add up to more than the sum of the transaction
+
+func (work *Work) AddRefund(tx *models.Transaction, sum models.Money) error {
+	row := work.transaction.QueryRowEx(work.ctx, `UPDATE transactions SET refunded = refunded + $2 WHERE id = $1
+		RETURNING refunded`, nil, tx.Id, sum)
+	err := row.Scan(&tx.Refunded)
+	if err == pgx.ErrNoRows {
+		return models.Errorf(models.ErrNotFound, "this transaction doesn't exist")
+	}
+	if err != nil {
+		dbError := fmt.Errorf("Failed to refund transaction: %v", err.Error())
+		logger.Errorf(dbError.Error())
+		return dbError
+	}
+	return nil
+}
+
+// reserves hold sum on the wallet, the wallet must be locked
+
+func (work *Work) AddHold(hold *models.Hold) error {
+	row := work.transaction.QueryRowEx(work.ctx, `INSERT INTO holds (user_id, currency, sum, captured, status, created, expires)
+		VALUES ($1, $2, $3, $4, $5, $6, $7) returning id`, nil,
+		hold.UserId, hold.Currency, hold.Sum, hold.Captured, hold.Status, hold.Created, hold.Expires)
+	err := row.Scan(&hold.Id)
+	if err != nil {
+		logger.Errorf("Failed to scan row: %v", err)
+		return err
+	}
+
+	_, err = work.transaction.ExecEx(work.ctx, `UPDATE balance SET held = held + $1 WHERE user_id = $2 AND currency = $3`, nil,
+		hold.Sum, hold.UserId, hold.Currency)
+	if err != nil {
+		dbError := fmt.Errorf("Failed to reserve funds: %v", err.Error())
+		logger.Errorf(dbError.Error())
+		return dbError
+	}
+	return nil
+}
+
+func (work *Work) GetHold(hold *models.Hold) error {
+	row := work.transaction.QueryRowEx(work.ctx, `SELECT id, user_id, currency, sum, captured, status, created, expires FROM holds
+		WHERE id = $1 FOR UPDATE`, nil, hold.Id)
+	err := row.Scan(&hold.Id, &hold.UserId, &hold.Currency, &hold.Sum, &hold.Captured, &hold.Status, &hold.Created, &hold.Expires)
+	if err == pgx.ErrNoRows {
+		return models.Errorf(models.ErrNotFound, "this hold doesn't exist")
+	}
+	if err != nil {
+		dbError := fmt.Errorf("Failed to retrieve hold: %v", err.Error())
+		logger.Errorf(dbError.Error())
+		return dbError
+	}
+	return nil
+}
+
+// stores the final hold status and releases the whole reserved sum
+
+func (work *Work) CloseHold(hold *models.Hold) error {
+	_, err := work.transaction.ExecEx(work.ctx, `UPDATE holds SET status = $1, captured = $2 WHERE id = $3`, nil,
+		hold.Status, hold.Captured, hold.Id)
+	if err != nil {
+		dbError := fmt.Errorf("Failed to update hold: %v", err.Error())
+		logger.Errorf(dbError.Error())
+		return dbError
+	}
+
+	_, err = work.transaction.ExecEx(work.ctx, `UPDATE balance SET held = held - $1 WHERE user_id = $2 AND currency = $3`, nil,
+		hold.Sum, hold.UserId, hold.Currency)
+	if err != nil {
+		dbError := fmt.Errorf("Failed to release funds: %v", err.Error())
+		logger.Errorf(dbError.Error())
+		return dbError
+	}
+	return nil
+}
+
+// locks the quote row, quote.TransactionId is the transfer that already used the quote
+
+func (work *Work) GetQuote(quote *models.Quote) error {
+	row := work.transaction.QueryRowEx(work.ctx, `SELECT id, COALESCE(user_from_id, 0), currency_from, currency, sum_from,
+		sum, rate, rate_id, created, expires FROM quotes WHERE id = $1 FOR UPDATE`, nil, quote.Id)
+	err := row.Scan(&quote.Id, &quote.UserFromId, &quote.CurrencyFrom, &quote.Currency, &quote.SumFrom, &quote.Sum, &quote.Rate, &quote.RateId,
+		&quote.Created, &quote.Expires)
+	if err == pgx.ErrNoRows {
+		return models.Errorf(models.ErrNotFound, "this quote doesn't exist")
+	}
+	if err != nil {
+		dbError := fmt.Errorf("Failed to retrieve quote: %v", err.Error())
+		logger.Errorf(dbError.Error())
+		return dbError
+	}
+	// the transaction that used the quote is looked up once the quote is locked,
+	// a statement snapshot taken before the lock misses a transfer that committed while waiting for it
+	row = work.transaction.QueryRowEx(work.ctx, `SELECT COALESCE((SELECT id FROM transactions WHERE quote_id = $1), 0)`,
+		nil, quote.Id)
+	err = row.Scan(&quote.TransactionId)
+	if err != nil {
+		dbError := fmt.Errorf("Failed to retrieve quote transaction: %v", err.Error())
+		logger.Errorf(dbError.Error())
+		return dbError
+	}
+	return nil
+}
@@ -0,0 +1,23 @@
+package repository
+
+import (
+	"context"
+	"github.com/saskamegaprogrammist/userBalanceService/models"
+)
+
+type UnitOfWorkI interface {
+	Do(ctx context.Context, work func(work WorkI) error) error
+}
+
+type WorkI interface {
+	LockBalances(balances ...*models.Balance) error
+	GetSystemAccount(account *models.Balance) error
+	AddTransaction(tx *models.Transaction) error
+	GetTransactionByIdempotencyKey(tx *models.Transaction, callerId int) error
+	GetTransaction(tx *models.Transaction) error
+	AddRefund(tx *models.Transaction, sum models.Money) error
+	AddHold(hold *models.Hold) error
+	GetHold(hold *models.Hold) error
+	CloseHold(hold *models.Hold) error
+	GetQuote(quote *models.Quote) error
+}
@@ -0,0 +1,384 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"github.com/google/logger"
+	"github.com/saskamegaprogrammist/userBalanceService/models"
+	"github.com/saskamegaprogrammist/userBalanceService/utils"
+	"sort"
+	"time"
+)
+
+type MemoryUnitOfWork struct {
+	store *MemoryStore
+}
+
+// MemoryWork changes the store in place and keeps an undo log to roll the changes back. After a failed
+// change the work is aborted, like a PostgreSQL transaction after a failed statement
+
+type MemoryWork struct {
+	store  *MemoryStore
+	undo   []func()
+	failed error
+}
+
+// runs work holding the store lock, rolling its changes back if work fails or ctx is done before it returns
+
+func (unitOfWork *MemoryUnitOfWork) Do(ctx context.Context, work func(work WorkI) error) error {
+	ctx, cancel := withOperationTimeout(ctx)
+	defer cancel()
+	store := unitOfWork.store
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	memoryWork := &MemoryWork{store: store}
+	err := work(memoryWork)
+	if err == nil {
+		err = memoryWork.failed
+	}
+	if err == nil {
+		err = ctx.Err()
+	}
+	if err != nil {
+		memoryWork.rollback()
+		return err
+	}
+	return nil
+}
+
+func (work *MemoryWork) onRollback(undo func()) {
+	work.undo = append(work.undo, undo)
+}
+
+func (work *MemoryWork) rollback() {
+	for i := len(work.undo) - 1; i >= 0; i-- {
+		work.undo[i]()
+	}
+	work.undo = nil
+}
+
+func (work *MemoryWork) fail(err error) error {
+	logger.Errorf(err.Error())
+	work.failed = err
+	return err
+}
+
+func (work *MemoryWork) lockWallet(userId int, currency string) *memoryWallet {
+	wallet := work.store.wallet(userId, currency)
+	if wallet == nil {
+		wallet = work.store.addWallet(userId, currency)
+		work.onRollback(work.store.removeLastWallet)
+	}
+	return wallet
+}
+
+// missing wallets are created with zero balance, the store is locked as a whole so the order
+// of balances doesn't matter
+
+func (work *MemoryWork) LockBalances(balances ...*models.Balance) error {
+	if work.failed != nil {
+		return work.failed
+	}
+	ordered := make([]*models.Balance, len(balances))
+	copy(ordered, balances)
+	sort.Slice(ordered, func(i, j int) bool {
+		if ordered[i].UserId != ordered[j].UserId {
+			return ordered[i].UserId < ordered[j].UserId
+		}
+		return ordered[i].Currency < ordered[j].Currency
+	})
+
+	for _, balance := range ordered {
+		work.lockWallet(balance.UserId, balance.Currency).read(balance)
+	}
+	return nil
+}
+
+func (work *MemoryWork) GetSystemAccount(account *models.Balance) error {
+	if work.failed != nil {
+		return work.failed
+	}
+	account.Id = work.lockWallet(account.UserId, account.Currency).id
+	return nil
+}
+
+// changes the balance of a user wallet by amount, system account balances are only derived from postings
+
+func (work *MemoryWork) changeBalance(wallet *memoryWallet, amount models.Money) error {
+	if wallet.userId <= 0 {
+		return nil
+	}
+	previous := wallet.balance
+	wallet.balance += amount
+	work.onRollback(func() {
+		wallet.balance = previous
+	})
+	return checkWallet(wallet)
+}
+
+func (work *MemoryWork) changeHeld(wallet *memoryWallet, amount models.Money) error {
+	previous := wallet.held
+	wallet.held += amount
+	work.onRollback(func() {
+		wallet.held = previous
+	})
+	return checkWallet(wallet)
+}
+
+// the constraints of the transactions table
+
+func (work *MemoryWork) checkTransaction(tx *models.Transaction) error {
+	store := work.store
+	if !utils.IsOperationType(tx.OperationType) {
+		return fmt.Errorf("transaction violates check constraint op_types")
+	}
+	if tx.Sum <= 0 {
+		return fmt.Errorf("transaction violates check constraint positive_sum")
+	}
+	if tx.UserFromId != 0 && tx.UserFromId == tx.UserId && (tx.CurrencyFrom == "" || tx.CurrencyFrom == tx.Currency) {
+		return fmt.Errorf("transaction violates check constraint no_self_transfer")
+	}
+	if tx.Balance < 0 || tx.BalanceFrom < 0 {
+		return fmt.Errorf("transaction violates check constraint non_negative_balance")
+	}
+	if store.wallet(tx.UserId, tx.Currency) == nil {
+		return fmt.Errorf("transaction violates foreign key constraint transactions_balance_fkey")
+	}
+	if tx.ReversedId != 0 && store.transaction(tx.ReversedId) == nil {
+		return fmt.Errorf("transaction violates foreign key constraint: transaction %d doesn't exist", tx.ReversedId)
+	}
+	if tx.QuoteId != 0 && findById(len(store.quotes), func(i int) int { return store.quotes[i].Id }, tx.QuoteId) < 0 {
+		return fmt.Errorf("transaction violates foreign key constraint: quote %d doesn't exist", tx.QuoteId)
+	}
+	for _, stored := range store.transactions {
+		switch {
+		case tx.IdempotencyKey != "" && stored.IdempotencyKey == tx.IdempotencyKey &&
+			idempotencyCaller(stored) == idempotencyCaller(tx):
+			return fmt.Errorf("transaction violates unique constraint transactions_idempotency_key")
+		case tx.QuoteId != 0 && stored.QuoteId == tx.QuoteId:
+			return fmt.Errorf("transaction violates unique constraint transactions_quote_id")
+		}
+	}
+	return nil
+}
+
+// inserts the transaction with its postings, balances are updated by the postings
+
+func (work *MemoryWork) AddTransaction(tx *models.Transaction) error {
+	if work.failed != nil {
+		return work.failed
+	}
+	var total models.Money
+	totals := make(map[string]models.Money)
+	for _, posting := range tx.Postings {
+		total += posting.Amount
+		totals[posting.Currency] += posting.Amount
+	}
+	if len(tx.Postings) == 0 || total != 0 {
+		dbError := fmt.Errorf("Postings of transaction are not balanced")
+		logger.Errorf(dbError.Error())
+		return dbError
+	}
+
+	store := work.store
+	err := work.checkTransaction(tx)
+	if err != nil {
+		return work.fail(err)
+	}
+	tx.Id = store.nextId("transactions")
+	stored := *tx
+	stored.Created = stored.Created.Round(time.Microsecond)
+	stored.Postings = nil
+	store.transactions = append(store.transactions, &stored)
+	work.onRollback(func() {
+		store.transactions = store.transactions[:len(store.transactions)-1]
+	})
+
+	for currency, currencyTotal := range totals {
+		if currencyTotal != 0 {
+			return work.fail(fmt.Errorf("postings of transaction %d in %s are not balanced", tx.Id, currency))
+		}
+	}
+	for i := range tx.Postings {
+		posting := &tx.Postings[i]
+		wallet := store.walletById(posting.AccountId)
+		if wallet == nil {
+			return work.fail(fmt.Errorf("Failed to insert posting: account %d doesn't exist", posting.AccountId))
+		}
+		if posting.Amount == 0 {
+			return work.fail(fmt.Errorf("Failed to insert posting: posting violates check constraint non_zero_amount"))
+		}
+		posting.TransactionId = tx.Id
+		posting.Id = store.nextId("postings")
+		store.postings = append(store.postings, *posting)
+		work.onRollback(func() {
+			store.postings = store.postings[:len(store.postings)-1]
+		})
+		err = work.changeBalance(wallet, posting.Amount)
+		if err != nil {
+			return work.fail(err)
+		}
+	}
+	return nil
+}
+
+// copies the stored transaction into tx, keeping the postings of tx
+
+func readTransaction(stored *models.Transaction, tx *models.Transaction) {
+	postings := tx.Postings
+	*tx = *stored
+	tx.Postings = postings
+}
+
+// caller is the user whose money is spent: user_from_id for transfers, user_id otherwise
+
+func (work *MemoryWork) GetTransactionByIdempotencyKey(tx *models.Transaction, callerId int) error {
+	if work.failed != nil {
+		return work.failed
+	}
+	for _, stored := range work.store.transactions {
+		if stored.IdempotencyKey != "" && stored.IdempotencyKey == tx.IdempotencyKey && idempotencyCaller(stored) == callerId {
+			readTransaction(stored, tx)
+			return nil
+		}
+	}
+	return models.Errorf(models.ErrNotFound, "this idempotency key doesn't exist")
+}
+
+// loads the transaction with its postings
+
+func (work *MemoryWork) GetTransaction(tx *models.Transaction) error {
+	if work.failed != nil {
+		return work.failed
+	}
+	stored := work.store.transaction(tx.Id)
+	if stored == nil {
+		return models.Errorf(models.ErrNotFound, "this transaction doesn't exist")
+	}
+	readTransaction(stored, tx)
+
+	tx.Postings = make([]models.Posting, 0)
+	for _, posting := range work.store.postings {
+		if posting.TransactionId == tx.Id {
+			tx.Postings = append(tx.Postings, posting)
+		}
+	}
+	return nil
+}
+
+// adds sum to the refunded total of tx, refunds can't add up to more than the sum of the transaction
+
+func (work *MemoryWork) AddRefund(tx *models.Transaction, sum models.Money) error {
+	if work.failed != nil {
+		return work.failed
+	}
+	stored := work.store.transaction(tx.Id)
+	if stored == nil {
+		return models.Errorf(models.ErrNotFound, "this transaction doesn't exist")
+	}
+	refunded := stored.Refunded + sum
+	if refunded < 0 || refunded > stored.Sum {
+		return work.fail(fmt.Errorf("Failed to refund transaction: transaction violates check constraint refunded_up_to_sum"))
+	}
+	previous := stored.Refunded
+	stored.Refunded = refunded
+	work.onRollback(func() {
+		stored.Refunded = previous
+	})
+	tx.Refunded = refunded
+	return nil
+}
+
+// reserves hold sum on the wallet, the wallet must be locked
+
+func (work *MemoryWork) AddHold(hold *models.Hold) error {
+	if work.failed != nil {
+		return work.failed
+	}
+	store := work.store
+	if hold.Sum <= 0 {
+		return work.fail(fmt.Errorf("hold violates check constraint positive_hold_sum"))
+	}
+	wallet := store.wallet(hold.UserId, hold.Currency)
+	if wallet == nil {
+		return work.fail(fmt.Errorf("hold violates foreign key constraint: wallet of user %d in %s doesn't exist",
+			hold.UserId, hold.Currency))
+	}
+	hold.Id = store.nextId("holds")
+	stored := *hold
+	stored.Created = stored.Created.Round(time.Microsecond)
+	stored.Expires = stored.Expires.Round(time.Microsecond)
+	store.holds = append(store.holds, &stored)
+	work.onRollback(func() {
+		store.holds = store.holds[:len(store.holds)-1]
+	})
+
+	err := work.changeHeld(wallet, hold.Sum)
+	if err != nil {
+		return work.fail(fmt.Errorf("Failed to reserve funds: %v", err))
+	}
+	return nil
+}
+
+func (work *MemoryWork) GetHold(hold *models.Hold) error {
+	if work.failed != nil {
+		return work.failed
+	}
+	stored := work.store.hold(hold.Id)
+	if stored == nil {
+		return models.Errorf(models.ErrNotFound, "this hold doesn't exist")
+	}
+	*hold = *stored
+	return nil
+}
+
+// stores the final hold status and releases the whole reserved sum
+
+func (work *MemoryWork) CloseHold(hold *models.Hold) error {
+	if work.failed != nil {
+		return work.failed
+	}
+	stored := work.store.hold(hold.Id)
+	if stored != nil {
+		previous := *stored
+		stored.Status = hold.Status
+		stored.Captured = hold.Captured
+		work.onRollback(func() {
+			*stored = previous
+		})
+	}
+
+	wallet := work.store.wallet(hold.UserId, hold.Currency)
+	if wallet == nil {
+		return nil
+	}
+	err := work.changeHeld(wallet, -hold.Sum)
+	if err != nil {
+		return work.fail(fmt.Errorf("Failed to release funds: %v", err))
+	}
+	return nil
+}
+
+// quote.TransactionId is the transfer that already used the quote
+
+func (work *MemoryWork) GetQuote(quote *models.Quote) error {
+	if work.failed != nil {
+		return work.failed
+	}
+	store := work.store
+	i := findById(len(store.quotes), func(i int) int { return store.quotes[i].Id }, quote.Id)
+	if i < 0 {
+		return models.Errorf(models.ErrNotFound, "this quote doesn't exist")
+	}
+	*quote = store.quotes[i]
+	for _, tx := range store.transactions {
+		if tx.QuoteId == quote.Id {
+			quote.TransactionId = tx.Id
+		}
+	}
+	return nil
+}
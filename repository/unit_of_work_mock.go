@@ -0,0 +1,216 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: repository/unit_of_work_interface.go
+
+// Package mock_repository is a generated GoMock package.
+package repository
+
+import (
+	context "context"
+	gomock "github.com/golang/mock/gomock"
+	models "github.com/saskamegaprogrammist/userBalanceService/models"
+	reflect "reflect"
+)
+
+// MockUnitOfWorkI is a mock of UnitOfWorkI interface
+type MockUnitOfWorkI struct {
+	ctrl     *gomock.Controller
+	recorder *MockUnitOfWorkIMockRecorder
+}
+
+// MockUnitOfWorkIMockRecorder is the mock recorder for MockUnitOfWorkI
+type MockUnitOfWorkIMockRecorder struct {
+	mock *MockUnitOfWorkI
+}
+
+// NewMockUnitOfWorkI creates a new mock instance
+func NewMockUnitOfWorkI(ctrl *gomock.Controller) *MockUnitOfWorkI {
+	mock := &MockUnitOfWorkI{ctrl: ctrl}
+	mock.recorder = &MockUnitOfWorkIMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockUnitOfWorkI) EXPECT() *MockUnitOfWorkIMockRecorder {
+	return m.recorder
+}
+
+// Do mocks base method
+func (m *MockUnitOfWorkI) Do(ctx context.Context, work func(WorkI) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Do", ctx, work)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Do indicates an expected call of Do
+func (mr *MockUnitOfWorkIMockRecorder) Do(ctx, work interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Do", reflect.TypeOf((*MockUnitOfWorkI)(nil).Do), ctx, work)
+}
+
+// MockWorkI is a mock of WorkI interface
+type MockWorkI struct {
+	ctrl     *gomock.Controller
+	recorder *MockWorkIMockRecorder
+}
+
+// MockWorkIMockRecorder is the mock recorder for MockWorkI
+type MockWorkIMockRecorder struct {
+	mock *MockWorkI
+}
+
+// NewMockWorkI creates a new mock instance
+func NewMockWorkI(ctrl *gomock.Controller) *MockWorkI {
+	mock := &MockWorkI{ctrl: ctrl}
+	mock.recorder = &MockWorkIMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockWorkI) EXPECT() *MockWorkIMockRecorder {
+	return m.recorder
+}
+
+// LockBalances mocks base method
+func (m *MockWorkI) LockBalances(balances ...*models.Balance) error {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{}
+	for _, a := range balances {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "LockBalances", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// LockBalances indicates an expected call of LockBalances
+func (mr *MockWorkIMockRecorder) LockBalances(balances ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LockBalances", reflect.TypeOf((*MockWorkI)(nil).LockBalances), balances...)
+}
+
+// GetSystemAccount mocks base method
+func (m *MockWorkI) GetSystemAccount(account *models.Balance) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSystemAccount", account)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// GetSystemAccount indicates an expected call of GetSystemAccount
+func (mr *MockWorkIMockRecorder) GetSystemAccount(account interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSystemAccount", reflect.TypeOf((*MockWorkI)(nil).GetSystemAccount), account)
+}
+
+// AddTransaction mocks base method
+func (m *MockWorkI) AddTransaction(tx *models.Transaction) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddTransaction", tx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddTransaction indicates an expected call of AddTransaction
+func (mr *MockWorkIMockRecorder) AddTransaction(tx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddTransaction", reflect.TypeOf((*MockWorkI)(nil).AddTransaction), tx)
+}
+
+// GetTransactionByIdempotencyKey mocks base method
+func (m *MockWorkI) GetTransactionByIdempotencyKey(tx *models.Transaction, callerId int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTransactionByIdempotencyKey", tx, callerId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// GetTransactionByIdempotencyKey indicates an expected call of GetTransactionByIdempotencyKey
+func (mr *MockWorkIMockRecorder) GetTransactionByIdempotencyKey(tx, callerId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTransactionByIdempotencyKey", reflect.TypeOf((*MockWorkI)(nil).GetTransactionByIdempotencyKey), tx, callerId)
+}
+
+// GetTransaction mocks base method
+func (m *MockWorkI) GetTransaction(tx *models.Transaction) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTransaction", tx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// GetTransaction indicates an expected call of GetTransaction
+func (mr *MockWorkIMockRecorder) GetTransaction(tx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTransaction", reflect.TypeOf((*MockWorkI)(nil).GetTransaction), tx)
+}
+
+// AddRefund mocks base method
+func (m *MockWorkI) AddRefund(tx *models.Transaction, sum models.Money) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddRefund", tx, sum)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddRefund indicates an expected call of AddRefund
+func (mr *MockWorkIMockRecorder) AddRefund(tx, sum interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddRefund", reflect.TypeOf((*MockWorkI)(nil).AddRefund), tx, sum)
+}
+
+// AddHold mocks base method
+func (m *MockWorkI) AddHold(hold *models.Hold) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddHold", hold)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddHold indicates an expected call of AddHold
+func (mr *MockWorkIMockRecorder) AddHold(hold interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddHold", reflect.TypeOf((*MockWorkI)(nil).AddHold), hold)
+}
+
+// GetHold mocks base method
+func (m *MockWorkI) GetHold(hold *models.Hold) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetHold", hold)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// GetHold indicates an expected call of GetHold
+func (mr *MockWorkIMockRecorder) GetHold(hold interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetHold", reflect.TypeOf((*MockWorkI)(nil).GetHold), hold)
+}
+
+// CloseHold mocks base method
+func (m *MockWorkI) CloseHold(hold *models.Hold) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CloseHold", hold)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CloseHold indicates an expected call of CloseHold
+func (mr *MockWorkIMockRecorder) CloseHold(hold interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CloseHold", reflect.TypeOf((*MockWorkI)(nil).CloseHold), hold)
+}
+
+// GetQuote mocks base method
+func (m *MockWorkI) GetQuote(quote *models.Quote) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetQuote", quote)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// GetQuote indicates an expected call of GetQuote
+func (mr *MockWorkIMockRecorder) GetQuote(quote interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetQuote", reflect.TypeOf((*MockWorkI)(nil).GetQuote), quote)
+}
@@ -0,0 +1,317 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/google/logger"
+	"github.com/saskamegaprogrammist/userBalanceService/models"
+)
+
+type SQLiteUnitOfWork struct {
+}
+
+// SQLite transactions take the write lock when they begin, so rows need no FOR UPDATE. A failed
+// statement doesn't abort a SQLite transaction, the work is aborted after it like in PostgreSQL
+
+type SQLiteWork struct {
+	ctx         context.Context
+	transaction *sql.Tx
+	failed      error
+}
+
+// runs work in one database transaction limited by the operation timeout, rolling it back if work fails
+// or ctx is done before the commit
+
+func (unitOfWork *SQLiteUnitOfWork) Do(ctx context.Context, work func(work WorkI) error) error {
+	ctx, cancel := withOperationTimeout(ctx)
+	defer cancel()
+	db := getSQLite()
+	transaction, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		dbError := fmt.Errorf("Failed to start transaction: %v", err.Error())
+		logger.Errorf(dbError.Error())
+		return dbError
+	}
+
+	sqliteWork := &SQLiteWork{ctx: ctx, transaction: transaction}
+	err = work(sqliteWork)
+	if err == nil {
+		err = sqliteWork.failed
+	}
+	if err == nil {
+		err = ctx.Err()
+	}
+	if err != nil {
+		errRollback := transaction.Rollback()
+		if errRollback != nil && errRollback != sql.ErrTxDone {
+			logger.Errorf("Failed to rollback: %v", errRollback)
+			return errRollback
+		}
+		return err
+	}
+
+	err = transaction.Commit()
+	if err != nil {
+		dbError := fmt.Errorf("Error commit: %v", err.Error())
+		logger.Errorf(dbError.Error())
+		return dbError
+	}
+	return nil
+}
+
+func (work *SQLiteWork) fail(err error) error {
+	logger.Errorf(err.Error())
+	work.failed = err
+	return err
+}
+
+func (work *SQLiteWork) ensureBalance(userId int, currency string) error {
+	_, err := work.transaction.ExecContext(work.ctx, `INSERT INTO balance (user_id, currency) VALUES (?, ?)
+		ON CONFLICT (user_id, currency) DO NOTHING`, userId, currency)
+	return err
+}
+
+// missing wallets are created with zero balance, the transaction already holds the write lock
+// so the order of balances doesn't matter
+
+func (work *SQLiteWork) LockBalances(balances ...*models.Balance) error {
+	if work.failed != nil {
+		return work.failed
+	}
+	for _, balance := range balances {
+		err := work.ensureBalance(balance.UserId, balance.Currency)
+		if err != nil {
+			return work.fail(fmt.Errorf("Failed to insert user: %v", err.Error()))
+		}
+
+		row := work.transaction.QueryRowContext(work.ctx, `SELECT id, user_id, balance, balance - held FROM balance
+			WHERE user_id = ? AND currency = ?`, balance.UserId, balance.Currency)
+		err = row.Scan(&balance.Id, &balance.UserId, sqliteMoney{&balance.Balance}, sqliteMoney{&balance.Available})
+		if err != nil {
+			return work.fail(fmt.Errorf("Failed to lock balance: %v", err.Error()))
+		}
+	}
+	return nil
+}
+
+// system account balances are only derived from postings
+
+func (work *SQLiteWork) GetSystemAccount(account *models.Balance) error {
+	if work.failed != nil {
+		return work.failed
+	}
+	err := work.ensureBalance(account.UserId, account.Currency)
+	if err != nil {
+		return work.fail(fmt.Errorf("Failed to insert account: %v", err.Error()))
+	}
+
+	row := work.transaction.QueryRowContext(work.ctx, `SELECT id FROM balance WHERE user_id = ? AND currency = ?`,
+		account.UserId, account.Currency)
+	err = row.Scan(&account.Id)
+	if err != nil {
+		return work.fail(fmt.Errorf("Failed to retrieve account: %v", err.Error()))
+	}
+	return nil
+}
+
+// inserts the transaction with its postings, balances are updated by the apply_posting trigger. The
+// postings of every currency must be balanced, which PostgreSQL checks with a deferred trigger
+
+func (work *SQLiteWork) AddTransaction(tx *models.Transaction) error {
+	if work.failed != nil {
+		return work.failed
+	}
+	var total models.Money
+	totals := make(map[string]models.Money)
+	for _, posting := range tx.Postings {
+		total += posting.Amount
+		totals[posting.Currency] += posting.Amount
+	}
+	if len(tx.Postings) == 0 || total != 0 {
+		dbError := fmt.Errorf("Postings of transaction are not balanced")
+		logger.Errorf(dbError.Error())
+		return dbError
+	}
+	for currency, currencyTotal := range totals {
+		if currencyTotal != 0 {
+			return work.fail(fmt.Errorf("postings of transaction in %s are not balanced", currency))
+		}
+	}
+
+	row := work.transaction.QueryRowContext(work.ctx, `INSERT INTO transactions (user_id, user_from_id, currency, operation, sum,
+		balance, balance_from, created, idempotency_key, request_hash, reversed_id, currency_from, sum_from, rate, quote_id)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, NULLIF(?, ''), NULLIF(?, ''), NULLIF(?, 0), NULLIF(?, ''), NULLIF(?, 0),
+		NULLIF(?, 0), NULLIF(?, 0)) RETURNING id`,
+		sqliteArgs(tx.UserId, tx.UserFromId, tx.Currency, tx.OperationType, tx.Sum, tx.Balance, tx.BalanceFrom, tx.Created,
+			tx.IdempotencyKey, tx.RequestHash, tx.ReversedId, tx.CurrencyFrom, tx.SumFrom, tx.Rate, tx.QuoteId)...)
+	err := row.Scan(&tx.Id)
+	if err != nil {
+		return work.fail(fmt.Errorf("Failed to scan row: %v", err))
+	}
+
+	for i := range tx.Postings {
+		posting := &tx.Postings[i]
+		posting.TransactionId = tx.Id
+		row = work.transaction.QueryRowContext(work.ctx, `INSERT INTO postings (transaction_id, account_id, currency, amount)
+			VALUES (?, ?, ?, ?) RETURNING id`,
+			sqliteArgs(posting.TransactionId, posting.AccountId, posting.Currency, posting.Amount)...)
+		err = row.Scan(&posting.Id)
+		if err != nil {
+			return work.fail(fmt.Errorf("Failed to insert posting: %v", err.Error()))
+		}
+	}
+	return nil
+}
+
+// caller is the user whose money is spent: user_from_id for transfers, user_id otherwise
+
+func (work *SQLiteWork) GetTransactionByIdempotencyKey(tx *models.Transaction, callerId int) error {
+	if work.failed != nil {
+		return work.failed
+	}
+	row := work.transaction.QueryRowContext(work.ctx, `SELECT `+sqliteTransactionColumns+` FROM transactions
+		WHERE (CASE WHEN user_from_id != 0 THEN user_from_id ELSE user_id END) = ? AND idempotency_key = ?`,
+		callerId, tx.IdempotencyKey)
+	err := scanSQLiteTransaction(row, tx)
+	if err == sql.ErrNoRows {
+		return models.Errorf(models.ErrNotFound, "this idempotency key doesn't exist")
+	}
+	if err != nil {
+		return work.fail(fmt.Errorf("Failed to retrieve transaction: %v", err.Error()))
+	}
+	return nil
+}
+
+// loads the transaction with its postings
+
+func (work *SQLiteWork) GetTransaction(tx *models.Transaction) error {
+	if work.failed != nil {
+		return work.failed
+	}
+	row := work.transaction.QueryRowContext(work.ctx, `SELECT `+sqliteTransactionColumns+` FROM transactions WHERE id = ?`, tx.Id)
+	err := scanSQLiteTransaction(row, tx)
+	if err == sql.ErrNoRows {
+		return models.Errorf(models.ErrNotFound, "this transaction doesn't exist")
+	}
+	if err != nil {
+		return work.fail(fmt.Errorf("Failed to retrieve transaction: %v", err.Error()))
+	}
+
+	rows, err := work.transaction.QueryContext(work.ctx, `SELECT id, transaction_id, account_id, currency, amount FROM postings
+		WHERE transaction_id = ? ORDER BY id`, tx.Id)
+	if err != nil {
+		return work.fail(fmt.Errorf("Failed to retrieve postings: %v", err.Error()))
+	}
+	defer rows.Close()
+
+	tx.Postings = make([]models.Posting, 0)
+	for rows.Next() {
+		var posting models.Posting
+		err = rows.Scan(&posting.Id, &posting.TransactionId, &posting.AccountId, &posting.Currency, sqliteMoney{&posting.Amount})
+		if err != nil {
+			return work.fail(fmt.Errorf("Failed to retrieve posting: %v", err.Error()))
+		}
+		tx.Postings = append(tx.Postings, posting)
+	}
+	if rows.Err() != nil {
+		return work.fail(rows.Err())
+	}
+	return nil
+}
+
+// adds sum to the refunded total of tx, refunds can't add up to more than the sum of the transaction
+
+func (work *SQLiteWork) AddRefund(tx *models.Transaction, sum models.Money) error {
+	if work.failed != nil {
+		return work.failed
+	}
+	row := work.transaction.QueryRowContext(work.ctx, `UPDATE transactions SET refunded = refunded + ? WHERE id = ?
+		RETURNING refunded`, sqliteArgs(sum, tx.Id)...)
+	err := row.Scan(sqliteMoney{&tx.Refunded})
+	if err == sql.ErrNoRows {
+		return models.Errorf(models.ErrNotFound, "this transaction doesn't exist")
+	}
+	if err != nil {
+		return work.fail(fmt.Errorf("Failed to refund transaction: %v", err.Error()))
+	}
+	return nil
+}
+
+// reserves hold sum on the wallet, the wallet must be locked
+
+func (work *SQLiteWork) AddHold(hold *models.Hold) error {
+	if work.failed != nil {
+		return work.failed
+	}
+	row := work.transaction.QueryRowContext(work.ctx, `INSERT INTO holds (user_id, currency, sum, captured, status, created, expires)
+		VALUES (?, ?, ?, ?, ?, ?, ?) RETURNING id`,
+		sqliteArgs(hold.UserId, hold.Currency, hold.Sum, hold.Captured, hold.Status, hold.Created, hold.Expires)...)
+	err := row.Scan(&hold.Id)
+	if err != nil {
+		return work.fail(fmt.Errorf("Failed to scan row: %v", err))
+	}
+
+	_, err = work.transaction.ExecContext(work.ctx, `UPDATE balance SET held = held + ? WHERE user_id = ? AND currency = ?`,
+		sqliteArgs(hold.Sum, hold.UserId, hold.Currency)...)
+	if err != nil {
+		return work.fail(fmt.Errorf("Failed to reserve funds: %v", err.Error()))
+	}
+	return nil
+}
+
+func (work *SQLiteWork) GetHold(hold *models.Hold) error {
+	if work.failed != nil {
+		return work.failed
+	}
+	row := work.transaction.QueryRowContext(work.ctx, `SELECT id, user_id, currency, sum, captured, status, created, expires
+		FROM holds WHERE id = ?`, hold.Id)
+	err := scanSQLiteHold(row, hold)
+	if err == sql.ErrNoRows {
+		return models.Errorf(models.ErrNotFound, "this hold doesn't exist")
+	}
+	if err != nil {
+		return work.fail(fmt.Errorf("Failed to retrieve hold: %v", err.Error()))
+	}
+	return nil
+}
+
+// stores the final hold status and releases the whole reserved sum
+
+func (work *SQLiteWork) CloseHold(hold *models.Hold) error {
+	if work.failed != nil {
+		return work.failed
+	}
+	_, err := work.transaction.ExecContext(work.ctx, `UPDATE holds SET status = ?, captured = ? WHERE id = ?`,
+		sqliteArgs(hold.Status, hold.Captured, hold.Id)...)
+	if err != nil {
+		return work.fail(fmt.Errorf("Failed to update hold: %v", err.Error()))
+	}
+
+	_, err = work.transaction.ExecContext(work.ctx, `UPDATE balance SET held = held - ? WHERE user_id = ? AND currency = ?`,
+		sqliteArgs(hold.Sum, hold.UserId, hold.Currency)...)
+	if err != nil {
+		return work.fail(fmt.Errorf("Failed to release funds: %v", err.Error()))
+	}
+	return nil
+}
+
+// quote.TransactionId is the transfer that already used the quote
+
+func (work *SQLiteWork) GetQuote(quote *models.Quote) error {
+	if work.failed != nil {
+		return work.failed
+	}
+	row := work.transaction.QueryRowContext(work.ctx, `SELECT id, COALESCE(user_from_id, 0), currency_from, currency,
+		sum_from, sum, rate, rate_id, COALESCE((SELECT transactions.id FROM transactions WHERE transactions.quote_id = quotes.id), 0),
+		created, expires FROM quotes WHERE id = ?`, quote.Id)
+	err := row.Scan(&quote.Id, &quote.UserFromId, &quote.CurrencyFrom, &quote.Currency, sqliteMoney{&quote.SumFrom}, sqliteMoney{&quote.Sum},
+		&quote.Rate, &quote.RateId, &quote.TransactionId, sqliteTime{&quote.Created}, sqliteTime{&quote.Expires})
+	if err == sql.ErrNoRows {
+		return models.Errorf(models.ErrNotFound, "this quote doesn't exist")
+	}
+	if err != nil {
+		return work.fail(fmt.Errorf("Failed to retrieve quote: %v", err.Error()))
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"context"
+	"github.com/saskamegaprogrammist/userBalanceService/models"
+	"github.com/saskamegaprogrammist/userBalanceService/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// the traced repositories wrap the repositories of a backend and run every call in a span
+// named by the repository and the method, like BalanceRepo.InsertUser
+
+func startSpan(ctx context.Context, system attribute.KeyValue, name string) (context.Context, trace.Span) {
+	return tracing.Start(ctx, name, system, semconv.DBOperation(name))
+}
+
+type TracedUnitOfWork struct {
+	unitOfWork UnitOfWorkI
+	system     attribute.KeyValue
+}
+
+// TracedWork runs the calls of a unit of work in spans that are children of the span of Do
+
+type TracedWork struct {
+	ctx    context.Context
+	work   WorkI
+	system attribute.KeyValue
+}
+
+func (unitOfWork *TracedUnitOfWork) Do(ctx context.Context, work func(work WorkI) error) error {
+	ctx, span := startSpan(ctx, unitOfWork.system, "UnitOfWork.Do")
+	err := unitOfWork.unitOfWork.Do(ctx, func(inner WorkI) error {
+		return work(&TracedWork{ctx: ctx, work: inner, system: unitOfWork.system})
+	})
+	tracing.End(span, err)
+	return err
+}
+
+func (work *TracedWork) LockBalances(balances ...*models.Balance) error {
+	_, span := startSpan(work.ctx, work.system, "Work.LockBalances")
+	err := work.work.LockBalances(balances...)
+	tracing.End(span, err)
+	return err
+}
+
+func (work *TracedWork) GetSystemAccount(account *models.Balance) error {
+	_, span := startSpan(work.ctx, work.system, "Work.GetSystemAccount")
+	err := work.work.GetSystemAccount(account)
+	tracing.End(span, err)
+	return err
+}
+
+// the span of AddTransaction includes the postings and the balance updates they make
+
+func (work *TracedWork) AddTransaction(tx *models.Transaction) error {
+	_, span := startSpan(work.ctx, work.system, "Work.AddTransaction")
+	span.SetAttributes(attribute.Int("balance.postings", len(tx.Postings)))
+	err := work.work.AddTransaction(tx)
+	tracing.End(span, err)
+	return err
+}
+
+func (work *TracedWork) GetTransactionByIdempotencyKey(tx *models.Transaction, callerId int) error {
+	_, span := startSpan(work.ctx, work.system, "Work.GetTransactionByIdempotencyKey")
+	err := work.work.GetTransactionByIdempotencyKey(tx, callerId)
+	tracing.End(span, err)
+	return err
+}
+
+func (work *TracedWork) GetTransaction(tx *models.Transaction) error {
+	_, span := startSpan(work.ctx, work.system, "Work.GetTransaction")
+	err := work.work.GetTransaction(tx)
+	tracing.End(span, err)
+	return err
+}
+
+func (work *TracedWork) AddRefund(tx *models.Transaction, sum models.Money) error {
+	_, span := startSpan(work.ctx, work.system, "Work.AddRefund")
+	err := work.work.AddRefund(tx, sum)
+	tracing.End(span, err)
+	return err
+}
+
+func (work *TracedWork) AddHold(hold *models.Hold) error {
+	_, span := startSpan(work.ctx, work.system, "Work.AddHold")
+	err := work.work.AddHold(hold)
+	tracing.End(span, err)
+	return err
+}
+
+func (work *TracedWork) GetHold(hold *models.Hold) error {
+	_, span := startSpan(work.ctx, work.system, "Work.GetHold")
+	err := work.work.GetHold(hold)
+	tracing.End(span, err)
+	return err
+}
+
+func (work *TracedWork) CloseHold(hold *models.Hold) error {
+	_, span := startSpan(work.ctx, work.system, "Work.CloseHold")
+	err := work.work.CloseHold(hold)
+	tracing.End(span, err)
+	return err
+}
+
+func (work *TracedWork) GetQuote(quote *models.Quote) error {
+	_, span := startSpan(work.ctx, work.system, "Work.GetQuote")
+	err := work.work.GetQuote(quote)
+	tracing.End(span, err)
+	return err
+}
@@ -0,0 +1,40 @@
+package tracing
+
+import (
+	"github.com/gorilla/mux"
+	"github.com/saskamegaprogrammist/userBalanceService/utils"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+	"net/http"
+)
+
+// Middleware is a mux middleware that serves every request in a server span named by the route, continuing
+// the trace of the traceparent header of the request
+
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(req.Context(), propagation.HeaderCarrier(req.Header))
+		route := req.URL.Path
+		if current := mux.CurrentRoute(req); current != nil {
+			template, err := current.GetPathTemplate()
+			if err == nil {
+				route = template
+			}
+		}
+
+		ctx, span := otel.Tracer(instrumentationName).Start(ctx, req.Method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(semconv.HTTPMethod(req.Method), semconv.HTTPRoute(route), semconv.HTTPTarget(req.URL.Path)))
+		defer span.End()
+
+		recorder := &utils.StatusRecorder{ResponseWriter: writer}
+		next.ServeHTTP(recorder, req.WithContext(ctx))
+		span.SetAttributes(semconv.HTTPStatusCode(recorder.Status()))
+		if recorder.Status() >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(recorder.Status()))
+		}
+	})
+}
@@ -0,0 +1,85 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	collectortrace "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/protobuf/proto"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// a collector that keeps the OTLP/HTTP requests it receives
+
+func newTestCollector(t *testing.T, status int) (*httptest.Server, *[]*collectortrace.ExportTraceServiceRequest) {
+	received := make([]*collectortrace.ExportTraceServiceRequest, 0)
+	collector := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, "/v1/traces", req.URL.Path)
+		assert.Equal(t, "application/x-protobuf", req.Header.Get("Content-Type"))
+		body, err := io.ReadAll(req.Body)
+		assert.NoError(t, err)
+		var traces collectortrace.ExportTraceServiceRequest
+		assert.NoError(t, proto.Unmarshal(body, &traces))
+		received = append(received, &traces)
+		writer.WriteHeader(status)
+	}))
+	t.Cleanup(collector.Close)
+	return collector, &received
+}
+
+func TestOTLPExporter(t *testing.T) {
+	previous := otel.GetTracerProvider()
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	t.Run("Export", func(t *testing.T) {
+		collector, received := newTestCollector(t, http.StatusOK)
+
+		shutdown, err := Init(Config{Exporter: ExporterOTLP, Endpoint: collector.URL + "/", ServiceName: "test", SampleRatio: 1})
+		assert.NoError(t, err)
+		ctx, parent := Start(context.Background(), "POST /funds/add")
+		_, child := Start(ctx, "FundsUC.Add", attribute.Int("user.id", 7))
+		End(child, errors.New("insufficient funds"))
+		End(parent, nil)
+		assert.NoError(t, shutdown(context.Background()))
+
+		assert.Len(t, *received, 1)
+		resourceSpans := (*received)[0].ResourceSpans
+		assert.Len(t, resourceSpans, 1)
+		assert.Equal(t, "service.name", resourceSpans[0].Resource.Attributes[0].Key)
+		assert.Equal(t, "test", resourceSpans[0].Resource.Attributes[0].Value.GetStringValue())
+		assert.Equal(t, instrumentationName, resourceSpans[0].ScopeSpans[0].Scope.Name)
+		spans := resourceSpans[0].ScopeSpans[0].Spans
+		assert.Len(t, spans, 2)
+
+		exported := spans[0]
+		assert.Equal(t, "FundsUC.Add", exported.Name)
+		traceId := child.SpanContext().TraceID()
+		parentId := parent.SpanContext().SpanID()
+		assert.Equal(t, traceId[:], exported.TraceId)
+		assert.Equal(t, parentId[:], exported.ParentSpanId)
+		assert.Equal(t, "insufficient funds", exported.Status.Message)
+		assert.Equal(t, int64(7), exported.Attributes[0].Value.GetIntValue())
+		assert.Empty(t, spans[1].ParentSpanId)
+	})
+
+	t.Run("CollectorError", func(t *testing.T) {
+		collector, _ := newTestCollector(t, http.StatusBadRequest)
+		recorder := tracetest.NewSpanRecorder()
+		tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+		_, span := tracer.Start(context.Background(), "FundsUC.Add")
+		span.End()
+
+		exporter, err := newOTLPExporter(collector.URL)
+		assert.NoError(t, err)
+		err = exporter.ExportSpans(context.Background(), recorder.Ended())
+
+		assert.Error(t, err)
+	})
+}
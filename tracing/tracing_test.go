@@ -0,0 +1,118 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+// records the spans of the test in memory
+
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return recorder
+}
+
+func TestMiddleware(t *testing.T) {
+	router := mux.NewRouter()
+	router.HandleFunc("/funds/hold/{id}/capture", func(writer http.ResponseWriter, req *http.Request) {
+		_, span := Start(req.Context(), "HoldsUC.Capture")
+		End(span, errors.New("hold is already closed"))
+		writer.WriteHeader(http.StatusConflict)
+	}).Methods("POST")
+	router.HandleFunc("/funds/get", func(writer http.ResponseWriter, req *http.Request) {
+		writer.WriteHeader(http.StatusInternalServerError)
+	}).Methods("POST")
+	router.Use(Middleware)
+
+	t.Run("Traceparent", func(t *testing.T) {
+		recorder := recordSpans(t)
+		req := httptest.NewRequest("POST", "/funds/hold/7/capture", nil)
+		req.Header.Set("traceparent", traceparent)
+
+		router.ServeHTTP(httptest.NewRecorder(), req)
+
+		spans := recorder.Ended()
+		assert.Equal(t, 2, len(spans))
+		useCase, server := spans[0], spans[1]
+		assert.Equal(t, "POST /funds/hold/{id}/capture", server.Name())
+		assert.Equal(t, trace.SpanKindServer, server.SpanKind())
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", server.SpanContext().TraceID().String())
+		assert.Equal(t, "00f067aa0ba902b7", server.Parent().SpanID().String())
+		assert.Contains(t, server.Attributes(), semconv.HTTPStatusCode(http.StatusConflict))
+		assert.Equal(t, codes.Unset, server.Status().Code)
+		assert.Equal(t, server.SpanContext().SpanID(), useCase.Parent().SpanID())
+		assert.Equal(t, codes.Error, useCase.Status().Code)
+	})
+
+	t.Run("ServerError", func(t *testing.T) {
+		recorder := recordSpans(t)
+
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/funds/get", nil))
+
+		spans := recorder.Ended()
+		assert.Equal(t, 1, len(spans))
+		assert.False(t, spans[0].Parent().IsValid())
+		assert.Equal(t, codes.Error, spans[0].Status().Code)
+	})
+}
+
+func TestInit(t *testing.T) {
+	previous := otel.GetTracerProvider()
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	t.Run("File", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "tracing")
+		assert.NoError(t, err)
+		defer os.RemoveAll(dir)
+		path := filepath.Join(dir, "spans.json")
+
+		shutdown, err := Init(Config{Exporter: ExporterFile, File: path, ServiceName: "test", SampleRatio: 1})
+		assert.NoError(t, err)
+		_, span := Start(context.Background(), "FundsUC.Add")
+		End(span, nil)
+		assert.NoError(t, shutdown(context.Background()))
+
+		data, err := ioutil.ReadFile(path)
+		assert.NoError(t, err)
+		assert.True(t, strings.Contains(string(data), `"Name":"FundsUC.Add"`))
+	})
+
+	t.Run("NotSampled", func(t *testing.T) {
+		shutdown, err := Init(Config{Exporter: ExporterStdout, ServiceName: "test", SampleRatio: 0})
+		assert.NoError(t, err)
+		defer shutdown(context.Background())
+
+		_, span := Start(context.Background(), "FundsUC.Add")
+		End(span, nil)
+
+		assert.False(t, span.SpanContext().IsSampled())
+	})
+
+	t.Run("UnknownExporter", func(t *testing.T) {
+		_, err := Init(Config{Exporter: "jaeger"})
+
+		assert.Error(t, err)
+	})
+}
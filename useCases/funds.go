@@ -1,6 +1,8 @@
 package useCases
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"github.com/saskamegaprogrammist/userBalanceService/models"
 	"github.com/saskamegaprogrammist/userBalanceService/repository"
@@ -11,162 +13,430 @@ import (
 type FundsUC struct {
 	BalanceRepo      repository.BalanceRepoI
 	TransactionsRepo repository.TransactionsRepoI
+	UnitOfWork       repository.UnitOfWorkI
+
+	IdempotencyRetention time.Duration
 }
 
-func (fundsUC *FundsUC) Add(tx *models.Transaction) (bool, error) {
-	if tx.UserId == utils.ERROR_ID {
-		return true, fmt.Errorf("incorrect user id")
+func (fundsUC *FundsUC) Add(ctx context.Context, tx *models.Transaction) error {
+	if tx.UserId <= utils.ERROR_ID {
+		return models.ErrInvalidUser
+	}
+	if tx.Sum <= 0 {
+		return models.Errorf(models.ErrValidation, "sum must be positive")
 	}
-	var newBalance models.Balance
-	newBalance.UserId = tx.UserId
-	errType, err := fundsUC.BalanceRepo.GetBalanceByUserId(&newBalance)
+	dropQuote(tx)
+	tx.ReversedId = utils.ERROR_ID
+	err := normalizeWalletCurrency(&tx.Currency, tx.Sum)
 	if err != nil {
-		if errType == utils.USER_ERROR {
-			err := fundsUC.BalanceRepo.InsertUser(&newBalance)
-			if err != nil {
-				return false, err
-			}
-		} else if errType == utils.SERVER_ERROR {
-			return false, err
-		}
+		return err
 	}
 
+	var replayed bool
+	err = fundsUC.UnitOfWork.Do(ctx, func(work repository.WorkI) error {
+		newBalance := models.Balance{UserId: tx.UserId, Currency: tx.Currency}
+		err := work.LockBalances(&newBalance)
+		if err != nil {
+			return err
+		}
+
+		tx.OperationType = utils.GetOperationType("Add")
+		replayed, err = replayTransaction(work, tx, tx.UserId)
+		if err != nil || replayed {
+			return err
+		}
+
+		tx.Balance = newBalance.Balance + tx.Sum
+		if tx.Balance < newBalance.Balance {
+			return models.Errorf(models.ErrValidation, "balance is too large")
+		}
+		cashIn, err := systemAccount(work, utils.CASH_IN_ACCOUNT, tx.Currency)
+		if err != nil {
+			return err
+		}
+		tx.Postings = postings(&newBalance, &cashIn, tx.Sum)
+		tx.Created = time.Now()
+
+		return work.AddTransaction(tx)
+	})
+	observeOperation("Add", tx, replayed, err)
+	return err
+}
+
+func (fundsUC *FundsUC) Withdraw(ctx context.Context, tx *models.Transaction) error {
+	if tx.UserId <= utils.ERROR_ID {
+		return models.ErrInvalidUser
+	}
 	if tx.Sum <= 0 {
-		return true, fmt.Errorf("sum must be positive")
+		return models.Errorf(models.ErrValidation, "sum must be positive")
+	}
+	dropQuote(tx)
+	tx.ReversedId = utils.ERROR_ID
+	err := normalizeWalletCurrency(&tx.Currency, tx.Sum)
+	if err != nil {
+		return err
 	}
 
-	tx.Balance = newBalance.Balance + tx.Sum
-	tx.OperationType = utils.GetOperationType("Add")
-	tx.Created = time.Now()
+	var replayed bool
+	err = fundsUC.UnitOfWork.Do(ctx, func(work repository.WorkI) error {
+		newBalance := models.Balance{UserId: tx.UserId, Currency: tx.Currency}
+		err := work.LockBalances(&newBalance)
+		if err != nil {
+			return err
+		}
+
+		tx.OperationType = utils.GetOperationType("Withdraw")
+		replayed, err = replayTransaction(work, tx, tx.UserId)
+		if err != nil || replayed {
+			return err
+		}
+
+		tx.Balance = newBalance.Balance - tx.Sum
+		if tx.Sum > newBalance.Available {
+			return models.Errorf(models.ErrInsufficientFunds, "you don't have enough funds")
+		}
+		cashOut, err := systemAccount(work, utils.CASH_OUT_ACCOUNT, tx.Currency)
+		if err != nil {
+			return err
+		}
+		tx.Postings = postings(&cashOut, &newBalance, tx.Sum)
+		tx.Created = time.Now()
 
-	err = fundsUC.TransactionsRepo.Add(tx)
-	return false, err
+		return work.AddTransaction(tx)
+	})
+	observeOperation("Withdraw", tx, replayed, err)
+	return err
 }
 
-func (fundsUC *FundsUC) Withdraw(tx *models.Transaction) (bool, bool, error) {
-	if tx.UserId == utils.ERROR_ID {
-		return true, false, fmt.Errorf("incorrect user id")
+func (fundsUC *FundsUC) Get(ctx context.Context, balance *models.Balance) error {
+	if balance.UserId <= utils.ERROR_ID {
+		return models.ErrInvalidUser
 	}
-	var newBalance models.Balance
-	newBalance.UserId = tx.UserId
-	errType, err := fundsUC.BalanceRepo.GetBalanceByUserId(&newBalance)
+	err := normalizeCurrency(&balance.Currency)
 	if err != nil {
-		if errType == utils.USER_ERROR {
-			err := fundsUC.BalanceRepo.InsertUser(&newBalance)
-			if err != nil {
-				return false, false, err
-			}
-		} else if errType == utils.SERVER_ERROR {
-			return false, false, err
+		return err
+	}
+	err = fundsUC.BalanceRepo.GetBalanceByUserId(ctx, balance)
+	if errors.Is(err, models.ErrNotFound) {
+		return fundsUC.BalanceRepo.InsertUser(ctx, balance)
+	}
+	return err
+}
+
+func (fundsUC *FundsUC) GetAll(ctx context.Context, user *models.UserId) ([]models.Balance, error) {
+	balances := make([]models.Balance, 0)
+	if user.UserId <= utils.ERROR_ID {
+		return balances, models.ErrInvalidUser
+	}
+	balances, err := fundsUC.BalanceRepo.GetBalancesByUserId(ctx, user)
+	if err != nil {
+		return balances, err
+	}
+	if len(balances) == 0 {
+		newBalance := models.Balance{UserId: user.UserId, Currency: utils.CURRENCY}
+		err = fundsUC.BalanceRepo.InsertUser(ctx, &newBalance)
+		if err != nil {
+			return balances, err
 		}
+		balances = append(balances, newBalance)
 	}
+	return balances, nil
+}
 
-	if tx.Sum <= 0 {
-		return true, false, fmt.Errorf("sum must be positive")
+// holds aren't kept in the transactions log, so historical balances are fully available.
+// Wallets without transactions before asOf had zero balance, a zero wallet in the default currency
+// is returned when the user had no transactions at all
+
+func (fundsUC *FundsUC) GetAsOf(ctx context.Context, user *models.UserId, asOf time.Time) ([]models.Balance, error) {
+	balances := make([]models.Balance, 0)
+	if user.UserId <= utils.ERROR_ID {
+		return balances, models.ErrInvalidUser
+	}
+	if asOf.IsZero() {
+		return balances, models.Errorf(models.ErrValidation, "as_of must be set")
 	}
+	if user.Currency != "" {
+		err := normalizeCurrency(&user.Currency)
+		if err != nil {
+			return balances, err
+		}
+	}
+	balances, err := fundsUC.TransactionsRepo.GetBalancesAsOf(ctx, user, asOf)
+	if err != nil {
+		return balances, err
+	}
+	if len(balances) == 0 {
+		currency := user.Currency
+		if currency == "" {
+			currency = utils.CURRENCY
+		}
+		balances = append(balances, models.Balance{UserId: user.UserId, Currency: currency})
+	}
+	return balances, nil
+}
 
-	tx.Balance = newBalance.Balance - tx.Sum
+// transfers between wallets of different currencies need tx.QuoteId: the sender is debited
+// tx.SumFrom in tx.CurrencyFrom and the receiver is credited tx.Sum in tx.Currency at the rate of the quote
 
-	if tx.Balance < 0 {
-		return false, true, fmt.Errorf("you don't have enough funds")
+func (fundsUC *FundsUC) Transfer(ctx context.Context, tx *models.Transaction) error {
+	if tx.UserId <= utils.ERROR_ID || tx.UserFromId <= utils.ERROR_ID {
+		return models.ErrInvalidUser
 	}
+	if tx.QuoteId < utils.ERROR_ID {
+		return models.Errorf(models.ErrValidation, "incorrect quote id")
+	}
+	if tx.Sum < 0 || tx.SumFrom < 0 || tx.QuoteId == utils.ERROR_ID && tx.Sum == 0 {
+		return models.Errorf(models.ErrValidation, "sum must be positive")
+	}
+	err := normalizeTransferCurrencies(tx)
+	if err != nil {
+		return err
+	}
+	tx.ReversedId = utils.ERROR_ID
+
+	var replayed bool
+	err = fundsUC.UnitOfWork.Do(ctx, func(work repository.WorkI) error {
+		var quote models.Quote
+		if tx.QuoteId != utils.ERROR_ID {
+			err := applyQuote(work, tx, &quote)
+			if err != nil {
+				return err
+			}
+		}
+		sumFrom, currencyFrom := tx.Sum, tx.Currency
+		if tx.QuoteId != utils.ERROR_ID {
+			sumFrom, currencyFrom = tx.SumFrom, tx.CurrencyFrom
+		}
+		// only a quoted conversion can move funds between two wallets of one user
+		if tx.UserId == tx.UserFromId && currencyFrom == tx.Currency {
+			return models.Errorf(models.ErrValidation, "can't transfer funds to the same wallet")
+		}
+
+		newBalance := models.Balance{UserId: tx.UserId, Currency: tx.Currency}
+		newBalanceFrom := models.Balance{UserId: tx.UserFromId, Currency: currencyFrom}
+		err := work.LockBalances(&newBalance, &newBalanceFrom)
+		if err != nil {
+			return err
+		}
+
+		tx.OperationType = utils.GetOperationType("Transfer")
+		replayed, err = replayTransaction(work, tx, tx.UserFromId)
+		if err != nil || replayed {
+			return err
+		}
+		now := time.Now()
+		if tx.QuoteId != utils.ERROR_ID {
+			err = checkQuote(&quote, now)
+			if err != nil {
+				return err
+			}
+		}
 
-	tx.OperationType = utils.GetOperationType("Withdraw")
-	tx.Created = time.Now()
+		tx.BalanceFrom = newBalanceFrom.Balance - sumFrom
+		if sumFrom > newBalanceFrom.Available {
+			return models.Errorf(models.ErrInsufficientFunds, "user doesn't have enough funds")
+		}
+		tx.Balance = newBalance.Balance + tx.Sum
+		if tx.Balance < newBalance.Balance {
+			return models.Errorf(models.ErrValidation, "balance is too large")
+		}
+		if tx.QuoteId == utils.ERROR_ID {
+			tx.Postings = postings(&newBalance, &newBalanceFrom, tx.Sum)
+		} else {
+			tx.Postings, err = exchangePostings(work, &newBalance, &newBalanceFrom, tx.Sum, sumFrom)
+			if err != nil {
+				return err
+			}
+		}
+		tx.Created = now
 
-	err = fundsUC.TransactionsRepo.Add(tx)
-	return false, false, err
+		return work.AddTransaction(tx)
+	})
+	observeOperation("Transfer", tx, replayed, err)
+	return err
 }
 
-func (fundsUC *FundsUC) Get(balance *models.Balance) (bool, error) {
-	if balance.UserId == utils.ERROR_ID {
-		return true, fmt.Errorf("incorrect user id")
+// quoted transfers take their currencies from the quote, so only the ones set in the request are normalized.
+// Without a quote both wallets must be in the same currency
+
+func normalizeTransferCurrencies(tx *models.Transaction) error {
+	if tx.QuoteId != utils.ERROR_ID {
+		for _, currency := range []*string{&tx.Currency, &tx.CurrencyFrom} {
+			if *currency != "" {
+				err := normalizeCurrency(currency)
+				if err != nil {
+					return err
+				}
+			}
+		}
+		return nil
 	}
-	errType, err := fundsUC.BalanceRepo.GetBalanceByUserId(balance)
+	err := normalizeWalletCurrency(&tx.Currency, tx.Sum)
 	if err != nil {
-		if errType == utils.USER_ERROR {
-			err := fundsUC.BalanceRepo.InsertUser(balance)
-			if err != nil {
-				return false, err
-			}
-			return false, nil
-		} else if errType == utils.SERVER_ERROR {
-			return false, err
+		return err
+	}
+	if tx.CurrencyFrom != "" {
+		err = normalizeCurrency(&tx.CurrencyFrom)
+		if err != nil {
+			return err
+		}
+		if tx.CurrencyFrom != tx.Currency {
+			return models.Errorf(models.ErrValidation, "transfers between currencies need a quote_id")
 		}
 	}
-	return false, nil
+	if tx.SumFrom != 0 && tx.SumFrom != tx.Sum {
+		return models.Errorf(models.ErrValidation, "sum_from must be equal to sum in transfers without a quote_id")
+	}
+	dropQuote(tx)
+	return nil
+}
+
+// the quoted fields of a transaction are only filled from its quote by applyQuote,
+// the ones sent in the request are dropped
+
+func dropQuote(tx *models.Transaction) {
+	tx.QuoteId, tx.CurrencyFrom, tx.SumFrom, tx.Rate = utils.ERROR_ID, "", 0, 0
 }
 
-func (fundsUC *FundsUC) Transfer(tx *models.Transaction) (bool, bool, error) {
-	var newBalance models.Balance
-	newBalance.UserId = tx.UserId
-	errType, err := fundsUC.BalanceRepo.GetBalanceByUserId(&newBalance)
+// returns up to filter.Limit transactions after cursor, the page has a next cursor when there are more transactions
+
+func (fundsUC *FundsUC) GetTransactions(ctx context.Context, user *models.UserId, filter *models.TransactionsFilter, since string,
+	cursor string) (models.TransactionsPage, error) {
+	page := models.TransactionsPage{Items: make([]models.Transaction, 0)}
+	query, err := historyQuery(user, filter, since)
 	if err != nil {
-		if errType == utils.USER_ERROR {
-			err := fundsUC.BalanceRepo.InsertUser(&newBalance)
-			if err != nil {
-				return false, false, err
-			}
-		} else if errType == utils.SERVER_ERROR {
-			return false, false, err
+		return page, err
+	}
+	limit := query.Limit
+	if cursor != "" {
+		decoded, err := models.DecodeCursor(cursor)
+		if err != nil {
+			return page, err
+		}
+		if decoded.Sort != query.Sort || decoded.Desc != query.Desc {
+			return page, fmt.Errorf("%w: cursor was issued for a different sort order", models.ErrInvalidCursor)
 		}
+		query.After = &decoded
+	}
+	balances, err := fundsUC.BalanceRepo.GetBalancesByUserId(ctx, user)
+	if err != nil {
+		return page, err
+	}
+	if len(balances) == 0 {
+		return page, nil
 	}
 
-	var newBalanceFrom models.Balance
-	newBalanceFrom.UserId = tx.UserFromId
-	errType, err = fundsUC.BalanceRepo.GetBalanceByUserId(&newBalanceFrom)
+	if limit != utils.LIMIT_DEFAULT {
+		query.Limit = limit + 1
+	}
+	txs, err := fundsUC.TransactionsRepo.GetUserTransactions(ctx, &query)
 	if err != nil {
-		if errType == utils.USER_ERROR {
-			err := fundsUC.BalanceRepo.InsertUser(&newBalanceFrom)
-			if err != nil {
-				return false, false, err
-			}
-		} else if errType == utils.SERVER_ERROR {
-			return false, false, err
-		}
+		return page, err
 	}
+	if limit != utils.LIMIT_DEFAULT && len(txs) > limit {
+		txs = txs[:limit]
+		page.NextCursor = models.NewCursor(query.Sort, query.Desc, &txs[limit-1]).Encode()
+	}
+	page.Items = txs
+	return page, nil
+}
 
-	if tx.Sum <= 0 {
-		return true, false, fmt.Errorf("sum must be positive")
+// streams the transactions matching filter to each without loading them all in memory
+
+func (fundsUC *FundsUC) ExportTransactions(ctx context.Context, user *models.UserId, filter *models.TransactionsFilter, since string,
+	each func(tx *models.Transaction) error) error {
+	query, err := historyQuery(user, filter, since)
+	if err != nil {
+		return err
 	}
+	return fundsUC.TransactionsRepo.ExportUserTransactions(ctx, &query, each)
+}
 
-	tx.BalanceFrom = newBalanceFrom.Balance - tx.Sum
+// validates the history filter of user and applies since to it: since narrows the start of the window
+// in ascending order and its end in descending order
 
-	if tx.BalanceFrom < 0 {
-		return false, true, fmt.Errorf("user doesn't have enough funds")
+func historyQuery(user *models.UserId, filter *models.TransactionsFilter, since string) (models.TransactionsFilter, error) {
+	query := *filter
+	if user.UserId <= utils.ERROR_ID {
+		return query, models.ErrInvalidUser
+	}
+	if query.Limit != utils.LIMIT_DEFAULT && query.Limit <= 0 {
+		return query, models.Errorf(models.ErrValidation, "limit must be positive")
+	}
+	for _, operationType := range query.OperationTypes {
+		if !utils.IsOperationType(operationType) {
+			return query, models.Errorf(models.ErrValidation, "unknown operation type %d", operationType)
+		}
+	}
+	if query.Counterparty < utils.ERROR_ID || query.Counterparty == user.UserId {
+		return query, models.Errorf(models.ErrValidation, "incorrect counterparty id")
+	}
+	if query.MinSum < 0 || query.MaxSum < 0 {
+		return query, models.Errorf(models.ErrValidation, "sum bounds must be positive")
+	}
+	if query.MaxSum != 0 && query.MinSum > query.MaxSum {
+		return query, models.Errorf(models.ErrValidation, "min_sum is greater than max_sum")
 	}
 
-	tx.Balance = newBalance.Balance + tx.Sum
+	query.UserId = user.UserId
+	if since != "" {
+		sinceTime, err := time.Parse(time.RFC3339Nano, since)
+		if err != nil {
+			return query, models.Errorf(models.ErrValidation, "bad since param: %v", err)
+		}
+		if query.Desc && (query.To.IsZero() || sinceTime.Before(query.To)) {
+			query.To = sinceTime
+		} else if !query.Desc && sinceTime.After(query.From) {
+			query.From = sinceTime
+		}
+	}
+	if !query.From.IsZero() && !query.To.IsZero() && query.From.After(query.To) {
+		return query, models.Errorf(models.ErrValidation, "created_after is later than created_before")
+	}
+	return query, nil
+}
 
-	tx.OperationType = utils.GetOperationType("Transfer")
-	tx.Created = time.Now()
+// empty currency means the default one
 
-	err = fundsUC.TransactionsRepo.Add(tx)
-	return false, false, err
+func normalizeCurrency(currency *string) error {
+	if *currency == "" {
+		*currency = utils.CURRENCY
+		return nil
+	}
+	code, err := models.NormalizeCurrency(*currency)
+	if err != nil {
+		return err
+	}
+	*currency = code
+	return nil
 }
 
-func (fundsUC *FundsUC) GetTransactions(user *models.UserId, limit int, since string, sort string, desc bool) (bool, []models.Transaction, error) {
-	txs := make([]models.Transaction, 0)
-	if user.UserId == utils.ERROR_ID {
-		return true, txs, fmt.Errorf("incorrect user id")
+// normalizes the currency of a wallet and checks that sums moved in it fit its minor units
+
+func normalizeWalletCurrency(currency *string, sums ...models.Money) error {
+	err := normalizeCurrency(currency)
+	if err != nil {
+		return err
 	}
-	var newBalance models.Balance
-	newBalance.UserId = user.UserId
-	errType, err := fundsUC.BalanceRepo.GetBalanceByUserId(&newBalance)
+	walletCurrency, err := models.GetWalletCurrency(*currency)
 	if err != nil {
-		if errType == utils.USER_ERROR {
-			return false, txs, nil
-		} else if errType == utils.SERVER_ERROR {
-			return false, txs, err
+		return err
+	}
+	for _, sum := range sums {
+		err = walletCurrency.CheckAmount(sum)
+		if err != nil {
+			return err
 		}
 	}
-	txs, errType, err = fundsUC.TransactionsRepo.GetUserTransactions(user, limit, since, sort, desc)
+	return nil
+}
+
+// checks that a sum moved in an existing wallet fits the minor units of its currency
+
+func checkSum(code string, sum models.Money) error {
+	currency, err := models.GetCurrency(code)
 	if err != nil {
-		if errType == utils.USER_ERROR {
-			return true, txs, err
-		} else if errType == utils.SERVER_ERROR {
-			return false, txs, err
-		}
+		return err
 	}
-	return false, txs, nil
+	return currency.CheckAmount(sum)
 }
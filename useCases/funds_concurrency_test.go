@@ -0,0 +1,407 @@
+package useCases
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgx"
+	"github.com/saskamegaprogrammist/userBalanceService/models"
+	"github.com/saskamegaprogrammist/userBalanceService/rates"
+	"github.com/saskamegaprogrammist/userBalanceService/repository"
+	"github.com/saskamegaprogrammist/userBalanceService/utils"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+const workers = 20
+const operationsPerWorker = 10
+
+// the tests run against every backend, the PostgreSQL one needs POSTGRES_DSN. Its database
+// isn't emptied, so every test works with users of its own
+
+var dbBackends = []struct {
+	name string
+	open func(t *testing.T)
+}{
+	{"Memory", func(t *testing.T) { repository.InitMemory(time.Minute) }},
+	{"SQLite", openTempSQLite},
+	{"Postgres", openPostgres},
+}
+
+func openTempSQLite(t *testing.T) {
+	err := repository.InitSQLite(filepath.Join(t.TempDir(), "balances.db"), workers, time.Minute)
+	if err != nil {
+		t.Fatalf("Couldn't initialize database: %v", err)
+	}
+	t.Cleanup(repository.Close)
+	_, err = repository.MigrateUp(context.Background())
+	if err != nil {
+		t.Fatalf("Couldn't migrate database: %v", err)
+	}
+}
+
+func openPostgres(t *testing.T) {
+	dsn := os.Getenv("POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("POSTGRES_DSN is not set")
+	}
+	config, err := pgx.ParseConnectionString(dsn)
+	if err != nil {
+		t.Fatalf("Couldn't parse POSTGRES_DSN: %v", err)
+	}
+	err = repository.Init(config, workers, time.Minute)
+	if err != nil {
+		t.Fatalf("Couldn't initialize database: %v", err)
+	}
+	t.Cleanup(repository.Close)
+	_, err = repository.MigrateUp(context.Background())
+	if err != nil {
+		t.Fatalf("Couldn't migrate database: %v", err)
+	}
+}
+
+var dbTests = []struct {
+	name string
+	run  func(t *testing.T, fundsUseCase FundsUC)
+}{
+	{"CancelledWorkRollsBack", testCancelledWorkRollsBack},
+	{"WithdrawConcurrent", testWithdrawConcurrent},
+	{"TransferConcurrent", testTransferConcurrent},
+	{"HoldLifecycle", testHoldLifecycle},
+	{"ReverseConcurrent", testReverseConcurrent},
+	{"ExportBatches", testExportBatches},
+	{"BalanceAsOf", testBalanceAsOf},
+	{"TransferQuoted", testTransferQuotedDB},
+	{"TransferQuotedConcurrent", testTransferQuotedConcurrent},
+}
+
+func TestFundsDB(t *testing.T) {
+	for _, backend := range dbBackends {
+		backend := backend
+		t.Run(backend.name, func(t *testing.T) {
+			for _, test := range dbTests {
+				test := test
+				t.Run(test.name, func(t *testing.T) {
+					backend.open(t)
+					test.run(t, FundsUC{
+						BalanceRepo:      repository.GetBalanceRepo(),
+						TransactionsRepo: repository.GetTransactionsRepo(),
+						UnitOfWork:       repository.GetUnitOfWork(),
+					})
+				})
+			}
+		})
+	}
+}
+
+func testCancelledWorkRollsBack(t *testing.T, fundsUseCase FundsUC) {
+	userId := int(time.Now().UnixNano() % 1000000000)
+
+	err := fundsUseCase.Add(context.Background(), &models.Transaction{UserId: userId, Sum: models.MoneyFromUnits(1000)})
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	err = fundsUseCase.UnitOfWork.Do(ctx, func(work repository.WorkI) error {
+		wallet := models.Balance{UserId: userId, Currency: utils.CURRENCY}
+		err := work.LockBalances(&wallet)
+		if err != nil {
+			return err
+		}
+		cashOut, err := systemAccount(work, utils.CASH_OUT_ACCOUNT, utils.CURRENCY)
+		if err != nil {
+			return err
+		}
+		err = work.AddTransaction(&models.Transaction{UserId: userId, Currency: utils.CURRENCY,
+			OperationType: utils.GetOperationType("Withdraw"), Sum: models.MoneyFromUnits(400),
+			Balance: wallet.Balance - models.MoneyFromUnits(400), Created: time.Now(),
+			Postings: postings(&cashOut, &wallet, models.MoneyFromUnits(400))})
+		cancel()
+		return err
+	})
+	assert.True(t, errors.Is(err, context.Canceled))
+
+	balance := models.Balance{UserId: userId}
+	err = fundsUseCase.Get(context.Background(), &balance)
+	assert.NoError(t, err)
+	assert.Equal(t, models.MoneyFromUnits(1000), balance.Balance)
+	assert.NoError(t, fundsUseCase.BalanceRepo.VerifyBalances(context.Background()))
+}
+
+func testWithdrawConcurrent(t *testing.T, fundsUseCase FundsUC) {
+	userId := int(time.Now().UnixNano() % 1000000000)
+
+	err := fundsUseCase.Add(context.Background(), &models.Transaction{UserId: userId, Sum: models.MoneyFromUnits(1000)})
+	assert.NoError(t, err)
+
+	var mutex sync.Mutex
+	succeeded, rejected := 0, 0
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < operationsPerWorker; j++ {
+				err := fundsUseCase.Withdraw(context.Background(), &models.Transaction{UserId: userId, Sum: models.MoneyFromUnits(10)})
+				mutex.Lock()
+				if err == nil {
+					succeeded++
+				} else if errors.Is(err, models.ErrInsufficientFunds) {
+					rejected++
+				} else {
+					t.Errorf("unexpected error: %v", err)
+				}
+				mutex.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	balance := models.Balance{UserId: userId}
+	err = fundsUseCase.Get(context.Background(), &balance)
+	assert.NoError(t, err)
+	assert.Equal(t, 100, succeeded)
+	assert.Equal(t, workers*operationsPerWorker-100, rejected)
+	assert.Equal(t, models.MoneyFromUnits(0), balance.Balance)
+	assert.NoError(t, fundsUseCase.BalanceRepo.VerifyBalances(context.Background()))
+}
+
+func testTransferConcurrent(t *testing.T, fundsUseCase FundsUC) {
+	userOne := int(time.Now().UnixNano() % 1000000000)
+	userTwo := userOne + 1
+
+	err := fundsUseCase.Add(context.Background(), &models.Transaction{UserId: userOne, Sum: models.MoneyFromUnits(1000)})
+	assert.NoError(t, err)
+	err = fundsUseCase.Add(context.Background(), &models.Transaction{UserId: userTwo, Sum: models.MoneyFromUnits(1000)})
+	assert.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			from, to := userOne, userTwo
+			if i%2 == 1 {
+				from, to = userTwo, userOne
+			}
+			for j := 0; j < operationsPerWorker; j++ {
+				err := fundsUseCase.Transfer(context.Background(), &models.Transaction{UserId: to, UserFromId: from, Sum: models.MoneyFromUnits(1)})
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	balanceOne := models.Balance{UserId: userOne}
+	err = fundsUseCase.Get(context.Background(), &balanceOne)
+	assert.NoError(t, err)
+	balanceTwo := models.Balance{UserId: userTwo}
+	err = fundsUseCase.Get(context.Background(), &balanceTwo)
+	assert.NoError(t, err)
+	assert.Equal(t, models.MoneyFromUnits(1000), balanceOne.Balance)
+	assert.Equal(t, models.MoneyFromUnits(1000), balanceTwo.Balance)
+	assert.NoError(t, fundsUseCase.BalanceRepo.VerifyBalances(context.Background()))
+}
+
+func testHoldLifecycle(t *testing.T, fundsUseCase FundsUC) {
+	holdsUseCase := HoldsUC{
+		HoldsRepo:  repository.GetHoldsRepo(),
+		UnitOfWork: repository.GetUnitOfWork(),
+		HoldTTL:    time.Hour,
+	}
+	userId := int(time.Now().UnixNano() % 1000000000)
+
+	err := fundsUseCase.Add(context.Background(), &models.Transaction{UserId: userId, Sum: models.MoneyFromUnits(1000)})
+	assert.NoError(t, err)
+
+	hold := models.Hold{UserId: userId, Sum: models.MoneyFromUnits(600)}
+	err = holdsUseCase.Hold(context.Background(), &hold)
+	assert.NoError(t, err)
+	err = holdsUseCase.Hold(context.Background(), &models.Hold{UserId: userId, Sum: models.MoneyFromUnits(500)})
+	assert.True(t, errors.Is(err, models.ErrInsufficientFunds))
+	err = fundsUseCase.Withdraw(context.Background(), &models.Transaction{UserId: userId, Sum: models.MoneyFromUnits(500)})
+	assert.True(t, errors.Is(err, models.ErrInsufficientFunds))
+
+	balance := models.Balance{UserId: userId}
+	err = fundsUseCase.Get(context.Background(), &balance)
+	assert.NoError(t, err)
+	assert.Equal(t, models.MoneyFromUnits(1000), balance.Balance)
+	assert.Equal(t, models.MoneyFromUnits(400), balance.Available)
+
+	err = holdsUseCase.Capture(context.Background(), &models.Hold{Id: hold.Id}, &models.Transaction{Sum: models.MoneyFromUnits(100)})
+	assert.NoError(t, err)
+	err = holdsUseCase.Void(context.Background(), &models.Hold{Id: hold.Id})
+	assert.True(t, errors.Is(err, ErrHoldNotActive))
+
+	balance = models.Balance{UserId: userId}
+	err = fundsUseCase.Get(context.Background(), &balance)
+	assert.NoError(t, err)
+	assert.Equal(t, models.MoneyFromUnits(900), balance.Balance)
+	assert.Equal(t, models.MoneyFromUnits(900), balance.Available)
+	assert.NoError(t, fundsUseCase.BalanceRepo.VerifyBalances(context.Background()))
+}
+
+// partial refunds add up, only two refunds of 400 fit in the original 1000
+
+func testReverseConcurrent(t *testing.T, fundsUseCase FundsUC) {
+	userId := int(time.Now().UnixNano() % 1000000000)
+
+	added := models.Transaction{UserId: userId, Sum: models.MoneyFromUnits(1000)}
+	err := fundsUseCase.Add(context.Background(), &added)
+	assert.NoError(t, err)
+
+	var mutex sync.Mutex
+	succeeded := 0
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var tx models.Transaction
+			err := fundsUseCase.Reverse(context.Background(), &models.Reversal{TransactionId: added.Id, Sum: models.MoneyFromUnits(400)}, &tx)
+			mutex.Lock()
+			if err == nil {
+				succeeded++
+			} else if !errors.Is(err, models.ErrValidation) && !errors.Is(err, ErrAlreadyReversed) {
+				t.Errorf("unexpected error: %v", err)
+			}
+			mutex.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	balance := models.Balance{UserId: userId}
+	err = fundsUseCase.Get(context.Background(), &balance)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, succeeded)
+	assert.Equal(t, models.MoneyFromUnits(200), balance.Balance)
+	assert.NoError(t, fundsUseCase.BalanceRepo.VerifyBalances(context.Background()))
+}
+
+func testExportBatches(t *testing.T, fundsUseCase FundsUC) {
+	userId := int(time.Now().UnixNano() % 1000000000)
+
+	const adds = utils.EXPORT_BATCH_SIZE + 5
+	for i := 0; i < adds; i++ {
+		err := fundsUseCase.Add(context.Background(), &models.Transaction{UserId: userId, Sum: models.MoneyFromUnits(1)})
+		assert.NoError(t, err)
+	}
+
+	exported, lastId := 0, 0
+	err := fundsUseCase.ExportTransactions(context.Background(), &models.UserId{UserId: userId},
+		&models.TransactionsFilter{Limit: utils.LIMIT_DEFAULT}, "", func(tx *models.Transaction) error {
+			assert.Greater(t, tx.Id, lastId)
+			exported, lastId = exported+1, tx.Id
+			return nil
+		})
+	assert.NoError(t, err)
+	assert.Equal(t, adds, exported)
+}
+
+func testBalanceAsOf(t *testing.T, fundsUseCase FundsUC) {
+	userId := int(time.Now().UnixNano() % 1000000000)
+	user := models.UserId{UserId: userId}
+
+	beforeActivity := time.Now()
+	err := fundsUseCase.Add(context.Background(), &models.Transaction{UserId: userId, Sum: models.MoneyFromUnits(100)})
+	assert.NoError(t, err)
+	afterAdd := time.Now()
+	err = fundsUseCase.Add(context.Background(), &models.Transaction{UserId: userId + 1, Sum: models.MoneyFromUnits(100)})
+	assert.NoError(t, err)
+	err = fundsUseCase.Transfer(context.Background(), &models.Transaction{UserId: userId + 1, UserFromId: userId, Sum: models.MoneyFromUnits(30)})
+	assert.NoError(t, err)
+
+	balances, err := fundsUseCase.GetAsOf(context.Background(), &user, beforeActivity)
+	assert.NoError(t, err)
+	assert.Equal(t, []models.Balance{{UserId: userId, Currency: "RUB"}}, balances)
+
+	balances, err = fundsUseCase.GetAsOf(context.Background(), &user, afterAdd)
+	assert.NoError(t, err)
+	assert.Equal(t, models.MoneyFromUnits(100), balances[0].Balance)
+
+	balances, err = fundsUseCase.GetAsOf(context.Background(), &user, time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, models.MoneyFromUnits(70), balances[0].Balance)
+
+	balances, err = fundsUseCase.GetAsOf(context.Background(), &models.UserId{UserId: userId + 1}, time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, models.MoneyFromUnits(130), balances[0].Balance)
+}
+
+func testTransferQuotedDB(t *testing.T, fundsUseCase FundsUC) {
+	fixedRates := models.CurrencyAll{Base: "USD", Rates: models.CurrencyRates{"RUB": 73.5}}
+	ratesUseCase := RatesUC{
+		RatesRepo:       repository.GetRatesRepo(),
+		Provider:        &rates.StaticProvider{Rates: fixedRates},
+		QuoteTTL:        time.Minute,
+		QuoteRateMaxAge: time.Hour,
+	}
+	userId := int(time.Now().UnixNano() % 1000000000)
+
+	err := fundsUseCase.Add(context.Background(), &models.Transaction{UserId: userId, Sum: models.MoneyFromUnits(100), Currency: "USD"})
+	assert.NoError(t, err)
+	quote := models.Quote{UserFromId: userId, CurrencyFrom: "USD", Currency: "RUB", SumFrom: models.MoneyFromUnits(10)}
+	err = ratesUseCase.Quote(context.Background(), &quote)
+	assert.NoError(t, err)
+	assert.Equal(t, quote.SumFrom.Convert(quote.Rate), quote.Sum)
+
+	tx := models.Transaction{UserId: userId + 1, UserFromId: userId, QuoteId: quote.Id}
+	err = fundsUseCase.Transfer(context.Background(), &tx)
+	assert.NoError(t, err)
+	err = fundsUseCase.Transfer(context.Background(), &models.Transaction{UserId: userId + 1, UserFromId: userId, QuoteId: quote.Id})
+	assert.True(t, errors.Is(err, ErrQuoteNotActive))
+
+	balances, err := fundsUseCase.GetAsOf(context.Background(), &models.UserId{UserId: userId}, time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, []models.Balance{{UserId: userId, Currency: "USD", Balance: models.MoneyFromUnits(90),
+		Available: models.MoneyFromUnits(90)}}, balances)
+	received := models.Balance{UserId: userId + 1, Currency: "RUB"}
+	err = fundsUseCase.Get(context.Background(), &received)
+	assert.NoError(t, err)
+	assert.Equal(t, quote.Sum, received.Balance)
+	assert.NoError(t, fundsUseCase.BalanceRepo.VerifyBalances(context.Background()))
+}
+
+func testTransferQuotedConcurrent(t *testing.T, fundsUseCase FundsUC) {
+	fixedRates := models.CurrencyAll{Base: "USD", Rates: models.CurrencyRates{"RUB": 73.5}}
+	ratesUseCase := RatesUC{
+		RatesRepo:       repository.GetRatesRepo(),
+		Provider:        &rates.StaticProvider{Rates: fixedRates},
+		QuoteTTL:        time.Minute,
+		QuoteRateMaxAge: time.Hour,
+	}
+	userId := int(time.Now().UnixNano() % 1000000000)
+
+	err := fundsUseCase.Add(context.Background(), &models.Transaction{UserId: userId, Sum: models.MoneyFromUnits(100), Currency: "USD"})
+	assert.NoError(t, err)
+	quote := models.Quote{UserFromId: userId, CurrencyFrom: "USD", Currency: "RUB", SumFrom: models.MoneyFromUnits(10)}
+	err = ratesUseCase.Quote(context.Background(), &quote)
+	assert.NoError(t, err)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- fundsUseCase.Transfer(context.Background(), &models.Transaction{UserId: userId + 1, UserFromId: userId, QuoteId: quote.Id})
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	succeeded := 0
+	for err := range errs {
+		if err == nil {
+			succeeded++
+			continue
+		}
+		assert.True(t, errors.Is(err, ErrQuoteNotActive), err)
+	}
+	assert.Equal(t, 1, succeeded)
+	assert.NoError(t, fundsUseCase.BalanceRepo.VerifyBalances(context.Background()))
+}
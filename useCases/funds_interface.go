@@ -1,11 +1,21 @@
 package useCases
 
-import "github.com/saskamegaprogrammist/userBalanceService/models"
+import (
+	"context"
+	"github.com/saskamegaprogrammist/userBalanceService/models"
+	"time"
+)
 
 type FundsUCInterface interface {
-	Add(tx *models.Transaction) (bool, error)
-	Withdraw(tx *models.Transaction) (bool, bool, error)
-	Get(balance *models.Balance) (bool, error)
-	Transfer(tx *models.Transaction) (bool, bool, error)
-	GetTransactions(user *models.UserId, limit int, since string, sort string, desc bool) (bool, []models.Transaction, error)
+	Add(ctx context.Context, tx *models.Transaction) error
+	Withdraw(ctx context.Context, tx *models.Transaction) error
+	Get(ctx context.Context, balance *models.Balance) error
+	GetAll(ctx context.Context, user *models.UserId) ([]models.Balance, error)
+	GetAsOf(ctx context.Context, user *models.UserId, asOf time.Time) ([]models.Balance, error)
+	Transfer(ctx context.Context, tx *models.Transaction) error
+	Reverse(ctx context.Context, reversal *models.Reversal, tx *models.Transaction) error
+	GetTransactions(ctx context.Context, user *models.UserId, filter *models.TransactionsFilter, since string,
+		cursor string) (models.TransactionsPage, error)
+	ExportTransactions(ctx context.Context, user *models.UserId, filter *models.TransactionsFilter, since string,
+		each func(tx *models.Transaction) error) error
 }
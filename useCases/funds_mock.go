@@ -5,9 +5,11 @@
 package useCases
 
 import (
+	context "context"
 	gomock "github.com/golang/mock/gomock"
 	models "github.com/saskamegaprogrammist/userBalanceService/models"
 	reflect "reflect"
+	time "time"
 )
 
 // MockFundsUCInterface is a mock of FundsUCInterface interface
@@ -34,79 +36,130 @@ func (m *MockFundsUCInterface) EXPECT() *MockFundsUCInterfaceMockRecorder {
 }
 
 // Add mocks base method
-func (m *MockFundsUCInterface) Add(tx *models.Transaction) (bool, error) {
+func (m *MockFundsUCInterface) Add(ctx context.Context, tx *models.Transaction) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Add", tx)
-	ret0, _ := ret[0].(bool)
-	ret1, _ := ret[1].(error)
-	return ret0, ret1
+	ret := m.ctrl.Call(m, "Add", ctx, tx)
+	ret0, _ := ret[0].(error)
+	return ret0
 }
 
 // Add indicates an expected call of Add
-func (mr *MockFundsUCInterfaceMockRecorder) Add(tx interface{}) *gomock.Call {
+func (mr *MockFundsUCInterfaceMockRecorder) Add(ctx, tx interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Add", reflect.TypeOf((*MockFundsUCInterface)(nil).Add), tx)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Add", reflect.TypeOf((*MockFundsUCInterface)(nil).Add), ctx, tx)
 }
 
 // Withdraw mocks base method
-func (m *MockFundsUCInterface) Withdraw(tx *models.Transaction) (bool, bool, error) {
+func (m *MockFundsUCInterface) Withdraw(ctx context.Context, tx *models.Transaction) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Withdraw", tx)
-	ret0, _ := ret[0].(bool)
-	ret1, _ := ret[1].(bool)
-	ret2, _ := ret[2].(error)
-	return ret0, ret1, ret2
+	ret := m.ctrl.Call(m, "Withdraw", ctx, tx)
+	ret0, _ := ret[0].(error)
+	return ret0
 }
 
 // Withdraw indicates an expected call of Withdraw
-func (mr *MockFundsUCInterfaceMockRecorder) Withdraw(tx interface{}) *gomock.Call {
+func (mr *MockFundsUCInterfaceMockRecorder) Withdraw(ctx, tx interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Withdraw", reflect.TypeOf((*MockFundsUCInterface)(nil).Withdraw), tx)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Withdraw", reflect.TypeOf((*MockFundsUCInterface)(nil).Withdraw), ctx, tx)
 }
 
 // Get mocks base method
-func (m *MockFundsUCInterface) Get(balance *models.Balance) (bool, error) {
+func (m *MockFundsUCInterface) Get(ctx context.Context, balance *models.Balance) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", ctx, balance)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Get indicates an expected call of Get
+func (mr *MockFundsUCInterfaceMockRecorder) Get(ctx, balance interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockFundsUCInterface)(nil).Get), ctx, balance)
+}
+
+// GetAll mocks base method
+func (m *MockFundsUCInterface) GetAll(ctx context.Context, user *models.UserId) ([]models.Balance, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Get", balance)
-	ret0, _ := ret[0].(bool)
+	ret := m.ctrl.Call(m, "GetAll", ctx, user)
+	ret0, _ := ret[0].([]models.Balance)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// Get indicates an expected call of Get
-func (mr *MockFundsUCInterfaceMockRecorder) Get(balance interface{}) *gomock.Call {
+// GetAll indicates an expected call of GetAll
+func (mr *MockFundsUCInterfaceMockRecorder) GetAll(ctx, user interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAll", reflect.TypeOf((*MockFundsUCInterface)(nil).GetAll), ctx, user)
+}
+
+// GetAsOf mocks base method
+func (m *MockFundsUCInterface) GetAsOf(ctx context.Context, user *models.UserId, asOf time.Time) ([]models.Balance, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAsOf", ctx, user, asOf)
+	ret0, _ := ret[0].([]models.Balance)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAsOf indicates an expected call of GetAsOf
+func (mr *MockFundsUCInterfaceMockRecorder) GetAsOf(ctx, user, asOf interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockFundsUCInterface)(nil).Get), balance)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAsOf", reflect.TypeOf((*MockFundsUCInterface)(nil).GetAsOf), ctx, user, asOf)
 }
 
 // Transfer mocks base method
-func (m *MockFundsUCInterface) Transfer(tx *models.Transaction) (bool, bool, error) {
+func (m *MockFundsUCInterface) Transfer(ctx context.Context, tx *models.Transaction) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Transfer", tx)
-	ret0, _ := ret[0].(bool)
-	ret1, _ := ret[1].(bool)
-	ret2, _ := ret[2].(error)
-	return ret0, ret1, ret2
+	ret := m.ctrl.Call(m, "Transfer", ctx, tx)
+	ret0, _ := ret[0].(error)
+	return ret0
 }
 
 // Transfer indicates an expected call of Transfer
-func (mr *MockFundsUCInterfaceMockRecorder) Transfer(tx interface{}) *gomock.Call {
+func (mr *MockFundsUCInterfaceMockRecorder) Transfer(ctx, tx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Transfer", reflect.TypeOf((*MockFundsUCInterface)(nil).Transfer), ctx, tx)
+}
+
+// Reverse mocks base method
+func (m *MockFundsUCInterface) Reverse(ctx context.Context, reversal *models.Reversal, tx *models.Transaction) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Reverse", ctx, reversal, tx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Reverse indicates an expected call of Reverse
+func (mr *MockFundsUCInterfaceMockRecorder) Reverse(ctx, reversal, tx interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Transfer", reflect.TypeOf((*MockFundsUCInterface)(nil).Transfer), tx)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Reverse", reflect.TypeOf((*MockFundsUCInterface)(nil).Reverse), ctx, reversal, tx)
 }
 
 // GetTransactions mocks base method
-func (m *MockFundsUCInterface) GetTransactions(user *models.UserId, limit int, since, sort string, desc bool) (bool, []models.Transaction, error) {
+func (m *MockFundsUCInterface) GetTransactions(ctx context.Context, user *models.UserId, filter *models.TransactionsFilter, since, cursor string) (models.TransactionsPage, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetTransactions", user, limit, since, sort, desc)
-	ret0, _ := ret[0].(bool)
-	ret1, _ := ret[1].([]models.Transaction)
-	ret2, _ := ret[2].(error)
-	return ret0, ret1, ret2
+	ret := m.ctrl.Call(m, "GetTransactions", ctx, user, filter, since, cursor)
+	ret0, _ := ret[0].(models.TransactionsPage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // GetTransactions indicates an expected call of GetTransactions
-func (mr *MockFundsUCInterfaceMockRecorder) GetTransactions(user, limit, since, sort, desc interface{}) *gomock.Call {
+func (mr *MockFundsUCInterfaceMockRecorder) GetTransactions(ctx, user, filter, since, cursor interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTransactions", reflect.TypeOf((*MockFundsUCInterface)(nil).GetTransactions), ctx, user, filter, since, cursor)
+}
+
+// ExportTransactions mocks base method
+func (m *MockFundsUCInterface) ExportTransactions(ctx context.Context, user *models.UserId, filter *models.TransactionsFilter, since string, each func(*models.Transaction) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExportTransactions", ctx, user, filter, since, each)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ExportTransactions indicates an expected call of ExportTransactions
+func (mr *MockFundsUCInterfaceMockRecorder) ExportTransactions(ctx, user, filter, since, each interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTransactions", reflect.TypeOf((*MockFundsUCInterface)(nil).GetTransactions), user, limit, since, sort, desc)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExportTransactions", reflect.TypeOf((*MockFundsUCInterface)(nil).ExportTransactions), ctx, user, filter, since, each)
 }
@@ -1,6 +1,7 @@
 package useCases
 
 import (
+	"context"
 	"errors"
 	"github.com/golang/mock/gomock"
 	"github.com/saskamegaprogrammist/userBalanceService/models"
@@ -13,22 +14,22 @@ import (
 
 var testTxOne = models.Transaction{
 	UserId: 1,
-	Sum:    100,
+	Sum:    models.MoneyFromUnits(100),
 }
 
 var testTxTwo = models.Transaction{
 	UserId: 1,
-	Sum:    50,
+	Sum:    models.MoneyFromUnits(50),
 }
 
 var testTxWrong = models.Transaction{
 	UserId: 0,
-	Sum:    1001,
+	Sum:    models.MoneyFromUnits(1001),
 }
 
 var testTxWrongSum = models.Transaction{
 	UserId: 1,
-	Sum:    -1001,
+	Sum:    models.MoneyFromUnits(-1001),
 }
 
 var testUserOne = models.UserId{
@@ -44,11 +45,13 @@ var testUserWrong = models.UserId{
 }
 
 var testBalanceOneGet = models.Balance{
-	UserId: 1,
+	UserId:   1,
+	Currency: utils.CURRENCY,
 }
 
 var testBalanceTwoGet = models.Balance{
-	UserId: 2,
+	UserId:   2,
+	Currency: utils.CURRENCY,
 }
 
 var testBalanceWrongGet = models.Balance{
@@ -59,24 +62,67 @@ var testBalanceWrongGet = models.Balance{
 var testTxOneTransfer = models.Transaction{
 	UserId:     2,
 	UserFromId: 1,
-	Sum:        100,
+	Sum:        models.MoneyFromUnits(100),
 }
 
 var testTxWrongTransfer = models.Transaction{
 	UserId:     2,
 	UserFromId: 1,
-	Sum:        -100,
+	Sum:        models.MoneyFromUnits(-100),
 }
 
 var testTransactions = []models.Transaction{
-	{Id: 1, UserId: 1, UserFromId: 2, Sum: 10, Balance: 101, BalanceFrom: 100, Created: time.Now()},
-	{Id: 2, UserId: 2, UserFromId: 1, Sum: 10, Balance: 101, BalanceFrom: 100, Created: time.Now()},
+	{Id: 1, UserId: 1, UserFromId: 2, Sum: models.MoneyFromUnits(10), Balance: models.MoneyFromUnits(101), BalanceFrom: models.MoneyFromUnits(100), Created: time.Now()},
+	{Id: 2, UserId: 2, UserFromId: 1, Sum: models.MoneyFromUnits(10), Balance: models.MoneyFromUnits(101), BalanceFrom: models.MoneyFromUnits(100), Created: time.Now()},
 }
 
 var limitInt = utils.LIMIT_DEFAULT
 var descBool = false
 var since = ""
 var sort = ""
+var testQuery = models.TransactionsFilter{Limit: limitInt}
+var testSumQuery = models.TransactionsFilter{Limit: 1, Sort: "sum", Desc: true}
+var testFilter = models.TransactionsFilter{UserId: testUserOne.UserId, Limit: limitInt}
+var cursor = ""
+
+func runWork(mockWork *repository.MockWorkI) func(ctx context.Context, work func(work repository.WorkI) error) error {
+	return func(ctx context.Context, work func(work repository.WorkI) error) error {
+		return work(mockWork)
+	}
+}
+
+// answers LockBalances with the balance and available funds stored for every locked wallet,
+// the wallets get UserId*10 as their id
+
+func lockWallets(stored ...models.Balance) func(balances ...*models.Balance) error {
+	return func(balances ...*models.Balance) error {
+		for i := range balances {
+			balances[i].Id = balances[i].UserId * 10
+			balances[i].Balance = stored[i].Balance
+			balances[i].Available = stored[i].Available
+		}
+		return nil
+	}
+}
+
+func wallet(units int64) models.Balance {
+	return heldWallet(units, units)
+}
+
+// a wallet with funds on hold, only availableUnits of its balance can be spent
+
+func heldWallet(units int64, availableUnits int64) models.Balance {
+	return models.Balance{Balance: models.MoneyFromUnits(units), Available: models.MoneyFromUnits(availableUnits)}
+}
+
+// answers a Get* call of the work with a stored hold, transaction or quote
+
+func getStored[T any](stored T) func(found *T) error {
+	return func(found *T) error {
+		*found = stored
+		return nil
+	}
+}
 
 func TestAddFunds(t *testing.T) {
 	t.Run("FundsAddOK", func(t *testing.T) {
@@ -84,113 +130,210 @@ func TestAddFunds(t *testing.T) {
 		defer ctrl.Finish()
 
 		var testBalanceOneGetLocal = models.Balance{
-			UserId: 1,
+			UserId:   1,
+			Currency: utils.CURRENCY,
 		}
 
-		mockRepoBalance := repository.NewMockBalanceRepoI(ctrl)
-		mockRepoBalance.EXPECT().GetBalanceByUserId(&testBalanceOneGetLocal).DoAndReturn(func(user *models.Balance) (int, error) {
-			user.Balance = 1000
-			return utils.NO_ERROR, nil
+		mockWork := repository.NewMockWorkI(ctrl)
+		mockWork.EXPECT().LockBalances(&testBalanceOneGetLocal).DoAndReturn(func(balances ...*models.Balance) error {
+			balances[0].Balance = models.MoneyFromUnits(1000)
+			balances[0].Available = models.MoneyFromUnits(1000)
+			return nil
 		})
+		mockWork.EXPECT().GetSystemAccount(gomock.Any()).Return(nil)
+		mockWork.EXPECT().AddTransaction(&testTxOne).Return(nil)
 
-		mockRepoTxs := repository.NewMockTransactionsRepoI(ctrl)
-		mockRepoTxs.EXPECT().Add(&testTxOne).Return(nil)
+		mockUnitOfWork := repository.NewMockUnitOfWorkI(ctrl)
+		mockUnitOfWork.EXPECT().Do(gomock.Any(), gomock.Any()).DoAndReturn(runWork(mockWork))
 
 		fundsUseCase := FundsUC{
-			BalanceRepo:      mockRepoBalance,
-			TransactionsRepo: mockRepoTxs,
+			UnitOfWork: mockUnitOfWork,
 		}
 
-		userError, err := fundsUseCase.Add(&testTxOne)
+		err := fundsUseCase.Add(context.Background(), &testTxOne)
 
 		assert.NoError(t, err)
 		assert.Equal(t, utils.GetOperationType("Add"), testTxOne.OperationType)
-		assert.Equal(t, testTxOne.Sum+1000, testTxOne.Balance)
-		assert.Equal(t, false, userError)
+		assert.Equal(t, testTxOne.Sum+models.MoneyFromUnits(1000), testTxOne.Balance)
+	})
+
+	t.Run("QuoteFieldsDropped", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockWork := repository.NewMockWorkI(ctrl)
+		mockWork.EXPECT().LockBalances(gomock.Any()).Return(nil)
+		mockWork.EXPECT().GetSystemAccount(gomock.Any()).Return(nil)
+		mockWork.EXPECT().AddTransaction(gomock.Any()).DoAndReturn(func(tx *models.Transaction) error {
+			assert.Equal(t, utils.ERROR_ID, tx.QuoteId)
+			assert.Equal(t, "", tx.CurrencyFrom)
+			assert.Equal(t, models.Money(0), tx.SumFrom)
+			assert.Equal(t, 0.0, tx.Rate)
+			return nil
+		})
+
+		mockUnitOfWork := repository.NewMockUnitOfWorkI(ctrl)
+		mockUnitOfWork.EXPECT().Do(gomock.Any(), gomock.Any()).DoAndReturn(runWork(mockWork))
+
+		fundsUseCase := FundsUC{
+			UnitOfWork: mockUnitOfWork,
+		}
+
+		err := fundsUseCase.Add(context.Background(), &models.Transaction{UserId: 1, Sum: models.MoneyFromUnits(10),
+			QuoteId: 9, CurrencyFrom: "USD", SumFrom: models.MoneyFromUnits(1), Rate: 73.5})
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("ReversalFieldsDropped", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockWork := repository.NewMockWorkI(ctrl)
+		mockWork.EXPECT().LockBalances(gomock.Any()).Return(nil)
+		mockWork.EXPECT().GetSystemAccount(gomock.Any()).Return(nil)
+		mockWork.EXPECT().AddTransaction(gomock.Any()).DoAndReturn(func(tx *models.Transaction) error {
+			assert.Equal(t, utils.ERROR_ID, tx.ReversedId)
+			assert.Equal(t, "", tx.CurrencyFrom)
+			return nil
+		})
+
+		mockUnitOfWork := repository.NewMockUnitOfWorkI(ctrl)
+		mockUnitOfWork.EXPECT().Do(gomock.Any(), gomock.Any()).DoAndReturn(runWork(mockWork))
+
+		fundsUseCase := FundsUC{
+			UnitOfWork: mockUnitOfWork,
+		}
+
+		err := fundsUseCase.Add(context.Background(), &models.Transaction{UserId: 1, Sum: models.MoneyFromUnits(10),
+			ReversedId: 3, CurrencyFrom: "USD"})
+
+		assert.NoError(t, err)
 	})
 
 	t.Run("FundsAddInvalidUserId", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
 
-		mockRepoBalance := repository.NewMockBalanceRepoI(ctrl)
-		mockRepoTxs := repository.NewMockTransactionsRepoI(ctrl)
+		mockUnitOfWork := repository.NewMockUnitOfWorkI(ctrl)
 
 		fundsUseCase := FundsUC{
-			BalanceRepo:      mockRepoBalance,
-			TransactionsRepo: mockRepoTxs,
+			UnitOfWork: mockUnitOfWork,
 		}
 
-		userError, err := fundsUseCase.Add(&testTxWrong)
+		err := fundsUseCase.Add(context.Background(), &testTxWrong)
 
 		assert.Error(t, err)
-		assert.Equal(t, true, userError)
+		assert.True(t, errors.Is(err, models.ErrValidation))
 		assert.Equal(t, "incorrect user id", err.Error())
-
 	})
 
 	t.Run("DBErrorFirst", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
 
-		mockRepoBalance := repository.NewMockBalanceRepoI(ctrl)
-		mockRepoBalance.EXPECT().GetBalanceByUserId(&testBalanceOneGet).Return(utils.SERVER_ERROR, errors.New("db error"))
+		mockWork := repository.NewMockWorkI(ctrl)
+		mockWork.EXPECT().LockBalances(&testBalanceOneGet).Return(errors.New("db error"))
 
-		mockRepoTxs := repository.NewMockTransactionsRepoI(ctrl)
+		mockUnitOfWork := repository.NewMockUnitOfWorkI(ctrl)
+		mockUnitOfWork.EXPECT().Do(gomock.Any(), gomock.Any()).DoAndReturn(runWork(mockWork))
 
 		fundsUseCase := FundsUC{
-			BalanceRepo:      mockRepoBalance,
-			TransactionsRepo: mockRepoTxs,
+			UnitOfWork: mockUnitOfWork,
 		}
 
-		userError, err := fundsUseCase.Add(&testTxOne)
+		err := fundsUseCase.Add(context.Background(), &testTxOne)
 
 		assert.Error(t, err)
-		assert.Equal(t, false, userError)
 	})
 
 	t.Run("DBErrorSecond", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
 
-		mockRepoBalance := repository.NewMockBalanceRepoI(ctrl)
-		mockRepoBalance.EXPECT().GetBalanceByUserId(&testBalanceOneGet).Return(utils.USER_ERROR, errors.New("no user"))
-		mockRepoBalance.EXPECT().InsertUser(&testBalanceOneGet).Return(errors.New("db error"))
+		mockWork := repository.NewMockWorkI(ctrl)
+		mockWork.EXPECT().LockBalances(&testBalanceOneGet).Return(nil)
+		mockWork.EXPECT().GetSystemAccount(gomock.Any()).Return(nil)
+		mockWork.EXPECT().AddTransaction(&testTxOne).Return(errors.New("db error"))
 
-		mockRepoTxs := repository.NewMockTransactionsRepoI(ctrl)
+		mockUnitOfWork := repository.NewMockUnitOfWorkI(ctrl)
+		mockUnitOfWork.EXPECT().Do(gomock.Any(), gomock.Any()).DoAndReturn(runWork(mockWork))
 
 		fundsUseCase := FundsUC{
-			BalanceRepo:      mockRepoBalance,
-			TransactionsRepo: mockRepoTxs,
+			UnitOfWork: mockUnitOfWork,
 		}
 
-		userError, err := fundsUseCase.Add(&testTxOne)
+		err := fundsUseCase.Add(context.Background(), &testTxOne)
 
 		assert.Error(t, err)
-		assert.Equal(t, false, userError)
 	})
 
 	t.Run("WrongSum", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
 
-		mockRepoBalance := repository.NewMockBalanceRepoI(ctrl)
-		mockRepoBalance.EXPECT().GetBalanceByUserId(&testBalanceOneGet).Return(utils.USER_ERROR, errors.New("no user"))
-		mockRepoBalance.EXPECT().InsertUser(&testBalanceOneGet).Return(nil)
-
-		mockRepoTxs := repository.NewMockTransactionsRepoI(ctrl)
+		mockUnitOfWork := repository.NewMockUnitOfWorkI(ctrl)
 
 		fundsUseCase := FundsUC{
-			BalanceRepo:      mockRepoBalance,
-			TransactionsRepo: mockRepoTxs,
+			UnitOfWork: mockUnitOfWork,
 		}
 
-		userError, err := fundsUseCase.Add(&testTxWrongSum)
+		err := fundsUseCase.Add(context.Background(), &testTxWrongSum)
 
 		assert.Error(t, err)
-		assert.Equal(t, true, userError)
+		assert.True(t, errors.Is(err, models.ErrValidation))
 		assert.Equal(t, "sum must be positive", err.Error())
 	})
+
+	t.Run("SumFinerThanCurrency", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		fundsUseCase := FundsUC{
+			UnitOfWork: repository.NewMockUnitOfWorkI(ctrl),
+		}
+
+		sum, _ := models.ParseMoney("1.50")
+		err := fundsUseCase.Add(context.Background(), &models.Transaction{UserId: 1, Sum: sum, Currency: "jpy"})
+
+		assert.True(t, errors.Is(err, models.ErrInvalidMoney))
+	})
+
+	t.Run("CurrencyWithThreeMinorUnits", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		fundsUseCase := FundsUC{
+			UnitOfWork: repository.NewMockUnitOfWorkI(ctrl),
+		}
+
+		err := fundsUseCase.Add(context.Background(), &models.Transaction{UserId: 1, Sum: models.MoneyFromUnits(10),
+			Currency: "KWD"})
+
+		assert.Error(t, err)
+		assert.True(t, errors.Is(err, models.ErrValidation))
+	})
+
+	t.Run("FundsAddCancelled", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		mockUnitOfWork := repository.NewMockUnitOfWorkI(ctrl)
+		mockUnitOfWork.EXPECT().Do(ctx, gomock.Any()).DoAndReturn(func(ctx context.Context, work func(work repository.WorkI) error) error {
+			return ctx.Err()
+		})
+
+		fundsUseCase := FundsUC{
+			UnitOfWork: mockUnitOfWork,
+		}
+
+		err := fundsUseCase.Add(ctx, &models.Transaction{UserId: 1, Sum: models.MoneyFromUnits(100)})
+
+		assert.True(t, errors.Is(err, context.Canceled))
+	})
 }
 
 func TestWithdrawFunds(t *testing.T) {
@@ -199,141 +342,152 @@ func TestWithdrawFunds(t *testing.T) {
 		defer ctrl.Finish()
 
 		var testBalanceOneGetLocal = models.Balance{
-			UserId: 1,
+			UserId:   1,
+			Currency: utils.CURRENCY,
 		}
 
-		mockRepoBalance := repository.NewMockBalanceRepoI(ctrl)
-		mockRepoBalance.EXPECT().GetBalanceByUserId(&testBalanceOneGetLocal).DoAndReturn(func(user *models.Balance) (int, error) {
-			user.Balance = 1000
-			return utils.NO_ERROR, nil
+		mockWork := repository.NewMockWorkI(ctrl)
+		mockWork.EXPECT().LockBalances(&testBalanceOneGetLocal).DoAndReturn(func(balances ...*models.Balance) error {
+			balances[0].Balance = models.MoneyFromUnits(1000)
+			balances[0].Available = models.MoneyFromUnits(1000)
+			return nil
 		})
+		mockWork.EXPECT().GetSystemAccount(gomock.Any()).Return(nil)
+		mockWork.EXPECT().AddTransaction(&testTxOne).Return(nil)
 
-		mockRepoTxs := repository.NewMockTransactionsRepoI(ctrl)
-		mockRepoTxs.EXPECT().Add(&testTxOne).Return(nil)
+		mockUnitOfWork := repository.NewMockUnitOfWorkI(ctrl)
+		mockUnitOfWork.EXPECT().Do(gomock.Any(), gomock.Any()).DoAndReturn(runWork(mockWork))
 
 		fundsUseCase := FundsUC{
-			BalanceRepo:      mockRepoBalance,
-			TransactionsRepo: mockRepoTxs,
+			UnitOfWork: mockUnitOfWork,
 		}
 
-		userError, lowFunds, err := fundsUseCase.Withdraw(&testTxOne)
+		err := fundsUseCase.Withdraw(context.Background(), &testTxOne)
 
 		assert.NoError(t, err)
 		assert.Equal(t, utils.GetOperationType("Withdraw"), testTxOne.OperationType)
-		assert.Equal(t, 1000-testTxOne.Sum, testTxOne.Balance)
-		assert.Equal(t, false, userError)
-		assert.Equal(t, false, lowFunds)
+		assert.Equal(t, models.MoneyFromUnits(1000)-testTxOne.Sum, testTxOne.Balance)
 	})
 
 	t.Run("FundsAddInvalidUserId", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
 
-		mockRepoBalance := repository.NewMockBalanceRepoI(ctrl)
-		mockRepoTxs := repository.NewMockTransactionsRepoI(ctrl)
+		mockUnitOfWork := repository.NewMockUnitOfWorkI(ctrl)
 
 		fundsUseCase := FundsUC{
-			BalanceRepo:      mockRepoBalance,
-			TransactionsRepo: mockRepoTxs,
+			UnitOfWork: mockUnitOfWork,
 		}
 
-		userError, lowFunds, err := fundsUseCase.Withdraw(&testTxWrong)
+		err := fundsUseCase.Withdraw(context.Background(), &testTxWrong)
 
 		assert.Error(t, err)
-		assert.Equal(t, true, userError)
-		assert.Equal(t, false, lowFunds)
+		assert.True(t, errors.Is(err, models.ErrValidation))
 		assert.Equal(t, "incorrect user id", err.Error())
-
 	})
 
 	t.Run("DBErrorFirst", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
 
-		mockRepoBalance := repository.NewMockBalanceRepoI(ctrl)
-		mockRepoBalance.EXPECT().GetBalanceByUserId(&testBalanceOneGet).Return(utils.SERVER_ERROR, errors.New("db error"))
+		mockWork := repository.NewMockWorkI(ctrl)
+		mockWork.EXPECT().LockBalances(&testBalanceOneGet).Return(errors.New("db error"))
 
-		mockRepoTxs := repository.NewMockTransactionsRepoI(ctrl)
+		mockUnitOfWork := repository.NewMockUnitOfWorkI(ctrl)
+		mockUnitOfWork.EXPECT().Do(gomock.Any(), gomock.Any()).DoAndReturn(runWork(mockWork))
 
 		fundsUseCase := FundsUC{
-			BalanceRepo:      mockRepoBalance,
-			TransactionsRepo: mockRepoTxs,
+			UnitOfWork: mockUnitOfWork,
 		}
 
-		userError, lowFunds, err := fundsUseCase.Withdraw(&testTxOne)
+		err := fundsUseCase.Withdraw(context.Background(), &testTxOne)
 
 		assert.Error(t, err)
-		assert.Equal(t, false, lowFunds)
-		assert.Equal(t, false, userError)
 	})
 
 	t.Run("DBErrorSecond", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
 
-		mockRepoBalance := repository.NewMockBalanceRepoI(ctrl)
-		mockRepoBalance.EXPECT().GetBalanceByUserId(&testBalanceOneGet).Return(utils.USER_ERROR, errors.New("no user"))
-		mockRepoBalance.EXPECT().InsertUser(&testBalanceOneGet).Return(errors.New("db error"))
+		mockWork := repository.NewMockWorkI(ctrl)
+		mockWork.EXPECT().LockBalances(&testBalanceOneGet).DoAndReturn(func(balances ...*models.Balance) error {
+			balances[0].Balance = models.MoneyFromUnits(1000)
+			balances[0].Available = models.MoneyFromUnits(1000)
+			return nil
+		})
+		mockWork.EXPECT().GetSystemAccount(gomock.Any()).Return(nil)
+		mockWork.EXPECT().AddTransaction(&testTxOne).Return(errors.New("db error"))
 
-		mockRepoTxs := repository.NewMockTransactionsRepoI(ctrl)
+		mockUnitOfWork := repository.NewMockUnitOfWorkI(ctrl)
+		mockUnitOfWork.EXPECT().Do(gomock.Any(), gomock.Any()).DoAndReturn(runWork(mockWork))
 
 		fundsUseCase := FundsUC{
-			BalanceRepo:      mockRepoBalance,
-			TransactionsRepo: mockRepoTxs,
+			UnitOfWork: mockUnitOfWork,
 		}
 
-		userError, lowFunds, err := fundsUseCase.Withdraw(&testTxOne)
+		err := fundsUseCase.Withdraw(context.Background(), &testTxOne)
 
 		assert.Error(t, err)
-		assert.Equal(t, false, lowFunds)
-		assert.Equal(t, false, userError)
 	})
 
 	t.Run("WrongSum", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
 
-		mockRepoBalance := repository.NewMockBalanceRepoI(ctrl)
-		mockRepoBalance.EXPECT().GetBalanceByUserId(&testBalanceOneGet).Return(utils.USER_ERROR, errors.New("no user"))
-		mockRepoBalance.EXPECT().InsertUser(&testBalanceOneGet).Return(nil)
-
-		mockRepoTxs := repository.NewMockTransactionsRepoI(ctrl)
+		mockUnitOfWork := repository.NewMockUnitOfWorkI(ctrl)
 
 		fundsUseCase := FundsUC{
-			BalanceRepo:      mockRepoBalance,
-			TransactionsRepo: mockRepoTxs,
+			UnitOfWork: mockUnitOfWork,
 		}
 
-		userError, lowFunds, err := fundsUseCase.Withdraw(&testTxWrongSum)
+		err := fundsUseCase.Withdraw(context.Background(), &testTxWrongSum)
 
 		assert.Error(t, err)
-		assert.Equal(t, true, userError)
-		assert.Equal(t, false, lowFunds)
+		assert.True(t, errors.Is(err, models.ErrValidation))
 		assert.Equal(t, "sum must be positive", err.Error())
 	})
 
+	t.Run("SumFinerThanCurrency", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		fundsUseCase := FundsUC{
+			UnitOfWork: repository.NewMockUnitOfWorkI(ctrl),
+		}
+
+		sum, _ := models.ParseMoney("0.50")
+		err := fundsUseCase.Withdraw(context.Background(), &models.Transaction{UserId: 1, Sum: sum, Currency: "JPY"})
+
+		assert.True(t, errors.Is(err, models.ErrInvalidMoney))
+	})
+
 	t.Run("LowFunds", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
 
-		mockRepoBalance := repository.NewMockBalanceRepoI(ctrl)
-		mockRepoBalance.EXPECT().GetBalanceByUserId(&testBalanceOneGet).DoAndReturn(func(user *models.Balance) (int, error) {
-			user.Balance = 50
-			return utils.NO_ERROR, nil
+		var testBalanceOneGetLocal = models.Balance{
+			UserId:   1,
+			Currency: utils.CURRENCY,
+		}
+
+		mockWork := repository.NewMockWorkI(ctrl)
+		mockWork.EXPECT().LockBalances(&testBalanceOneGetLocal).DoAndReturn(func(balances ...*models.Balance) error {
+			balances[0].Balance = models.MoneyFromUnits(50)
+			balances[0].Available = models.MoneyFromUnits(50)
+			return nil
 		})
 
-		mockRepoTxs := repository.NewMockTransactionsRepoI(ctrl)
+		mockUnitOfWork := repository.NewMockUnitOfWorkI(ctrl)
+		mockUnitOfWork.EXPECT().Do(gomock.Any(), gomock.Any()).DoAndReturn(runWork(mockWork))
 
 		fundsUseCase := FundsUC{
-			BalanceRepo:      mockRepoBalance,
-			TransactionsRepo: mockRepoTxs,
+			UnitOfWork: mockUnitOfWork,
 		}
 
-		userError, lowFunds, err := fundsUseCase.Withdraw(&testTxOne)
+		err := fundsUseCase.Withdraw(context.Background(), &testTxOne)
 
 		assert.Error(t, err)
-		assert.Equal(t, false, userError)
-		assert.Equal(t, true, lowFunds)
+		assert.True(t, errors.Is(err, models.ErrInsufficientFunds))
 		assert.Equal(t, "you don't have enough funds", err.Error())
 	})
 }
@@ -344,13 +498,14 @@ func TestGetFunds(t *testing.T) {
 		defer ctrl.Finish()
 
 		var testBalanceOneGetLocal = models.Balance{
-			UserId: 1,
+			UserId:   1,
+			Currency: utils.CURRENCY,
 		}
 
 		mockRepoBalance := repository.NewMockBalanceRepoI(ctrl)
-		mockRepoBalance.EXPECT().GetBalanceByUserId(&testBalanceOneGetLocal).DoAndReturn(func(user *models.Balance) (int, error) {
-			user.Balance = 1000
-			return utils.NO_ERROR, nil
+		mockRepoBalance.EXPECT().GetBalanceByUserId(gomock.Any(), &testBalanceOneGetLocal).DoAndReturn(func(ctx context.Context, user *models.Balance) error {
+			user.Balance = models.MoneyFromUnits(1000)
+			return nil
 		})
 
 		mockRepoTxs := repository.NewMockTransactionsRepoI(ctrl)
@@ -360,10 +515,9 @@ func TestGetFunds(t *testing.T) {
 			TransactionsRepo: mockRepoTxs,
 		}
 
-		userError, err := fundsUseCase.Get(&testBalanceOneGetLocal)
+		err := fundsUseCase.Get(context.Background(), &testBalanceOneGetLocal)
 
 		assert.NoError(t, err)
-		assert.Equal(t, false, userError)
 	})
 
 	t.Run("FundsAddInvalidUserId", func(t *testing.T) {
@@ -378,10 +532,10 @@ func TestGetFunds(t *testing.T) {
 			TransactionsRepo: mockRepoTxs,
 		}
 
-		userError, err := fundsUseCase.Get(&testBalanceWrongGet)
+		err := fundsUseCase.Get(context.Background(), &testBalanceWrongGet)
 
 		assert.Error(t, err)
-		assert.Equal(t, true, userError)
+		assert.True(t, errors.Is(err, models.ErrValidation))
 	})
 
 	t.Run("DBErrorFirst", func(t *testing.T) {
@@ -389,7 +543,7 @@ func TestGetFunds(t *testing.T) {
 		defer ctrl.Finish()
 
 		mockRepoBalance := repository.NewMockBalanceRepoI(ctrl)
-		mockRepoBalance.EXPECT().GetBalanceByUserId(&testBalanceOneGet).Return(utils.SERVER_ERROR, errors.New("db error"))
+		mockRepoBalance.EXPECT().GetBalanceByUserId(gomock.Any(), &testBalanceOneGet).Return(errors.New("db error"))
 
 		mockRepoTxs := repository.NewMockTransactionsRepoI(ctrl)
 
@@ -398,10 +552,9 @@ func TestGetFunds(t *testing.T) {
 			TransactionsRepo: mockRepoTxs,
 		}
 
-		userError, err := fundsUseCase.Get(&testBalanceOneGet)
+		err := fundsUseCase.Get(context.Background(), &testBalanceOneGet)
 
 		assert.Error(t, err)
-		assert.Equal(t, false, userError)
 	})
 
 	t.Run("DBErrorSecond", func(t *testing.T) {
@@ -409,8 +562,8 @@ func TestGetFunds(t *testing.T) {
 		defer ctrl.Finish()
 
 		mockRepoBalance := repository.NewMockBalanceRepoI(ctrl)
-		mockRepoBalance.EXPECT().GetBalanceByUserId(&testBalanceTwoGet).Return(utils.USER_ERROR, errors.New("no user"))
-		mockRepoBalance.EXPECT().InsertUser(&testBalanceTwoGet).Return(errors.New("db error"))
+		mockRepoBalance.EXPECT().GetBalanceByUserId(gomock.Any(), &testBalanceTwoGet).Return(models.Errorf(models.ErrNotFound, "no user"))
+		mockRepoBalance.EXPECT().InsertUser(gomock.Any(), &testBalanceTwoGet).Return(errors.New("db error"))
 
 		mockRepoTxs := repository.NewMockTransactionsRepoI(ctrl)
 
@@ -419,10 +572,9 @@ func TestGetFunds(t *testing.T) {
 			TransactionsRepo: mockRepoTxs,
 		}
 
-		userError, err := fundsUseCase.Get(&testBalanceTwoGet)
+		err := fundsUseCase.Get(context.Background(), &testBalanceTwoGet)
 
 		assert.Error(t, err)
-		assert.Equal(t, false, userError)
 	})
 
 	t.Run("NewUser", func(t *testing.T) {
@@ -430,8 +582,8 @@ func TestGetFunds(t *testing.T) {
 		defer ctrl.Finish()
 
 		mockRepoBalance := repository.NewMockBalanceRepoI(ctrl)
-		mockRepoBalance.EXPECT().GetBalanceByUserId(&testBalanceTwoGet).Return(utils.USER_ERROR, errors.New("no user"))
-		mockRepoBalance.EXPECT().InsertUser(&testBalanceTwoGet).Return(nil)
+		mockRepoBalance.EXPECT().GetBalanceByUserId(gomock.Any(), &testBalanceTwoGet).Return(models.Errorf(models.ErrNotFound, "no user"))
+		mockRepoBalance.EXPECT().InsertUser(gomock.Any(), &testBalanceTwoGet).Return(nil)
 
 		mockRepoTxs := repository.NewMockTransactionsRepoI(ctrl)
 
@@ -440,61 +592,60 @@ func TestGetFunds(t *testing.T) {
 			TransactionsRepo: mockRepoTxs,
 		}
 
-		userError, err := fundsUseCase.Get(&testBalanceTwoGet)
+		err := fundsUseCase.Get(context.Background(), &testBalanceTwoGet)
 
 		assert.NoError(t, err)
-		assert.Equal(t, false, userError)
 	})
-}
-
-func TestTransferFunds(t *testing.T) {
-	t.Run("FundsTransferOK", func(t *testing.T) {
+	t.Run("InvalidCurrency", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
 
 		mockRepoBalance := repository.NewMockBalanceRepoI(ctrl)
-		var testBalanceOneGetLocal = models.Balance{
-			UserId: 1,
+		mockRepoTxs := repository.NewMockTransactionsRepoI(ctrl)
+
+		fundsUseCase := FundsUC{
+			BalanceRepo:      mockRepoBalance,
+			TransactionsRepo: mockRepoTxs,
 		}
 
-		var testBalanceTwoGetLocal = models.Balance{
-			UserId: 2,
+		err := fundsUseCase.Get(context.Background(), &models.Balance{UserId: 1, Currency: "dollars"})
+
+		assert.Error(t, err)
+		assert.True(t, errors.Is(err, models.ErrValidation))
+	})
+}
+
+func TestGetAllFunds(t *testing.T) {
+	t.Run("FundsGetAllOK", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		wallets := []models.Balance{
+			{UserId: 1, Balance: models.MoneyFromUnits(100), Currency: "EUR"},
+			{UserId: 1, Balance: models.MoneyFromUnits(1000), Currency: utils.CURRENCY},
 		}
 
-		firstMock := mockRepoBalance.EXPECT().GetBalanceByUserId(&testBalanceTwoGetLocal).DoAndReturn(func(user *models.Balance) (int, error) {
-			user.Balance = 10
-			return utils.NO_ERROR, nil
-		})
-		mockRepoBalance.EXPECT().GetBalanceByUserId(&testBalanceOneGetLocal).DoAndReturn(func(user *models.Balance) (int, error) {
-			user.Balance = 100
-			return utils.NO_ERROR, nil
-		}).After(firstMock)
+		mockRepoBalance := repository.NewMockBalanceRepoI(ctrl)
+		mockRepoBalance.EXPECT().GetBalancesByUserId(gomock.Any(), &testUserOne).Return(wallets, nil)
 
 		mockRepoTxs := repository.NewMockTransactionsRepoI(ctrl)
-		mockRepoTxs.EXPECT().Add(&testTxOneTransfer).Return(nil)
 
 		fundsUseCase := FundsUC{
 			BalanceRepo:      mockRepoBalance,
 			TransactionsRepo: mockRepoTxs,
 		}
 
-		userError, lowFunds, err := fundsUseCase.Transfer(&testTxOneTransfer)
+		balances, err := fundsUseCase.GetAll(context.Background(), &testUserOne)
 
 		assert.NoError(t, err)
-		assert.Equal(t, utils.GetOperationType("Transfer"), testTxOneTransfer.OperationType)
-		assert.Equal(t, float64(0), testTxOneTransfer.BalanceFrom)
-		assert.Equal(t, float64(110), testTxOneTransfer.Balance)
-		assert.Equal(t, false, userError)
-		assert.Equal(t, false, lowFunds)
+		assert.Equal(t, wallets, balances)
 	})
 
-	t.Run("DBErrorFirst", func(t *testing.T) {
+	t.Run("InvalidUserId", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
 
 		mockRepoBalance := repository.NewMockBalanceRepoI(ctrl)
-		mockRepoBalance.EXPECT().GetBalanceByUserId(&testBalanceTwoGet).Return(utils.SERVER_ERROR, errors.New("db error"))
-
 		mockRepoTxs := repository.NewMockTransactionsRepoI(ctrl)
 
 		fundsUseCase := FundsUC{
@@ -502,20 +653,18 @@ func TestTransferFunds(t *testing.T) {
 			TransactionsRepo: mockRepoTxs,
 		}
 
-		userError, lowFunds, err := fundsUseCase.Transfer(&testTxOneTransfer)
+		_, err := fundsUseCase.GetAll(context.Background(), &testUserWrong)
 
 		assert.Error(t, err)
-		assert.Equal(t, false, lowFunds)
-		assert.Equal(t, false, userError)
+		assert.True(t, errors.Is(err, models.ErrValidation))
 	})
 
-	t.Run("DBErrorSecond", func(t *testing.T) {
+	t.Run("DBError", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
 
 		mockRepoBalance := repository.NewMockBalanceRepoI(ctrl)
-		mockRepoBalance.EXPECT().GetBalanceByUserId(&testBalanceTwoGet).Return(utils.USER_ERROR, errors.New("no user"))
-		mockRepoBalance.EXPECT().InsertUser(&testBalanceTwoGet).Return(errors.New("db error"))
+		mockRepoBalance.EXPECT().GetBalancesByUserId(gomock.Any(), &testUserOne).Return([]models.Balance{}, errors.New("db error"))
 
 		mockRepoTxs := repository.NewMockTransactionsRepoI(ctrl)
 
@@ -524,20 +673,18 @@ func TestTransferFunds(t *testing.T) {
 			TransactionsRepo: mockRepoTxs,
 		}
 
-		userError, lowFunds, err := fundsUseCase.Transfer(&testTxOneTransfer)
+		_, err := fundsUseCase.GetAll(context.Background(), &testUserOne)
 
 		assert.Error(t, err)
-		assert.Equal(t, false, lowFunds)
-		assert.Equal(t, false, userError)
 	})
 
-	t.Run("DBErrorThird", func(t *testing.T) {
+	t.Run("NewUser", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
 
 		mockRepoBalance := repository.NewMockBalanceRepoI(ctrl)
-		mockRepoBalance.EXPECT().GetBalanceByUserId(&testBalanceTwoGet).Return(utils.NO_ERROR, nil)
-		mockRepoBalance.EXPECT().GetBalanceByUserId(&testBalanceOneGet).Return(utils.SERVER_ERROR, errors.New("db error"))
+		mockRepoBalance.EXPECT().GetBalancesByUserId(gomock.Any(), &testUserTwo).Return([]models.Balance{}, nil)
+		mockRepoBalance.EXPECT().InsertUser(gomock.Any(), &testBalanceTwoGet).Return(nil)
 
 		mockRepoTxs := repository.NewMockTransactionsRepoI(ctrl)
 
@@ -546,90 +693,320 @@ func TestTransferFunds(t *testing.T) {
 			TransactionsRepo: mockRepoTxs,
 		}
 
-		userError, lowFunds, err := fundsUseCase.Transfer(&testTxOneTransfer)
+		balances, err := fundsUseCase.GetAll(context.Background(), &testUserTwo)
 
-		assert.Error(t, err)
-		assert.Equal(t, false, lowFunds)
-		assert.Equal(t, false, userError)
+		assert.NoError(t, err)
+		assert.Equal(t, []models.Balance{testBalanceTwoGet}, balances)
 	})
+}
 
-	t.Run("DBErrorFourth", func(t *testing.T) {
+func TestGetFundsAsOf(t *testing.T) {
+	asOf := time.Date(2026, 3, 31, 23, 59, 59, 0, time.UTC)
+
+	t.Run("AsOfOK", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
 
-		mockRepoBalance := repository.NewMockBalanceRepoI(ctrl)
-		mockRepoBalance.EXPECT().GetBalanceByUserId(&testBalanceTwoGet).Return(utils.NO_ERROR, nil)
-		mockRepoBalance.EXPECT().GetBalanceByUserId(&testBalanceOneGet).Return(utils.USER_ERROR, errors.New("no user"))
-		mockRepoBalance.EXPECT().InsertUser(&testBalanceOneGet).Return(errors.New("db error"))
+		wallets := []models.Balance{
+			{UserId: 1, Balance: models.MoneyFromUnits(100), Available: models.MoneyFromUnits(100), Currency: "EUR"},
+			{UserId: 1, Balance: models.MoneyFromUnits(10), Available: models.MoneyFromUnits(10), Currency: utils.CURRENCY},
+		}
 
 		mockRepoTxs := repository.NewMockTransactionsRepoI(ctrl)
+		mockRepoTxs.EXPECT().GetBalancesAsOf(gomock.Any(), &testUserOne, asOf).Return(wallets, nil)
 
 		fundsUseCase := FundsUC{
-			BalanceRepo:      mockRepoBalance,
+			BalanceRepo:      repository.NewMockBalanceRepoI(ctrl),
+			TransactionsRepo: mockRepoTxs,
+		}
+
+		balances, err := fundsUseCase.GetAsOf(context.Background(), &testUserOne, asOf)
+
+		assert.NoError(t, err)
+		assert.Equal(t, wallets, balances)
+	})
+
+	t.Run("NoActivityBefore", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepoTxs := repository.NewMockTransactionsRepoI(ctrl)
+		mockRepoTxs.EXPECT().GetBalancesAsOf(gomock.Any(), &testUserOne, asOf).Return([]models.Balance{}, nil)
+
+		fundsUseCase := FundsUC{
+			BalanceRepo:      repository.NewMockBalanceRepoI(ctrl),
+			TransactionsRepo: mockRepoTxs,
+		}
+
+		balances, err := fundsUseCase.GetAsOf(context.Background(), &testUserOne, asOf)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []models.Balance{{UserId: 1, Currency: utils.CURRENCY}}, balances)
+	})
+
+	t.Run("NoActivityBeforeInCurrency", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		user := models.UserId{UserId: 1, Currency: "usd"}
+		mockRepoTxs := repository.NewMockTransactionsRepoI(ctrl)
+		mockRepoTxs.EXPECT().GetBalancesAsOf(gomock.Any(), &models.UserId{UserId: 1, Currency: "USD"}, asOf).Return([]models.Balance{}, nil)
+
+		fundsUseCase := FundsUC{
+			BalanceRepo:      repository.NewMockBalanceRepoI(ctrl),
 			TransactionsRepo: mockRepoTxs,
 		}
 
-		userError, lowFunds, err := fundsUseCase.Transfer(&testTxOneTransfer)
+		balances, err := fundsUseCase.GetAsOf(context.Background(), &user, asOf)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []models.Balance{{UserId: 1, Currency: "USD"}}, balances)
+	})
+
+	t.Run("InvalidUserId", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		fundsUseCase := FundsUC{
+			BalanceRepo:      repository.NewMockBalanceRepoI(ctrl),
+			TransactionsRepo: repository.NewMockTransactionsRepoI(ctrl),
+		}
+
+		_, err := fundsUseCase.GetAsOf(context.Background(), &testUserWrong, asOf)
 
 		assert.Error(t, err)
-		assert.Equal(t, false, lowFunds)
-		assert.Equal(t, false, userError)
+		assert.True(t, errors.Is(err, models.ErrValidation))
 	})
 
-	t.Run("WrongSum", func(t *testing.T) {
+	t.Run("WrongCurrency", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
 
-		mockRepoBalance := repository.NewMockBalanceRepoI(ctrl)
-		mockRepoBalance.EXPECT().GetBalanceByUserId(&testBalanceTwoGet).Return(utils.NO_ERROR, nil)
-		mockRepoBalance.EXPECT().GetBalanceByUserId(&testBalanceOneGet).Return(utils.NO_ERROR, nil)
+		fundsUseCase := FundsUC{
+			BalanceRepo:      repository.NewMockBalanceRepoI(ctrl),
+			TransactionsRepo: repository.NewMockTransactionsRepoI(ctrl),
+		}
+
+		_, err := fundsUseCase.GetAsOf(context.Background(), &models.UserId{UserId: 1, Currency: "RUBLES"}, asOf)
+
+		assert.Error(t, err)
+		assert.True(t, errors.Is(err, models.ErrValidation))
+	})
+
+	t.Run("DBError", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
 
 		mockRepoTxs := repository.NewMockTransactionsRepoI(ctrl)
+		mockRepoTxs.EXPECT().GetBalancesAsOf(gomock.Any(), &testUserOne, asOf).Return([]models.Balance{}, errors.New("db error"))
 
 		fundsUseCase := FundsUC{
-			BalanceRepo:      mockRepoBalance,
+			BalanceRepo:      repository.NewMockBalanceRepoI(ctrl),
 			TransactionsRepo: mockRepoTxs,
 		}
 
-		userError, lowFunds, err := fundsUseCase.Transfer(&testTxWrongTransfer)
+		_, err := fundsUseCase.GetAsOf(context.Background(), &testUserOne, asOf)
 
 		assert.Error(t, err)
-		assert.Equal(t, true, userError)
-		assert.Equal(t, false, lowFunds)
+	})
+}
+
+func TestTransferFunds(t *testing.T) {
+	t.Run("FundsTransferOK", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		var testBalanceOneGetLocal = models.Balance{
+			UserId:   1,
+			Currency: utils.CURRENCY,
+		}
+
+		var testBalanceTwoGetLocal = models.Balance{
+			UserId:   2,
+			Currency: utils.CURRENCY,
+		}
+
+		mockWork := repository.NewMockWorkI(ctrl)
+		mockWork.EXPECT().LockBalances(&testBalanceTwoGetLocal, &testBalanceOneGetLocal).DoAndReturn(func(balances ...*models.Balance) error {
+			balances[0].Balance = models.MoneyFromUnits(10)
+			balances[0].Available = models.MoneyFromUnits(10)
+			balances[1].Balance = models.MoneyFromUnits(100)
+			balances[1].Available = models.MoneyFromUnits(100)
+			return nil
+		})
+		mockWork.EXPECT().AddTransaction(&testTxOneTransfer).Return(nil)
+
+		mockUnitOfWork := repository.NewMockUnitOfWorkI(ctrl)
+		mockUnitOfWork.EXPECT().Do(gomock.Any(), gomock.Any()).DoAndReturn(runWork(mockWork))
+
+		fundsUseCase := FundsUC{
+			UnitOfWork: mockUnitOfWork,
+		}
+
+		err := fundsUseCase.Transfer(context.Background(), &testTxOneTransfer)
+
+		assert.NoError(t, err)
+		assert.Equal(t, utils.GetOperationType("Transfer"), testTxOneTransfer.OperationType)
+		assert.Equal(t, models.MoneyFromUnits(0), testTxOneTransfer.BalanceFrom)
+		assert.Equal(t, models.MoneyFromUnits(110), testTxOneTransfer.Balance)
+	})
+
+	t.Run("DBErrorFirst", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockWork := repository.NewMockWorkI(ctrl)
+		mockWork.EXPECT().LockBalances(&testBalanceTwoGet, &testBalanceOneGet).Return(errors.New("db error"))
+
+		mockUnitOfWork := repository.NewMockUnitOfWorkI(ctrl)
+		mockUnitOfWork.EXPECT().Do(gomock.Any(), gomock.Any()).DoAndReturn(runWork(mockWork))
+
+		fundsUseCase := FundsUC{
+			UnitOfWork: mockUnitOfWork,
+		}
+
+		err := fundsUseCase.Transfer(context.Background(), &testTxOneTransfer)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("DBErrorSecond", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockWork := repository.NewMockWorkI(ctrl)
+		mockWork.EXPECT().LockBalances(&testBalanceTwoGet, &testBalanceOneGet).DoAndReturn(func(balances ...*models.Balance) error {
+			balances[1].Balance = models.MoneyFromUnits(1000)
+			balances[1].Available = models.MoneyFromUnits(1000)
+			return nil
+		})
+		mockWork.EXPECT().AddTransaction(&testTxOneTransfer).Return(errors.New("db error"))
+
+		mockUnitOfWork := repository.NewMockUnitOfWorkI(ctrl)
+		mockUnitOfWork.EXPECT().Do(gomock.Any(), gomock.Any()).DoAndReturn(runWork(mockWork))
+
+		fundsUseCase := FundsUC{
+			UnitOfWork: mockUnitOfWork,
+		}
+
+		err := fundsUseCase.Transfer(context.Background(), &testTxOneTransfer)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("WrongSum", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockUnitOfWork := repository.NewMockUnitOfWorkI(ctrl)
+
+		fundsUseCase := FundsUC{
+			UnitOfWork: mockUnitOfWork,
+		}
+
+		err := fundsUseCase.Transfer(context.Background(), &testTxWrongTransfer)
+
+		assert.Error(t, err)
+		assert.True(t, errors.Is(err, models.ErrValidation))
 		assert.Equal(t, "sum must be positive", err.Error())
 	})
 
+	t.Run("ForgedFieldsDropped", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockWork := repository.NewMockWorkI(ctrl)
+		mockWork.EXPECT().LockBalances(gomock.Any(), gomock.Any()).DoAndReturn(func(balances ...*models.Balance) error {
+			balances[1].Balance = models.MoneyFromUnits(100)
+			balances[1].Available = models.MoneyFromUnits(100)
+			return nil
+		})
+		mockWork.EXPECT().AddTransaction(gomock.Any()).DoAndReturn(func(tx *models.Transaction) error {
+			assert.Equal(t, 0.0, tx.Rate)
+			assert.Equal(t, utils.ERROR_ID, tx.ReversedId)
+			return nil
+		})
+
+		mockUnitOfWork := repository.NewMockUnitOfWorkI(ctrl)
+		mockUnitOfWork.EXPECT().Do(gomock.Any(), gomock.Any()).DoAndReturn(runWork(mockWork))
+
+		fundsUseCase := FundsUC{
+			UnitOfWork: mockUnitOfWork,
+		}
+
+		err := fundsUseCase.Transfer(context.Background(), &models.Transaction{UserId: 2, UserFromId: 1,
+			Sum: models.MoneyFromUnits(10), Rate: 73.5, ReversedId: 3})
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("SameUser", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockWork := repository.NewMockWorkI(ctrl)
+
+		mockUnitOfWork := repository.NewMockUnitOfWorkI(ctrl)
+		mockUnitOfWork.EXPECT().Do(gomock.Any(), gomock.Any()).DoAndReturn(runWork(mockWork))
+
+		fundsUseCase := FundsUC{
+			UnitOfWork: mockUnitOfWork,
+		}
+
+		err := fundsUseCase.Transfer(context.Background(), &models.Transaction{UserId: 1, UserFromId: 1,
+			Sum: models.MoneyFromUnits(30)})
+
+		assert.Error(t, err)
+		assert.True(t, errors.Is(err, models.ErrValidation))
+	})
+
+	t.Run("SumFinerThanCurrency", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		fundsUseCase := FundsUC{
+			UnitOfWork: repository.NewMockUnitOfWorkI(ctrl),
+		}
+
+		sum, _ := models.ParseMoney("30.01")
+		err := fundsUseCase.Transfer(context.Background(), &models.Transaction{UserId: 1, UserFromId: 2, Sum: sum,
+			Currency: "JPY", CurrencyFrom: "JPY"})
+
+		assert.True(t, errors.Is(err, models.ErrInvalidMoney))
+	})
+
 	t.Run("LowFunds", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
 
 		var testBalanceOneGetLocal = models.Balance{
-			UserId: 1,
+			UserId:   1,
+			Currency: utils.CURRENCY,
 		}
 
 		var testBalanceTwoGetLocal = models.Balance{
-			UserId: 2,
+			UserId:   2,
+			Currency: utils.CURRENCY,
 		}
 
-		mockRepoBalance := repository.NewMockBalanceRepoI(ctrl)
-		mockRepoBalance.EXPECT().GetBalanceByUserId(&testBalanceTwoGetLocal).DoAndReturn(func(user *models.Balance) (int, error) {
-			user.Balance = 50
-			return utils.NO_ERROR, nil
+		mockWork := repository.NewMockWorkI(ctrl)
+		mockWork.EXPECT().LockBalances(&testBalanceTwoGetLocal, &testBalanceOneGetLocal).DoAndReturn(func(balances ...*models.Balance) error {
+			balances[1].Balance = models.MoneyFromUnits(50)
+			balances[1].Available = models.MoneyFromUnits(50)
+			return nil
 		})
-		mockRepoBalance.EXPECT().GetBalanceByUserId(&testBalanceOneGetLocal).Return(utils.NO_ERROR, nil)
 
-		mockRepoTxs := repository.NewMockTransactionsRepoI(ctrl)
+		mockUnitOfWork := repository.NewMockUnitOfWorkI(ctrl)
+		mockUnitOfWork.EXPECT().Do(gomock.Any(), gomock.Any()).DoAndReturn(runWork(mockWork))
 
 		fundsUseCase := FundsUC{
-			BalanceRepo:      mockRepoBalance,
-			TransactionsRepo: mockRepoTxs,
+			UnitOfWork: mockUnitOfWork,
 		}
 
-		userError, lowFunds, err := fundsUseCase.Transfer(&testTxOneTransfer)
+		err := fundsUseCase.Transfer(context.Background(), &testTxOneTransfer)
 
 		assert.Error(t, err)
-		assert.Equal(t, false, userError)
-		assert.Equal(t, true, lowFunds)
+		assert.True(t, errors.Is(err, models.ErrInsufficientFunds))
 		assert.Equal(t, "user doesn't have enough funds", err.Error())
 	})
 }
@@ -641,28 +1018,21 @@ func TestGetTransactions(t *testing.T) {
 
 		mockRepoBalance := repository.NewMockBalanceRepoI(ctrl)
 
-		var testBalanceOneGetLocal = models.Balance{
-			UserId: 1,
-		}
-
-		mockRepoBalance.EXPECT().GetBalanceByUserId(&testBalanceOneGetLocal).DoAndReturn(func(user *models.Balance) (int, error) {
-			user.Balance = 1000
-			return utils.NO_ERROR, nil
-		})
+		mockRepoBalance.EXPECT().GetBalancesByUserId(gomock.Any(), &testUserOne).Return([]models.Balance{testBalanceOneGet}, nil)
 
 		mockRepoTxs := repository.NewMockTransactionsRepoI(ctrl)
-		mockRepoTxs.EXPECT().GetUserTransactions(&testUserOne, limitInt, since, sort, descBool).Return(testTransactions, utils.NO_ERROR, nil)
+		mockRepoTxs.EXPECT().GetUserTransactions(gomock.Any(), &testFilter).Return(testTransactions, nil)
 
 		fundsUseCase := FundsUC{
 			BalanceRepo:      mockRepoBalance,
 			TransactionsRepo: mockRepoTxs,
 		}
 
-		userError, txs, err := fundsUseCase.GetTransactions(&testUserOne, limitInt, since, sort, descBool)
+		page, err := fundsUseCase.GetTransactions(context.Background(), &testUserOne, &testQuery, since, cursor)
 
 		assert.NoError(t, err)
-		assert.Equal(t, testTransactions, txs)
-		assert.Equal(t, false, userError)
+		assert.Equal(t, models.Transactions(testTransactions), page.Items)
+		assert.Equal(t, "", page.NextCursor)
 	})
 
 	t.Run("InvalidUserId", func(t *testing.T) {
@@ -677,12 +1047,12 @@ func TestGetTransactions(t *testing.T) {
 			TransactionsRepo: mockRepoTxs,
 		}
 
-		userError, txs, err := fundsUseCase.GetTransactions(&testUserWrong, limitInt, since, sort, descBool)
+		page, err := fundsUseCase.GetTransactions(context.Background(), &testUserWrong, &testQuery, since, cursor)
 
 		assert.Error(t, err)
 		assert.Equal(t, "incorrect user id", err.Error())
-		assert.Equal(t, true, userError)
-		assert.Equal(t, []models.Transaction{}, txs)
+		assert.True(t, errors.Is(err, models.ErrValidation))
+		assert.Equal(t, models.Transactions([]models.Transaction{}), page.Items)
 	})
 
 	t.Run("DBErrorFirst", func(t *testing.T) {
@@ -690,7 +1060,7 @@ func TestGetTransactions(t *testing.T) {
 		defer ctrl.Finish()
 
 		mockRepoBalance := repository.NewMockBalanceRepoI(ctrl)
-		mockRepoBalance.EXPECT().GetBalanceByUserId(&testBalanceOneGet).Return(utils.SERVER_ERROR, errors.New("db error"))
+		mockRepoBalance.EXPECT().GetBalancesByUserId(gomock.Any(), &testUserOne).Return([]models.Balance{}, errors.New("db error"))
 
 		mockRepoTxs := repository.NewMockTransactionsRepoI(ctrl)
 
@@ -699,10 +1069,9 @@ func TestGetTransactions(t *testing.T) {
 			TransactionsRepo: mockRepoTxs,
 		}
 
-		userError, _, err := fundsUseCase.GetTransactions(&testUserOne, limitInt, since, sort, descBool)
+		_, err := fundsUseCase.GetTransactions(context.Background(), &testUserOne, &testQuery, since, cursor)
 
 		assert.Error(t, err)
-		assert.Equal(t, false, userError)
 	})
 
 	t.Run("DBErrorSecond", func(t *testing.T) {
@@ -710,20 +1079,19 @@ func TestGetTransactions(t *testing.T) {
 		defer ctrl.Finish()
 
 		mockRepoBalance := repository.NewMockBalanceRepoI(ctrl)
-		mockRepoBalance.EXPECT().GetBalanceByUserId(&testBalanceOneGet).Return(utils.NO_ERROR, nil)
+		mockRepoBalance.EXPECT().GetBalancesByUserId(gomock.Any(), &testUserOne).Return([]models.Balance{testBalanceOneGet}, nil)
 
 		mockRepoTxs := repository.NewMockTransactionsRepoI(ctrl)
-		mockRepoTxs.EXPECT().GetUserTransactions(&testUserOne, limitInt, since, sort, descBool).Return([]models.Transaction{}, utils.SERVER_ERROR, errors.New("db error"))
+		mockRepoTxs.EXPECT().GetUserTransactions(gomock.Any(), &testFilter).Return([]models.Transaction{}, errors.New("db error"))
 
 		fundsUseCase := FundsUC{
 			BalanceRepo:      mockRepoBalance,
 			TransactionsRepo: mockRepoTxs,
 		}
 
-		userError, _, err := fundsUseCase.GetTransactions(&testUserOne, limitInt, since, sort, descBool)
+		_, err := fundsUseCase.GetTransactions(context.Background(), &testUserOne, &testQuery, since, cursor)
 
 		assert.Error(t, err)
-		assert.Equal(t, false, userError)
 	})
 
 	t.Run("NewUser", func(t *testing.T) {
@@ -731,7 +1099,7 @@ func TestGetTransactions(t *testing.T) {
 		defer ctrl.Finish()
 
 		mockRepoBalance := repository.NewMockBalanceRepoI(ctrl)
-		mockRepoBalance.EXPECT().GetBalanceByUserId(&testBalanceOneGet).Return(utils.USER_ERROR, errors.New("user doens't exist"))
+		mockRepoBalance.EXPECT().GetBalancesByUserId(gomock.Any(), &testUserOne).Return([]models.Balance{}, nil)
 
 		mockRepoTxs := repository.NewMockTransactionsRepoI(ctrl)
 
@@ -740,11 +1108,10 @@ func TestGetTransactions(t *testing.T) {
 			TransactionsRepo: mockRepoTxs,
 		}
 
-		userError, txs, err := fundsUseCase.GetTransactions(&testUserOne, limitInt, since, sort, descBool)
+		page, err := fundsUseCase.GetTransactions(context.Background(), &testUserOne, &testQuery, since, cursor)
 
 		assert.NoError(t, err)
-		assert.Equal(t, []models.Transaction{}, txs)
-		assert.Equal(t, false, userError)
+		assert.Equal(t, models.Transactions([]models.Transaction{}), page.Items)
 	})
 
 	t.Run("UserError", func(t *testing.T) {
@@ -752,20 +1119,348 @@ func TestGetTransactions(t *testing.T) {
 		defer ctrl.Finish()
 
 		mockRepoBalance := repository.NewMockBalanceRepoI(ctrl)
-		mockRepoBalance.EXPECT().GetBalanceByUserId(&testBalanceOneGet).Return(utils.NO_ERROR, nil)
+		mockRepoBalance.EXPECT().GetBalancesByUserId(gomock.Any(), &testUserOne).Return([]models.Balance{testBalanceOneGet}, nil)
+
+		mockRepoTxs := repository.NewMockTransactionsRepoI(ctrl)
+		mockRepoTxs.EXPECT().GetUserTransactions(gomock.Any(), &testFilter).Return([]models.Transaction{}, models.Errorf(models.ErrValidation, "Wrong sort param"))
+
+		fundsUseCase := FundsUC{
+			BalanceRepo:      mockRepoBalance,
+			TransactionsRepo: mockRepoTxs,
+		}
+
+		page, err := fundsUseCase.GetTransactions(context.Background(), &testUserOne, &testQuery, since, cursor)
+
+		assert.Error(t, err)
+		assert.Equal(t, models.Transactions([]models.Transaction{}), page.Items)
+		assert.True(t, errors.Is(err, models.ErrValidation))
+	})
+
+	t.Run("NextPage", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepoBalance := repository.NewMockBalanceRepoI(ctrl)
+		mockRepoBalance.EXPECT().GetBalancesByUserId(gomock.Any(), &testUserOne).Return([]models.Balance{testBalanceOneGet}, nil)
+
+		mockRepoTxs := repository.NewMockTransactionsRepoI(ctrl)
+		mockRepoTxs.EXPECT().GetUserTransactions(gomock.Any(), &models.TransactionsFilter{UserId: testUserOne.UserId, Sort: "sum", Desc: true, Limit: 2}).Return(testTransactions, nil)
+
+		fundsUseCase := FundsUC{
+			BalanceRepo:      mockRepoBalance,
+			TransactionsRepo: mockRepoTxs,
+		}
+
+		page, err := fundsUseCase.GetTransactions(context.Background(), &testUserOne, &testSumQuery, since, cursor)
+
+		assert.NoError(t, err)
+		assert.Equal(t, models.Transactions(testTransactions[:1]), page.Items)
+
+		next, err := models.DecodeCursor(page.NextCursor)
+		assert.NoError(t, err)
+		assert.Equal(t, models.NewCursor("sum", true, &testTransactions[0]).Id, next.Id)
+		assert.Equal(t, testTransactions[0].Sum, next.Sum)
+
+		mockRepoBalance.EXPECT().GetBalancesByUserId(gomock.Any(), &testUserOne).Return([]models.Balance{testBalanceOneGet}, nil)
+		mockRepoTxs.EXPECT().GetUserTransactions(gomock.Any(), &models.TransactionsFilter{UserId: testUserOne.UserId, Sort: "sum", Desc: true, Limit: 2, After: &next}).Return(testTransactions[1:], nil)
+
+		page, err = fundsUseCase.GetTransactions(context.Background(), &testUserOne, &testSumQuery, since, page.NextCursor)
+
+		assert.NoError(t, err)
+		assert.Equal(t, models.Transactions(testTransactions[1:]), page.Items)
+		assert.Equal(t, "", page.NextCursor)
+	})
+
+	t.Run("CursorForOtherSort", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		fundsUseCase := FundsUC{
+			BalanceRepo:      repository.NewMockBalanceRepoI(ctrl),
+			TransactionsRepo: repository.NewMockTransactionsRepoI(ctrl),
+		}
+
+		otherCursor := models.NewCursor("date", false, &testTransactions[0]).Encode()
+		_, err := fundsUseCase.GetTransactions(context.Background(), &testUserOne, &testSumQuery, since, otherCursor)
+
+		assert.True(t, errors.Is(err, models.ErrInvalidCursor))
+		assert.True(t, errors.Is(err, models.ErrValidation))
+	})
+
+	t.Run("MalformedCursor", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		fundsUseCase := FundsUC{
+			BalanceRepo:      repository.NewMockBalanceRepoI(ctrl),
+			TransactionsRepo: repository.NewMockTransactionsRepoI(ctrl),
+		}
+
+		_, err := fundsUseCase.GetTransactions(context.Background(), &testUserOne, &testSumQuery, since, "not a cursor")
+
+		assert.Equal(t, models.ErrInvalidCursor, err)
+		assert.True(t, errors.Is(err, models.ErrValidation))
+	})
+
+	t.Run("WrongLimit", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		fundsUseCase := FundsUC{
+			BalanceRepo:      repository.NewMockBalanceRepoI(ctrl),
+			TransactionsRepo: repository.NewMockTransactionsRepoI(ctrl),
+		}
+
+		_, err := fundsUseCase.GetTransactions(context.Background(), &testUserOne, &models.TransactionsFilter{Limit: 0}, since, cursor)
+
+		assert.Error(t, err)
+		assert.True(t, errors.Is(err, models.ErrValidation))
+	})
+
+	t.Run("SinceBounds", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		sinceTime := time.Date(2020, 8, 2, 0, 10, 9, 0, time.UTC)
+		mockRepoBalance := repository.NewMockBalanceRepoI(ctrl)
+		mockRepoBalance.EXPECT().GetBalancesByUserId(gomock.Any(), &testUserOne).Return([]models.Balance{testBalanceOneGet}, nil).Times(2)
+
+		mockRepoTxs := repository.NewMockTransactionsRepoI(ctrl)
+		mockRepoTxs.EXPECT().GetUserTransactions(gomock.Any(), &models.TransactionsFilter{UserId: testUserOne.UserId, Limit: limitInt,
+			From: sinceTime}).Return(testTransactions, nil)
+		mockRepoTxs.EXPECT().GetUserTransactions(gomock.Any(), &models.TransactionsFilter{UserId: testUserOne.UserId, Limit: limitInt,
+			To: sinceTime, Desc: true}).Return(testTransactions, nil)
+
+		fundsUseCase := FundsUC{
+			BalanceRepo:      mockRepoBalance,
+			TransactionsRepo: mockRepoTxs,
+		}
+
+		_, err := fundsUseCase.GetTransactions(context.Background(), &testUserOne, &testQuery, sinceTime.Format(time.RFC3339Nano), cursor)
+		assert.NoError(t, err)
+		_, err = fundsUseCase.GetTransactions(context.Background(), &testUserOne, &models.TransactionsFilter{Limit: limitInt, Desc: true},
+			sinceTime.Format(time.RFC3339Nano), cursor)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Filters", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		after := time.Date(2020, 8, 1, 0, 0, 0, 0, time.UTC)
+		before := time.Date(2020, 9, 1, 0, 0, 0, 0, time.UTC)
+		sinceTime := time.Date(2020, 8, 15, 0, 0, 0, 0, time.UTC)
+		filter := models.TransactionsFilter{Limit: limitInt, OperationTypes: []int{3}, Counterparty: 42,
+			MinSum: models.MoneyFromUnits(1000), From: after, To: before}
+
+		mockRepoBalance := repository.NewMockBalanceRepoI(ctrl)
+		mockRepoBalance.EXPECT().GetBalancesByUserId(gomock.Any(), &testUserOne).Return([]models.Balance{testBalanceOneGet}, nil)
 
 		mockRepoTxs := repository.NewMockTransactionsRepoI(ctrl)
-		mockRepoTxs.EXPECT().GetUserTransactions(&testUserOne, limitInt, since, sort, descBool).Return([]models.Transaction{}, utils.USER_ERROR, errors.New("user error"))
+		mockRepoTxs.EXPECT().GetUserTransactions(gomock.Any(), &models.TransactionsFilter{UserId: testUserOne.UserId, Limit: limitInt,
+			OperationTypes: []int{3}, Counterparty: 42, MinSum: models.MoneyFromUnits(1000), From: sinceTime, To: before}).
+			Return(testTransactions, nil)
 
 		fundsUseCase := FundsUC{
 			BalanceRepo:      mockRepoBalance,
 			TransactionsRepo: mockRepoTxs,
 		}
 
-		userError, txs, err := fundsUseCase.GetTransactions(&testUserOne, limitInt, since, sort, descBool)
+		page, err := fundsUseCase.GetTransactions(context.Background(), &testUserOne, &filter, sinceTime.Format(time.RFC3339Nano), cursor)
+
+		assert.NoError(t, err)
+		assert.Equal(t, models.Transactions(testTransactions), page.Items)
+		assert.Equal(t, after, filter.From)
+	})
+
+	t.Run("WrongFilters", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		fundsUseCase := FundsUC{
+			BalanceRepo:      repository.NewMockBalanceRepoI(ctrl),
+			TransactionsRepo: repository.NewMockTransactionsRepoI(ctrl),
+		}
+
+		after := time.Date(2020, 9, 1, 0, 0, 0, 0, time.UTC)
+		before := time.Date(2020, 8, 1, 0, 0, 0, 0, time.UTC)
+		filters := []models.TransactionsFilter{
+			{Limit: limitInt, OperationTypes: []int{1, 9}},
+			{Limit: limitInt, Counterparty: testUserOne.UserId},
+			{Limit: limitInt, Counterparty: -1},
+			{Limit: limitInt, MinSum: models.MoneyFromUnits(-1)},
+			{Limit: limitInt, MinSum: models.MoneyFromUnits(20), MaxSum: models.MoneyFromUnits(10)},
+			{Limit: limitInt, From: after, To: before},
+		}
+		for _, filter := range filters {
+			_, err := fundsUseCase.GetTransactions(context.Background(), &testUserOne, &filter, since, cursor)
+
+			assert.Error(t, err)
+			assert.True(t, errors.Is(err, models.ErrValidation))
+		}
+	})
+
+	t.Run("WrongSince", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		fundsUseCase := FundsUC{
+			BalanceRepo:      repository.NewMockBalanceRepoI(ctrl),
+			TransactionsRepo: repository.NewMockTransactionsRepoI(ctrl),
+		}
+
+		_, err := fundsUseCase.GetTransactions(context.Background(), &testUserOne, &testQuery, "yesterday", cursor)
+
+		assert.Error(t, err)
+		assert.True(t, errors.Is(err, models.ErrValidation))
+	})
+}
+
+func TestExportTransactions(t *testing.T) {
+	t.Run("ExportOK", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepoTxs := repository.NewMockTransactionsRepoI(ctrl)
+		mockRepoTxs.EXPECT().ExportUserTransactions(gomock.Any(), &testFilter, gomock.Any()).
+			DoAndReturn(func(ctx context.Context, filter *models.TransactionsFilter, each func(tx *models.Transaction) error) error {
+				for i := range testTransactions {
+					err := each(&testTransactions[i])
+					if err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+
+		fundsUseCase := FundsUC{
+			TransactionsRepo: mockRepoTxs,
+		}
+
+		exported := make([]models.Transaction, 0)
+		err := fundsUseCase.ExportTransactions(context.Background(), &testUserOne, &testQuery, since, func(tx *models.Transaction) error {
+			exported = append(exported, *tx)
+			return nil
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, testTransactions, exported)
+	})
+
+	t.Run("WrongFilter", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		fundsUseCase := FundsUC{
+			TransactionsRepo: repository.NewMockTransactionsRepoI(ctrl),
+		}
+
+		err := fundsUseCase.ExportTransactions(context.Background(), &testUserOne, &models.TransactionsFilter{Limit: limitInt,
+			OperationTypes: []int{7}}, since, nil)
+
+		assert.Error(t, err)
+		assert.True(t, errors.Is(err, models.ErrValidation))
+	})
+
+	t.Run("DBError", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepoTxs := repository.NewMockTransactionsRepoI(ctrl)
+		mockRepoTxs.EXPECT().ExportUserTransactions(gomock.Any(), &testFilter, gomock.Any()).Return(errors.New("db error"))
+
+		fundsUseCase := FundsUC{
+			TransactionsRepo: mockRepoTxs,
+		}
+
+		err := fundsUseCase.ExportTransactions(context.Background(), &testUserOne, &testQuery, since, nil)
 
 		assert.Error(t, err)
-		assert.Equal(t, []models.Transaction{}, txs)
-		assert.Equal(t, true, userError)
+	})
+}
+
+func TestIdempotency(t *testing.T) {
+	t.Run("NewKey", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		tx := models.Transaction{UserId: 1, Sum: models.MoneyFromUnits(100), IdempotencyKey: "key"}
+
+		mockWork := repository.NewMockWorkI(ctrl)
+		mockWork.EXPECT().LockBalances(gomock.Any()).Return(nil)
+		mockWork.EXPECT().GetTransactionByIdempotencyKey(gomock.Any(), 1).Return(models.Errorf(models.ErrNotFound, "no key"))
+		mockWork.EXPECT().GetSystemAccount(gomock.Any()).Return(nil)
+		mockWork.EXPECT().AddTransaction(&tx).Return(nil)
+
+		mockUnitOfWork := repository.NewMockUnitOfWorkI(ctrl)
+		mockUnitOfWork.EXPECT().Do(gomock.Any(), gomock.Any()).DoAndReturn(runWork(mockWork))
+
+		fundsUseCase := FundsUC{
+			UnitOfWork: mockUnitOfWork,
+		}
+
+		err := fundsUseCase.Add(context.Background(), &tx)
+
+		assert.NoError(t, err)
+		assert.NotEmpty(t, tx.RequestHash)
+		assert.Equal(t, models.MoneyFromUnits(100), tx.Balance)
+	})
+
+	t.Run("Replay", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		tx := models.Transaction{UserId: 1, Sum: models.MoneyFromUnits(100), IdempotencyKey: "key", OperationType: utils.GetOperationType("Withdraw"),
+			Currency: utils.CURRENCY}
+		hash := requestHash(&tx)
+
+		mockWork := repository.NewMockWorkI(ctrl)
+		mockWork.EXPECT().LockBalances(gomock.Any()).Return(nil)
+		mockWork.EXPECT().GetTransactionByIdempotencyKey(gomock.Any(), 1).DoAndReturn(func(original *models.Transaction, callerId int) error {
+			original.Id = 7
+			original.UserId = 1
+			original.Sum = models.MoneyFromUnits(100)
+			original.Balance = models.MoneyFromUnits(900)
+			original.RequestHash = hash
+			return nil
+		})
+
+		mockUnitOfWork := repository.NewMockUnitOfWorkI(ctrl)
+		mockUnitOfWork.EXPECT().Do(gomock.Any(), gomock.Any()).DoAndReturn(runWork(mockWork))
+
+		fundsUseCase := FundsUC{
+			UnitOfWork: mockUnitOfWork,
+		}
+
+		err := fundsUseCase.Withdraw(context.Background(), &tx)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 7, tx.Id)
+		assert.Equal(t, models.MoneyFromUnits(900), tx.Balance)
+	})
+
+	t.Run("Conflict", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		tx := models.Transaction{UserId: 2, UserFromId: 1, Sum: models.MoneyFromUnits(100), IdempotencyKey: "key"}
+
+		mockWork := repository.NewMockWorkI(ctrl)
+		mockWork.EXPECT().LockBalances(gomock.Any(), gomock.Any()).Return(nil)
+		mockWork.EXPECT().GetTransactionByIdempotencyKey(gomock.Any(), 1).DoAndReturn(func(original *models.Transaction, callerId int) error {
+			original.RequestHash = "other"
+			return nil
+		})
+
+		mockUnitOfWork := repository.NewMockUnitOfWorkI(ctrl)
+		mockUnitOfWork.EXPECT().Do(gomock.Any(), gomock.Any()).DoAndReturn(runWork(mockWork))
+
+		fundsUseCase := FundsUC{
+			UnitOfWork: mockUnitOfWork,
+		}
+
+		err := fundsUseCase.Transfer(context.Background(), &tx)
+
+		assert.Equal(t, ErrIdempotencyConflict, err)
 	})
 }
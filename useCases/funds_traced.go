@@ -0,0 +1,85 @@
+package useCases
+
+import (
+	"context"
+	"github.com/saskamegaprogrammist/userBalanceService/models"
+	"github.com/saskamegaprogrammist/userBalanceService/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"time"
+)
+
+// TracedFundsUC runs every call of the wrapped use case in a span named like FundsUC.Add
+
+type TracedFundsUC struct {
+	FundsUC FundsUCInterface
+}
+
+func userAttribute(userId int) attribute.KeyValue {
+	return attribute.Int("balance.user_id", userId)
+}
+
+func (fundsUC *TracedFundsUC) Add(ctx context.Context, tx *models.Transaction) error {
+	ctx, span := tracing.Start(ctx, "FundsUC.Add", userAttribute(tx.UserId))
+	err := fundsUC.FundsUC.Add(ctx, tx)
+	tracing.End(span, err)
+	return err
+}
+
+func (fundsUC *TracedFundsUC) Withdraw(ctx context.Context, tx *models.Transaction) error {
+	ctx, span := tracing.Start(ctx, "FundsUC.Withdraw", userAttribute(tx.UserId))
+	err := fundsUC.FundsUC.Withdraw(ctx, tx)
+	tracing.End(span, err)
+	return err
+}
+
+func (fundsUC *TracedFundsUC) Get(ctx context.Context, balance *models.Balance) error {
+	ctx, span := tracing.Start(ctx, "FundsUC.Get", userAttribute(balance.UserId))
+	err := fundsUC.FundsUC.Get(ctx, balance)
+	tracing.End(span, err)
+	return err
+}
+
+func (fundsUC *TracedFundsUC) GetAll(ctx context.Context, user *models.UserId) ([]models.Balance, error) {
+	ctx, span := tracing.Start(ctx, "FundsUC.GetAll", userAttribute(user.UserId))
+	balances, err := fundsUC.FundsUC.GetAll(ctx, user)
+	tracing.End(span, err)
+	return balances, err
+}
+
+func (fundsUC *TracedFundsUC) GetAsOf(ctx context.Context, user *models.UserId, asOf time.Time) ([]models.Balance, error) {
+	ctx, span := tracing.Start(ctx, "FundsUC.GetAsOf", userAttribute(user.UserId))
+	balances, err := fundsUC.FundsUC.GetAsOf(ctx, user, asOf)
+	tracing.End(span, err)
+	return balances, err
+}
+
+func (fundsUC *TracedFundsUC) Transfer(ctx context.Context, tx *models.Transaction) error {
+	ctx, span := tracing.Start(ctx, "FundsUC.Transfer", userAttribute(tx.UserFromId),
+		attribute.Int("balance.user_to_id", tx.UserId))
+	err := fundsUC.FundsUC.Transfer(ctx, tx)
+	tracing.End(span, err)
+	return err
+}
+
+func (fundsUC *TracedFundsUC) Reverse(ctx context.Context, reversal *models.Reversal, tx *models.Transaction) error {
+	ctx, span := tracing.Start(ctx, "FundsUC.Reverse")
+	err := fundsUC.FundsUC.Reverse(ctx, reversal, tx)
+	tracing.End(span, err)
+	return err
+}
+
+func (fundsUC *TracedFundsUC) GetTransactions(ctx context.Context, user *models.UserId, filter *models.TransactionsFilter,
+	since string, cursor string) (models.TransactionsPage, error) {
+	ctx, span := tracing.Start(ctx, "FundsUC.GetTransactions", userAttribute(user.UserId))
+	page, err := fundsUC.FundsUC.GetTransactions(ctx, user, filter, since, cursor)
+	tracing.End(span, err)
+	return page, err
+}
+
+func (fundsUC *TracedFundsUC) ExportTransactions(ctx context.Context, user *models.UserId, filter *models.TransactionsFilter,
+	since string, each func(tx *models.Transaction) error) error {
+	ctx, span := tracing.Start(ctx, "FundsUC.ExportTransactions", userAttribute(user.UserId))
+	err := fundsUC.FundsUC.ExportTransactions(ctx, user, filter, since, each)
+	tracing.End(span, err)
+	return err
+}
@@ -0,0 +1,196 @@
+package useCases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/logger"
+	"github.com/saskamegaprogrammist/userBalanceService/metrics"
+	"github.com/saskamegaprogrammist/userBalanceService/models"
+	"github.com/saskamegaprogrammist/userBalanceService/repository"
+	"github.com/saskamegaprogrammist/userBalanceService/utils"
+	"time"
+)
+
+var ErrHoldNotFound = models.NewError(models.ErrNotFound, "this hold doesn't exist")
+var ErrHoldNotActive = models.NewError(models.ErrConflict, "hold is not active")
+
+type HoldsUC struct {
+	HoldsRepo  repository.HoldsRepoI
+	UnitOfWork repository.UnitOfWorkI
+	HoldTTL    time.Duration
+}
+
+func (holdsUC *HoldsUC) Hold(ctx context.Context, hold *models.Hold) error {
+	if hold.UserId <= utils.ERROR_ID {
+		return models.ErrInvalidUser
+	}
+	if hold.Sum <= 0 {
+		return models.Errorf(models.ErrValidation, "sum must be positive")
+	}
+	err := normalizeWalletCurrency(&hold.Currency, hold.Sum)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	if hold.Expires.IsZero() {
+		hold.Expires = now.Add(holdsUC.HoldTTL)
+	} else if !hold.Expires.After(now) {
+		return models.Errorf(models.ErrValidation, "expiration time must be in the future")
+	}
+
+	err = holdsUC.UnitOfWork.Do(ctx, func(work repository.WorkI) error {
+		balance := models.Balance{UserId: hold.UserId, Currency: hold.Currency}
+		err := work.LockBalances(&balance)
+		if err != nil {
+			return err
+		}
+		if balance.Available < hold.Sum {
+			return models.Errorf(models.ErrInsufficientFunds, "you don't have enough funds")
+		}
+
+		hold.Captured = 0
+		hold.Status = utils.HOLD_ACTIVE
+		hold.Created = now
+		return work.AddHold(hold)
+	})
+	if errors.Is(err, models.ErrInsufficientFunds) {
+		metrics.ObserveInsufficientFunds("Hold")
+	}
+	return err
+}
+
+// captures tx.Sum from the hold, or the whole hold if tx.Sum is zero,
+// the rest of the hold is released
+
+func (holdsUC *HoldsUC) Capture(ctx context.Context, hold *models.Hold, tx *models.Transaction) error {
+	if tx.Sum < 0 {
+		return models.Errorf(models.ErrValidation, "sum must be positive")
+	}
+
+	err := holdsUC.UnitOfWork.Do(ctx, func(work repository.WorkI) error {
+		err := getHold(work, hold)
+		if err != nil {
+			return err
+		}
+		sum := tx.Sum
+		if sum == 0 {
+			sum = hold.Sum
+		}
+		if sum > hold.Sum {
+			return models.Errorf(models.ErrValidation, "sum is larger than the held amount")
+		}
+		err = checkSum(hold.Currency, sum)
+		if err != nil {
+			return err
+		}
+
+		balance := models.Balance{UserId: hold.UserId, Currency: hold.Currency}
+		err = work.LockBalances(&balance)
+		if err != nil {
+			return err
+		}
+		// the capture is dated once the wallet is locked, so that it isn't older than the transactions
+		// committed while it waited, nor captures a hold that expired in the meantime
+		now := time.Now()
+		if !hold.Expires.After(now) {
+			return fmt.Errorf("%w: hold has expired", ErrHoldNotActive)
+		}
+		hold.Status = utils.HOLD_CAPTURED
+		hold.Captured = sum
+		err = work.CloseHold(hold)
+		if err != nil {
+			return err
+		}
+		cashOut, err := systemAccount(work, utils.CASH_OUT_ACCOUNT, hold.Currency)
+		if err != nil {
+			return err
+		}
+
+		*tx = models.Transaction{
+			UserId:        hold.UserId,
+			Currency:      hold.Currency,
+			OperationType: utils.GetOperationType("Capture"),
+			Sum:           sum,
+			Balance:       balance.Balance - sum,
+			Created:       now,
+			Postings:      postings(&cashOut, &balance, sum),
+		}
+		return work.AddTransaction(tx)
+	})
+	observeOperation("Capture", tx, false, err)
+	return err
+}
+
+func (holdsUC *HoldsUC) Void(ctx context.Context, hold *models.Hold) error {
+	return holdsUC.UnitOfWork.Do(ctx, func(work repository.WorkI) error {
+		return releaseHold(work, hold, utils.HOLD_VOIDED)
+	})
+}
+
+func (holdsUC *HoldsUC) ExpireHolds(ctx context.Context) error {
+	holds, err := holdsUC.HoldsRepo.GetExpiredHolds(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+	var lastErr error
+	for i := range holds {
+		err = holdsUC.UnitOfWork.Do(ctx, func(work repository.WorkI) error {
+			return releaseHold(work, &holds[i], utils.HOLD_EXPIRED)
+		})
+		if err != nil && !errors.Is(err, ErrHoldNotActive) {
+			logger.Errorf("Failed to expire hold %d: %v", holds[i].Id, err)
+			lastErr = err
+		}
+	}
+	return lastErr
+}
+
+// locks the hold row and checks that it is still active
+
+func getHold(work repository.WorkI, hold *models.Hold) error {
+	if hold.Id == utils.ERROR_ID {
+		return ErrHoldNotFound
+	}
+	err := work.GetHold(hold)
+	if errors.Is(err, models.ErrNotFound) {
+		return ErrHoldNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if hold.Status != utils.HOLD_ACTIVE {
+		return fmt.Errorf("%w: hold is already %s", ErrHoldNotActive, hold.Status)
+	}
+	return nil
+}
+
+func releaseHold(work repository.WorkI, hold *models.Hold, status string) error {
+	err := getHold(work, hold)
+	if err != nil {
+		return err
+	}
+	balance := models.Balance{UserId: hold.UserId, Currency: hold.Currency}
+	err = work.LockBalances(&balance)
+	if err != nil {
+		return err
+	}
+	hold.Status = status
+	return work.CloseHold(hold)
+}
+
+func RunHoldsExpiry(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		err := uc.HoldsUC.ExpireHolds(ctx)
+		if err != nil {
+			logger.Errorf("Failed to expire holds: %v", err)
+		}
+	}
+}
@@ -0,0 +1,12 @@
+package useCases
+
+import (
+	"context"
+	"github.com/saskamegaprogrammist/userBalanceService/models"
+)
+
+type HoldsUCInterface interface {
+	Hold(ctx context.Context, hold *models.Hold) error
+	Capture(ctx context.Context, hold *models.Hold, tx *models.Transaction) error
+	Void(ctx context.Context, hold *models.Hold) error
+}
@@ -0,0 +1,77 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: useCases/holds_interface.go
+
+// Package mock_useCases is a generated GoMock package.
+package useCases
+
+import (
+	context "context"
+	gomock "github.com/golang/mock/gomock"
+	models "github.com/saskamegaprogrammist/userBalanceService/models"
+	reflect "reflect"
+)
+
+// MockHoldsUCInterface is a mock of HoldsUCInterface interface
+type MockHoldsUCInterface struct {
+	ctrl     *gomock.Controller
+	recorder *MockHoldsUCInterfaceMockRecorder
+}
+
+// MockHoldsUCInterfaceMockRecorder is the mock recorder for MockHoldsUCInterface
+type MockHoldsUCInterfaceMockRecorder struct {
+	mock *MockHoldsUCInterface
+}
+
+// NewMockHoldsUCInterface creates a new mock instance
+func NewMockHoldsUCInterface(ctrl *gomock.Controller) *MockHoldsUCInterface {
+	mock := &MockHoldsUCInterface{ctrl: ctrl}
+	mock.recorder = &MockHoldsUCInterfaceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockHoldsUCInterface) EXPECT() *MockHoldsUCInterfaceMockRecorder {
+	return m.recorder
+}
+
+// Hold mocks base method
+func (m *MockHoldsUCInterface) Hold(ctx context.Context, hold *models.Hold) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Hold", ctx, hold)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Hold indicates an expected call of Hold
+func (mr *MockHoldsUCInterfaceMockRecorder) Hold(ctx, hold interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Hold", reflect.TypeOf((*MockHoldsUCInterface)(nil).Hold), ctx, hold)
+}
+
+// Capture mocks base method
+func (m *MockHoldsUCInterface) Capture(ctx context.Context, hold *models.Hold, tx *models.Transaction) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Capture", ctx, hold, tx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Capture indicates an expected call of Capture
+func (mr *MockHoldsUCInterfaceMockRecorder) Capture(ctx, hold, tx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Capture", reflect.TypeOf((*MockHoldsUCInterface)(nil).Capture), ctx, hold, tx)
+}
+
+// Void mocks base method
+func (m *MockHoldsUCInterface) Void(ctx context.Context, hold *models.Hold) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Void", ctx, hold)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Void indicates an expected call of Void
+func (mr *MockHoldsUCInterfaceMockRecorder) Void(ctx, hold interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Void", reflect.TypeOf((*MockHoldsUCInterface)(nil).Void), ctx, hold)
+}
@@ -0,0 +1,403 @@
+package useCases
+
+import (
+	"context"
+	"errors"
+	"github.com/golang/mock/gomock"
+	"github.com/saskamegaprogrammist/userBalanceService/models"
+	"github.com/saskamegaprogrammist/userBalanceService/repository"
+	"github.com/saskamegaprogrammist/userBalanceService/utils"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+var testHoldActive = models.Hold{
+	Id:       1,
+	UserId:   1,
+	Currency: utils.CURRENCY,
+	Sum:      models.MoneyFromUnits(100),
+	Status:   utils.HOLD_ACTIVE,
+	Expires:  time.Now().Add(time.Hour),
+}
+
+func TestHold(t *testing.T) {
+	t.Run("HoldOK", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		hold := models.Hold{UserId: 1, Sum: models.MoneyFromUnits(100)}
+
+		mockWork := repository.NewMockWorkI(ctrl)
+		mockWork.EXPECT().LockBalances(&testBalanceOneGet).DoAndReturn(lockWallets(heldWallet(1000, 100)))
+		mockWork.EXPECT().AddHold(&hold).Return(nil)
+
+		mockUnitOfWork := repository.NewMockUnitOfWorkI(ctrl)
+		mockUnitOfWork.EXPECT().Do(gomock.Any(), gomock.Any()).DoAndReturn(runWork(mockWork))
+
+		holdsUseCase := HoldsUC{
+			UnitOfWork: mockUnitOfWork,
+			HoldTTL:    time.Hour,
+		}
+
+		err := holdsUseCase.Hold(context.Background(), &hold)
+
+		assert.NoError(t, err)
+		assert.Equal(t, utils.HOLD_ACTIVE, hold.Status)
+		assert.Equal(t, utils.CURRENCY, hold.Currency)
+		assert.True(t, hold.Expires.After(time.Now().Add(time.Hour-time.Minute)))
+	})
+
+	t.Run("LowAvailableFunds", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		hold := models.Hold{UserId: 1, Sum: models.MoneyFromUnits(100)}
+
+		mockWork := repository.NewMockWorkI(ctrl)
+		mockWork.EXPECT().LockBalances(&testBalanceOneGet).DoAndReturn(lockWallets(heldWallet(1000, 99)))
+
+		mockUnitOfWork := repository.NewMockUnitOfWorkI(ctrl)
+		mockUnitOfWork.EXPECT().Do(gomock.Any(), gomock.Any()).DoAndReturn(runWork(mockWork))
+
+		holdsUseCase := HoldsUC{
+			UnitOfWork: mockUnitOfWork,
+		}
+
+		err := holdsUseCase.Hold(context.Background(), &hold)
+
+		assert.Error(t, err)
+		assert.True(t, errors.Is(err, models.ErrInsufficientFunds))
+	})
+
+	t.Run("InvalidUserId", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		holdsUseCase := HoldsUC{
+			UnitOfWork: repository.NewMockUnitOfWorkI(ctrl),
+		}
+
+		err := holdsUseCase.Hold(context.Background(), &models.Hold{UserId: 0, Sum: models.MoneyFromUnits(100)})
+
+		assert.Error(t, err)
+		assert.True(t, errors.Is(err, models.ErrValidation))
+	})
+
+	t.Run("ExpiresInPast", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		holdsUseCase := HoldsUC{
+			UnitOfWork: repository.NewMockUnitOfWorkI(ctrl),
+		}
+
+		err := holdsUseCase.Hold(context.Background(), &models.Hold{UserId: 1, Sum: models.MoneyFromUnits(100),
+			Expires: time.Now().Add(-time.Hour)})
+
+		assert.Error(t, err)
+		assert.True(t, errors.Is(err, models.ErrValidation))
+	})
+
+	t.Run("SumFinerThanCurrency", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		holdsUseCase := HoldsUC{
+			UnitOfWork: repository.NewMockUnitOfWorkI(ctrl),
+		}
+
+		sum, _ := models.ParseMoney("99.90")
+		err := holdsUseCase.Hold(context.Background(), &models.Hold{UserId: 1, Sum: sum, Currency: "JPY"})
+
+		assert.True(t, errors.Is(err, models.ErrInvalidMoney))
+	})
+}
+
+func TestCapture(t *testing.T) {
+	t.Run("CaptureFull", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		hold := models.Hold{Id: 1}
+		var tx models.Transaction
+
+		mockWork := repository.NewMockWorkI(ctrl)
+		mockWork.EXPECT().GetHold(&hold).DoAndReturn(getStored(testHoldActive))
+		mockWork.EXPECT().LockBalances(gomock.Any()).DoAndReturn(lockWallets(heldWallet(1000, 900)))
+		mockWork.EXPECT().CloseHold(&hold).Return(nil)
+		mockWork.EXPECT().GetSystemAccount(gomock.Any()).Return(nil)
+		mockWork.EXPECT().AddTransaction(&tx).Return(nil)
+
+		mockUnitOfWork := repository.NewMockUnitOfWorkI(ctrl)
+		mockUnitOfWork.EXPECT().Do(gomock.Any(), gomock.Any()).DoAndReturn(runWork(mockWork))
+
+		holdsUseCase := HoldsUC{
+			UnitOfWork: mockUnitOfWork,
+		}
+
+		err := holdsUseCase.Capture(context.Background(), &hold, &tx)
+
+		assert.NoError(t, err)
+		assert.Equal(t, utils.HOLD_CAPTURED, hold.Status)
+		assert.Equal(t, models.MoneyFromUnits(100), hold.Captured)
+		assert.Equal(t, utils.GetOperationType("Capture"), tx.OperationType)
+		assert.Equal(t, models.MoneyFromUnits(100), tx.Sum)
+		assert.Equal(t, models.MoneyFromUnits(900), tx.Balance)
+	})
+
+	t.Run("CapturePartial", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		hold := models.Hold{Id: 1}
+		tx := models.Transaction{Sum: models.MoneyFromUnits(40)}
+
+		mockWork := repository.NewMockWorkI(ctrl)
+		mockWork.EXPECT().GetHold(&hold).DoAndReturn(getStored(testHoldActive))
+		mockWork.EXPECT().LockBalances(gomock.Any()).DoAndReturn(lockWallets(heldWallet(1000, 900)))
+		mockWork.EXPECT().CloseHold(&hold).Return(nil)
+		mockWork.EXPECT().GetSystemAccount(gomock.Any()).Return(nil)
+		mockWork.EXPECT().AddTransaction(&tx).Return(nil)
+
+		mockUnitOfWork := repository.NewMockUnitOfWorkI(ctrl)
+		mockUnitOfWork.EXPECT().Do(gomock.Any(), gomock.Any()).DoAndReturn(runWork(mockWork))
+
+		holdsUseCase := HoldsUC{
+			UnitOfWork: mockUnitOfWork,
+		}
+
+		err := holdsUseCase.Capture(context.Background(), &hold, &tx)
+
+		assert.NoError(t, err)
+		assert.Equal(t, models.MoneyFromUnits(40), hold.Captured)
+		assert.Equal(t, models.MoneyFromUnits(960), tx.Balance)
+	})
+
+	t.Run("CaptureTooMuch", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		hold := models.Hold{Id: 1}
+		tx := models.Transaction{Sum: models.MoneyFromUnits(101)}
+
+		mockWork := repository.NewMockWorkI(ctrl)
+		mockWork.EXPECT().GetHold(&hold).DoAndReturn(getStored(testHoldActive))
+
+		mockUnitOfWork := repository.NewMockUnitOfWorkI(ctrl)
+		mockUnitOfWork.EXPECT().Do(gomock.Any(), gomock.Any()).DoAndReturn(runWork(mockWork))
+
+		holdsUseCase := HoldsUC{
+			UnitOfWork: mockUnitOfWork,
+		}
+
+		err := holdsUseCase.Capture(context.Background(), &hold, &tx)
+
+		assert.Error(t, err)
+		assert.True(t, errors.Is(err, models.ErrValidation))
+	})
+
+	t.Run("CaptureExpired", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		hold := models.Hold{Id: 1}
+		expired := testHoldActive
+		expired.Expires = time.Now().Add(-time.Minute)
+
+		mockWork := repository.NewMockWorkI(ctrl)
+		mockWork.EXPECT().GetHold(&hold).DoAndReturn(getStored(expired))
+		mockWork.EXPECT().LockBalances(gomock.Any()).DoAndReturn(lockWallets(heldWallet(1000, 900)))
+
+		mockUnitOfWork := repository.NewMockUnitOfWorkI(ctrl)
+		mockUnitOfWork.EXPECT().Do(gomock.Any(), gomock.Any()).DoAndReturn(runWork(mockWork))
+
+		holdsUseCase := HoldsUC{
+			UnitOfWork: mockUnitOfWork,
+		}
+
+		err := holdsUseCase.Capture(context.Background(), &hold, &models.Transaction{})
+
+		assert.True(t, errors.Is(err, ErrHoldNotActive))
+	})
+
+	t.Run("ExpiredWhileLocking", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		hold := models.Hold{Id: 1}
+		expiring := testHoldActive
+		expiring.Expires = time.Now().Add(10 * time.Millisecond)
+		lock := lockWallets(heldWallet(1000, 900))
+
+		mockWork := repository.NewMockWorkI(ctrl)
+		mockWork.EXPECT().GetHold(&hold).DoAndReturn(getStored(expiring))
+		mockWork.EXPECT().LockBalances(gomock.Any()).DoAndReturn(func(balances ...*models.Balance) error {
+			time.Sleep(20 * time.Millisecond)
+			return lock(balances...)
+		})
+
+		mockUnitOfWork := repository.NewMockUnitOfWorkI(ctrl)
+		mockUnitOfWork.EXPECT().Do(gomock.Any(), gomock.Any()).DoAndReturn(runWork(mockWork))
+
+		holdsUseCase := HoldsUC{
+			UnitOfWork: mockUnitOfWork,
+		}
+
+		err := holdsUseCase.Capture(context.Background(), &hold, &models.Transaction{})
+
+		assert.True(t, errors.Is(err, ErrHoldNotActive))
+	})
+
+	t.Run("HoldNotFound", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		hold := models.Hold{Id: 2}
+
+		mockWork := repository.NewMockWorkI(ctrl)
+		mockWork.EXPECT().GetHold(&hold).Return(models.Errorf(models.ErrNotFound, "this hold doesn't exist"))
+
+		mockUnitOfWork := repository.NewMockUnitOfWorkI(ctrl)
+		mockUnitOfWork.EXPECT().Do(gomock.Any(), gomock.Any()).DoAndReturn(runWork(mockWork))
+
+		holdsUseCase := HoldsUC{
+			UnitOfWork: mockUnitOfWork,
+		}
+
+		err := holdsUseCase.Capture(context.Background(), &hold, &models.Transaction{})
+
+		assert.True(t, errors.Is(err, ErrHoldNotFound))
+	})
+}
+
+func TestVoid(t *testing.T) {
+	t.Run("VoidOK", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		hold := models.Hold{Id: 1}
+
+		mockWork := repository.NewMockWorkI(ctrl)
+		mockWork.EXPECT().GetHold(&hold).DoAndReturn(getStored(testHoldActive))
+		mockWork.EXPECT().LockBalances(gomock.Any()).Return(nil)
+		mockWork.EXPECT().CloseHold(&hold).Return(nil)
+
+		mockUnitOfWork := repository.NewMockUnitOfWorkI(ctrl)
+		mockUnitOfWork.EXPECT().Do(gomock.Any(), gomock.Any()).DoAndReturn(runWork(mockWork))
+
+		holdsUseCase := HoldsUC{
+			UnitOfWork: mockUnitOfWork,
+		}
+
+		err := holdsUseCase.Void(context.Background(), &hold)
+
+		assert.NoError(t, err)
+		assert.Equal(t, utils.HOLD_VOIDED, hold.Status)
+		assert.Equal(t, models.MoneyFromUnits(0), hold.Captured)
+	})
+
+	t.Run("AlreadyCaptured", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		hold := models.Hold{Id: 1}
+		captured := testHoldActive
+		captured.Status = utils.HOLD_CAPTURED
+
+		mockWork := repository.NewMockWorkI(ctrl)
+		mockWork.EXPECT().GetHold(&hold).DoAndReturn(getStored(captured))
+
+		mockUnitOfWork := repository.NewMockUnitOfWorkI(ctrl)
+		mockUnitOfWork.EXPECT().Do(gomock.Any(), gomock.Any()).DoAndReturn(runWork(mockWork))
+
+		holdsUseCase := HoldsUC{
+			UnitOfWork: mockUnitOfWork,
+		}
+
+		err := holdsUseCase.Void(context.Background(), &hold)
+
+		assert.True(t, errors.Is(err, ErrHoldNotActive))
+	})
+}
+
+func TestExpireHolds(t *testing.T) {
+	t.Run("ExpireOK", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		expired := testHoldActive
+		expired.Expires = time.Now().Add(-time.Minute)
+
+		mockRepoHolds := repository.NewMockHoldsRepoI(ctrl)
+		mockRepoHolds.EXPECT().GetExpiredHolds(gomock.Any(), gomock.Any()).Return([]models.Hold{expired}, nil)
+
+		mockWork := repository.NewMockWorkI(ctrl)
+		mockWork.EXPECT().GetHold(gomock.Any()).DoAndReturn(getStored(expired))
+		mockWork.EXPECT().LockBalances(gomock.Any()).Return(nil)
+		mockWork.EXPECT().CloseHold(gomock.Any()).DoAndReturn(func(hold *models.Hold) error {
+			assert.Equal(t, utils.HOLD_EXPIRED, hold.Status)
+			return nil
+		})
+
+		mockUnitOfWork := repository.NewMockUnitOfWorkI(ctrl)
+		mockUnitOfWork.EXPECT().Do(gomock.Any(), gomock.Any()).DoAndReturn(runWork(mockWork))
+
+		holdsUseCase := HoldsUC{
+			HoldsRepo:  mockRepoHolds,
+			UnitOfWork: mockUnitOfWork,
+		}
+
+		err := holdsUseCase.ExpireHolds(context.Background())
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("AlreadyClosed", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		voided := testHoldActive
+		voided.Status = utils.HOLD_VOIDED
+
+		mockRepoHolds := repository.NewMockHoldsRepoI(ctrl)
+		mockRepoHolds.EXPECT().GetExpiredHolds(gomock.Any(), gomock.Any()).Return([]models.Hold{testHoldActive}, nil)
+
+		mockWork := repository.NewMockWorkI(ctrl)
+		mockWork.EXPECT().GetHold(gomock.Any()).DoAndReturn(getStored(voided))
+
+		mockUnitOfWork := repository.NewMockUnitOfWorkI(ctrl)
+		mockUnitOfWork.EXPECT().Do(gomock.Any(), gomock.Any()).DoAndReturn(runWork(mockWork))
+
+		holdsUseCase := HoldsUC{
+			HoldsRepo:  mockRepoHolds,
+			UnitOfWork: mockUnitOfWork,
+		}
+
+		err := holdsUseCase.ExpireHolds(context.Background())
+
+		assert.NoError(t, err)
+	})
+}
+
+func TestWithdrawHeldFunds(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	tx := models.Transaction{UserId: 1, Sum: models.MoneyFromUnits(100)}
+
+	mockWork := repository.NewMockWorkI(ctrl)
+	mockWork.EXPECT().LockBalances(&testBalanceOneGet).DoAndReturn(lockWallets(heldWallet(1000, 50)))
+
+	mockUnitOfWork := repository.NewMockUnitOfWorkI(ctrl)
+	mockUnitOfWork.EXPECT().Do(gomock.Any(), gomock.Any()).DoAndReturn(runWork(mockWork))
+
+	fundsUseCase := FundsUC{
+		UnitOfWork: mockUnitOfWork,
+	}
+
+	err := fundsUseCase.Withdraw(context.Background(), &tx)
+
+	assert.Error(t, err)
+	assert.True(t, errors.Is(err, models.ErrInsufficientFunds))
+}
@@ -0,0 +1,32 @@
+package useCases
+
+import (
+	"context"
+	"github.com/saskamegaprogrammist/userBalanceService/models"
+	"github.com/saskamegaprogrammist/userBalanceService/tracing"
+)
+
+type TracedHoldsUC struct {
+	HoldsUC HoldsUCInterface
+}
+
+func (holdsUC *TracedHoldsUC) Hold(ctx context.Context, hold *models.Hold) error {
+	ctx, span := tracing.Start(ctx, "HoldsUC.Hold", userAttribute(hold.UserId))
+	err := holdsUC.HoldsUC.Hold(ctx, hold)
+	tracing.End(span, err)
+	return err
+}
+
+func (holdsUC *TracedHoldsUC) Capture(ctx context.Context, hold *models.Hold, tx *models.Transaction) error {
+	ctx, span := tracing.Start(ctx, "HoldsUC.Capture")
+	err := holdsUC.HoldsUC.Capture(ctx, hold, tx)
+	tracing.End(span, err)
+	return err
+}
+
+func (holdsUC *TracedHoldsUC) Void(ctx context.Context, hold *models.Hold) error {
+	ctx, span := tracing.Start(ctx, "HoldsUC.Void")
+	err := holdsUC.HoldsUC.Void(ctx, hold)
+	tracing.End(span, err)
+	return err
+}
@@ -0,0 +1,70 @@
+package useCases
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/google/logger"
+	"github.com/saskamegaprogrammist/userBalanceService/models"
+	"github.com/saskamegaprogrammist/userBalanceService/repository"
+	"github.com/saskamegaprogrammist/userBalanceService/utils"
+	"time"
+)
+
+var ErrIdempotencyConflict = models.NewError(models.ErrConflict, "idempotency key was already used with a different request")
+
+func requestHash(tx *models.Transaction) string {
+	request := fmt.Sprintf("%d:%d:%d:%s:%v", tx.OperationType, tx.UserId, tx.UserFromId, tx.Currency, tx.Sum)
+	if tx.QuoteId != utils.ERROR_ID {
+		request += fmt.Sprintf(":%d:%s:%v", tx.QuoteId, tx.CurrencyFrom, tx.SumFrom)
+	}
+	hash := sha256.Sum256([]byte(request))
+	return hex.EncodeToString(hash[:])
+}
+
+// looks up a transaction stored earlier by the caller with the same idempotency key,
+// on a replay tx is replaced with the stored transaction
+
+func replayTransaction(work repository.WorkI, tx *models.Transaction, callerId int) (bool, error) {
+	if tx.IdempotencyKey == "" {
+		return false, nil
+	}
+	tx.RequestHash = requestHash(tx)
+
+	original := models.Transaction{IdempotencyKey: tx.IdempotencyKey}
+	err := work.GetTransactionByIdempotencyKey(&original, callerId)
+	if errors.Is(err, models.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if original.RequestHash != tx.RequestHash {
+		return false, ErrIdempotencyConflict
+	}
+	*tx = original
+	return true, nil
+}
+
+func (fundsUC *FundsUC) ExpireIdempotencyKeys(ctx context.Context) error {
+	return fundsUC.TransactionsRepo.ExpireIdempotencyKeys(ctx, time.Now().Add(-fundsUC.IdempotencyRetention))
+}
+
+func RunIdempotencyKeysExpiry(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		err := uc.FundsUC.ExpireIdempotencyKeys(ctx)
+		if err != nil {
+			logger.Errorf("Failed to expire idempotency keys: %v", err)
+		}
+	}
+}
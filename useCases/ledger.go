@@ -0,0 +1,38 @@
+package useCases
+
+import (
+	"github.com/saskamegaprogrammist/userBalanceService/models"
+	"github.com/saskamegaprogrammist/userBalanceService/repository"
+	"github.com/saskamegaprogrammist/userBalanceService/utils"
+)
+
+// moves sum from the debited account to the credited one
+
+func postings(credited *models.Balance, debited *models.Balance, sum models.Money) []models.Posting {
+	return []models.Posting{
+		{AccountId: credited.Id, Currency: credited.Currency, Amount: sum},
+		{AccountId: debited.Id, Currency: debited.Currency, Amount: -sum},
+	}
+}
+
+// the legs of a cross-currency transfer are balanced in each currency through the system exchange account:
+// it receives sumFrom from the sender and pays sum to the receiver
+
+func exchangePostings(work repository.WorkI, credited *models.Balance, debited *models.Balance, sum models.Money,
+	sumFrom models.Money) ([]models.Posting, error) {
+	exchangeFrom, err := systemAccount(work, utils.EXCHANGE_ACCOUNT, debited.Currency)
+	if err != nil {
+		return nil, err
+	}
+	exchange, err := systemAccount(work, utils.EXCHANGE_ACCOUNT, credited.Currency)
+	if err != nil {
+		return nil, err
+	}
+	return append(postings(&exchangeFrom, debited, sumFrom), postings(credited, &exchange, sum)...), nil
+}
+
+func systemAccount(work repository.WorkI, userId int, currency string) (models.Balance, error) {
+	account := models.Balance{UserId: userId, Currency: currency}
+	err := work.GetSystemAccount(&account)
+	return account, err
+}
@@ -0,0 +1,128 @@
+package useCases
+
+import (
+	"context"
+	"errors"
+	"github.com/golang/mock/gomock"
+	"github.com/saskamegaprogrammist/userBalanceService/models"
+	"github.com/saskamegaprogrammist/userBalanceService/repository"
+	"github.com/saskamegaprogrammist/userBalanceService/utils"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func getSystemAccount(id int) func(account *models.Balance) error {
+	return func(account *models.Balance) error {
+		account.Id = id
+		return nil
+	}
+}
+
+func sumPostings(postings []models.Posting) models.Money {
+	var total models.Money
+	for _, posting := range postings {
+		total += posting.Amount
+	}
+	return total
+}
+
+func TestPostings(t *testing.T) {
+	sum := models.MoneyFromUnits(100)
+
+	t.Run("Add", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		tx := models.Transaction{UserId: 1, Sum: sum}
+
+		mockWork := repository.NewMockWorkI(ctrl)
+		mockWork.EXPECT().LockBalances(gomock.Any()).DoAndReturn(lockWallets(wallet(1000)))
+		mockWork.EXPECT().GetSystemAccount(&models.Balance{UserId: utils.CASH_IN_ACCOUNT, Currency: utils.CURRENCY}).
+			DoAndReturn(getSystemAccount(1))
+		mockWork.EXPECT().AddTransaction(&tx).Return(nil)
+
+		mockUnitOfWork := repository.NewMockUnitOfWorkI(ctrl)
+		mockUnitOfWork.EXPECT().Do(gomock.Any(), gomock.Any()).DoAndReturn(runWork(mockWork))
+
+		fundsUseCase := FundsUC{
+			UnitOfWork: mockUnitOfWork,
+		}
+
+		err := fundsUseCase.Add(context.Background(), &tx)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []models.Posting{
+			{AccountId: 10, Currency: utils.CURRENCY, Amount: sum},
+			{AccountId: 1, Currency: utils.CURRENCY, Amount: -sum},
+		}, tx.Postings)
+	})
+
+	t.Run("Withdraw", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		tx := models.Transaction{UserId: 1, Sum: sum}
+
+		mockWork := repository.NewMockWorkI(ctrl)
+		mockWork.EXPECT().LockBalances(gomock.Any()).DoAndReturn(lockWallets(wallet(1000)))
+		mockWork.EXPECT().GetSystemAccount(&models.Balance{UserId: utils.CASH_OUT_ACCOUNT, Currency: utils.CURRENCY}).
+			DoAndReturn(getSystemAccount(2))
+		mockWork.EXPECT().AddTransaction(&tx).Return(nil)
+
+		mockUnitOfWork := repository.NewMockUnitOfWorkI(ctrl)
+		mockUnitOfWork.EXPECT().Do(gomock.Any(), gomock.Any()).DoAndReturn(runWork(mockWork))
+
+		fundsUseCase := FundsUC{
+			UnitOfWork: mockUnitOfWork,
+		}
+
+		err := fundsUseCase.Withdraw(context.Background(), &tx)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []models.Posting{
+			{AccountId: 2, Currency: utils.CURRENCY, Amount: sum},
+			{AccountId: 10, Currency: utils.CURRENCY, Amount: -sum},
+		}, tx.Postings)
+	})
+
+	t.Run("Transfer", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		tx := models.Transaction{UserId: 2, UserFromId: 1, Sum: sum}
+
+		mockWork := repository.NewMockWorkI(ctrl)
+		mockWork.EXPECT().LockBalances(gomock.Any(), gomock.Any()).DoAndReturn(lockWallets(wallet(1000), wallet(1000)))
+		mockWork.EXPECT().AddTransaction(&tx).Return(nil)
+
+		mockUnitOfWork := repository.NewMockUnitOfWorkI(ctrl)
+		mockUnitOfWork.EXPECT().Do(gomock.Any(), gomock.Any()).DoAndReturn(runWork(mockWork))
+
+		fundsUseCase := FundsUC{
+			UnitOfWork: mockUnitOfWork,
+		}
+
+		err := fundsUseCase.Transfer(context.Background(), &tx)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []models.Posting{
+			{AccountId: 20, Currency: utils.CURRENCY, Amount: sum},
+			{AccountId: 10, Currency: utils.CURRENCY, Amount: -sum},
+		}, tx.Postings)
+		assert.Equal(t, models.Money(0), sumPostings(tx.Postings))
+	})
+
+	t.Run("TransferToSystemAccount", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		fundsUseCase := FundsUC{
+			UnitOfWork: repository.NewMockUnitOfWorkI(ctrl),
+		}
+
+		err := fundsUseCase.Transfer(context.Background(), &models.Transaction{UserId: utils.CASH_IN_ACCOUNT, UserFromId: 1, Sum: sum})
+
+		assert.Error(t, err)
+		assert.True(t, errors.Is(err, models.ErrValidation))
+	})
+}
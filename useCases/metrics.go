@@ -0,0 +1,18 @@
+package useCases
+
+import (
+	"errors"
+	"github.com/saskamegaprogrammist/userBalanceService/metrics"
+	"github.com/saskamegaprogrammist/userBalanceService/models"
+)
+
+// counts the operation once its unit of work is committed, replays of stored transactions aren't counted
+
+func observeOperation(operation string, tx *models.Transaction, replayed bool, err error) {
+	switch {
+	case errors.Is(err, models.ErrInsufficientFunds):
+		metrics.ObserveInsufficientFunds(operation)
+	case err == nil && !replayed:
+		metrics.ObserveOperation(tx)
+	}
+}
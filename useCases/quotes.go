@@ -0,0 +1,97 @@
+package useCases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/saskamegaprogrammist/userBalanceService/models"
+	"github.com/saskamegaprogrammist/userBalanceService/repository"
+	"github.com/saskamegaprogrammist/userBalanceService/utils"
+	"time"
+)
+
+var ErrQuoteNotFound = models.NewError(models.ErrNotFound, "this quote doesn't exist")
+var ErrQuoteNotActive = models.NewError(models.ErrConflict, "quote can't be used")
+
+// prices quote.SumFrom in quote.Currency at the latest stored rate and locks the rate for QuoteTTL,
+// the rate must have been fetched at most QuoteRateMaxAge ago. Only quote.UserFromId can use the quote
+
+func (ratesUC *RatesUC) Quote(ctx context.Context, quote *models.Quote) error {
+	if quote.UserFromId <= utils.ERROR_ID {
+		return models.ErrInvalidUser
+	}
+	if quote.SumFrom <= 0 {
+		return models.Errorf(models.ErrValidation, "sum must be positive")
+	}
+	err := normalizeWalletCurrency(&quote.CurrencyFrom, quote.SumFrom)
+	if err != nil {
+		return err
+	}
+	err = normalizeWalletCurrency(&quote.Currency)
+	if err != nil {
+		return err
+	}
+	if quote.CurrencyFrom == quote.Currency {
+		return models.Errorf(models.ErrValidation, "currencies of a quote must differ")
+	}
+
+	rate, err := ratesUC.latestRate(ctx, quote.CurrencyFrom, quote.Currency, ratesUC.QuoteRateMaxAge)
+	if err != nil {
+		return err
+	}
+	currency, err := models.GetCurrency(quote.Currency)
+	if err != nil {
+		return err
+	}
+	quote.Sum = currency.Convert(quote.SumFrom, rate.Rate)
+	if quote.Sum <= 0 {
+		return models.Errorf(models.ErrValidation, "sum is too small to convert")
+	}
+	quote.Rate = rate.Rate
+	quote.RateId = rate.Id
+	quote.TransactionId = 0
+	quote.Created = time.Now()
+	quote.Expires = quote.Created.Add(ratesUC.QuoteTTL)
+	return ratesUC.RatesRepo.AddQuote(ctx, quote)
+}
+
+// locks the quote of tx and fills the legs of tx from it, currencies and sums set in the transfer
+// request must match the quote. Quotes of other senders are answered as missing
+
+func applyQuote(work repository.WorkI, tx *models.Transaction, quote *models.Quote) error {
+	quote.Id = tx.QuoteId
+	err := work.GetQuote(quote)
+	if errors.Is(err, models.ErrNotFound) {
+		return ErrQuoteNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if quote.UserFromId != tx.UserFromId {
+		return ErrQuoteNotFound
+	}
+	if tx.CurrencyFrom != "" && tx.CurrencyFrom != quote.CurrencyFrom ||
+		tx.Currency != "" && tx.Currency != quote.Currency ||
+		tx.SumFrom != 0 && tx.SumFrom != quote.SumFrom ||
+		tx.Sum != 0 && tx.Sum != quote.Sum {
+		return models.Errorf(models.ErrValidation, "transfer doesn't match quote %d", quote.Id)
+	}
+	tx.CurrencyFrom = quote.CurrencyFrom
+	tx.SumFrom = quote.SumFrom
+	tx.Currency = quote.Currency
+	tx.Sum = quote.Sum
+	tx.Rate = quote.Rate
+	return nil
+}
+
+// a quote can be used by one transfer before it expires
+
+func checkQuote(quote *models.Quote, now time.Time) error {
+	if quote.TransactionId != utils.ERROR_ID {
+		return fmt.Errorf("%w: quote is already used by transaction %d", ErrQuoteNotActive, quote.TransactionId)
+	}
+	if !quote.Expires.After(now) {
+		return fmt.Errorf("%w: quote has expired", ErrQuoteNotActive)
+	}
+	return nil
+}
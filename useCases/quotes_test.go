@@ -0,0 +1,440 @@
+package useCases
+
+import (
+	"context"
+	"errors"
+	"github.com/golang/mock/gomock"
+	"github.com/saskamegaprogrammist/userBalanceService/models"
+	"github.com/saskamegaprogrammist/userBalanceService/rates"
+	"github.com/saskamegaprogrammist/userBalanceService/repository"
+	"github.com/saskamegaprogrammist/userBalanceService/utils"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestQuote(t *testing.T) {
+	t.Run("QuoteOK", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepoRates := repository.NewMockRatesRepoI(ctrl)
+		mockRepoRates.EXPECT().GetLatestRate(gomock.Any(), &models.Rate{Base: "USD", Quote: "RUB"}, gomock.Any()).
+			DoAndReturn(func(ctx context.Context, rate *models.Rate, before time.Time) error {
+				rate.Id, rate.Rate, rate.FetchedAt = 7, 73.5, time.Now()
+				return nil
+			})
+		mockRepoRates.EXPECT().AddQuote(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, quote *models.Quote) error {
+			quote.Id = 3
+			return nil
+		})
+
+		ratesUseCase := RatesUC{
+			RatesRepo:       mockRepoRates,
+			Provider:        rates.NewMockRatesProvider(ctrl),
+			QuoteRateMaxAge: time.Hour,
+			QuoteTTL:        30 * time.Second,
+		}
+
+		quote := models.Quote{UserFromId: 1, CurrencyFrom: "usd", Currency: "RUB", SumFrom: models.MoneyFromUnits(10)}
+		err := ratesUseCase.Quote(context.Background(), &quote)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 3, quote.Id)
+		assert.Equal(t, "USD", quote.CurrencyFrom)
+		assert.Equal(t, models.MoneyFromUnits(735), quote.Sum)
+		assert.Equal(t, 73.5, quote.Rate)
+		assert.Equal(t, 7, quote.RateId)
+		assert.Equal(t, 30*time.Second, quote.Expires.Sub(quote.Created))
+	})
+
+	t.Run("StaleRateRefetched", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		stored := make([]models.Rate, 0)
+		mockRepoRates := repository.NewMockRatesRepoI(ctrl)
+		mockRepoRates.EXPECT().GetLatestRate(gomock.Any(), &models.Rate{Base: "USD", Quote: "RUB"}, gomock.Any()).
+			DoAndReturn(func(ctx context.Context, rate *models.Rate, before time.Time) error {
+				rate.Id, rate.Rate, rate.FetchedAt = 7, 70, time.Now().Add(-10*time.Minute)
+				return nil
+			})
+		mockRepoRates.EXPECT().AddRates(gomock.Any(), gomock.Any()).DoAndReturn(addRates(&stored))
+		mockRepoRates.EXPECT().AddQuote(gomock.Any(), gomock.Any()).Return(nil)
+
+		mockProvider := rates.NewMockRatesProvider(ctrl)
+		mockProvider.EXPECT().GetRates(gomock.Any(), "USD").Return(testProviderRates("USD"), nil)
+
+		ratesUseCase := RatesUC{
+			RatesRepo:       mockRepoRates,
+			Provider:        mockProvider,
+			RateMaxAge:      time.Hour,
+			QuoteRateMaxAge: 5 * time.Minute,
+		}
+
+		quote := models.Quote{UserFromId: 1, CurrencyFrom: "USD", Currency: "RUB", SumFrom: models.MoneyFromUnits(10)}
+		err := ratesUseCase.Quote(context.Background(), &quote)
+
+		assert.NoError(t, err)
+		assert.Equal(t, stored[len(stored)-1].Id, quote.RateId)
+		assert.Equal(t, 1.0, quote.Rate)
+	})
+
+	t.Run("CachedRateTooOld", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		stored := make([]models.Rate, 0)
+		mockRepoRates := repository.NewMockRatesRepoI(ctrl)
+		mockRepoRates.EXPECT().GetLatestRate(gomock.Any(), &models.Rate{Base: "USD", Quote: "RUB"}, gomock.Any()).
+			DoAndReturn(func(ctx context.Context, rate *models.Rate, before time.Time) error {
+				rate.Id, rate.Rate, rate.FetchedAt = 7, 70, time.Now().Add(-10*time.Minute)
+				return nil
+			})
+		mockRepoRates.EXPECT().AddRates(gomock.Any(), gomock.Any()).DoAndReturn(addRates(&stored))
+
+		cached := testProviderRates("USD")
+		cached.FetchedAt = time.Now().Add(-10 * time.Minute)
+		mockProvider := rates.NewMockRatesProvider(ctrl)
+		mockProvider.EXPECT().GetRates(gomock.Any(), "USD").Return(cached, nil)
+
+		ratesUseCase := RatesUC{
+			RatesRepo:       mockRepoRates,
+			Provider:        mockProvider,
+			RateMaxAge:      time.Hour,
+			QuoteRateMaxAge: 5 * time.Minute,
+		}
+
+		quote := models.Quote{UserFromId: 1, CurrencyFrom: "USD", Currency: "RUB", SumFrom: models.MoneyFromUnits(10)}
+		err := ratesUseCase.Quote(context.Background(), &quote)
+
+		assert.True(t, errors.Is(err, ErrRatesUnavailable))
+		assert.Equal(t, cached.FetchedAt, stored[0].FetchedAt)
+	})
+
+	t.Run("InvalidUserId", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		ratesUseCase := RatesUC{
+			RatesRepo: repository.NewMockRatesRepoI(ctrl),
+			Provider:  rates.NewMockRatesProvider(ctrl),
+		}
+
+		quote := models.Quote{CurrencyFrom: "USD", Currency: "RUB", SumFrom: models.MoneyFromUnits(10)}
+		err := ratesUseCase.Quote(context.Background(), &quote)
+
+		assert.Equal(t, models.ErrInvalidUser, err)
+	})
+
+	t.Run("SameCurrency", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		ratesUseCase := RatesUC{
+			RatesRepo:       repository.NewMockRatesRepoI(ctrl),
+			Provider:        rates.NewMockRatesProvider(ctrl),
+			QuoteRateMaxAge: time.Hour,
+		}
+
+		quote := models.Quote{UserFromId: 1, Currency: utils.CURRENCY, SumFrom: models.MoneyFromUnits(10)}
+		err := ratesUseCase.Quote(context.Background(), &quote)
+
+		assert.Error(t, err)
+		assert.True(t, errors.Is(err, models.ErrValidation))
+	})
+
+	t.Run("SumFinerThanCurrency", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		ratesUseCase := RatesUC{
+			RatesRepo: repository.NewMockRatesRepoI(ctrl),
+			Provider:  rates.NewMockRatesProvider(ctrl),
+		}
+
+		sumFrom, _ := models.ParseMoney("1.50")
+		quote := models.Quote{UserFromId: 1, CurrencyFrom: "JPY", Currency: "USD", SumFrom: sumFrom}
+		err := ratesUseCase.Quote(context.Background(), &quote)
+
+		assert.True(t, errors.Is(err, models.ErrInvalidMoney))
+	})
+
+	t.Run("WrongSum", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		ratesUseCase := RatesUC{
+			RatesRepo:       repository.NewMockRatesRepoI(ctrl),
+			Provider:        rates.NewMockRatesProvider(ctrl),
+			QuoteRateMaxAge: time.Hour,
+		}
+
+		quote := models.Quote{UserFromId: 1, CurrencyFrom: "USD", SumFrom: models.MoneyFromUnits(-10)}
+		err := ratesUseCase.Quote(context.Background(), &quote)
+
+		assert.Error(t, err)
+		assert.True(t, errors.Is(err, models.ErrValidation))
+	})
+
+	t.Run("TooSmall", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepoRates := repository.NewMockRatesRepoI(ctrl)
+		mockRepoRates.EXPECT().GetLatestRate(gomock.Any(), gomock.Any(), gomock.Any()).
+			DoAndReturn(func(ctx context.Context, rate *models.Rate, before time.Time) error {
+				rate.Id, rate.Rate, rate.FetchedAt = 7, 0.0136, time.Now()
+				return nil
+			})
+
+		ratesUseCase := RatesUC{
+			RatesRepo:       mockRepoRates,
+			Provider:        rates.NewMockRatesProvider(ctrl),
+			QuoteRateMaxAge: time.Hour,
+		}
+
+		sumFrom, _ := models.ParseMoney("0.10")
+		quote := models.Quote{UserFromId: 1, CurrencyFrom: utils.CURRENCY, Currency: "USD", SumFrom: sumFrom}
+		err := ratesUseCase.Quote(context.Background(), &quote)
+
+		assert.Error(t, err)
+		assert.True(t, errors.Is(err, models.ErrValidation))
+	})
+
+	t.Run("DBError", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepoRates := repository.NewMockRatesRepoI(ctrl)
+		mockRepoRates.EXPECT().GetLatestRate(gomock.Any(), gomock.Any(), gomock.Any()).
+			DoAndReturn(func(ctx context.Context, rate *models.Rate, before time.Time) error {
+				rate.Id, rate.Rate, rate.FetchedAt = 7, 73.5, time.Now()
+				return nil
+			})
+		mockRepoRates.EXPECT().AddQuote(gomock.Any(), gomock.Any()).Return(errors.New("db error"))
+
+		ratesUseCase := RatesUC{
+			RatesRepo:       mockRepoRates,
+			Provider:        rates.NewMockRatesProvider(ctrl),
+			QuoteRateMaxAge: time.Hour,
+		}
+
+		quote := models.Quote{UserFromId: 1, CurrencyFrom: "USD", SumFrom: models.MoneyFromUnits(10)}
+		err := ratesUseCase.Quote(context.Background(), &quote)
+
+		assert.Error(t, err)
+	})
+}
+
+func TestTransferQuoted(t *testing.T) {
+	testQuote := models.Quote{Id: 3, UserFromId: 1, CurrencyFrom: "USD", Currency: "RUB", SumFrom: models.MoneyFromUnits(10),
+		Sum: models.MoneyFromUnits(735), Rate: 73.5, RateId: 7, Expires: time.Now().Add(time.Minute)}
+
+	t.Run("TransferOK", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockWork := repository.NewMockWorkI(ctrl)
+		mockWork.EXPECT().GetQuote(&models.Quote{Id: 3}).DoAndReturn(getStored(testQuote))
+		mockWork.EXPECT().LockBalances(&models.Balance{UserId: 2, Currency: "RUB"}, &models.Balance{UserId: 1, Currency: "USD"}).
+			DoAndReturn(lockWallets(wallet(0), wallet(15)))
+		mockWork.EXPECT().GetSystemAccount(&models.Balance{UserId: utils.EXCHANGE_ACCOUNT, Currency: "USD"}).DoAndReturn(getSystemAccount(304))
+		mockWork.EXPECT().GetSystemAccount(&models.Balance{UserId: utils.EXCHANGE_ACCOUNT, Currency: "RUB"}).DoAndReturn(getSystemAccount(303))
+		mockWork.EXPECT().AddTransaction(gomock.Any()).Return(nil)
+
+		mockUnitOfWork := repository.NewMockUnitOfWorkI(ctrl)
+		mockUnitOfWork.EXPECT().Do(gomock.Any(), gomock.Any()).DoAndReturn(runWork(mockWork))
+
+		fundsUseCase := FundsUC{
+			UnitOfWork: mockUnitOfWork,
+		}
+
+		tx := models.Transaction{UserId: 2, UserFromId: 1, QuoteId: 3}
+		err := fundsUseCase.Transfer(context.Background(), &tx)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "USD", tx.CurrencyFrom)
+		assert.Equal(t, models.MoneyFromUnits(10), tx.SumFrom)
+		assert.Equal(t, "RUB", tx.Currency)
+		assert.Equal(t, models.MoneyFromUnits(735), tx.Sum)
+		assert.Equal(t, 73.5, tx.Rate)
+		assert.Equal(t, models.MoneyFromUnits(5), tx.BalanceFrom)
+		assert.Equal(t, models.MoneyFromUnits(735), tx.Balance)
+		assert.Equal(t, []models.Posting{
+			{AccountId: 304, Currency: "USD", Amount: models.MoneyFromUnits(10)},
+			{AccountId: 10, Currency: "USD", Amount: models.MoneyFromUnits(-10)},
+			{AccountId: 20, Currency: "RUB", Amount: models.MoneyFromUnits(735)},
+			{AccountId: 303, Currency: "RUB", Amount: models.MoneyFromUnits(-735)},
+		}, tx.Postings)
+	})
+
+	t.Run("OwnWallets", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockWork := repository.NewMockWorkI(ctrl)
+		mockWork.EXPECT().GetQuote(&models.Quote{Id: 3}).DoAndReturn(getStored(testQuote))
+		mockWork.EXPECT().LockBalances(&models.Balance{UserId: 1, Currency: "RUB"}, &models.Balance{UserId: 1, Currency: "USD"}).
+			DoAndReturn(lockWallets(wallet(0), wallet(15)))
+		mockWork.EXPECT().GetSystemAccount(&models.Balance{UserId: utils.EXCHANGE_ACCOUNT, Currency: "USD"}).DoAndReturn(getSystemAccount(304))
+		mockWork.EXPECT().GetSystemAccount(&models.Balance{UserId: utils.EXCHANGE_ACCOUNT, Currency: "RUB"}).DoAndReturn(getSystemAccount(303))
+		mockWork.EXPECT().AddTransaction(gomock.Any()).Return(nil)
+
+		mockUnitOfWork := repository.NewMockUnitOfWorkI(ctrl)
+		mockUnitOfWork.EXPECT().Do(gomock.Any(), gomock.Any()).DoAndReturn(runWork(mockWork))
+
+		fundsUseCase := FundsUC{
+			UnitOfWork: mockUnitOfWork,
+		}
+
+		tx := models.Transaction{UserId: 1, UserFromId: 1, QuoteId: 3}
+		err := fundsUseCase.Transfer(context.Background(), &tx)
+
+		assert.NoError(t, err)
+		assert.Equal(t, models.MoneyFromUnits(5), tx.BalanceFrom)
+		assert.Equal(t, models.MoneyFromUnits(735), tx.Balance)
+	})
+
+	t.Run("LowFunds", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockWork := repository.NewMockWorkI(ctrl)
+		mockWork.EXPECT().GetQuote(gomock.Any()).DoAndReturn(getStored(testQuote))
+		mockWork.EXPECT().LockBalances(gomock.Any(), gomock.Any()).
+			DoAndReturn(lockWallets(wallet(1000), wallet(9)))
+
+		mockUnitOfWork := repository.NewMockUnitOfWorkI(ctrl)
+		mockUnitOfWork.EXPECT().Do(gomock.Any(), gomock.Any()).DoAndReturn(runWork(mockWork))
+
+		fundsUseCase := FundsUC{
+			UnitOfWork: mockUnitOfWork,
+		}
+
+		tx := models.Transaction{UserId: 2, UserFromId: 1, QuoteId: 3}
+		err := fundsUseCase.Transfer(context.Background(), &tx)
+
+		assert.Error(t, err)
+		assert.True(t, errors.Is(err, models.ErrInsufficientFunds))
+	})
+
+	t.Run("QuoteExpired", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		expired := testQuote
+		expired.Expires = time.Now().Add(-time.Second)
+
+		mockWork := repository.NewMockWorkI(ctrl)
+		mockWork.EXPECT().GetQuote(gomock.Any()).DoAndReturn(getStored(expired))
+		mockWork.EXPECT().LockBalances(gomock.Any(), gomock.Any()).Return(nil)
+
+		mockUnitOfWork := repository.NewMockUnitOfWorkI(ctrl)
+		mockUnitOfWork.EXPECT().Do(gomock.Any(), gomock.Any()).DoAndReturn(runWork(mockWork))
+
+		fundsUseCase := FundsUC{
+			UnitOfWork: mockUnitOfWork,
+		}
+
+		tx := models.Transaction{UserId: 2, UserFromId: 1, QuoteId: 3}
+		err := fundsUseCase.Transfer(context.Background(), &tx)
+
+		assert.True(t, errors.Is(err, ErrQuoteNotActive))
+	})
+
+	t.Run("QuoteUsed", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		used := testQuote
+		used.TransactionId = 12
+
+		mockWork := repository.NewMockWorkI(ctrl)
+		mockWork.EXPECT().GetQuote(gomock.Any()).DoAndReturn(getStored(used))
+		mockWork.EXPECT().LockBalances(gomock.Any(), gomock.Any()).Return(nil)
+
+		mockUnitOfWork := repository.NewMockUnitOfWorkI(ctrl)
+		mockUnitOfWork.EXPECT().Do(gomock.Any(), gomock.Any()).DoAndReturn(runWork(mockWork))
+
+		fundsUseCase := FundsUC{
+			UnitOfWork: mockUnitOfWork,
+		}
+
+		tx := models.Transaction{UserId: 2, UserFromId: 1, QuoteId: 3}
+		err := fundsUseCase.Transfer(context.Background(), &tx)
+
+		assert.True(t, errors.Is(err, ErrQuoteNotActive))
+	})
+
+	t.Run("QuoteNotFound", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockWork := repository.NewMockWorkI(ctrl)
+		mockWork.EXPECT().GetQuote(gomock.Any()).Return(models.Errorf(models.ErrNotFound, "this quote doesn't exist"))
+
+		mockUnitOfWork := repository.NewMockUnitOfWorkI(ctrl)
+		mockUnitOfWork.EXPECT().Do(gomock.Any(), gomock.Any()).DoAndReturn(runWork(mockWork))
+
+		fundsUseCase := FundsUC{
+			UnitOfWork: mockUnitOfWork,
+		}
+
+		tx := models.Transaction{UserId: 2, UserFromId: 1, QuoteId: 3}
+		err := fundsUseCase.Transfer(context.Background(), &tx)
+
+		assert.Equal(t, ErrQuoteNotFound, err)
+	})
+
+	t.Run("QuoteOfOtherSender", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockWork := repository.NewMockWorkI(ctrl)
+		mockWork.EXPECT().GetQuote(gomock.Any()).DoAndReturn(getStored(testQuote))
+
+		mockUnitOfWork := repository.NewMockUnitOfWorkI(ctrl)
+		mockUnitOfWork.EXPECT().Do(gomock.Any(), gomock.Any()).DoAndReturn(runWork(mockWork))
+
+		fundsUseCase := FundsUC{
+			UnitOfWork: mockUnitOfWork,
+		}
+
+		tx := models.Transaction{UserId: 1, UserFromId: 2, QuoteId: 3}
+		err := fundsUseCase.Transfer(context.Background(), &tx)
+
+		assert.Equal(t, ErrQuoteNotFound, err)
+	})
+
+	t.Run("QuoteMismatch", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockWork := repository.NewMockWorkI(ctrl)
+		mockWork.EXPECT().GetQuote(gomock.Any()).DoAndReturn(getStored(testQuote))
+
+		mockUnitOfWork := repository.NewMockUnitOfWorkI(ctrl)
+		mockUnitOfWork.EXPECT().Do(gomock.Any(), gomock.Any()).DoAndReturn(runWork(mockWork))
+
+		fundsUseCase := FundsUC{
+			UnitOfWork: mockUnitOfWork,
+		}
+
+		tx := models.Transaction{UserId: 2, UserFromId: 1, QuoteId: 3, CurrencyFrom: "eur"}
+		err := fundsUseCase.Transfer(context.Background(), &tx)
+
+		assert.Error(t, err)
+		assert.True(t, errors.Is(err, models.ErrValidation))
+	})
+
+	t.Run("NoQuote", func(t *testing.T) {
+		fundsUseCase := FundsUC{}
+
+		tx := models.Transaction{UserId: 2, UserFromId: 1, CurrencyFrom: "USD", Sum: models.MoneyFromUnits(10)}
+		err := fundsUseCase.Transfer(context.Background(), &tx)
+
+		assert.Error(t, err)
+		assert.True(t, errors.Is(err, models.ErrValidation))
+	})
+}
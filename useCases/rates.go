@@ -0,0 +1,183 @@
+package useCases
+
+import (
+	"context"
+	"errors"
+	"github.com/google/logger"
+	"github.com/saskamegaprogrammist/userBalanceService/models"
+	"github.com/saskamegaprogrammist/userBalanceService/rates"
+	"github.com/saskamegaprogrammist/userBalanceService/repository"
+	"github.com/saskamegaprogrammist/userBalanceService/utils"
+	"time"
+)
+
+const rateDateLayout = "2006-01-02"
+
+var ErrRatesUnavailable = models.NewError(models.ErrUnavailable, "exchange rates are unavailable")
+
+// conversions use stored rates fetched at most RateMaxAge ago, quotes ones fetched at most QuoteRateMaxAge ago
+
+type RatesUC struct {
+	RatesRepo repository.RatesRepoI
+	Provider  rates.RatesProvider
+
+	QuoteTTL        time.Duration
+	RateMaxAge      time.Duration
+	QuoteRateMaxAge time.Duration
+}
+
+// stores a snapshot of rates for every wallet currency and the default one
+
+func (ratesUC *RatesUC) Refresh(ctx context.Context) error {
+	bases, err := ratesUC.RatesRepo.GetBases(ctx)
+	if err != nil {
+		return err
+	}
+	hasDefault := false
+	for _, base := range bases {
+		hasDefault = hasDefault || base == utils.CURRENCY
+	}
+	if !hasDefault {
+		bases = append(bases, utils.CURRENCY)
+	}
+
+	var lastErr error
+	for _, base := range bases {
+		_, err = ratesUC.storeRates(ctx, base)
+		if err != nil {
+			logger.Errorf("Failed to refresh %s rates: %v", base, err)
+			lastErr = err
+		}
+	}
+	return lastErr
+}
+
+// converts balance with the latest stored rate and records the rate in balance.RateId,
+// rates of a base that was never stored or was stored more than RateMaxAge ago are fetched first
+
+func (ratesUC *RatesUC) Convert(ctx context.Context, balance *models.Balance, code string) error {
+	currency, err := models.GetCurrency(code)
+	if err != nil {
+		return err
+	}
+	if currency.Code == balance.Currency {
+		return nil
+	}
+	rate, err := ratesUC.latestRate(ctx, balance.Currency, currency.Code, ratesUC.RateMaxAge)
+	if err != nil {
+		return err
+	}
+
+	balance.Balance = currency.Convert(balance.Balance, rate.Rate)
+	balance.Available = currency.Convert(balance.Available, rate.Rate)
+	balance.Currency = currency.Code
+	balance.RateId = rate.Id
+	return nil
+}
+
+// finds the latest stored rate from base to quote, fetching the rates of base when none are stored or
+// the stored one was fetched more than maxAge ago. A stale rate is never used, when the rates can't be
+// fetched or the provider answers cached ones that are too old as well the conversion fails
+
+func (ratesUC *RatesUC) latestRate(ctx context.Context, base string, quote string, maxAge time.Duration) (models.Rate, error) {
+	rate := models.Rate{Base: base, Quote: quote}
+	err := ratesUC.RatesRepo.GetLatestRate(ctx, &rate, time.Now())
+	if err == nil && time.Since(rate.FetchedAt) <= maxAge {
+		return rate, nil
+	}
+	if err != nil && !errors.Is(err, models.ErrNotFound) {
+		return rate, err
+	}
+
+	stored, fetchErr := ratesUC.storeRates(ctx, base)
+	if fetchErr != nil && err == nil {
+		logger.Errorf("Latest %s/%s rate was fetched at %s, failed to fetch a new one: %v", base, quote,
+			rate.FetchedAt.Format(time.RFC3339), fetchErr)
+		return rate, ErrRatesUnavailable
+	}
+	if fetchErr != nil {
+		return rate, fetchErr
+	}
+	for _, storedRate := range stored {
+		if storedRate.Quote != quote {
+			continue
+		}
+		if time.Since(storedRate.FetchedAt) > maxAge {
+			logger.Errorf("Provider answered %s/%s rate fetched at %s", base, quote, storedRate.FetchedAt.Format(time.RFC3339))
+			return rate, ErrRatesUnavailable
+		}
+		return storedRate, nil
+	}
+	return rate, models.Errorf(models.ErrValidation, "invalid currency")
+}
+
+// returns the latest rates of base, or the latest ones stored by the end of date when it is set
+
+func (ratesUC *RatesUC) GetRates(ctx context.Context, base string, date string) ([]models.Rate, error) {
+	found := make([]models.Rate, 0)
+	err := normalizeCurrency(&base)
+	if err != nil {
+		return found, err
+	}
+	before := time.Now()
+	if date != "" {
+		day, err := time.Parse(rateDateLayout, date)
+		if err != nil {
+			return found, models.Errorf(models.ErrValidation, "date must be formatted as %s", rateDateLayout)
+		}
+		before = day.AddDate(0, 0, 1)
+	}
+
+	found, err = ratesUC.RatesRepo.GetRates(ctx, base, before)
+	if err != nil {
+		return found, err
+	}
+	if len(found) == 0 && date == "" {
+		found, err = ratesUC.storeRates(ctx, base)
+		if err != nil {
+			return found, err
+		}
+	}
+	return found, nil
+}
+
+func (ratesUC *RatesUC) storeRates(ctx context.Context, base string) ([]models.Rate, error) {
+	snapshot := make([]models.Rate, 0)
+	fetched, err := ratesUC.Provider.GetRates(ctx, base)
+	if err != nil {
+		return snapshot, err
+	}
+	fetchedAt := fetched.FetchedAt
+	if fetchedAt.IsZero() {
+		fetchedAt = time.Now()
+	}
+	for quote, rate := range fetched.Rates {
+		if quote == base {
+			continue
+		}
+		snapshot = append(snapshot, models.Rate{Base: base, Quote: quote, Rate: rate, Source: fetched.Source, FetchedAt: fetchedAt})
+	}
+	if len(snapshot) == 0 {
+		return snapshot, nil
+	}
+	err = ratesUC.RatesRepo.AddRates(ctx, snapshot)
+	return snapshot, err
+}
+
+// refreshes rates right away and then every interval until ctx is done
+
+func RunRatesRefresh(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		err := uc.RatesUC.Refresh(ctx)
+		if err != nil {
+			logger.Errorf("Failed to refresh rates: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
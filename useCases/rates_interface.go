@@ -0,0 +1,13 @@
+package useCases
+
+import (
+	"context"
+	"github.com/saskamegaprogrammist/userBalanceService/models"
+)
+
+type RatesUCInterface interface {
+	Refresh(ctx context.Context) error
+	Convert(ctx context.Context, balance *models.Balance, currency string) error
+	GetRates(ctx context.Context, base string, date string) ([]models.Rate, error)
+	Quote(ctx context.Context, quote *models.Quote) error
+}
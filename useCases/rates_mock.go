@@ -0,0 +1,92 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: useCases/rates_interface.go
+
+// Package mock_useCases is a generated GoMock package.
+package useCases
+
+import (
+	context "context"
+	gomock "github.com/golang/mock/gomock"
+	models "github.com/saskamegaprogrammist/userBalanceService/models"
+	reflect "reflect"
+)
+
+// MockRatesUCInterface is a mock of RatesUCInterface interface
+type MockRatesUCInterface struct {
+	ctrl     *gomock.Controller
+	recorder *MockRatesUCInterfaceMockRecorder
+}
+
+// MockRatesUCInterfaceMockRecorder is the mock recorder for MockRatesUCInterface
+type MockRatesUCInterfaceMockRecorder struct {
+	mock *MockRatesUCInterface
+}
+
+// NewMockRatesUCInterface creates a new mock instance
+func NewMockRatesUCInterface(ctrl *gomock.Controller) *MockRatesUCInterface {
+	mock := &MockRatesUCInterface{ctrl: ctrl}
+	mock.recorder = &MockRatesUCInterfaceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockRatesUCInterface) EXPECT() *MockRatesUCInterfaceMockRecorder {
+	return m.recorder
+}
+
+// Refresh mocks base method
+func (m *MockRatesUCInterface) Refresh(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Refresh", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Refresh indicates an expected call of Refresh
+func (mr *MockRatesUCInterfaceMockRecorder) Refresh(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Refresh", reflect.TypeOf((*MockRatesUCInterface)(nil).Refresh), ctx)
+}
+
+// Convert mocks base method
+func (m *MockRatesUCInterface) Convert(ctx context.Context, balance *models.Balance, currency string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Convert", ctx, balance, currency)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Convert indicates an expected call of Convert
+func (mr *MockRatesUCInterfaceMockRecorder) Convert(ctx, balance, currency interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Convert", reflect.TypeOf((*MockRatesUCInterface)(nil).Convert), ctx, balance, currency)
+}
+
+// GetRates mocks base method
+func (m *MockRatesUCInterface) GetRates(ctx context.Context, base, date string) ([]models.Rate, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRates", ctx, base, date)
+	ret0, _ := ret[0].([]models.Rate)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRates indicates an expected call of GetRates
+func (mr *MockRatesUCInterfaceMockRecorder) GetRates(ctx, base, date interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRates", reflect.TypeOf((*MockRatesUCInterface)(nil).GetRates), ctx, base, date)
+}
+
+// Quote mocks base method
+func (m *MockRatesUCInterface) Quote(ctx context.Context, quote *models.Quote) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Quote", ctx, quote)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Quote indicates an expected call of Quote
+func (mr *MockRatesUCInterfaceMockRecorder) Quote(ctx, quote interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Quote", reflect.TypeOf((*MockRatesUCInterface)(nil).Quote), ctx, quote)
+}
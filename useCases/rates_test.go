@@ -0,0 +1,411 @@
+package useCases
+
+import (
+	"context"
+	"errors"
+	"github.com/golang/mock/gomock"
+	"github.com/saskamegaprogrammist/userBalanceService/models"
+	"github.com/saskamegaprogrammist/userBalanceService/rates"
+	"github.com/saskamegaprogrammist/userBalanceService/repository"
+	"github.com/saskamegaprogrammist/userBalanceService/utils"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func testProviderRates(base string) models.CurrencyAll {
+	return models.CurrencyAll{Base: base, Source: "test", Rates: models.CurrencyRates{"USD": 0.5, "RUB": 1}}
+}
+
+// stores rates like the database does, giving them ids
+
+func addRates(stored *[]models.Rate) func(ctx context.Context, rates []models.Rate) error {
+	return func(ctx context.Context, rates []models.Rate) error {
+		for i := range rates {
+			rates[i].Id = len(*stored) + 1
+			*stored = append(*stored, rates[i])
+		}
+		return nil
+	}
+}
+
+func TestRefreshRates(t *testing.T) {
+	t.Run("RefreshOK", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		stored := make([]models.Rate, 0)
+		mockRepoRates := repository.NewMockRatesRepoI(ctrl)
+		mockRepoRates.EXPECT().GetBases(gomock.Any()).Return([]string{"USD"}, nil)
+		mockRepoRates.EXPECT().AddRates(gomock.Any(), gomock.Any()).DoAndReturn(addRates(&stored)).Times(2)
+
+		mockProvider := rates.NewMockRatesProvider(ctrl)
+		mockProvider.EXPECT().GetRates(gomock.Any(), "USD").Return(testProviderRates("USD"), nil)
+		mockProvider.EXPECT().GetRates(gomock.Any(), utils.CURRENCY).Return(testProviderRates(utils.CURRENCY), nil)
+
+		ratesUseCase := RatesUC{
+			RatesRepo: mockRepoRates,
+			Provider:  mockProvider,
+		}
+
+		err := ratesUseCase.Refresh(context.Background())
+
+		assert.NoError(t, err)
+		assert.Len(t, stored, 2)
+		for _, rate := range stored {
+			assert.NotEqual(t, rate.Base, rate.Quote)
+			assert.Equal(t, "test", rate.Source)
+		}
+	})
+
+	t.Run("ProviderError", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepoRates := repository.NewMockRatesRepoI(ctrl)
+		mockRepoRates.EXPECT().GetBases(gomock.Any()).Return([]string{utils.CURRENCY}, nil)
+
+		mockProvider := rates.NewMockRatesProvider(ctrl)
+		mockProvider.EXPECT().GetRates(gomock.Any(), utils.CURRENCY).Return(models.CurrencyAll{}, errors.New("rates API is unavailable"))
+
+		ratesUseCase := RatesUC{
+			RatesRepo: mockRepoRates,
+			Provider:  mockProvider,
+		}
+
+		err := ratesUseCase.Refresh(context.Background())
+
+		assert.Error(t, err)
+	})
+}
+
+func TestConvert(t *testing.T) {
+	t.Run("StoredRate", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepoRates := repository.NewMockRatesRepoI(ctrl)
+		mockRepoRates.EXPECT().GetLatestRate(gomock.Any(), &models.Rate{Base: utils.CURRENCY, Quote: "USD"}, gomock.Any()).
+			DoAndReturn(func(ctx context.Context, rate *models.Rate, before time.Time) error {
+				rate.Id, rate.Rate, rate.FetchedAt = 3, 0.5, time.Now()
+				return nil
+			})
+
+		ratesUseCase := RatesUC{
+			RatesRepo:  mockRepoRates,
+			Provider:   rates.NewMockRatesProvider(ctrl),
+			RateMaxAge: time.Hour,
+		}
+
+		balance := models.Balance{UserId: 1, Balance: models.MoneyFromUnits(10), Available: models.MoneyFromUnits(8), Currency: utils.CURRENCY}
+		err := ratesUseCase.Convert(context.Background(), &balance, "USD")
+
+		assert.NoError(t, err)
+		assert.Equal(t, models.Balance{UserId: 1, Balance: models.MoneyFromUnits(5), Available: models.MoneyFromUnits(4),
+			Currency: "USD", RateId: 3}, balance)
+	})
+
+	t.Run("FetchedRate", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		stored := make([]models.Rate, 0)
+		mockRepoRates := repository.NewMockRatesRepoI(ctrl)
+		mockRepoRates.EXPECT().GetLatestRate(gomock.Any(), gomock.Any(), gomock.Any()).Return(models.Errorf(models.ErrNotFound, "this rate doesn't exist"))
+		mockRepoRates.EXPECT().AddRates(gomock.Any(), gomock.Any()).DoAndReturn(addRates(&stored))
+
+		mockProvider := rates.NewMockRatesProvider(ctrl)
+		mockProvider.EXPECT().GetRates(gomock.Any(), utils.CURRENCY).Return(testProviderRates(utils.CURRENCY), nil)
+
+		ratesUseCase := RatesUC{
+			RatesRepo:  mockRepoRates,
+			Provider:   mockProvider,
+			RateMaxAge: time.Hour,
+		}
+
+		balance := models.Balance{UserId: 1, Balance: models.MoneyFromUnits(10), Currency: utils.CURRENCY}
+		err := ratesUseCase.Convert(context.Background(), &balance, "USD")
+
+		assert.NoError(t, err)
+		assert.Equal(t, models.MoneyFromUnits(5), balance.Balance)
+		assert.Equal(t, stored[0].Id, balance.RateId)
+	})
+
+	t.Run("UnknownCurrency", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepoRates := repository.NewMockRatesRepoI(ctrl)
+		mockRepoRates.EXPECT().GetLatestRate(gomock.Any(), gomock.Any(), gomock.Any()).Return(models.Errorf(models.ErrNotFound, "this rate doesn't exist"))
+		mockRepoRates.EXPECT().AddRates(gomock.Any(), gomock.Any()).Return(nil)
+
+		mockProvider := rates.NewMockRatesProvider(ctrl)
+		mockProvider.EXPECT().GetRates(gomock.Any(), utils.CURRENCY).Return(testProviderRates(utils.CURRENCY), nil)
+
+		ratesUseCase := RatesUC{
+			RatesRepo:  mockRepoRates,
+			Provider:   mockProvider,
+			RateMaxAge: time.Hour,
+		}
+
+		balance := models.Balance{UserId: 1, Currency: utils.CURRENCY}
+		err := ratesUseCase.Convert(context.Background(), &balance, "JPY")
+
+		assert.Error(t, err)
+		assert.True(t, errors.Is(err, models.ErrValidation))
+		assert.Equal(t, utils.CURRENCY, balance.Currency)
+	})
+
+	t.Run("InvalidCurrency", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		ratesUseCase := RatesUC{
+			RatesRepo:  repository.NewMockRatesRepoI(ctrl),
+			Provider:   rates.NewMockRatesProvider(ctrl),
+			RateMaxAge: time.Hour,
+		}
+
+		balance := models.Balance{UserId: 1, Currency: utils.CURRENCY}
+		err := ratesUseCase.Convert(context.Background(), &balance, "dsgsdg")
+
+		assert.Error(t, err)
+		assert.True(t, errors.Is(err, models.ErrValidation))
+	})
+
+	t.Run("LowercaseCurrency", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepoRates := repository.NewMockRatesRepoI(ctrl)
+		mockRepoRates.EXPECT().GetLatestRate(gomock.Any(), &models.Rate{Base: utils.CURRENCY, Quote: "USD"}, gomock.Any()).
+			DoAndReturn(func(ctx context.Context, rate *models.Rate, before time.Time) error {
+				rate.Id, rate.Rate, rate.FetchedAt = 3, 0.5, time.Now()
+				return nil
+			})
+
+		ratesUseCase := RatesUC{
+			RatesRepo:  mockRepoRates,
+			Provider:   rates.NewMockRatesProvider(ctrl),
+			RateMaxAge: time.Hour,
+		}
+
+		balance := models.Balance{UserId: 1, Balance: models.MoneyFromUnits(10), Currency: utils.CURRENCY}
+		err := ratesUseCase.Convert(context.Background(), &balance, "usd")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "USD", balance.Currency)
+		assert.Equal(t, models.MoneyFromUnits(5), balance.Balance)
+	})
+
+	t.Run("RoundedToMinorUnits", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepoRates := repository.NewMockRatesRepoI(ctrl)
+		mockRepoRates.EXPECT().GetLatestRate(gomock.Any(), &models.Rate{Base: "USD", Quote: "JPY"}, gomock.Any()).
+			DoAndReturn(func(ctx context.Context, rate *models.Rate, before time.Time) error {
+				rate.Id, rate.Rate, rate.FetchedAt = 4, 105.87, time.Now()
+				return nil
+			})
+
+		ratesUseCase := RatesUC{
+			RatesRepo:  mockRepoRates,
+			Provider:   rates.NewMockRatesProvider(ctrl),
+			RateMaxAge: time.Hour,
+		}
+
+		balance := models.Balance{UserId: 1, Balance: models.MoneyFromUnits(10), Currency: "USD"}
+		err := ratesUseCase.Convert(context.Background(), &balance, "JPY")
+
+		assert.NoError(t, err)
+		assert.Equal(t, models.MoneyFromUnits(1059), balance.Balance)
+	})
+
+	t.Run("StaleRateRefetched", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		stored := make([]models.Rate, 0)
+		mockRepoRates := repository.NewMockRatesRepoI(ctrl)
+		mockRepoRates.EXPECT().GetLatestRate(gomock.Any(), &models.Rate{Base: utils.CURRENCY, Quote: "USD"}, gomock.Any()).
+			DoAndReturn(func(ctx context.Context, rate *models.Rate, before time.Time) error {
+				rate.Id, rate.Rate, rate.FetchedAt = 3, 0.25, time.Now().Add(-2*time.Hour)
+				return nil
+			})
+		mockRepoRates.EXPECT().AddRates(gomock.Any(), gomock.Any()).DoAndReturn(addRates(&stored))
+
+		mockProvider := rates.NewMockRatesProvider(ctrl)
+		mockProvider.EXPECT().GetRates(gomock.Any(), utils.CURRENCY).Return(testProviderRates(utils.CURRENCY), nil)
+
+		ratesUseCase := RatesUC{
+			RatesRepo:  mockRepoRates,
+			Provider:   mockProvider,
+			RateMaxAge: time.Hour,
+		}
+
+		balance := models.Balance{UserId: 1, Balance: models.MoneyFromUnits(10), Currency: utils.CURRENCY}
+		err := ratesUseCase.Convert(context.Background(), &balance, "USD")
+
+		assert.NoError(t, err)
+		assert.Equal(t, models.MoneyFromUnits(5), balance.Balance)
+		assert.Equal(t, stored[0].Id, balance.RateId)
+	})
+
+	t.Run("StaleRateUnavailable", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepoRates := repository.NewMockRatesRepoI(ctrl)
+		mockRepoRates.EXPECT().GetLatestRate(gomock.Any(), &models.Rate{Base: utils.CURRENCY, Quote: "USD"}, gomock.Any()).
+			DoAndReturn(func(ctx context.Context, rate *models.Rate, before time.Time) error {
+				rate.Id, rate.Rate, rate.FetchedAt = 3, 0.25, time.Now().Add(-2*time.Hour)
+				return nil
+			})
+
+		mockProvider := rates.NewMockRatesProvider(ctrl)
+		mockProvider.EXPECT().GetRates(gomock.Any(), utils.CURRENCY).Return(models.CurrencyAll{}, errors.New("rates API is unavailable"))
+
+		ratesUseCase := RatesUC{
+			RatesRepo:  mockRepoRates,
+			Provider:   mockProvider,
+			RateMaxAge: time.Hour,
+		}
+
+		balance := models.Balance{UserId: 1, Balance: models.MoneyFromUnits(10), Currency: utils.CURRENCY}
+		err := ratesUseCase.Convert(context.Background(), &balance, "USD")
+
+		assert.True(t, errors.Is(err, ErrRatesUnavailable))
+		assert.Equal(t, utils.CURRENCY, balance.Currency)
+		assert.Equal(t, models.MoneyFromUnits(10), balance.Balance)
+	})
+
+	t.Run("SameCurrency", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		ratesUseCase := RatesUC{
+			RatesRepo:  repository.NewMockRatesRepoI(ctrl),
+			Provider:   rates.NewMockRatesProvider(ctrl),
+			RateMaxAge: time.Hour,
+		}
+
+		balance := models.Balance{UserId: 1, Balance: models.MoneyFromUnits(10), Currency: utils.CURRENCY}
+		err := ratesUseCase.Convert(context.Background(), &balance, utils.CURRENCY)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 0, balance.RateId)
+	})
+
+	t.Run("DBError", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepoRates := repository.NewMockRatesRepoI(ctrl)
+		mockRepoRates.EXPECT().GetLatestRate(gomock.Any(), gomock.Any(), gomock.Any()).Return(errors.New("db error"))
+
+		ratesUseCase := RatesUC{
+			RatesRepo:  mockRepoRates,
+			Provider:   rates.NewMockRatesProvider(ctrl),
+			RateMaxAge: time.Hour,
+		}
+
+		balance := models.Balance{UserId: 1, Currency: utils.CURRENCY}
+		err := ratesUseCase.Convert(context.Background(), &balance, "USD")
+
+		assert.Error(t, err)
+	})
+}
+
+func TestGetRates(t *testing.T) {
+	snapshot := []models.Rate{{Id: 1, Base: "USD", Quote: "RUB", Rate: 2, Source: "test"}}
+
+	t.Run("HistoricalOK", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepoRates := repository.NewMockRatesRepoI(ctrl)
+		mockRepoRates.EXPECT().GetRates(gomock.Any(), "USD", time.Date(2020, 8, 3, 0, 0, 0, 0, time.UTC)).Return(snapshot, nil)
+
+		ratesUseCase := RatesUC{
+			RatesRepo: mockRepoRates,
+			Provider:  rates.NewMockRatesProvider(ctrl),
+		}
+
+		found, err := ratesUseCase.GetRates(context.Background(), "usd", "2020-08-02")
+
+		assert.NoError(t, err)
+		assert.Equal(t, snapshot, found)
+	})
+
+	t.Run("HistoricalEmpty", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepoRates := repository.NewMockRatesRepoI(ctrl)
+		mockRepoRates.EXPECT().GetRates(gomock.Any(), utils.CURRENCY, gomock.Any()).Return([]models.Rate{}, nil)
+
+		ratesUseCase := RatesUC{
+			RatesRepo: mockRepoRates,
+			Provider:  rates.NewMockRatesProvider(ctrl),
+		}
+
+		found, err := ratesUseCase.GetRates(context.Background(), "", "2000-01-01")
+
+		assert.NoError(t, err)
+		assert.Empty(t, found)
+	})
+
+	t.Run("CurrentFetched", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		stored := make([]models.Rate, 0)
+		mockRepoRates := repository.NewMockRatesRepoI(ctrl)
+		mockRepoRates.EXPECT().GetRates(gomock.Any(), "USD", gomock.Any()).Return([]models.Rate{}, nil)
+		mockRepoRates.EXPECT().AddRates(gomock.Any(), gomock.Any()).DoAndReturn(addRates(&stored))
+
+		mockProvider := rates.NewMockRatesProvider(ctrl)
+		mockProvider.EXPECT().GetRates(gomock.Any(), "USD").Return(testProviderRates("USD"), nil)
+
+		ratesUseCase := RatesUC{
+			RatesRepo: mockRepoRates,
+			Provider:  mockProvider,
+		}
+
+		found, err := ratesUseCase.GetRates(context.Background(), "USD", "")
+
+		assert.NoError(t, err)
+		assert.Equal(t, stored, found)
+	})
+
+	t.Run("WrongDate", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		ratesUseCase := RatesUC{
+			RatesRepo: repository.NewMockRatesRepoI(ctrl),
+			Provider:  rates.NewMockRatesProvider(ctrl),
+		}
+
+		_, err := ratesUseCase.GetRates(context.Background(), "USD", "02.08.2020")
+
+		assert.Error(t, err)
+		assert.True(t, errors.Is(err, models.ErrValidation))
+	})
+
+	t.Run("WrongBase", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		ratesUseCase := RatesUC{
+			RatesRepo: repository.NewMockRatesRepoI(ctrl),
+			Provider:  rates.NewMockRatesProvider(ctrl),
+		}
+
+		_, err := ratesUseCase.GetRates(context.Background(), "dollars", "")
+
+		assert.Error(t, err)
+		assert.True(t, errors.Is(err, models.ErrValidation))
+	})
+}
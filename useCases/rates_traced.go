@@ -0,0 +1,39 @@
+package useCases
+
+import (
+	"context"
+	"github.com/saskamegaprogrammist/userBalanceService/models"
+	"github.com/saskamegaprogrammist/userBalanceService/tracing"
+)
+
+type TracedRatesUC struct {
+	RatesUC RatesUCInterface
+}
+
+func (ratesUC *TracedRatesUC) Refresh(ctx context.Context) error {
+	ctx, span := tracing.Start(ctx, "RatesUC.Refresh")
+	err := ratesUC.RatesUC.Refresh(ctx)
+	tracing.End(span, err)
+	return err
+}
+
+func (ratesUC *TracedRatesUC) Convert(ctx context.Context, balance *models.Balance, currency string) error {
+	ctx, span := tracing.Start(ctx, "RatesUC.Convert", userAttribute(balance.UserId))
+	err := ratesUC.RatesUC.Convert(ctx, balance, currency)
+	tracing.End(span, err)
+	return err
+}
+
+func (ratesUC *TracedRatesUC) GetRates(ctx context.Context, base string, date string) ([]models.Rate, error) {
+	ctx, span := tracing.Start(ctx, "RatesUC.GetRates")
+	rates, err := ratesUC.RatesUC.GetRates(ctx, base, date)
+	tracing.End(span, err)
+	return rates, err
+}
+
+func (ratesUC *TracedRatesUC) Quote(ctx context.Context, quote *models.Quote) error {
+	ctx, span := tracing.Start(ctx, "RatesUC.Quote")
+	err := ratesUC.RatesUC.Quote(ctx, quote)
+	tracing.End(span, err)
+	return err
+}
@@ -0,0 +1,116 @@
+package useCases
+
+import (
+	"context"
+	"errors"
+	"github.com/saskamegaprogrammist/userBalanceService/models"
+	"github.com/saskamegaprogrammist/userBalanceService/repository"
+	"github.com/saskamegaprogrammist/userBalanceService/utils"
+	"time"
+)
+
+var ErrTransactionNotFound = models.NewError(models.ErrNotFound, "this transaction doesn't exist")
+var ErrAlreadyReversed = models.NewError(models.ErrConflict, "transaction is already fully reversed")
+
+// creates a compensating transaction for reversal.TransactionId, reversal.Sum allows
+// a partial refund, without it the part of the sum that isn't refunded yet is reversed. Partial refunds
+// add up to at most the original sum. Cross-currency transfers are reversed in full at their original rate
+
+func (fundsUC *FundsUC) Reverse(ctx context.Context, reversal *models.Reversal, tx *models.Transaction) error {
+	if reversal.TransactionId <= utils.ERROR_ID {
+		return models.Errorf(models.ErrValidation, "incorrect transaction id")
+	}
+	if reversal.Sum < 0 {
+		return models.Errorf(models.ErrValidation, "sum must be positive")
+	}
+
+	err := fundsUC.UnitOfWork.Do(ctx, func(work repository.WorkI) error {
+		original := models.Transaction{Id: reversal.TransactionId}
+		err := work.GetTransaction(&original)
+		if errors.Is(err, models.ErrNotFound) {
+			return ErrTransactionNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if original.OperationType == utils.GetOperationType("Reverse") {
+			return models.Errorf(models.ErrValidation, "reversals can't be reversed")
+		}
+		rest := original.Sum - original.Refunded
+		if rest <= 0 {
+			return ErrAlreadyReversed
+		}
+		sum := reversal.Sum
+		if sum == 0 {
+			sum = rest
+		}
+		if sum > rest {
+			return models.Errorf(models.ErrValidation, "sum is larger than the part of the original sum that isn't refunded")
+		}
+		err = checkSum(original.Currency, sum)
+		if err != nil {
+			return err
+		}
+		currencyFrom := original.Currency
+		if original.CurrencyFrom != "" {
+			if sum != original.Sum {
+				return models.Errorf(models.ErrValidation, "cross-currency transfers can only be reversed in full")
+			}
+			currencyFrom = original.CurrencyFrom
+		}
+
+		wallet := models.Balance{UserId: original.UserId, Currency: original.Currency}
+		walletFrom := models.Balance{UserId: original.UserFromId, Currency: currencyFrom}
+		wallets := []*models.Balance{&wallet}
+		if original.UserFromId != 0 {
+			wallets = append(wallets, &walletFrom)
+		}
+		err = work.LockBalances(wallets...)
+		if err != nil {
+			return err
+		}
+
+		*tx = models.Transaction{
+			UserId:        original.UserId,
+			UserFromId:    original.UserFromId,
+			Currency:      original.Currency,
+			OperationType: utils.GetOperationType("Reverse"),
+			Sum:           sum,
+			ReversedId:    original.Id,
+			CurrencyFrom:  original.CurrencyFrom,
+			SumFrom:       original.SumFrom,
+			Rate:          original.Rate,
+			Created:       time.Now(),
+		}
+		for _, posting := range original.Postings {
+			amount := sum
+			if posting.Amount > 0 {
+				amount = -sum
+			}
+			if original.CurrencyFrom != "" {
+				amount = -posting.Amount
+			}
+			tx.Postings = append(tx.Postings, models.Posting{AccountId: posting.AccountId, Currency: posting.Currency, Amount: amount})
+			for _, balance := range wallets {
+				if balance.Id != posting.AccountId {
+					continue
+				}
+				if amount < 0 && -amount > balance.Available {
+					return models.Errorf(models.ErrInsufficientFunds, "user doesn't have enough funds")
+				}
+				balance.Balance += amount
+				balance.Available += amount
+			}
+		}
+		tx.Balance = wallet.Balance
+		tx.BalanceFrom = walletFrom.Balance
+
+		err = work.AddRefund(&original, sum)
+		if err != nil {
+			return err
+		}
+		return work.AddTransaction(tx)
+	})
+	observeOperation("Reverse", tx, false, err)
+	return err
+}
@@ -0,0 +1,323 @@
+package useCases
+
+import (
+	"context"
+	"errors"
+	"github.com/golang/mock/gomock"
+	"github.com/saskamegaprogrammist/userBalanceService/models"
+	"github.com/saskamegaprogrammist/userBalanceService/repository"
+	"github.com/saskamegaprogrammist/userBalanceService/utils"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+var testTxTransferred = models.Transaction{
+	Id:            5,
+	UserId:        2,
+	UserFromId:    1,
+	Currency:      utils.CURRENCY,
+	OperationType: utils.GetOperationType("Transfer"),
+	Sum:           models.MoneyFromUnits(100),
+	Postings: []models.Posting{
+		{AccountId: 20, Currency: utils.CURRENCY, Amount: models.MoneyFromUnits(100)},
+		{AccountId: 10, Currency: utils.CURRENCY, Amount: models.MoneyFromUnits(-100)},
+	},
+}
+
+func TestReverse(t *testing.T) {
+	t.Run("ReverseFull", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		var tx models.Transaction
+
+		mockWork := repository.NewMockWorkI(ctrl)
+		mockWork.EXPECT().GetTransaction(&models.Transaction{Id: 5}).DoAndReturn(getStored(testTxTransferred))
+		mockWork.EXPECT().LockBalances(gomock.Any(), gomock.Any()).DoAndReturn(lockWallets(wallet(100), wallet(0)))
+		mockWork.EXPECT().AddRefund(gomock.Any(), models.MoneyFromUnits(100)).Return(nil)
+		mockWork.EXPECT().AddTransaction(&tx).Return(nil)
+
+		mockUnitOfWork := repository.NewMockUnitOfWorkI(ctrl)
+		mockUnitOfWork.EXPECT().Do(gomock.Any(), gomock.Any()).DoAndReturn(runWork(mockWork))
+
+		fundsUseCase := FundsUC{
+			UnitOfWork: mockUnitOfWork,
+		}
+
+		err := fundsUseCase.Reverse(context.Background(), &models.Reversal{TransactionId: 5}, &tx)
+
+		assert.NoError(t, err)
+		assert.Equal(t, utils.GetOperationType("Reverse"), tx.OperationType)
+		assert.Equal(t, 5, tx.ReversedId)
+		assert.Equal(t, models.MoneyFromUnits(0), tx.Balance)
+		assert.Equal(t, models.MoneyFromUnits(100), tx.BalanceFrom)
+		assert.Equal(t, []models.Posting{
+			{AccountId: 20, Currency: utils.CURRENCY, Amount: models.MoneyFromUnits(-100)},
+			{AccountId: 10, Currency: utils.CURRENCY, Amount: models.MoneyFromUnits(100)},
+		}, tx.Postings)
+	})
+
+	t.Run("ReversePartial", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		var tx models.Transaction
+
+		mockWork := repository.NewMockWorkI(ctrl)
+		mockWork.EXPECT().GetTransaction(gomock.Any()).DoAndReturn(getStored(testTxTransferred))
+		mockWork.EXPECT().LockBalances(gomock.Any(), gomock.Any()).DoAndReturn(lockWallets(wallet(50), wallet(0)))
+		mockWork.EXPECT().AddRefund(gomock.Any(), models.MoneyFromUnits(30)).Return(nil)
+		mockWork.EXPECT().AddTransaction(&tx).Return(nil)
+
+		mockUnitOfWork := repository.NewMockUnitOfWorkI(ctrl)
+		mockUnitOfWork.EXPECT().Do(gomock.Any(), gomock.Any()).DoAndReturn(runWork(mockWork))
+
+		fundsUseCase := FundsUC{
+			UnitOfWork: mockUnitOfWork,
+		}
+
+		err := fundsUseCase.Reverse(context.Background(), &models.Reversal{TransactionId: 5, Sum: models.MoneyFromUnits(30)}, &tx)
+
+		assert.NoError(t, err)
+		assert.Equal(t, models.MoneyFromUnits(30), tx.Sum)
+		assert.Equal(t, models.MoneyFromUnits(20), tx.Balance)
+		assert.Equal(t, models.MoneyFromUnits(30), tx.BalanceFrom)
+	})
+
+	t.Run("LowFunds", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		var tx models.Transaction
+
+		mockWork := repository.NewMockWorkI(ctrl)
+		mockWork.EXPECT().GetTransaction(gomock.Any()).DoAndReturn(getStored(testTxTransferred))
+		mockWork.EXPECT().LockBalances(gomock.Any(), gomock.Any()).DoAndReturn(lockWallets(wallet(99), wallet(0)))
+
+		mockUnitOfWork := repository.NewMockUnitOfWorkI(ctrl)
+		mockUnitOfWork.EXPECT().Do(gomock.Any(), gomock.Any()).DoAndReturn(runWork(mockWork))
+
+		fundsUseCase := FundsUC{
+			UnitOfWork: mockUnitOfWork,
+		}
+
+		err := fundsUseCase.Reverse(context.Background(), &models.Reversal{TransactionId: 5}, &tx)
+
+		assert.Error(t, err)
+		assert.True(t, errors.Is(err, models.ErrInsufficientFunds))
+	})
+
+	t.Run("SumTooLarge", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockWork := repository.NewMockWorkI(ctrl)
+		mockWork.EXPECT().GetTransaction(gomock.Any()).DoAndReturn(getStored(testTxTransferred))
+
+		mockUnitOfWork := repository.NewMockUnitOfWorkI(ctrl)
+		mockUnitOfWork.EXPECT().Do(gomock.Any(), gomock.Any()).DoAndReturn(runWork(mockWork))
+
+		fundsUseCase := FundsUC{
+			UnitOfWork: mockUnitOfWork,
+		}
+
+		err := fundsUseCase.Reverse(context.Background(), &models.Reversal{TransactionId: 5, Sum: models.MoneyFromUnits(101)}, &models.Transaction{})
+
+		assert.Error(t, err)
+		assert.True(t, errors.Is(err, models.ErrValidation))
+	})
+
+	t.Run("ReverseRest", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		var tx models.Transaction
+		refunded := testTxTransferred
+		refunded.Refunded = models.MoneyFromUnits(40)
+
+		mockWork := repository.NewMockWorkI(ctrl)
+		mockWork.EXPECT().GetTransaction(gomock.Any()).DoAndReturn(getStored(refunded))
+		mockWork.EXPECT().LockBalances(gomock.Any(), gomock.Any()).DoAndReturn(lockWallets(wallet(60), wallet(0)))
+		mockWork.EXPECT().AddRefund(gomock.Any(), models.MoneyFromUnits(60)).Return(nil)
+		mockWork.EXPECT().AddTransaction(&tx).Return(nil)
+
+		mockUnitOfWork := repository.NewMockUnitOfWorkI(ctrl)
+		mockUnitOfWork.EXPECT().Do(gomock.Any(), gomock.Any()).DoAndReturn(runWork(mockWork))
+
+		fundsUseCase := FundsUC{
+			UnitOfWork: mockUnitOfWork,
+		}
+
+		err := fundsUseCase.Reverse(context.Background(), &models.Reversal{TransactionId: 5}, &tx)
+
+		assert.NoError(t, err)
+		assert.Equal(t, models.MoneyFromUnits(60), tx.Sum)
+		assert.Equal(t, models.MoneyFromUnits(0), tx.Balance)
+	})
+
+	t.Run("SumLargerThanRest", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		refunded := testTxTransferred
+		refunded.Refunded = models.MoneyFromUnits(40)
+
+		mockWork := repository.NewMockWorkI(ctrl)
+		mockWork.EXPECT().GetTransaction(gomock.Any()).DoAndReturn(getStored(refunded))
+
+		mockUnitOfWork := repository.NewMockUnitOfWorkI(ctrl)
+		mockUnitOfWork.EXPECT().Do(gomock.Any(), gomock.Any()).DoAndReturn(runWork(mockWork))
+
+		fundsUseCase := FundsUC{
+			UnitOfWork: mockUnitOfWork,
+		}
+
+		err := fundsUseCase.Reverse(context.Background(), &models.Reversal{TransactionId: 5, Sum: models.MoneyFromUnits(61)}, &models.Transaction{})
+
+		assert.Error(t, err)
+		assert.True(t, errors.Is(err, models.ErrValidation))
+	})
+
+	t.Run("AlreadyReversed", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		refunded := testTxTransferred
+		refunded.Refunded = refunded.Sum
+
+		mockWork := repository.NewMockWorkI(ctrl)
+		mockWork.EXPECT().GetTransaction(gomock.Any()).DoAndReturn(getStored(refunded))
+
+		mockUnitOfWork := repository.NewMockUnitOfWorkI(ctrl)
+		mockUnitOfWork.EXPECT().Do(gomock.Any(), gomock.Any()).DoAndReturn(runWork(mockWork))
+
+		fundsUseCase := FundsUC{
+			UnitOfWork: mockUnitOfWork,
+		}
+
+		err := fundsUseCase.Reverse(context.Background(), &models.Reversal{TransactionId: 5}, &models.Transaction{})
+
+		assert.Equal(t, ErrAlreadyReversed, err)
+	})
+
+	t.Run("ReverseReversal", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		reversed := testTxTransferred
+		reversed.OperationType = utils.GetOperationType("Reverse")
+
+		mockWork := repository.NewMockWorkI(ctrl)
+		mockWork.EXPECT().GetTransaction(gomock.Any()).DoAndReturn(getStored(reversed))
+
+		mockUnitOfWork := repository.NewMockUnitOfWorkI(ctrl)
+		mockUnitOfWork.EXPECT().Do(gomock.Any(), gomock.Any()).DoAndReturn(runWork(mockWork))
+
+		fundsUseCase := FundsUC{
+			UnitOfWork: mockUnitOfWork,
+		}
+
+		err := fundsUseCase.Reverse(context.Background(), &models.Reversal{TransactionId: 5}, &models.Transaction{})
+
+		assert.Error(t, err)
+		assert.True(t, errors.Is(err, models.ErrValidation))
+	})
+
+	t.Run("TransactionNotFound", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockWork := repository.NewMockWorkI(ctrl)
+		mockWork.EXPECT().GetTransaction(gomock.Any()).Return(models.Errorf(models.ErrNotFound, "this transaction doesn't exist"))
+
+		mockUnitOfWork := repository.NewMockUnitOfWorkI(ctrl)
+		mockUnitOfWork.EXPECT().Do(gomock.Any(), gomock.Any()).DoAndReturn(runWork(mockWork))
+
+		fundsUseCase := FundsUC{
+			UnitOfWork: mockUnitOfWork,
+		}
+
+		err := fundsUseCase.Reverse(context.Background(), &models.Reversal{TransactionId: 5}, &models.Transaction{})
+
+		assert.Equal(t, ErrTransactionNotFound, err)
+	})
+}
+
+func TestReverseCrossCurrency(t *testing.T) {
+	testTxExchanged := models.Transaction{
+		Id:            6,
+		UserId:        2,
+		UserFromId:    1,
+		Currency:      "RUB",
+		CurrencyFrom:  "USD",
+		OperationType: utils.GetOperationType("Transfer"),
+		Sum:           models.MoneyFromUnits(735),
+		SumFrom:       models.MoneyFromUnits(10),
+		Rate:          73.5,
+		QuoteId:       3,
+		Postings: []models.Posting{
+			{AccountId: 304, Currency: "USD", Amount: models.MoneyFromUnits(10)},
+			{AccountId: 10, Currency: "USD", Amount: models.MoneyFromUnits(-10)},
+			{AccountId: 20, Currency: "RUB", Amount: models.MoneyFromUnits(735)},
+			{AccountId: 303, Currency: "RUB", Amount: models.MoneyFromUnits(-735)},
+		},
+	}
+
+	t.Run("ReverseFull", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		var tx models.Transaction
+
+		mockWork := repository.NewMockWorkI(ctrl)
+		mockWork.EXPECT().GetTransaction(gomock.Any()).DoAndReturn(getStored(testTxExchanged))
+		mockWork.EXPECT().LockBalances(&models.Balance{UserId: 2, Currency: "RUB"}, &models.Balance{UserId: 1, Currency: "USD"}).
+			DoAndReturn(lockWallets(wallet(735), wallet(0)))
+		mockWork.EXPECT().AddRefund(gomock.Any(), models.MoneyFromUnits(735)).Return(nil)
+		mockWork.EXPECT().AddTransaction(&tx).Return(nil)
+
+		mockUnitOfWork := repository.NewMockUnitOfWorkI(ctrl)
+		mockUnitOfWork.EXPECT().Do(gomock.Any(), gomock.Any()).DoAndReturn(runWork(mockWork))
+
+		fundsUseCase := FundsUC{
+			UnitOfWork: mockUnitOfWork,
+		}
+
+		err := fundsUseCase.Reverse(context.Background(), &models.Reversal{TransactionId: 6}, &tx)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "USD", tx.CurrencyFrom)
+		assert.Equal(t, models.MoneyFromUnits(10), tx.SumFrom)
+		assert.Equal(t, 0, tx.QuoteId)
+		assert.Equal(t, models.MoneyFromUnits(0), tx.Balance)
+		assert.Equal(t, models.MoneyFromUnits(10), tx.BalanceFrom)
+		assert.Equal(t, []models.Posting{
+			{AccountId: 304, Currency: "USD", Amount: models.MoneyFromUnits(-10)},
+			{AccountId: 10, Currency: "USD", Amount: models.MoneyFromUnits(10)},
+			{AccountId: 20, Currency: "RUB", Amount: models.MoneyFromUnits(-735)},
+			{AccountId: 303, Currency: "RUB", Amount: models.MoneyFromUnits(735)},
+		}, tx.Postings)
+	})
+
+	t.Run("ReversePartial", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		var tx models.Transaction
+
+		mockWork := repository.NewMockWorkI(ctrl)
+		mockWork.EXPECT().GetTransaction(gomock.Any()).DoAndReturn(getStored(testTxExchanged))
+
+		mockUnitOfWork := repository.NewMockUnitOfWorkI(ctrl)
+		mockUnitOfWork.EXPECT().Do(gomock.Any(), gomock.Any()).DoAndReturn(runWork(mockWork))
+
+		fundsUseCase := FundsUC{
+			UnitOfWork: mockUnitOfWork,
+		}
+
+		err := fundsUseCase.Reverse(context.Background(), &models.Reversal{TransactionId: 6, Sum: models.MoneyFromUnits(100)}, &tx)
+
+		assert.Error(t, err)
+		assert.True(t, errors.Is(err, models.ErrValidation))
+	})
+}
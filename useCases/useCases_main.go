@@ -1,18 +1,39 @@
 package useCases
 
-import "github.com/saskamegaprogrammist/userBalanceService/repository"
+import (
+	"github.com/saskamegaprogrammist/userBalanceService/rates"
+	"github.com/saskamegaprogrammist/userBalanceService/repository"
+	"time"
+)
 
 type UseCases struct {
 	FundsUC *FundsUC
+	HoldsUC *HoldsUC
+	RatesUC *RatesUC
 }
 
 var uc UseCases
 
-func Init(balanceRepo repository.BalanceRepoI, transactionsRepo repository.TransactionsRepoI) error {
-	uc.FundsUC = &FundsUC{balanceRepo, transactionsRepo}
+func Init(balanceRepo repository.BalanceRepoI, transactionsRepo repository.TransactionsRepoI, holdsRepo repository.HoldsRepoI,
+	ratesRepo repository.RatesRepoI, unitOfWork repository.UnitOfWorkI, ratesProvider rates.RatesProvider,
+	idempotencyRetention time.Duration, quoteTTL time.Duration, rateMaxAge time.Duration,
+	quoteRateMaxAge time.Duration, holdTTL time.Duration) error {
+	uc.FundsUC = &FundsUC{balanceRepo, transactionsRepo, unitOfWork, idempotencyRetention}
+	uc.HoldsUC = &HoldsUC{holdsRepo, unitOfWork, holdTTL}
+	uc.RatesUC = &RatesUC{ratesRepo, ratesProvider, quoteTTL, rateMaxAge, quoteRateMaxAge}
 	return nil
 }
 
+// the use cases handed out to the handlers are traced, the background runners call them directly
+
 func GetFundsUC() FundsUCInterface {
-	return uc.FundsUC
+	return &TracedFundsUC{uc.FundsUC}
+}
+
+func GetHoldsUC() HoldsUCInterface {
+	return &TracedHoldsUC{uc.HoldsUC}
+}
+
+func GetRatesUC() RatesUCInterface {
+	return &TracedRatesUC{uc.RatesUC}
 }
@@ -1,5 +1,7 @@
 package utils
 
+import "time"
+
 var statusCodes = map[string]int{
 	"OK":                    200,
 	"Created":               201,
@@ -7,20 +9,30 @@ var statusCodes = map[string]int{
 	"Unauthorized":          401,
 	"Payment Required":      402,
 	"Not Found":             404,
+	"Not Acceptable":        406,
 	"Method Not Allowed":    405,
 	"Conflict":              409,
 	"Internal Server Error": 500,
 	"Not Implemented":       501,
 	"Bad Gateway":           502,
 	"Service Unavailable":   503,
+	"Gateway Timeout":       504,
 }
 
 var API = map[string]string{
-	"addFunds":        "/funds/add",
-	"withdrawFunds":   "/funds/withdraw",
-	"getFunds":        "/funds/get",
-	"transferFunds":   "/funds/transfer",
-	"getTransactions": "/funds/details",
+	"addFunds":           "/funds/add",
+	"withdrawFunds":      "/funds/withdraw",
+	"getFunds":           "/funds/get",
+	"transferFunds":      "/funds/transfer",
+	"getTransactions":    "/funds/details",
+	"exportTransactions": "/funds/export",
+	"reverseFunds":       "/funds/reverse",
+	"holdFunds":          "/funds/hold",
+	"captureHold":        "/funds/hold/{id}/capture",
+	"voidHold":           "/funds/hold/{id}/void",
+	"getRates":           "/rates",
+	"quoteTransfer":      "/funds/quote",
+	"metrics":            "/metrics",
 }
 
 func StatusCode(mess string) int {
@@ -33,25 +45,48 @@ func GetAPIAddress(address string) string {
 
 const ERROR_ID = 0
 const LIMIT_DEFAULT = -1
-const CURRENCY_API = "http://api.exchangeratesapi.io/latest"
-const CURRENCY_API_BASE = "?base=RUB&symbols="
 const CURRENCY = "RUB"
-const LogFile = "log.log"
-const DBName = "user_balance_service"
-const PortNum = ":5000"
+const CASH_IN_ACCOUNT = -1
+const CASH_OUT_ACCOUNT = -2
+const EXCHANGE_ACCOUNT = -3
+const EXPORT_BATCH_SIZE = 1000
+
+// every batch of an export gets its own write deadline instead of the write timeout of the server
+const EXPORT_BATCH_TIMEOUT = 30 * time.Second
 
 const (
-	NO_ERROR = iota
-	USER_ERROR
-	SERVER_ERROR
+	HOLD_ACTIVE   = "active"
+	HOLD_CAPTURED = "captured"
+	HOLD_VOIDED   = "voided"
+	HOLD_EXPIRED  = "expired"
 )
 
 var operationTypes = map[string]int{
 	"Add":      1,
 	"Withdraw": 2,
 	"Transfer": 3,
+	"Capture":  4,
+	"Reverse":  5,
 }
 
 func GetOperationType(operation string) int {
 	return operationTypes[operation]
 }
+
+func GetOperationName(operationType int) string {
+	for name, known := range operationTypes {
+		if known == operationType {
+			return name
+		}
+	}
+	return ""
+}
+
+func IsOperationType(operationType int) bool {
+	for _, known := range operationTypes {
+		if known == operationType {
+			return true
+		}
+	}
+	return false
+}
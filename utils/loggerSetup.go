@@ -5,13 +5,11 @@ import (
 	"os"
 )
 
-const verbose = false
-
 var loggerFile *os.File
 
-func LoggerSetup() {
+func LoggerSetup(file string, verbose bool) {
 	var err error
-	loggerFile, err = os.OpenFile(LogFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0660)
+	loggerFile, err = os.OpenFile(file, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0660)
 	if err != nil {
 		logger.Fatalf("Failed to open log file: %v", err)
 	}
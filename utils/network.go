@@ -45,10 +45,93 @@ func CreateAnswerBalanceJson(writer http.ResponseWriter, statusCode int, chats b
 	createAnswerJson(writer, statusCode, marshalledBalance)
 }
 
-func CreateAnswerTransactionsJson(writer http.ResponseWriter, statusCode int, chats balance_models.Transactions) {
-	marshalledTransactions, err := json.Marshal(chats)
+func CreateAnswerBalancesJson(writer http.ResponseWriter, statusCode int, balances balance_models.Balances) {
+	marshalledBalances, err := json.Marshal(balances)
+	if err != nil {
+		logger.Errorf("Error marhalling json: %v", err)
+	}
+	createAnswerJson(writer, statusCode, marshalledBalances)
+}
+
+func CreateAnswerTransactionJson(writer http.ResponseWriter, statusCode int, tx balance_models.Transaction) {
+	marshalledTransaction, err := json.Marshal(tx)
+	if err != nil {
+		logger.Errorf("Error marhalling json: %v", err)
+	}
+	createAnswerJson(writer, statusCode, marshalledTransaction)
+}
+
+func CreateAnswerTransactionsPageJson(writer http.ResponseWriter, statusCode int, page balance_models.TransactionsPage) {
+	marshalledTransactions, err := json.Marshal(page)
 	if err != nil {
 		logger.Errorf("Error marhalling json: %v", err)
 	}
 	createAnswerJson(writer, statusCode, marshalledTransactions)
 }
+
+func CreateAnswerHoldJson(writer http.ResponseWriter, statusCode int, hold balance_models.Hold) {
+	marshalledHold, err := json.Marshal(hold)
+	if err != nil {
+		logger.Errorf("Error marhalling json: %v", err)
+	}
+	createAnswerJson(writer, statusCode, marshalledHold)
+}
+
+func CreateAnswerRatesJson(writer http.ResponseWriter, statusCode int, rates balance_models.Rates) {
+	marshalledRates, err := json.Marshal(rates)
+	if err != nil {
+		logger.Errorf("Error marhalling json: %v", err)
+	}
+	createAnswerJson(writer, statusCode, marshalledRates)
+}
+
+func CreateAnswerQuoteJson(writer http.ResponseWriter, statusCode int, quote balance_models.Quote) {
+	marshalledQuote, err := json.Marshal(quote)
+	if err != nil {
+		logger.Errorf("Error marhalling json: %v", err)
+	}
+	createAnswerJson(writer, statusCode, marshalledQuote)
+}
+
+// StatusRecorder keeps the status a handler answered with, flushes are passed on so that streamed answers
+// reach the connection
+
+type StatusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (recorder *StatusRecorder) WriteHeader(status int) {
+	if recorder.status == 0 {
+		recorder.status = status
+	}
+	recorder.ResponseWriter.WriteHeader(status)
+}
+
+func (recorder *StatusRecorder) Write(data []byte) (int, error) {
+	if recorder.status == 0 {
+		recorder.status = http.StatusOK
+	}
+	return recorder.ResponseWriter.Write(data)
+}
+
+func (recorder *StatusRecorder) Flush() {
+	if flusher, ok := recorder.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the connection, to extend the write deadline of streamed answers
+
+func (recorder *StatusRecorder) Unwrap() http.ResponseWriter {
+	return recorder.ResponseWriter
+}
+
+// the status answered, handlers that wrote nothing answered 200
+
+func (recorder *StatusRecorder) Status() int {
+	if recorder.status == 0 {
+		return http.StatusOK
+	}
+	return recorder.status
+}
@@ -0,0 +1,43 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"github.com/google/logger"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// serves until SIGINT or SIGTERM, then stops accepting connections and waits for the requests in flight
+// for at most shutdownTimeout, the connections still open after that are closed
+
+func Serve(server *http.Server, listener net.Listener, shutdownTimeout time.Duration) error {
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(stop)
+
+	served := make(chan error, 1)
+	go func() {
+		served <- server.Serve(listener)
+	}()
+
+	select {
+	case err := <-served:
+		return err
+	case received := <-stop:
+		logger.Infof("Received %v, shutting down", received)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	err := server.Shutdown(ctx)
+	if err != nil {
+		server.Close()
+		return fmt.Errorf("Failed to drain connections: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package utils
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"syscall"
+	"testing"
+	"time"
+)
+
+type answer struct {
+	status int
+	body   string
+	err    error
+}
+
+func startServe(t *testing.T, handler http.HandlerFunc, shutdownTimeout time.Duration) (string, chan error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Couldn't listen: %v", err)
+	}
+	served := make(chan error, 1)
+	go func() {
+		served <- Serve(&http.Server{Handler: handler}, listener, shutdownTimeout)
+	}()
+	return "http://" + listener.Addr().String(), served
+}
+
+func get(address string) chan answer {
+	answers := make(chan answer, 1)
+	go func() {
+		resp, err := http.Get(address)
+		if err != nil {
+			answers <- answer{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, err := ioutil.ReadAll(resp.Body)
+		answers <- answer{resp.StatusCode, string(body), err}
+	}()
+	return answers
+}
+
+func TestServe(t *testing.T) {
+	t.Run("DrainsSlowRequest", func(t *testing.T) {
+		started := make(chan struct{})
+		address, served := startServe(t, func(writer http.ResponseWriter, req *http.Request) {
+			close(started)
+			time.Sleep(300 * time.Millisecond)
+			writer.Write([]byte("done"))
+		}, 5*time.Second)
+
+		answers := get(address)
+		<-started
+		assert.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGTERM))
+
+		select {
+		case err := <-served:
+			assert.NoError(t, err)
+		case <-time.After(5 * time.Second):
+			t.Fatal("server didn't shut down")
+		}
+		answer := <-answers
+		assert.NoError(t, answer.err)
+		assert.Equal(t, http.StatusOK, answer.status)
+		assert.Equal(t, "done", answer.body)
+
+		_, err := http.Get(address)
+		assert.Error(t, err)
+	})
+
+	t.Run("ShutdownTimeout", func(t *testing.T) {
+		started := make(chan struct{})
+		release := make(chan struct{})
+		defer close(release)
+		address, served := startServe(t, func(writer http.ResponseWriter, req *http.Request) {
+			close(started)
+			<-release
+		}, 50*time.Millisecond)
+
+		answers := get(address)
+		<-started
+		assert.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGINT))
+
+		select {
+		case err := <-served:
+			assert.Error(t, err)
+		case <-time.After(5 * time.Second):
+			t.Fatal("server didn't shut down")
+		}
+		assert.Error(t, (<-answers).err)
+	})
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/saskamegaprogrammist/userBalanceService/repository"
+)
+
+const verifyUsage = "usage: userBalanceService [flags] verify"
+
+// runs the verify subcommand, that checks the wallet balances against the journal. The check aggregates
+// all postings, so it is run on demand instead of on every start. SIGINT and SIGTERM cancel it
+
+func runVerify(args []string) error {
+	if len(args) != 0 {
+		return errors.New(verifyUsage)
+	}
+	ctx, stop := interruptibleContext()
+	defer stop()
+
+	err := repository.GetBalanceRepo().VerifyBalances(ctx)
+	if err != nil {
+		return fmt.Errorf("ledger verification failed: %v", err)
+	}
+	fmt.Println("ledger is balanced")
+	return nil
+}